@@ -0,0 +1,134 @@
+//go:build cgo
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/naotama2002/dalv/internal/config"
+	"github.com/naotama2002/dalv/internal/duckdb"
+	"github.com/naotama2002/dalv/pkg/utils"
+)
+
+// 以下のテストは testdata/alb のALBログを実際にDuckDBで読み込みます
+// DuckDBのCLIがインストールされている場合はCLIを、なければ組み込みのDuckDBを使用します
+
+// fixtureDir はテスト用のALBログのディレクトリです (2ファイル、7リクエスト)
+const fixtureDir = "testdata/alb/"
+
+// runIntegration はDuckDBのエンジンを使用して dalv を実行し、標準出力を返します
+func runIntegration(t *testing.T, args ...string) (string, string) {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("DuckDBを使用するテストは -short では実行しません")
+	}
+
+	var stdout, stderr bytes.Buffer
+	a := &app{
+		logger:    utils.NewLoggerWithWriter(utils.INFO, &stderr),
+		stdin:     strings.NewReader(""),
+		stdout:    &stdout,
+		newEngine: duckdb.NewEngine,
+	}
+	if code := a.run(args); code != 0 {
+		t.Fatalf("run(%v): expected exit code 0, got %d: %s", args, code, stderr.String())
+	}
+	return stdout.String(), stderr.String()
+}
+
+func TestIntegrationQuery(t *testing.T) {
+	out, stderr := runIntegration(t, "-t", "alb_logs", "--output-format", "csv",
+		"-q", "SELECT elb_status_code, count(*) AS requests FROM alb_logs GROUP BY 1 ORDER BY 1", fixtureDir)
+
+	// ログ形式は1行目から自動判定する
+	if !strings.Contains(stderr, "ログ形式を自動判定しました: alb") {
+		t.Errorf("Expected format to be detected as alb, got %s", stderr)
+	}
+	expected := "elb_status_code,requests\n200,3\n301,1\n404,1\n500,1\n502,1\n"
+	if out != expected {
+		t.Errorf("Expected output %q, got %q", expected, out)
+	}
+}
+
+func TestIntegrationDerivedColumns(t *testing.T) {
+	// ターゲットに到達しなかったリクエストのセンチネル値はNULLになる
+	out, _ := runIntegration(t, "-t", "alb_logs", "--format", "alb", "--output-format", "csv",
+		"-q", "SELECT http_method, url_path, url_query, client_port, target_port, target_processing_time FROM alb_logs WHERE elb_status_code = 502", fixtureDir)
+
+	expected := "http_method,url_path,url_query,client_port,target_port,target_processing_time\nGET,/api/items,id=2,2817,,\n"
+	if out != expected {
+		t.Errorf("Expected output %q, got %q", expected, out)
+	}
+}
+
+func TestIntegrationCache(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "alb.duckdb")
+	args := []string{"--db", dbPath, "-t", "alb_logs", "--format", "alb", "--output-format", "csv", "-q", "SELECT count(*) AS requests FROM alb_logs", fixtureDir}
+
+	out, stderr := runIntegration(t, args...)
+	if out != "requests\n7\n" {
+		t.Errorf("Expected 7 requests, got %q", out)
+	}
+	if !strings.Contains(stderr, "2 件のオブジェクトのうち 0 件を再利用し、2 件を新たに読み込みます") {
+		t.Errorf("Expected all objects to be loaded, got %s", stderr)
+	}
+
+	// 2回目は読み込み済みのオブジェクトを再利用する
+	out, stderr = runIntegration(t, args...)
+	if out != "requests\n7\n" {
+		t.Errorf("Expected 7 requests from cache, got %q", out)
+	}
+	if !strings.Contains(stderr, "2 件のオブジェクトのうち 2 件を再利用し、0 件を新たに読み込みます") {
+		t.Errorf("Expected all objects to be reused, got %s", stderr)
+	}
+}
+
+func TestIntegrationExport(t *testing.T) {
+	dir := t.TempDir()
+	runIntegration(t, "export", "--to", dir, "--format", "alb", fixtureDir)
+
+	// タイムスタンプの日付でパーティション分割する
+	files, err := filepath.Glob(filepath.Join(dir, "date=2025-03-03", "*.parquet"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("Expected parquet files in date=2025-03-03, got %v (%v)", files, err)
+	}
+
+	out, _ := runIntegration(t, "--format", "alb", "--output-format", "csv",
+		"-q", "SELECT count(*) AS requests FROM read_parquet('"+filepath.Join(dir, "**", "*.parquet")+"')", fixtureDir)
+	if out != "requests\n7\n" {
+		t.Errorf("Expected 7 exported requests, got %q", out)
+	}
+}
+
+func TestIntegrationReport(t *testing.T) {
+	out, _ := runIntegration(t, "report", "status-codes", "--format", "alb", "--output-format", "markdown", fixtureDir)
+	for _, expected := range []string{"status-codes", "502"} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("Expected report to contain %q, got:\n%s", expected, out)
+		}
+	}
+}
+
+func TestIntegrationS3Endpoint(t *testing.T) {
+	// MinIOなどのS3互換のストレージに testdata/alb のログを alb/ 以下に配置して実行する
+	// 例: DALV_TEST_S3_ENDPOINT=http://localhost:9000 DALV_TEST_S3_BUCKET=dalv-test AWS_ACCESS_KEY_ID=... AWS_SECRET_ACCESS_KEY=...
+	endpoint := os.Getenv("DALV_TEST_S3_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("DALV_TEST_S3_ENDPOINT が設定されていないため、S3互換のストレージを使用するテストは実行しません")
+	}
+	bucket := os.Getenv("DALV_TEST_S3_BUCKET")
+	if bucket == "" {
+		bucket = "dalv-test"
+	}
+	t.Setenv(config.EndpointEnv, endpoint)
+
+	out, _ := runIntegration(t, "--format", "alb", "--output-format", "csv",
+		"-q", "SELECT count(*) AS requests FROM alb_logs", "-t", "alb_logs", "s3://"+bucket+"/alb/*")
+	if out != "requests\n7\n" {
+		t.Errorf("Expected 7 requests, got %q", out)
+	}
+}
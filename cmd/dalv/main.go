@@ -1,12 +1,29 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net"
+	"net/http"
 	"os"
+	"os/exec"
+	"os/signal"
 	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/naotama2002/dalv/internal/chart"
 	"github.com/naotama2002/dalv/internal/cli"
 	"github.com/naotama2002/dalv/internal/duckdb"
+	"github.com/naotama2002/dalv/internal/mcp"
+	"github.com/naotama2002/dalv/internal/report"
+	"github.com/naotama2002/dalv/internal/schema"
+	"github.com/naotama2002/dalv/internal/server"
+	"github.com/naotama2002/dalv/internal/source"
 	"github.com/naotama2002/dalv/internal/validator"
 	"github.com/naotama2002/dalv/internal/version"
 	"github.com/naotama2002/dalv/pkg/utils"
@@ -16,59 +33,645 @@ func main() {
 	// バージョン情報の初期化
 	initVersion()
 
-	// ロガーの初期化
-	logger := utils.NewLogger(utils.INFO)
+	os.Exit(newApp().run(os.Args[1:]))
+}
+
+// app はコマンドライン引数の解析からDuckDBでの実行までの処理を行います
+// テストではエンジンと入出力を差し替えます
+type app struct {
+	logger *utils.Logger
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+	// stdinIsTerminal は標準入力が端末かどうかです (端末の場合のみ読み込む前に確認します)
+	stdinIsTerminal bool
+	// newEngine は名前を指定してDuckDBを実行するエンジンを作成します
+	newEngine func(name string) (duckdb.Engine, error)
+}
+
+// newApp は標準入出力とDuckDBのエンジンを使用する app を作成します
+func newApp() *app {
+	return &app{
+		logger:          utils.NewLogger(utils.INFO),
+		stdin:           os.Stdin,
+		stdout:          os.Stdout,
+		stderr:          os.Stderr,
+		stdinIsTerminal: isTerminal(os.Stdin),
+		newEngine:       duckdb.NewEngine,
+	}
+}
+
+// isTerminal はファイルが端末かどうかを返します
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// run はコマンドライン引数に従って処理を実行し、終了コードを返します
+func (a *app) run(args []string) int {
+	logger := a.logger
 	logger.Info("dalvを起動しています...")
 
 	// コマンドライン引数の解析
-	cliParser := cli.NewCLI(os.Args[1:])
-	s3Path, tableName, err := cliParser.Parse()
+	cliParser := cli.NewCLI(args)
+	opts, err := cliParser.Parse()
 	if err != nil {
 		logger.Error("コマンドライン引数の解析に失敗しました: %v", err)
-		os.Exit(1)
+		return 1
 	}
 
 	// ヘルプまたはバージョン表示の場合は終了
-	if s3Path == "" {
-		os.Exit(0)
+	if opts == nil {
+		return 0
 	}
 
-	// S3パスの検証
-	pathValidator := validator.NewS3PathValidator()
-	if err := pathValidator.ValidateS3Path(s3Path); err != nil {
-		logger.Error("S3パスの検証に失敗しました: %v", err)
-		os.Exit(1)
+	// cache サブコマンドの場合
+	if opts.Command == cli.CommandCache {
+		if err := a.runCache(opts.Cache); err != nil {
+			logger.Error("%v", err)
+			return 1
+		}
+		return 0
 	}
 
-	// DuckDBのインストール確認
-	if err := pathValidator.ValidateDuckDBInstallation(); err != nil {
-		logger.Error("DuckDBの検証に失敗しました: %v", err)
-		fmt.Println("\nDuckDBがインストールされていないようです。")
-		fmt.Println("インストール方法: https://duckdb.org/docs/installation/")
-		os.Exit(1)
+	// report サブコマンドの場合はログを読み込む前にレポートを確認
+	var rep *report.Report
+	if opts.Command == cli.CommandReport {
+		library, err := loadReports(opts.Report)
+		if err != nil {
+			logger.Error("%v", err)
+			return 1
+		}
+		if opts.Report.List {
+			printReports(a.stdout, library)
+			return 0
+		}
+		rep, err = library.Get(opts.Report.Name)
+		if err != nil {
+			logger.Error("%v", err)
+			return 1
+		}
 	}
+	paths, tableName := opts.Paths, opts.TableName
 
-	// DuckDBの実行
-	executor := duckdb.NewExecutor()
-	if err := executor.CheckDuckDBInstallation(); err != nil {
+	// パスの検証
+	pathValidator := validator.NewS3PathValidatorWithEndpoint(opts.S3.Endpoint)
+	for _, p := range paths {
+		if err := pathValidator.ValidatePath(p); err != nil {
+			logger.Error("パスの検証に失敗しました: %v", err)
+			return 1
+		}
+	}
+
+	// テーブル名の検証
+	if tableName != "" {
+		if err := pathValidator.ValidateTableName(tableName); err != nil {
+			logger.Error("テーブル名の検証に失敗しました: %v", err)
+			return 1
+		}
+	}
+
+	// --dry-run の場合はDuckDBを起動せずに実行計画を表示して終了
+	if opts.DryRun {
+		if err := a.printPlan(paths, tableName, opts); err != nil {
+			logger.Error("%v", err)
+			return 1
+		}
+		return 0
+	}
+
+	// DuckDBのエンジンの準備
+	engine, err := a.engine(opts.Engine)
+	if err != nil {
 		logger.Error("DuckDBの検証に失敗しました: %v", err)
-		fmt.Println("\nDuckDBがインストールされていないようです。")
-		fmt.Println("インストール方法: https://duckdb.org/docs/installation/")
-		os.Exit(1)
+		a.printEngineHint(opts.Engine)
+		return 1
+	}
+
+	// 読み込むオブジェクトの確認
+	summary, err := a.preflight(engine, paths, opts)
+	if err != nil {
+		logger.Error("%v", err)
+		return 1
+	}
+	// 一致するオブジェクトがないパスを含むとDuckDBの読み込みが失敗するため除外する
+	if len(summary.EmptyPaths) > 0 {
+		logger.Warn("一致するオブジェクトがないパスを除外します: %s", strings.Join(summary.EmptyPaths, ", "))
+		paths = summary.NonEmptyPaths(paths)
+	}
+
+	// ログ形式の自動判定
+	format := opts.Format
+	if format == nil {
+		detection := schema.Detect(paths[0], func() (string, error) {
+			logger.Info("ログの1行目からログ形式を判定しています...")
+			return duckdb.NewExecutorWithConfig(duckdb.Config{Engine: engine, S3: opts.S3}).SampleFirstLine(paths[0])
+		})
+		format = detection.Format
+		logger.Info("ログ形式を自動判定しました: %s (%s)", format.Name, detection.Reason)
+	}
+
+	executor := duckdb.NewExecutorWithConfig(duckdb.Config{
+		Format:           format,
+		TimeRange:        opts.TimeRange,
+		DBPath:           opts.DBPath,
+		Output:           opts.Output,
+		NoDerivedColumns: opts.NoDerivedColumns,
+		Raw:              opts.Raw,
+		Engine:           engine,
+		Stdout:           a.stdout,
+		S3:               opts.S3,
+	})
+
+	// キャッシュの確認
+	if opts.DBPath != "" {
+		logger.Info("キャッシュを確認しています: %s", opts.DBPath)
+		stats, err := executor.PrepareCacheForObjects(summary.Objects)
+		if err != nil {
+			logger.Error("%v", err)
+			return 1
+		}
+		logger.Info("キャッシュ: %d 件のオブジェクトのうち %d 件を再利用し、%d 件を新たに読み込みます", len(stats.Objects), stats.CachedCount(), len(stats.NewObjects))
+		if stats.CachedCount() > 0 {
+			logger.Info("キャッシュはパス・サイズ・更新日時で照合しています。同じ秒に同じサイズで上書きされたオブジェクトは検出できないため、その場合は dalv cache prune --db %s --all を実行してください", opts.DBPath)
+		}
+	}
+
+	// テーブル名は1度だけ決定し、--print-sql の表示とサブコマンドの実行で同じ名前を使用する
+	tableName = effectiveTableName(opts.Command, tableName, format, executor.SQLGenerator())
+	if opts.PrintSQL {
+		fmt.Fprintf(a.stderr, "%s\n", executor.CompleteSQL(paths, tableName))
 	}
 
 	logger.Info("DuckDBを起動しています...")
-	logger.Info("S3パス: %s", s3Path)
-	if tableName != "" {
-		logger.Info("テーブル名: %s", tableName)
+	for _, p := range paths {
+		logger.Info("読み込むパス: %s", p)
+	}
+	logger.Info("ログ形式: %s", format.Description)
+	if opts.TimeRange != nil {
+		logger.Info("時間範囲: %s", opts.TimeRange)
+	}
+	logger.Info("テーブル名: %s", tableName)
+
+	// export サブコマンドの場合はエクスポートして終了
+	if opts.Command == cli.CommandExport {
+		logger.Info("エクスポート先: %s", opts.Export.Destination)
+		count, err := executor.Export(paths, tableName, duckdb.ExportConfig{
+			Destination: opts.Export.Destination,
+			PartitionBy: opts.Export.PartitionBy,
+			Compression: opts.Export.Compression,
+		})
+		if err != nil {
+			logger.Error("%v", err)
+			return exitCode(err)
+		}
+		logger.Info("%d 行をエクスポートしました: %s", count, opts.Export.Destination)
+		return 0
+	}
+
+	// report サブコマンドの場合はレポートを実行して終了
+	if opts.Command == cli.CommandReport {
+		if err := runReport(executor, rep, paths, tableName, format, opts); err != nil {
+			logger.Error("%v", err)
+			return exitCode(err)
+		}
+		if opts.Output.Path != "" {
+			logger.Info("レポートを出力しました: %s", opts.Output.Path)
+		}
+		return 0
+	}
+
+	// latency サブコマンドの場合はレイテンシを集計して終了
+	if opts.Command == cli.CommandLatency {
+		if err := executor.ExecuteLatency(paths, tableName, duckdb.LatencyConfig{
+			Dimensions:  opts.Latency.Dimensions,
+			Interval:    opts.Latency.Interval,
+			Percentiles: opts.Latency.Percentiles,
+			Limit:       opts.Latency.Limit,
+		}); err != nil {
+			logger.Error("%v", err)
+			return exitCode(err)
+		}
+		if opts.Output.Path != "" {
+			logger.Info("結果を出力しました: %s", opts.Output.Path)
+		}
+		return 0
 	}
 
-	if err := executor.ExecuteDuckDB(s3Path, tableName); err != nil {
+	// chart サブコマンドの場合はグラフを表示して終了
+	if opts.Command == cli.CommandChart {
+		data, err := executor.ChartData(paths, tableName, duckdb.ChartConfig{
+			Interval:  opts.Chart.Interval,
+			SplitBy:   opts.Chart.SplitBy,
+			MaxSeries: opts.Chart.Top,
+		})
+		if err != nil {
+			logger.Error("%v", err)
+			return exitCode(err)
+		}
+		chart.Render(a.stdout, chart.BuildSeries(data), data.Interval, opts.Chart.Display)
+		return 0
+	}
+
+	// serve サブコマンドの場合はHTTP APIサーバーを起動
+	if opts.Command == cli.CommandServe {
+		if err := a.runServe(executor, paths, tableName, format, opts.Serve); err != nil {
+			logger.Error("%v", err)
+			return exitCode(err)
+		}
+		return 0
+	}
+
+	// mcp サブコマンドの場合は標準入出力でMCPサーバーを起動
+	if opts.Command == cli.CommandMCP {
+		if err := a.runMCP(executor, paths, tableName, format, opts.MCP); err != nil {
+			logger.Error("%v", err)
+			return exitCode(err)
+		}
+		return 0
+	}
+
+	// クエリが指定された場合は実行して終了
+	if !opts.Interactive() {
+		if err := executor.ExecuteQuery(paths, tableName, opts.Query); err != nil {
+			logger.Error("%v", err)
+			return exitCode(err)
+		}
+		if opts.Output.Path != "" {
+			logger.Info("クエリ結果を出力しました: %s", opts.Output.Path)
+		}
+		return 0
+	}
+
+	if err := executor.ExecuteDuckDB(paths, tableName); err != nil {
 		logger.Error("DuckDBの実行に失敗しました: %v", err)
-		os.Exit(1)
+		return 1
 	}
 
 	logger.Info("正常に終了しました")
+	return 0
+}
+
+// preflight はログを読み込む前にパスに一致するオブジェクトを一覧し、件数と合計サイズを確認します
+// 合計サイズが --confirm-size を超える場合は、標準入力が端末であれば続行するかを確認します
+func (a *app) preflight(engine duckdb.Engine, paths []string, opts *cli.Options) (*duckdb.ObjectSummary, error) {
+	a.logger.Info("読み込むオブジェクトを確認しています...")
+	summary, err := duckdb.NewExecutorWithConfig(duckdb.Config{Engine: engine, S3: opts.S3}).Preflight(paths)
+	if err != nil {
+		return nil, err
+	}
+	a.logger.Info("読み込むオブジェクト: %s", summary)
+
+	if opts.ConfirmSize <= 0 || summary.TotalSize <= opts.ConfirmSize || opts.AssumeYes {
+		return summary, nil
+	}
+	exceeded := fmt.Sprintf("読み込むオブジェクトの合計サイズ (%s) が --confirm-size (%s) を超えています",
+		duckdb.FormatSize(summary.TotalSize), duckdb.FormatSize(opts.ConfirmSize))
+	notConfirmed := fmt.Errorf("%s。読み込む場合は --yes を指定するか、--confirm-size を大きくしてください", exceeded)
+	// mcp サブコマンドは標準入力をMCPのメッセージに使用するため確認できない
+	if !a.stdinIsTerminal || opts.Command == cli.CommandMCP {
+		return nil, notConfirmed
+	}
+
+	fmt.Fprintf(a.stderr, "%s。続行しますか? [y/N]: ", exceeded)
+	answer, err := bufio.NewReader(a.stdin).ReadString('\n')
+	if err != nil {
+		// /dev/null などの入力のない端末以外のデバイスは確認できない
+		if errors.Is(err, io.EOF) && answer == "" {
+			fmt.Fprintln(a.stderr)
+			return nil, notConfirmed
+		}
+		if !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("確認の入力の読み込みに失敗しました: %w", err)
+		}
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return summary, nil
+	}
+	return nil, fmt.Errorf("読み込みを中止しました")
+}
+
+// printPlan はDuckDBを起動せずに、生成したSQLと読み込むパス、ログ形式、推定オブジェクト数を表示します
+// 実行計画はSQLのコメントとして出力するため、そのままSQLファイルとして保存できます
+func (a *app) printPlan(paths []string, tableName string, opts *cli.Options) error {
+	format, reason := opts.Format, "--format で指定"
+	if format == nil {
+		detection := schema.Detect(paths[0], localSampler(paths[0]))
+		format, reason = detection.Format, detection.Reason
+	}
+
+	// 拡張機能の確認にもDuckDBを起動しないため、派生カラムの型は拡張機能がない場合の型になる
+	executor := duckdb.NewExecutorWithConfig(duckdb.Config{
+		Format:           format,
+		TimeRange:        opts.TimeRange,
+		NoDerivedColumns: opts.NoDerivedColumns,
+		Raw:              opts.Raw,
+		Extensions:       []string{},
+		S3:               opts.S3,
+	})
+	tableName = effectiveTableName(opts.Command, tableName, format, executor.SQLGenerator())
+	estimate, err := estimateObjects(paths)
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	b.WriteString("-- 実行計画 (--dry-run のためDuckDBは起動していません)\n")
+	fmt.Fprintf(&b, "-- ログ形式: %s (%s)\n", format.Name, reason)
+	b.WriteString("-- 読み込むパス:\n")
+	for _, p := range paths {
+		fmt.Fprintf(&b, "--   %s\n", p)
+	}
+	fmt.Fprintf(&b, "-- 推定オブジェクト数: %s\n", estimate)
+	if opts.TimeRange != nil {
+		fmt.Fprintf(&b, "-- 時間範囲: %s\n", opts.TimeRange)
+	}
+	fmt.Fprintf(&b, "-- テーブル名: %s\n", tableName)
+	if opts.DBPath != "" {
+		fmt.Fprintf(&b, "-- キャッシュ: %s (実行時は読み込み済みのオブジェクトを再利用します)\n", opts.DBPath)
+	}
+	fmt.Fprintf(&b, "\n%s", executor.CompleteSQL(paths, tableName))
+
+	_, err = io.WriteString(a.stdout, b.String())
+	return err
+}
+
+// effectiveTableName はサブコマンドが実際に使用するテーブル名を返します
+// テーブル名が指定されていない場合、report, serve, mcp はログ形式のテーブル名のプレフィックスを、
+// それ以外は現在時刻から生成したテーブル名を使用します
+func effectiveTableName(command string, tableName string, format *schema.Format, generator *duckdb.SQLGenerator) string {
+	if tableName != "" {
+		return tableName
+	}
+	switch command {
+	case cli.CommandReport, cli.CommandServe, cli.CommandMCP:
+		return format.TableNamePrefix
+	}
+	return generator.GenerateTableName()
+}
+
+// localSampler はローカルのパスに一致する最初のファイルから1行目を取得する関数を返します
+// リモートのパスはDuckDBを起動しないと取得できないため nil を返し、パスのみで判定します
+func localSampler(p string) func() (string, error) {
+	if source.IsRemote(p) {
+		return nil
+	}
+	return func() (string, error) {
+		matches, err := source.MatchLocal(p)
+		if err != nil {
+			return "", err
+		}
+		if len(matches) == 0 {
+			return "", fmt.Errorf("パスに一致するファイルがありません: %s", p)
+		}
+		return source.FirstLine(matches[0])
+	}
+}
+
+// estimateObjects は読み込むオブジェクト数の見積もりを返します
+// ローカルのファイルは数えられますが、S3のオブジェクトはDuckDBを起動しないと一覧できないため不明とします
+func estimateObjects(paths []string) (string, error) {
+	count, local := 0, 0
+	for _, p := range paths {
+		if source.IsRemote(p) {
+			continue
+		}
+		matches, err := source.MatchLocal(p)
+		if err != nil {
+			return "", err
+		}
+		count += len(matches)
+		local++
+	}
+	switch {
+	case local == len(paths):
+		return fmt.Sprintf("%d 件", count), nil
+	case local > 0:
+		return fmt.Sprintf("ローカルのファイル %d 件とS3のオブジェクト (S3は一覧していないため件数は不明)", count), nil
+	}
+	return "不明 (S3のオブジェクトは一覧していません。--dry-run を外すと読み込む前に件数と合計サイズを表示します)", nil
+}
+
+// runCache は cache サブコマンドを実行します
+func (a *app) runCache(opts *cli.CacheOptions) error {
+	engine, err := a.engine(opts.Engine)
+	if err != nil {
+		return err
+	}
+
+	executor := duckdb.NewExecutorWithConfig(duckdb.Config{DBPath: opts.DBPath, Engine: engine})
+	switch opts.Action {
+	case cli.CacheActionList:
+		return executor.ListCache(a.stdout)
+	case cli.CacheActionPrune:
+		pruned, err := executor.PruneCache(opts.LoadedBefore)
+		if err != nil {
+			return err
+		}
+		a.logger.Info("キャッシュから %d 件のオブジェクトを削除しました", pruned)
+	}
+	return nil
+}
+
+// engine はDuckDBを実行するエンジンを作成し、実行できるかを確認します
+func (a *app) engine(name string) (duckdb.Engine, error) {
+	engine, err := a.newEngine(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := engine.Check(); err != nil {
+		return nil, err
+	}
+	a.logger.Debug("DuckDBのエンジン: %s", engine.Name())
+	return engine, nil
+}
+
+// printEngineHint はエンジンを使用できない場合の対処方法を標準エラー出力に表示します
+// mcp サブコマンドは標準出力をMCPのメッセージに使用するため、標準出力には書き込みません
+func (a *app) printEngineHint(name string) {
+	if name == duckdb.EngineEmbedded {
+		return
+	}
+	fmt.Fprintln(a.stderr, "\nDuckDBがインストールされていないようです。")
+	fmt.Fprintln(a.stderr, "インストール方法: https://duckdb.org/docs/installation/")
+	fmt.Fprintln(a.stderr, "CGO_ENABLED=1 でビルドしたdalvでは --engine embedded で組み込みのDuckDBを使用できます")
+}
+
+// loadReports は組み込みレポートとユーザー定義のレポートを読み込みます
+// --reports-dir が指定されていない場合はデフォルトのディレクトリが存在するときのみ読み込みます
+func loadReports(opts *cli.ReportOptions) (*report.Library, error) {
+	library, err := report.NewLibrary()
+	if err != nil {
+		return nil, err
+	}
+
+	dir := opts.Dir
+	if dir == "" {
+		dir = report.DefaultDir()
+		if _, err := os.Stat(dir); dir == "" || err != nil {
+			return library, nil
+		}
+	}
+	if err := library.LoadDir(dir); err != nil {
+		return nil, err
+	}
+	return library, nil
+}
+
+// printReports はレポートの一覧を表示します
+func printReports(w io.Writer, library *report.Library) {
+	for _, r := range library.Reports() {
+		formats := "all"
+		if len(r.Formats) > 0 {
+			formats = strings.Join(r.Formats, ",")
+		}
+		fmt.Fprintf(w, "%-18s %-8s %s\n", r.Name, formats, r.Description)
+		for _, p := range r.Params {
+			value := p.Default
+			if strings.Contains(value, " ") {
+				value = fmt.Sprintf("%q", value)
+			}
+			fmt.Fprintf(w, "%-18s %-8s   --param %s=%s  (%s) %s\n", "", "", p.Name, value, p.Type, p.Description)
+		}
+		if r.Source != "" {
+			fmt.Fprintf(w, "%-18s %-8s   (%s)\n", "", "", r.Source)
+		}
+	}
+}
+
+// runReport はレポートのパラメータを置き換えて実行します
+func runReport(executor *duckdb.Executor, rep *report.Report, paths []string, tableName string, format *schema.Format, opts *cli.Options) error {
+	if !rep.Supports(format.Name) {
+		return fmt.Errorf("レポート %s は %s ログに対応していません (対応形式: %s)", rep.Name, format.Name, strings.Join(rep.Formats, ", "))
+	}
+
+	rendered, err := rep.Render(tableName, opts.Report.Params)
+	if err != nil {
+		return err
+	}
+	sections := make([]duckdb.ReportSection, 0, len(rendered))
+	for _, s := range rendered {
+		sections = append(sections, duckdb.ReportSection{Title: s.Title, SQL: s.SQL})
+	}
+
+	title := rep.Name
+	if rep.Description != "" {
+		title = fmt.Sprintf("%s - %s", rep.Name, rep.Description)
+	}
+	return executor.ExecuteReport(paths, tableName, title, sections)
+}
+
+// runServe はログを読み込んだ後にHTTP APIサーバーを起動し、SIGINT/SIGTERMを受け取るまで待ち受けます
+func (a *app) runServe(executor *duckdb.Executor, paths []string, tableName string, format *schema.Format, opts *cli.ServeOptions) error {
+	if opts.Token == "" && !opts.IsLoopback() {
+		a.logger.Warn("--token (または %s) が指定されていないため、%s に接続できる誰でもクエリを実行できます", cli.ServeTokenEnv, opts.Listen)
+	}
+
+	db, cleanup, err := a.loadShared(executor, paths, tableName, format)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	listener, err := net.Listen("tcp", opts.Listen)
+	if err != nil {
+		return fmt.Errorf("%s で待ち受けられません: %w", opts.Listen, err)
+	}
+	httpServer := &http.Server{
+		Handler: server.NewServer(server.Config{
+			Database: db,
+			Token:    opts.Token,
+			Timeout:  opts.Timeout,
+			MaxBytes: opts.MaxBytes,
+		}),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- httpServer.Serve(listener)
+	}()
+	a.logger.Info("テーブル %s へのクエリを http://%s/query で受け付けています (Ctrl+C で終了)", db.TableName(), listener.Addr())
+
+	select {
+	case err := <-errCh:
+		return fmt.Errorf("HTTP APIサーバーが停止しました: %w", err)
+	case <-ctx.Done():
+	}
+
+	a.logger.Info("HTTP APIサーバーを停止しています...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("HTTP APIサーバーの停止に失敗しました: %w", err)
+	}
+	return nil
+}
+
+// runMCP はログを読み込んだ後に標準入出力でMCPサーバーを起動し、標準入力が閉じられるまで待ち受けます
+// 標準出力はMCPのメッセージのみに使用し、ログは標準エラー出力に出力します
+func (a *app) runMCP(executor *duckdb.Executor, paths []string, tableName string, format *schema.Format, opts *cli.MCPOptions) error {
+	db, cleanup, err := a.loadShared(executor, paths, tableName, format)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	mcpServer := mcp.NewServer(mcp.Config{
+		Database:  db,
+		Generator: executor.SQLGenerator(),
+		MaxRows:   opts.MaxRows,
+		Timeout:   opts.Timeout,
+		Version:   version.GetVersion(),
+	})
+	// 標準入力の読み込みは中断できないため、シグナルを受け取った場合は待たずに終了する
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- mcpServer.Serve(ctx, a.stdin, a.stdout)
+	}()
+	a.logger.Info("テーブル %s を調査するMCPサーバーを標準入出力で起動しました", db.TableName())
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return nil
+	}
+}
+
+// loadShared はログを読み込み、一時ディレクトリのデータベースファイルに保存します
+// 返した関数でデータベースファイルを削除します
+func (a *app) loadShared(executor *duckdb.Executor, paths []string, tableName string, format *schema.Format) (*duckdb.SharedDatabase, func(), error) {
+	dir, err := os.MkdirTemp("", "dalv-shared-")
+	if err != nil {
+		return nil, nil, fmt.Errorf("一時ディレクトリの作成に失敗しました: %w", err)
+	}
+	cleanup := func() { os.RemoveAll(dir) }
+
+	a.logger.Info("ログを読み込んでいます...")
+	db, err := executor.LoadShared(paths, tableName, filepath.Join(dir, "shared.duckdb"))
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	return db, cleanup, nil
+}
+
+// exitCode はエラーに対応する終了コードを返します
+// DuckDBのCLIが異常終了した場合はその終了コードを引き継ぎます
+func exitCode(err error) int {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() > 0 {
+		return exitErr.ExitCode()
+	}
+	return 1
 }
 
 // initVersion はバージョン情報を初期化します
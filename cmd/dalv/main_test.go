@@ -0,0 +1,531 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/naotama2002/dalv/internal/duckdb"
+	"github.com/naotama2002/dalv/internal/duckdb/duckdbtest"
+	"github.com/naotama2002/dalv/pkg/utils"
+)
+
+// clbLine はログ形式の自動判定で返すCLBログの1行目です
+const clbLine = `2025-03-03T23:39:43.945958Z my-loadbalancer 192.168.131.39:2817 10.0.0.1:80 0.000073 0.001048 0.000057 200 200 0 29 "GET http://www.example.com:80/ HTTP/1.1" "curl/7.38.0" - -`
+
+// objectsCSV はオブジェクトの一覧の取得で返すデフォルトの結果です
+const objectsCSV = "path,size,last_modified\ns3://bucket/AWSLogs/a.log.gz,1024,2025-03-03 23:40:00\n"
+
+// testApp はテスト用のエンジンと出力先を使用する app です
+type testApp struct {
+	*app
+	engine     *duckdbtest.Engine
+	engineName string
+	stdout     bytes.Buffer
+	stderr     bytes.Buffer
+	// objects はオブジェクトの一覧の取得で返すCSVです
+	objects string
+}
+
+// newTestApp は呼び出しを記録するエンジンを使用する app を作成します
+func newTestApp() *testApp {
+	ta := &testApp{engine: duckdbtest.NewEngine(), objects: objectsCSV}
+	ta.app = &app{
+		logger: utils.NewLoggerWithWriter(utils.INFO, &ta.stderr),
+		stdin:  strings.NewReader(""),
+		stdout: &ta.stdout,
+		stderr: &ta.stderr,
+		newEngine: func(name string) (duckdb.Engine, error) {
+			ta.engineName = name
+			return ta.engine, nil
+		},
+	}
+	ta.respond(nil)
+	return ta
+}
+
+// respond はオブジェクトの一覧の取得に objects を返し、それ以外の呼び出しに f の結果を返すように設定します
+func (ta *testApp) respond(f func(call duckdbtest.Call) duckdbtest.Response) {
+	ta.engine.Respond = func(call duckdbtest.Call) duckdbtest.Response {
+		if strings.Contains(call.Request.Query, "read_blob(") {
+			return duckdbtest.Response{Output: ta.objects}
+		}
+		if f == nil {
+			return duckdbtest.Response{}
+		}
+		return f(call)
+	}
+}
+
+func TestRunQuery(t *testing.T) {
+	ta := newTestApp()
+	ta.respond(func(call duckdbtest.Call) duckdbtest.Response {
+		if strings.Contains(call.Script(), "SELECT count(*) FROM alb_logs") {
+			return duckdbtest.Response{Output: "42\n"}
+		}
+		return duckdbtest.Response{}
+	})
+
+	code := ta.run([]string{"--engine", "embedded", "--format", "alb", "-t", "alb_logs", "-q", "SELECT count(*) FROM alb_logs", "s3://bucket/AWSLogs/*.log.gz"})
+	if code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, ta.stderr.String())
+	}
+	if ta.engineName != "embedded" {
+		t.Errorf("Expected engine 'embedded', got '%s'", ta.engineName)
+	}
+	if ta.stdout.String() != "42\n" {
+		t.Errorf("Expected query result on stdout, got %q", ta.stdout.String())
+	}
+
+	// 認証情報の設定、テーブルの作成、クエリの順に1つのスクリプトで実行する
+	call, ok := ta.engine.Last()
+	if !ok || call.Method != duckdbtest.MethodRun {
+		t.Fatalf("Expected last call to be run, got %+v", call)
+	}
+	script := call.Script()
+	secret := strings.Index(script, "CREATE SECRET")
+	table := strings.Index(script, "CREATE TABLE alb_logs")
+	query := strings.Index(script, "SELECT count(*) FROM alb_logs")
+	if secret < 0 || table < secret || query < table {
+		t.Errorf("Unexpected script order:\n%s", script)
+	}
+	if !strings.Contains(script, "'s3://bucket/AWSLogs/*.log.gz'") {
+		t.Errorf("Expected script to read the S3 path:\n%s", script)
+	}
+}
+
+func TestRunWithS3Config(t *testing.T) {
+	ta := newTestApp()
+
+	code := ta.run([]string{"--format", "alb", "-q", "SELECT 1", "--profile", "prod", "--region", "us-west-2",
+		"--role-arn", "arn:aws:iam::123456789012:role/log-reader", "--external-id", "ext-1234",
+		"s3://bucket/AWSLogs/*.log.gz"})
+	if code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, ta.stderr.String())
+	}
+
+	call, _ := ta.engine.Last()
+	for _, expected := range []string{"PROFILE 'prod'", "REGION 'us-west-2'", "ASSUME_ROLE_ARN 'arn:aws:iam::123456789012:role/log-reader'"} {
+		if !strings.Contains(call.Script(), expected) {
+			t.Errorf("Expected script to contain %q:\n%s", expected, call.Script())
+		}
+	}
+	// 外部IDはログに出力しない
+	if strings.Contains(ta.stderr.String(), "ext-1234") {
+		t.Errorf("Expected external ID not to be logged, got %s", ta.stderr.String())
+	}
+}
+
+func TestRunWithS3Endpoint(t *testing.T) {
+	ta := newTestApp()
+
+	// S3互換のエンドポイントではAWSのバケット名の規則を適用しない
+	code := ta.run([]string{"--format", "alb", "-q", "SELECT 1", "--s3-endpoint", "http://localhost:9000", "s3://Dalv_Test"})
+	if code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, ta.stderr.String())
+	}
+	call, _ := ta.engine.Last()
+	for _, expected := range []string{"ENDPOINT 'localhost:9000'", "URL_STYLE 'path'", "USE_SSL false"} {
+		if !strings.Contains(call.Script(), expected) {
+			t.Errorf("Expected script to contain %q:\n%s", expected, call.Script())
+		}
+	}
+
+	// AWSのS3ではバケット名を検証する
+	ta = newTestApp()
+	if code := ta.run([]string{"--format", "alb", "-q", "SELECT 1", "s3://localhost:9000/dalv-test/"}); code != 1 {
+		t.Errorf("Expected exit code 1, got %d", code)
+	}
+	if !strings.Contains(ta.stderr.String(), "--s3-endpoint") {
+		t.Errorf("Expected hint for --s3-endpoint, got %s", ta.stderr.String())
+	}
+}
+
+func TestRunDetectsFormat(t *testing.T) {
+	ta := newTestApp()
+	ta.respond(func(call duckdbtest.Call) duckdbtest.Response {
+		if strings.Contains(call.Script(), "{'line': 'VARCHAR'}") {
+			return duckdbtest.Response{Output: clbLine + "\n"}
+		}
+		return duckdbtest.Response{}
+	})
+
+	// ローカルのパスはキーのレイアウトから判定できないため、1行目から判定する
+	code := ta.run([]string{"-t", "logs", "-q", "SELECT 1", "testdata/alb/first.log"})
+	if code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, ta.stderr.String())
+	}
+	call, _ := ta.engine.Last()
+	if !strings.Contains(call.Script(), "backend_ip_port") {
+		t.Errorf("Expected table to be created with CLB columns:\n%s", call.Script())
+	}
+	if strings.Contains(call.Script(), "CREATE SECRET") {
+		t.Errorf("Expected no S3 credentials for local path:\n%s", call.Script())
+	}
+}
+
+func TestRunInteractive(t *testing.T) {
+	ta := newTestApp()
+
+	code := ta.run([]string{"--format", "alb", "-t", "alb_logs", "s3://bucket/AWSLogs/*.log.gz"})
+	if code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, ta.stderr.String())
+	}
+	call, _ := ta.engine.Last()
+	if call.Method != duckdbtest.MethodInteractive {
+		t.Fatalf("Expected interactive console, got %s", call.Method)
+	}
+	if !strings.Contains(call.Request.Script, "CREATE TABLE alb_logs") {
+		t.Errorf("Expected table to be created before console:\n%s", call.Request.Script)
+	}
+}
+
+func TestRunInteractiveWithExternalID(t *testing.T) {
+	ta := newTestApp()
+
+	// 外部IDを含むスクリプトはディスクに書き込まないようにエンジンに伝える
+	code := ta.run([]string{"--format", "alb", "--role-arn", "arn:aws:iam::123456789012:role/log-reader", "--external-id", "ext-1234", "s3://bucket/AWSLogs/*.log.gz"})
+	if code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, ta.stderr.String())
+	}
+	call, _ := ta.engine.Last()
+	if call.Method != duckdbtest.MethodInteractive || !call.Request.Sensitive {
+		t.Errorf("Expected sensitive interactive request, got %s, %v", call.Method, call.Request.Sensitive)
+	}
+
+	// ローカルのパスのみの場合は認証情報を設定しない
+	ta = newTestApp()
+	if code := ta.run([]string{"--format", "alb", "--role-arn", "arn:aws:iam::123456789012:role/log-reader", "--external-id", "ext-1234", "testdata/alb/first.log"}); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, ta.stderr.String())
+	}
+	if call, _ := ta.engine.Last(); call.Request.Sensitive {
+		t.Error("Expected request without S3 credentials not to be sensitive")
+	}
+}
+
+func TestRunQueryError(t *testing.T) {
+	ta := newTestApp()
+	ta.respond(func(call duckdbtest.Call) duckdbtest.Response {
+		if strings.Contains(call.Script(), "SELECT * FROM missing") {
+			return duckdbtest.Response{Err: &duckdb.QueryError{Message: "Catalog Error: Table with name missing does not exist!"}}
+		}
+		return duckdbtest.Response{}
+	})
+
+	code := ta.run([]string{"--format", "alb", "-q", "SELECT * FROM missing", "s3://bucket/AWSLogs/*.log.gz"})
+	if code != 1 {
+		t.Errorf("Expected exit code 1, got %d", code)
+	}
+	if !strings.Contains(ta.stderr.String(), "Table with name missing does not exist") {
+		t.Errorf("Expected query error to be logged, got %q", ta.stderr.String())
+	}
+}
+
+func TestRunRejectsInvalidInput(t *testing.T) {
+	testCases := [][]string{
+		{"-q", "SELECT 1", "https://example.com/logs/*.log.gz"},
+		{"-q", "SELECT 1", "testdata/alb/missing.log"},
+		{"--output-format", "xml", "-q", "SELECT 1", "testdata/alb/first.log"},
+		{"-t", "x; DROP TABLE y", "-q", "SELECT 1", "testdata/alb/first.log"},
+	}
+
+	for _, args := range testCases {
+		ta := newTestApp()
+		if code := ta.run(args); code != 1 {
+			t.Errorf("run(%v): expected exit code 1, got %d", args, code)
+		}
+		// 入力の検証に失敗した場合はDuckDBを起動しない
+		if calls := ta.engine.Calls(); len(calls) != 0 {
+			t.Errorf("run(%v): expected no engine calls, got %d", args, len(calls))
+		}
+	}
+}
+
+func TestRunEngineUnavailable(t *testing.T) {
+	ta := newTestApp()
+	ta.engine.CheckErr = errors.New("duckdb: executable file not found in $PATH")
+
+	code := ta.run([]string{"-q", "SELECT 1", "s3://bucket/AWSLogs/*.log.gz"})
+	if code != 1 {
+		t.Errorf("Expected exit code 1, got %d", code)
+	}
+	if !strings.Contains(ta.stderr.String(), "--engine embedded") {
+		t.Errorf("Expected hint for embedded engine, got %q", ta.stderr.String())
+	}
+	// mcp サブコマンドは標準出力をMCPのメッセージに使用するため、案内は標準エラー出力に表示する
+	if ta.stdout.Len() != 0 {
+		t.Errorf("Expected no output on stdout, got %q", ta.stdout.String())
+	}
+	if calls := ta.engine.Calls(); len(calls) != 0 {
+		t.Errorf("Expected no engine calls, got %d", len(calls))
+	}
+}
+
+func TestRunVersion(t *testing.T) {
+	ta := newTestApp()
+	if code := ta.run([]string{"-v"}); code != 0 {
+		t.Errorf("Expected exit code 0, got %d", code)
+	}
+	if ta.engineName != "" {
+		t.Errorf("Expected engine not to be created, got '%s'", ta.engineName)
+	}
+}
+
+func TestRunPreflight(t *testing.T) {
+	// 一致するオブジェクトがない場合はテーブルを作成せずに終了する
+	ta := newTestApp()
+	ta.objects = "path,size,last_modified\n"
+	if code := ta.run([]string{"--format", "alb", "-q", "SELECT 1", "s3://bucket/AWSLogs/missing/*.log.gz"}); code != 1 {
+		t.Errorf("Expected exit code 1, got %d", code)
+	}
+	if !strings.Contains(ta.stderr.String(), "パスに一致するオブジェクトがありません: s3://bucket/AWSLogs/missing/*.log.gz") {
+		t.Errorf("Expected empty match to be logged, got %s", ta.stderr.String())
+	}
+	if calls := ta.engine.Calls(); len(calls) != 1 {
+		t.Errorf("Expected only the listing call, got %d calls", len(calls))
+	}
+
+	// アクセスが拒否された場合は認証情報の確認を促す
+	ta = newTestApp()
+	ta.engine.Respond = func(call duckdbtest.Call) duckdbtest.Response {
+		return duckdbtest.Response{Err: &duckdb.QueryError{Message: "HTTP Error: HTTP GET error on 'https://bucket.s3.amazonaws.com/' (HTTP 403)"}}
+	}
+	if code := ta.run([]string{"--format", "alb", "-q", "SELECT 1", "s3://bucket/AWSLogs/*.log.gz"}); code != 1 {
+		t.Errorf("Expected exit code 1, got %d", code)
+	}
+	if !strings.Contains(ta.stderr.String(), "AccessDenied") || !strings.Contains(ta.stderr.String(), "--profile") {
+		t.Errorf("Expected access denied hint, got %s", ta.stderr.String())
+	}
+
+	// オブジェクトのキーを誤って指定した場合の404はバケットが存在しないとは報告しない
+	ta = newTestApp()
+	ta.engine.Respond = func(call duckdbtest.Call) duckdbtest.Response {
+		return duckdbtest.Response{Err: &duckdb.QueryError{Message: "HTTP Error: HTTP GET error on 'https://bucket.s3.amazonaws.com/AWSLogs/typo.log.gz' (HTTP 404)"}}
+	}
+	if code := ta.run([]string{"--format", "alb", "-q", "SELECT 1", "s3://bucket/AWSLogs/typo.log.gz"}); code != 1 {
+		t.Errorf("Expected exit code 1, got %d", code)
+	}
+	if !strings.Contains(ta.stderr.String(), "パスに一致するオブジェクトがありません") || strings.Contains(ta.stderr.String(), "NoSuchBucket") {
+		t.Errorf("Expected exact-key 404 to be reported as no matching objects, got %s", ta.stderr.String())
+	}
+
+	// 件数と合計サイズを表示する
+	ta = newTestApp()
+	if code := ta.run([]string{"--format", "alb", "-q", "SELECT 1", "s3://bucket/AWSLogs/*.log.gz"}); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, ta.stderr.String())
+	}
+	if !strings.Contains(ta.stderr.String(), "1 件のオブジェクト (合計 1.0 KiB)") {
+		t.Errorf("Expected object summary to be logged, got %s", ta.stderr.String())
+	}
+}
+
+func TestRunPreflightEmptyPath(t *testing.T) {
+	// 一部のパスに一致するオブジェクトがない場合は、そのパスを除外して読み込む
+	ta := newTestApp()
+	code := ta.run([]string{"--format", "alb", "-q", "SELECT 1", "s3://bucket/AWSLogs/*.log.gz", "s3://bucket/missing/*.log.gz"})
+	if code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, ta.stderr.String())
+	}
+	if !strings.Contains(ta.stderr.String(), "一致するオブジェクトがないパスを除外します: s3://bucket/missing/*.log.gz") {
+		t.Errorf("Expected empty path to be logged, got %s", ta.stderr.String())
+	}
+	last, _ := ta.engine.Last()
+	if !strings.Contains(last.Script(), "s3://bucket/AWSLogs/*.log.gz") || strings.Contains(last.Script(), "s3://bucket/missing/") {
+		t.Errorf("Expected empty path to be excluded from the load, got %s", last.Script())
+	}
+}
+
+func TestRunPreflightTimeRangeLocation(t *testing.T) {
+	// 範囲の最後の日にまだログがない場合も、その日のglobを除外して読み込む
+	ta := newTestApp()
+	base := "s3://bucket/AWSLogs/123456789012/elasticloadbalancing/ap-northeast-1"
+	ta.objects = "path,size,last_modified\n" + base + "/2025/03/02/a.log.gz,1024,2025-03-02 23:55:00\n"
+	code := ta.run([]string{"--format", "alb", "-q", "SELECT 1",
+		"--bucket", "bucket", "--account", "123456789012", "--region", "ap-northeast-1",
+		"--from", "2025-03-02", "--to", "2025-03-03"})
+	if code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, ta.stderr.String())
+	}
+	last, _ := ta.engine.Last()
+	if !strings.Contains(last.Script(), base+"/2025/03/02/*.log*") || strings.Contains(last.Script(), base+"/2025/03/03/") {
+		t.Errorf("Expected only the day with objects to be loaded, got %s", last.Script())
+	}
+}
+
+func TestRunPreflightWithCache(t *testing.T) {
+	ta := newTestApp()
+
+	code := ta.run([]string{"--format", "alb", "-q", "SELECT 1", "--db", "cache.duckdb", "s3://bucket/AWSLogs/*.log.gz"})
+	if code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, ta.stderr.String())
+	}
+	// キャッシュの確認では事前に取得したオブジェクトの一覧を再利用する
+	listings := 0
+	for _, call := range ta.engine.Calls() {
+		if strings.Contains(call.Script(), "read_blob(") {
+			listings++
+		}
+	}
+	if listings != 1 {
+		t.Errorf("Expected objects to be listed once, got %d", listings)
+	}
+	if strings.Contains(ta.stderr.String(), "cache prune") {
+		t.Errorf("Expected no stale cache hint without cached objects, got %s", ta.stderr.String())
+	}
+
+	// キャッシュを再利用する場合は上書きを検出できない制限を案内する
+	ta = newTestApp()
+	ta.respond(func(call duckdbtest.Call) duckdbtest.Response {
+		if strings.Contains(call.Script(), "FROM _dalv_objects") {
+			return duckdbtest.Response{Output: objectsCSV}
+		}
+		return duckdbtest.Response{}
+	})
+	code = ta.run([]string{"--format", "alb", "-q", "SELECT 1", "--db", "cache.duckdb", "s3://bucket/AWSLogs/*.log.gz"})
+	if code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, ta.stderr.String())
+	}
+	if !strings.Contains(ta.stderr.String(), "1 件を再利用") || !strings.Contains(ta.stderr.String(), "dalv cache prune --db cache.duckdb --all") {
+		t.Errorf("Expected stale cache hint, got %s", ta.stderr.String())
+	}
+}
+
+func TestRunConfirmSize(t *testing.T) {
+	args := []string{"--format", "alb", "-q", "SELECT 1", "--confirm-size", "512", "s3://bucket/AWSLogs/*.log.gz"}
+	testCases := []struct {
+		name     string
+		args     []string
+		terminal bool
+		input    string
+		code     int
+		message  string
+	}{
+		{"non-terminal", args, false, "", 1, "--yes"},
+		{"yes flag", append([]string{"--yes"}, args...), false, "", 0, ""},
+		{"disabled", []string{"--format", "alb", "-q", "SELECT 1", "--confirm-size", "0", "s3://bucket/AWSLogs/*.log.gz"}, false, "", 0, ""},
+		{"confirmed", args, true, "y\n", 0, "続行しますか? [y/N]"},
+		{"declined", args, true, "n\n", 1, "読み込みを中止しました"},
+		{"no input", args, true, "", 1, "--yes"},
+	}
+
+	for _, tc := range testCases {
+		ta := newTestApp()
+		ta.stdinIsTerminal = tc.terminal
+		ta.stdin = strings.NewReader(tc.input)
+		if code := ta.run(tc.args); code != tc.code {
+			t.Errorf("%s: expected exit code %d, got %d: %s", tc.name, tc.code, code, ta.stderr.String())
+		}
+		if !strings.Contains(ta.stderr.String(), tc.message) {
+			t.Errorf("%s: expected %q, got %s", tc.name, tc.message, ta.stderr.String())
+		}
+		// 確認で中止した場合はテーブルを作成しない
+		if tc.code != 0 {
+			if calls := ta.engine.Calls(); len(calls) != 1 {
+				t.Errorf("%s: expected only the listing call, got %d calls", tc.name, len(calls))
+			}
+		}
+	}
+}
+
+func TestRunDryRun(t *testing.T) {
+	ta := newTestApp()
+
+	code := ta.run([]string{"--dry-run", "-t", "alb_logs", "--role-arn", "arn:aws:iam::123456789012:role/log-reader", "--external-id", "ext-1234",
+		"s3://bucket/AWSLogs/123456789012/elasticloadbalancing/us-east-1/2025/03/03/*app.my-alb*.log.gz"})
+	if code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, ta.stderr.String())
+	}
+	// DuckDBは起動しない
+	if ta.engineName != "" {
+		t.Errorf("Expected engine not to be created, got '%s'", ta.engineName)
+	}
+	if calls := ta.engine.Calls(); len(calls) != 0 {
+		t.Errorf("Expected no engine calls, got %d", len(calls))
+	}
+
+	plan := ta.stdout.String()
+	for _, expected := range []string{
+		"-- ログ形式: alb (",
+		"--   s3://bucket/AWSLogs/123456789012/elasticloadbalancing/us-east-1/2025/03/03/*app.my-alb*.log.gz",
+		"-- 推定オブジェクト数: 不明",
+		"-- テーブル名: alb_logs",
+		"ASSUME_ROLE_EXTERNAL_ID '********'",
+		"CREATE TABLE alb_logs AS",
+	} {
+		if !strings.Contains(plan, expected) {
+			t.Errorf("Expected plan to contain %q:\n%s", expected, plan)
+		}
+	}
+	if strings.Contains(plan+ta.stderr.String(), "ext-1234") {
+		t.Errorf("Expected external ID to be redacted:\n%s", plan)
+	}
+}
+
+func TestRunDryRunLocal(t *testing.T) {
+	ta := newTestApp()
+
+	// ローカルのファイルは数え、ログ形式はDuckDBを起動せずに1行目から判定する
+	code := ta.run([]string{"--dry-run", "testdata/alb/"})
+	if code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, ta.stderr.String())
+	}
+	if calls := ta.engine.Calls(); len(calls) != 0 {
+		t.Errorf("Expected no engine calls, got %d", len(calls))
+	}
+	plan := ta.stdout.String()
+	for _, expected := range []string{"-- ログ形式: alb (1行目", "-- 推定オブジェクト数: 2 件"} {
+		if !strings.Contains(plan, expected) {
+			t.Errorf("Expected plan to contain %q:\n%s", expected, plan)
+		}
+	}
+	if strings.Contains(plan, "CREATE SECRET") {
+		t.Errorf("Expected no S3 credentials for local path:\n%s", plan)
+	}
+}
+
+func TestRunPrintSQL(t *testing.T) {
+	ta := newTestApp()
+
+	code := ta.run([]string{"--print-sql", "--format", "alb", "-q", "SELECT 1", "--external-id", "ext-1234",
+		"--role-arn", "arn:aws:iam::123456789012:role/log-reader", "s3://bucket/AWSLogs/*.log.gz"})
+	if code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, ta.stderr.String())
+	}
+	// SQLは標準エラー出力に表示し、クエリ結果の出力先には書き込まない
+	if strings.Contains(ta.stdout.String(), "CREATE TABLE") {
+		t.Errorf("Expected SQL not to be printed on stdout, got %q", ta.stdout.String())
+	}
+	if !strings.Contains(ta.stderr.String(), "ASSUME_ROLE_EXTERNAL_ID '********'") || strings.Contains(ta.stderr.String(), "ext-1234") {
+		t.Errorf("Expected redacted SQL on stderr, got %s", ta.stderr.String())
+	}
+
+	// テーブル名を指定していない場合は生成したテーブル名で表示する
+	match := regexp.MustCompile(`CREATE TABLE (alb_logs_\w+)`).FindStringSubmatch(ta.stderr.String())
+	if match == nil {
+		t.Fatalf("Expected printed SQL to use a generated table name, got %s", ta.stderr.String())
+	}
+	// 表示したテーブル名と実行時のテーブル名は同じ
+	call, _ := ta.engine.Last()
+	if !strings.Contains(call.Script(), "CREATE TABLE "+match[1]+" AS") {
+		t.Errorf("Expected query to use printed table name %s, got %s", match[1], call.Script())
+	}
+}
+
+func TestRunPrintSQLReport(t *testing.T) {
+	ta := newTestApp()
+
+	// report はテーブル名を指定していない場合にログ形式のプレフィックスを使用し、--print-sql でも変わらない
+	code := ta.run([]string{"report", "status-codes", "--print-sql", "--format", "alb", "s3://bucket/AWSLogs/*.log.gz"})
+	if code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, ta.stderr.String())
+	}
+	call, _ := ta.engine.Last()
+	if !strings.Contains(call.Script(), "CREATE TABLE alb_logs AS") || !strings.Contains(call.Script(), "FROM alb_logs") {
+		t.Errorf("Expected report to run against alb_logs:\n%s", call.Script())
+	}
+	if !strings.Contains(ta.stderr.String(), "CREATE TABLE alb_logs AS") {
+		t.Errorf("Expected printed SQL to use alb_logs, got %s", ta.stderr.String())
+	}
+}
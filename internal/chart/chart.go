@@ -0,0 +1,284 @@
+package chart
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/naotama2002/dalv/internal/duckdb"
+)
+
+const (
+	// StyleSparkline は系列ごとに各指標を1行のスパークラインで表示する形式です
+	StyleSparkline = "sparkline"
+	// StyleBar は時間帯ごとに1行の横棒グラフで表示する形式です
+	StyleBar = "bar"
+)
+
+// Styles は対応しているグラフの形式です
+var Styles = []string{StyleSparkline, StyleBar}
+
+// labelLayout はグラフに表示する時間帯の形式です
+const labelLayout = "2006-01-02 15:04"
+
+var (
+	// unicodeLevels は値の大きさを表すUnicodeのブロック文字です
+	unicodeLevels = []rune("▁▂▃▄▅▆▇█")
+	// asciiLevels は値の大きさを表すASCII文字です
+	asciiLevels = []rune("_.-:=+*#")
+)
+
+// Options はグラフの表示設定です
+type Options struct {
+	// Style はグラフの形式です (sparkline または bar)
+	Style string
+	// Width はスパークラインの最大の長さ、または横棒の最大の長さ (文字数) です
+	// スパークラインが幅を超える場合は隣接する時間帯の最大値にまとめます
+	Width int
+	// ASCII はUnicodeのブロック文字の代わりにASCII文字を使用するかどうかです
+	ASCII bool
+}
+
+// Metric はグラフに表示する1つの指標の時系列の値です
+type Metric struct {
+	// Name は指標の名前です
+	Name string
+	// Values は時間帯ごとの値です (値がない時間帯はNaN)
+	Values []float64
+	// Format は値を表示用の文字列にする関数です
+	Format func(float64) string
+}
+
+// Series は1つの系列のグラフです
+type Series struct {
+	// Name は系列の名前です (系列を分けない場合は空)
+	Name string
+	// Buckets は時間帯の開始日時です (欠けている時間帯を含む)
+	Buckets []time.Time
+	// Metrics は表示する指標です
+	Metrics []Metric
+}
+
+// BuildSeries は集計したデータを系列ごとの指標に変換します
+// データのない時間帯はリクエスト数を0、割合とp99をNaNとして補います
+func BuildSeries(data *duckdb.ChartData) []Series {
+	if len(data.Points) == 0 {
+		return nil
+	}
+
+	// すべての系列で共通の時間軸を作る
+	first, last := data.Points[0].Bucket, data.Points[0].Bucket
+	for _, p := range data.Points {
+		if p.Bucket.Before(first) {
+			first = p.Bucket
+		}
+		if p.Bucket.After(last) {
+			last = p.Bucket
+		}
+	}
+	var buckets []time.Time
+	index := make(map[time.Time]int)
+	for t := first; !t.After(last); t = t.Add(data.Interval) {
+		index[t] = len(buckets)
+		buckets = append(buckets, t)
+	}
+
+	var series []Series
+	values := make(map[string][]duckdb.ChartPoint)
+	for _, p := range data.Points {
+		if _, ok := values[p.Series]; !ok {
+			series = append(series, Series{Name: p.Series, Buckets: buckets})
+		}
+		values[p.Series] = append(values[p.Series], p)
+	}
+
+	for i := range series {
+		requests := filled(len(buckets), 0)
+		rate4xx := filled(len(buckets), math.NaN())
+		rate5xx := filled(len(buckets), math.NaN())
+		p99 := filled(len(buckets), math.NaN())
+		for _, p := range values[series[i].Name] {
+			j, ok := index[p.Bucket]
+			if !ok {
+				continue
+			}
+			requests[j] = float64(p.Requests)
+			if p.Requests > 0 {
+				rate4xx[j] = 100 * float64(p.Errors4xx) / float64(p.Requests)
+				rate5xx[j] = 100 * float64(p.Errors5xx) / float64(p.Requests)
+			}
+			p99[j] = p.P99
+		}
+
+		series[i].Metrics = append(series[i].Metrics, Metric{Name: "requests", Values: requests, Format: formatCount})
+		if data.HasStatusCodes {
+			series[i].Metrics = append(series[i].Metrics,
+				Metric{Name: "4xx rate", Values: rate4xx, Format: formatPercent},
+				Metric{Name: "5xx rate", Values: rate5xx, Format: formatPercent},
+			)
+		}
+		if data.HasLatency {
+			series[i].Metrics = append(series[i].Metrics, Metric{Name: "p99", Values: p99, Format: formatSeconds})
+		}
+	}
+	return series
+}
+
+// Render は系列ごとのグラフを出力します
+func Render(w io.Writer, series []Series, interval time.Duration, opts Options) {
+	if len(series) == 0 {
+		fmt.Fprintln(w, "表示するデータがありません")
+		return
+	}
+
+	buckets := series[0].Buckets
+	fmt.Fprintf(w, "%s - %s (%s 間隔, %d 区間)\n", buckets[0].Format(labelLayout), buckets[len(buckets)-1].Format(labelLayout), formatInterval(interval), len(buckets))
+	for _, s := range series {
+		fmt.Fprintln(w)
+		if s.Name != "" {
+			fmt.Fprintf(w, "[%s]\n", s.Name)
+		}
+		if opts.Style == StyleBar {
+			renderBars(w, s, opts)
+		} else {
+			renderSparklines(w, s, opts)
+		}
+	}
+}
+
+// renderSparklines は系列の各指標を1行のスパークラインで出力します
+func renderSparklines(w io.Writer, s Series, opts Options) {
+	for _, m := range s.Metrics {
+		line := Sparkline(Resample(m.Values, opts.Width), opts.ASCII)
+		fmt.Fprintf(w, "%-9s %s  max %s\n", m.Name, line, m.Format(maxValue(m.Values)))
+	}
+}
+
+// renderBars は系列の各指標を時間帯ごとの横棒グラフで出力します
+func renderBars(w io.Writer, s Series, opts Options) {
+	for _, m := range s.Metrics {
+		fmt.Fprintf(w, "%s:\n", m.Name)
+		peak := maxValue(m.Values)
+		for i, v := range m.Values {
+			fmt.Fprintf(w, "  %s %-*s %s\n", s.Buckets[i].Format(labelLayout), opts.Width, Bar(v, peak, opts.Width, opts.ASCII), m.Format(v))
+		}
+	}
+}
+
+// Sparkline は値の大きさを1文字ずつのブロック文字で表した文字列を返します
+// 0を最小として最大値に対する割合で高さを決め、NaNは空白にします
+func Sparkline(values []float64, ascii bool) string {
+	levels := unicodeLevels
+	if ascii {
+		levels = asciiLevels
+	}
+
+	peak := maxValue(values)
+	var b strings.Builder
+	for _, v := range values {
+		switch {
+		case math.IsNaN(v):
+			b.WriteRune(' ')
+		case peak <= 0:
+			b.WriteRune(levels[0])
+		default:
+			b.WriteRune(levels[int(math.Round(v/peak*float64(len(levels)-1)))])
+		}
+	}
+	return b.String()
+}
+
+// Bar は最大値に対する値の割合を長さにした横棒を返します
+func Bar(value, peak float64, width int, ascii bool) string {
+	if math.IsNaN(value) || peak <= 0 || value <= 0 {
+		return ""
+	}
+	char := "█"
+	if ascii {
+		char = "#"
+	}
+	n := int(math.Round(value / peak * float64(width)))
+	if n == 0 {
+		// 0より大きい値は最小でも1文字表示する
+		n = 1
+	}
+	return strings.Repeat(char, n)
+}
+
+// Resample は値の数が width を超える場合に隣接する値の最大値にまとめます
+// すべてNaNの区間はNaNにします
+func Resample(values []float64, width int) []float64 {
+	if width <= 0 || len(values) <= width {
+		return values
+	}
+
+	size := (len(values) + width - 1) / width
+	resampled := make([]float64, 0, width)
+	for i := 0; i < len(values); i += size {
+		end := i + size
+		if end > len(values) {
+			end = len(values)
+		}
+		resampled = append(resampled, maxValue(values[i:end]))
+	}
+	return resampled
+}
+
+// maxValue はNaNを除いた最大値を返します (すべてNaNの場合はNaN)
+func maxValue(values []float64) float64 {
+	peak := math.NaN()
+	for _, v := range values {
+		if !math.IsNaN(v) && (math.IsNaN(peak) || v > peak) {
+			peak = v
+		}
+	}
+	return peak
+}
+
+// filled は同じ値で埋めたスライスを返します
+func filled(n int, value float64) []float64 {
+	values := make([]float64, n)
+	for i := range values {
+		values[i] = value
+	}
+	return values
+}
+
+// formatInterval は集計の間隔を 5m や 1h のような短い文字列にします
+func formatInterval(d time.Duration) string {
+	switch {
+	case d%(24*time.Hour) == 0:
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	case d%time.Minute == 0:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	}
+	return d.String()
+}
+
+// formatCount はリクエスト数を表示用の文字列にします
+func formatCount(v float64) string {
+	if math.IsNaN(v) {
+		return "-"
+	}
+	return fmt.Sprintf("%.0f", v)
+}
+
+// formatPercent は割合を表示用の文字列にします
+func formatPercent(v float64) string {
+	if math.IsNaN(v) {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%%", v)
+}
+
+// formatSeconds は処理時間を表示用の文字列にします
+func formatSeconds(v float64) string {
+	if math.IsNaN(v) {
+		return "-"
+	}
+	return fmt.Sprintf("%.3fs", v)
+}
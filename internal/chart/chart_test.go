@@ -0,0 +1,135 @@
+package chart
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/naotama2002/dalv/internal/duckdb"
+)
+
+func TestSparkline(t *testing.T) {
+	testCases := []struct {
+		values   []float64
+		ascii    bool
+		expected string
+	}{
+		{[]float64{0, 1, 2, 3, 4, 5, 6, 7}, false, "▁▂▃▄▅▆▇█"},
+		{[]float64{0, 7, math.NaN(), 3.5}, true, "_# ="},
+		// すべて0の場合は最小の高さ
+		{[]float64{0, 0}, false, "▁▁"},
+	}
+
+	for _, tc := range testCases {
+		if got := Sparkline(tc.values, tc.ascii); got != tc.expected {
+			t.Errorf("Sparkline(%v, %v) = %q, expected %q", tc.values, tc.ascii, got, tc.expected)
+		}
+	}
+}
+
+func TestBar(t *testing.T) {
+	testCases := []struct {
+		value    float64
+		peak     float64
+		expected string
+	}{
+		{10, 10, "##########"},
+		{5, 10, "#####"},
+		// 0より大きい値は最小でも1文字
+		{0.01, 10, "#"},
+		{0, 10, ""},
+		{math.NaN(), 10, ""},
+	}
+
+	for _, tc := range testCases {
+		if got := Bar(tc.value, tc.peak, 10, true); got != tc.expected {
+			t.Errorf("Bar(%v, %v) = %q, expected %q", tc.value, tc.peak, got, tc.expected)
+		}
+	}
+}
+
+func TestResample(t *testing.T) {
+	values := []float64{1, 5, 2, math.NaN(), math.NaN(), math.NaN(), 3}
+	got := Resample(values, 3)
+
+	// 3件ずつ最大値にまとめ、すべてNaNの区間はNaNにする
+	if len(got) != 3 || got[0] != 5 || !math.IsNaN(got[1]) || got[2] != 3 {
+		t.Errorf("Resample(%v, 3) = %v", values, got)
+	}
+	if got := Resample(values, 10); len(got) != len(values) {
+		t.Errorf("Resample should not change values shorter than width, got %v", got)
+	}
+}
+
+func TestBuildSeries(t *testing.T) {
+	base := time.Date(2025, 3, 30, 22, 0, 0, 0, time.UTC)
+	data := &duckdb.ChartData{
+		Points: []duckdb.ChartPoint{
+			{Series: "app/a", Bucket: base, Requests: 10, Errors4xx: 1, Errors5xx: 2, P99: 0.5},
+			{Series: "app/a", Bucket: base.Add(10 * time.Minute), Requests: 20, P99: 1.5},
+			{Series: "app/b", Bucket: base.Add(5 * time.Minute), Requests: 4, Errors5xx: 4, P99: math.NaN()},
+		},
+		Interval:       5 * time.Minute,
+		HasStatusCodes: true,
+		HasLatency:     true,
+	}
+
+	series := BuildSeries(data)
+	if len(series) != 2 || series[0].Name != "app/a" || series[1].Name != "app/b" {
+		t.Fatalf("Unexpected series: %+v", series)
+	}
+	// すべての系列で共通の時間軸にする
+	if len(series[1].Buckets) != 3 || !series[1].Buckets[0].Equal(base) {
+		t.Errorf("Expected 3 buckets from %v, got %v", base, series[1].Buckets)
+	}
+
+	a := series[0].Metrics
+	if len(a) != 4 {
+		t.Fatalf("Expected 4 metrics, got %d", len(a))
+	}
+	if a[0].Values[0] != 10 || a[0].Values[1] != 0 || a[0].Values[2] != 20 {
+		t.Errorf("Unexpected requests: %v", a[0].Values)
+	}
+	// データのない時間帯の割合はNaN
+	if a[2].Values[0] != 20 || !math.IsNaN(a[2].Values[1]) {
+		t.Errorf("Unexpected 5xx rate: %v", a[2].Values)
+	}
+
+	// ステータスコードと処理時間がないログ形式ではリクエスト数のみ
+	data.HasStatusCodes, data.HasLatency = false, false
+	if metrics := BuildSeries(data)[0].Metrics; len(metrics) != 1 || metrics[0].Name != "requests" {
+		t.Errorf("Expected only requests metric, got %+v", metrics)
+	}
+}
+
+func TestRender(t *testing.T) {
+	base := time.Date(2025, 3, 30, 22, 0, 0, 0, time.UTC)
+	data := &duckdb.ChartData{
+		Points: []duckdb.ChartPoint{
+			{Bucket: base, Requests: 1},
+			{Bucket: base.Add(time.Minute), Requests: 2},
+		},
+		Interval: time.Minute,
+	}
+
+	var out bytes.Buffer
+	Render(&out, BuildSeries(data), data.Interval, Options{Style: StyleSparkline, Width: 60})
+	expected := "2025-03-30 22:00 - 2025-03-30 22:01 (1m 間隔, 2 区間)\n\nrequests  ▅█  max 2\n"
+	if out.String() != expected {
+		t.Errorf("Unexpected sparkline output:\n%s", out.String())
+	}
+
+	out.Reset()
+	Render(&out, BuildSeries(data), data.Interval, Options{Style: StyleBar, Width: 4, ASCII: true})
+	if !strings.Contains(out.String(), "  2025-03-30 22:00 ##   1\n  2025-03-30 22:01 #### 2\n") {
+		t.Errorf("Unexpected bar output:\n%s", out.String())
+	}
+
+	out.Reset()
+	Render(&out, nil, time.Minute, Options{})
+	if out.String() != "表示するデータがありません\n" {
+		t.Errorf("Unexpected output for empty data: %q", out.String())
+	}
+}
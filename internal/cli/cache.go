@@ -0,0 +1,137 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// CommandCache はキャッシュを管理するサブコマンドです
+	CommandCache = "cache"
+
+	// CacheActionList はキャッシュの内容を表示する操作です
+	CacheActionList = "ls"
+	// CacheActionPrune はキャッシュからオブジェクトを削除する操作です
+	CacheActionPrune = "prune"
+)
+
+// CacheOptions は cache サブコマンドの解析結果です
+type CacheOptions struct {
+	// Action は実行する操作 (ls または prune) です
+	Action string
+	// DBPath はキャッシュを保存しているDuckDBのデータベースファイルです
+	DBPath string
+	// LoadedBefore はこの日時より前に読み込んだオブジェクトを削除します (ゼロ値の場合はすべて削除)
+	LoadedBefore time.Time
+	// Engine はDuckDBを実行するエンジンの名前です (auto, cli, embedded)
+	Engine string
+}
+
+// parseCache は cache サブコマンドの引数を解析します
+// ヘルプを表示した場合は nil を返します
+func (c *CLI) parseCache(args []string, now time.Time) (*Options, error) {
+	fs := flag.NewFlagSet("dalv cache", flag.ContinueOnError)
+	fs.Usage = func() { printCacheHelp(fs) }
+
+	helpFlag := fs.Bool("help", false, "ヘルプ情報を表示します")
+	fs.BoolVar(helpFlag, "h", false, "ヘルプ情報を表示します (短縮形)")
+	dbFlag := fs.String("db", "", "キャッシュを保存しているDuckDBのデータベースファイル")
+	olderThanFlag := fs.String("older-than", "", "prune: 指定した期間より前に読み込んだオブジェクトを削除します (例: 7d, 12h)")
+	allFlag := fs.Bool("all", false, "prune: すべてのオブジェクトを削除します")
+	engineFlag := newEngineFlag(fs)
+
+	if len(args) == 0 {
+		return nil, fmt.Errorf("cache サブコマンドの操作が指定されていません。使用方法: dalv cache ls|prune --db <path>")
+	}
+	action := args[0]
+	if action == "-h" || action == "--help" || action == "-help" {
+		printCacheHelp(fs)
+		return nil, nil
+	}
+	if action != CacheActionList && action != CacheActionPrune {
+		return nil, fmt.Errorf("未対応の cache サブコマンドの操作です: %s (対応操作: %s, %s)", action, CacheActionList, CacheActionPrune)
+	}
+
+	if err := fs.Parse(args[1:]); err != nil {
+		return nil, err
+	}
+	if *helpFlag {
+		printCacheHelp(fs)
+		return nil, nil
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("cache サブコマンドに不明な引数が指定されました: %s", strings.Join(fs.Args(), " "))
+	}
+	if *dbFlag == "" {
+		return nil, fmt.Errorf("--db でデータベースファイルを指定してください")
+	}
+	engine, err := parseEngine(*engineFlag)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &CacheOptions{
+		Action: action,
+		DBPath: *dbFlag,
+		Engine: engine,
+	}
+
+	if action == CacheActionPrune {
+		if *olderThanFlag != "" && *allFlag {
+			return nil, fmt.Errorf("--older-than と --all は同時に指定できません")
+		}
+		if *olderThanFlag == "" && !*allFlag {
+			return nil, fmt.Errorf("prune には --older-than または --all を指定してください")
+		}
+		if *olderThanFlag != "" {
+			d, err := parseAge(*olderThanFlag)
+			if err != nil {
+				return nil, err
+			}
+			opts.LoadedBefore = now.Add(-d)
+		}
+	} else if *olderThanFlag != "" || *allFlag {
+		return nil, fmt.Errorf("--older-than と --all は prune でのみ指定できます")
+	}
+
+	return &Options{
+		Command: CommandCache,
+		Cache:   opts,
+	}, nil
+}
+
+// parseAge は 7d や 12h のような期間を解析します
+// time.ParseDuration の単位に加えて日単位 (d) を指定できます
+func parseAge(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("期間の形式が不正です: %s (例: 7d, 12h)", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("期間の形式が不正です: %s (例: 7d, 12h)", s)
+	}
+	return d, nil
+}
+
+// printCacheHelp は cache サブコマンドのヘルプ情報を表示します
+func printCacheHelp(fs *flag.FlagSet) {
+	fmt.Println("dalv cache - 読み込み済みログのキャッシュを管理します")
+	fmt.Println()
+	fmt.Println("使用方法: dalv cache ls --db <path>")
+	fmt.Println("          dalv cache prune --db <path> (--older-than <期間> | --all)")
+	fmt.Println()
+	fmt.Println("操作:")
+	fmt.Println("  ls         キャッシュ済みのオブジェクトを表示します")
+	fmt.Println("  prune      キャッシュからオブジェクトを削除します")
+	fmt.Println()
+	fmt.Println("オプション:")
+	fs.PrintDefaults()
+}
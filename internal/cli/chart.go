@@ -0,0 +1,98 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/naotama2002/dalv/internal/chart"
+)
+
+// CommandChart は時系列グラフを表示するサブコマンドです
+const CommandChart = "chart"
+
+// ChartOptions は chart サブコマンドの解析結果です
+type ChartOptions struct {
+	// Interval は集計する時間帯の間隔です
+	Interval time.Duration
+	// SplitBy は系列を分けるカラムです (空の場合は全体で1つの系列)
+	SplitBy string
+	// Top は系列を分ける場合に表示する系列数の上限です
+	Top int
+	// Display はグラフの表示設定です
+	Display chart.Options
+}
+
+// parseChart は chart サブコマンドの引数を解析します
+// ヘルプを表示した場合は nil を返します
+func (c *CLI) parseChart(args []string) (*Options, error) {
+	fs := flag.NewFlagSet("dalv chart", flag.ContinueOnError)
+	fs.Usage = func() { printChartHelp(fs) }
+
+	helpFlag := fs.Bool("help", false, "ヘルプ情報を表示します")
+	fs.BoolVar(helpFlag, "h", false, "ヘルプ情報を表示します (短縮形)")
+	intervalFlag := fs.String("interval", "5m", "集計する時間帯の間隔 (例: 1m, 5m, 1h)")
+	splitByFlag := fs.String("split-by", "", "系列を分けるカラム (例: elb, target_group_arn)")
+	topFlag := fs.Int("top", 5, "--split-by で表示する系列数の上限 (リクエスト数の多い順。0 で制限なし)")
+	styleFlag := fs.String("style", chart.StyleSparkline, fmt.Sprintf("グラフの形式 (%s)", strings.Join(chart.Styles, "|")))
+	widthFlag := fs.Int("width", 60, "グラフの幅 (文字数。sparkline で時間帯の数が幅を超える場合は隣接する時間帯の最大値にまとめます)")
+	asciiFlag := fs.Bool("ascii", false, "Unicodeのブロック文字の代わりにASCII文字でグラフを表示します")
+	src := c.newSourceFlags(fs, "to")
+
+	if err := src.parseArgs(args); err != nil {
+		return nil, err
+	}
+	if *helpFlag {
+		printChartHelp(fs)
+		return nil, nil
+	}
+	if *src.rawFlag {
+		return nil, fmt.Errorf("chart では -1 をNULLに変換する必要があるため --raw は指定できません")
+	}
+
+	interval, err := parseAge(*intervalFlag)
+	if err != nil || interval < time.Second {
+		return nil, fmt.Errorf("集計の間隔の形式が不正です: %s (例: 1m, 5m, 1h)", *intervalFlag)
+	}
+	if !contains(chart.Styles, *styleFlag) {
+		return nil, fmt.Errorf("未対応のグラフの形式です: %s (対応形式: %s)", *styleFlag, strings.Join(chart.Styles, ", "))
+	}
+	if *widthFlag < 10 {
+		return nil, fmt.Errorf("--width には10以上の値を指定してください: %d", *widthFlag)
+	}
+	if *topFlag < 0 {
+		return nil, fmt.Errorf("--top には0以上の値を指定してください: %d", *topFlag)
+	}
+
+	opts, err := src.parse()
+	if err != nil {
+		return nil, err
+	}
+
+	opts.Command = CommandChart
+	opts.Chart = &ChartOptions{
+		Interval: interval,
+		SplitBy:  *splitByFlag,
+		Top:      *topFlag,
+		Display: chart.Options{
+			Style: *styleFlag,
+			Width: *widthFlag,
+			ASCII: *asciiFlag,
+		},
+	}
+	return opts, nil
+}
+
+// printChartHelp は chart サブコマンドのヘルプ情報を表示します
+func printChartHelp(fs *flag.FlagSet) {
+	fmt.Println("dalv chart - リクエスト数・エラー率・p99の推移をターミナルにグラフで表示します")
+	fmt.Println()
+	fmt.Println("使用方法: dalv chart [--interval <間隔>] [--split-by <column>] [--style sparkline|bar] [options] <path> [<path>...]")
+	fmt.Println()
+	fmt.Println("時間帯ごとのリクエスト数、4xx/5xxの割合、処理時間の合計のp99を表示します")
+	fmt.Println("ステータスコードや処理時間を記録していないログ形式では、その指標を表示しません")
+	fmt.Println()
+	fmt.Println("オプション:")
+	fs.PrintDefaults()
+}
@@ -3,77 +3,582 @@ package cli
 import (
 	"flag"
 	"fmt"
-	
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/naotama2002/dalv/internal/config"
+	"github.com/naotama2002/dalv/internal/duckdb"
+	"github.com/naotama2002/dalv/internal/schema"
+	"github.com/naotama2002/dalv/internal/source"
 	"github.com/naotama2002/dalv/internal/version"
 )
 
+// Options はコマンドライン引数の解析結果です
+type Options struct {
+	// Command は実行するサブコマンドです (空の場合はログの読み込み)
+	Command string
+	// Cache は cache サブコマンドの解析結果です
+	Cache *CacheOptions
+	// Export は export サブコマンドの解析結果です
+	Export *ExportOptions
+	// Report は report サブコマンドの解析結果です
+	Report *ReportOptions
+	// Latency は latency サブコマンドの解析結果です
+	Latency *LatencyOptions
+	// Chart は chart サブコマンドの解析結果です
+	Chart *ChartOptions
+	// Serve は serve サブコマンドの解析結果です
+	Serve *ServeOptions
+	// MCP は mcp サブコマンドの解析結果です
+	MCP *MCPOptions
+	// Paths はログを読み込むS3パス (glob) の一覧です
+	Paths []string
+	// TableName は作成するテーブル名です (空の場合は自動生成)
+	TableName string
+	// Query は非インタラクティブモードで実行するSQLです (空の場合はインタラクティブモード)
+	Query string
+	// Output は非インタラクティブモードのクエリ結果の出力設定です
+	Output duckdb.Output
+	// Format はログ形式です (nil の場合は自動判定)
+	Format *schema.Format
+	// TimeRange は読み込むログの時間範囲です (nil の場合は絞り込みなし)
+	TimeRange *source.TimeRange
+	// DBPath はキャッシュを保存するDuckDBのデータベースファイルです (空の場合はキャッシュしない)
+	DBPath string
+	// NoDerivedColumns はリクエストの分解などの派生カラムを追加しないかどうかです
+	NoDerivedColumns bool
+	// Raw はセンチネル値をNULLに変換せず、派生カラムも追加せずにログをそのまま読み込むかどうかです
+	Raw bool
+	// Engine はDuckDBを実行するエンジンの名前です (auto, cli, embedded)
+	Engine string
+	// S3 はS3の認証情報と接続先の設定です
+	S3 duckdb.S3Config
+	// ConfirmSize は読み込む前に確認する合計サイズのしきい値 (バイト) です (0 の場合は確認しない)
+	ConfirmSize int64
+	// AssumeYes は合計サイズがしきい値を超えても確認せずに読み込むかどうかです
+	AssumeYes bool
+	// DryRun はDuckDBを起動せずに、生成したSQLと読み込むパスなどの実行計画を表示して終了するかどうかです
+	DryRun bool
+	// PrintSQL は実行前にテーブルの作成に使用するSQLを表示するかどうかです
+	PrintSQL bool
+}
+
+// Interactive はインタラクティブコンソールを起動するかどうかを返します
+func (o *Options) Interactive() bool {
+	return o.Query == ""
+}
+
 // CLI はコマンドライン引数を処理するための構造体です
 type CLI struct {
-	helpFlag    *bool
-	versionFlag *bool
-	tableFlag   *string
-	args        []string
+	flagSet          *flag.FlagSet
+	helpFlag         *bool
+	versionFlag      *bool
+	queryFlag        *string
+	fileFlag         *string
+	outputFlag       *string
+	outputFormatFlag *string
+	source           *sourceFlags
+	args             []string
+	// loadConfig は名前付きのソースを定義した設定ファイルを読み込みます
+	loadConfig func() (*config.Config, error)
+}
+
+// sourceFlags はログの読み込み元を指定するフラグです
+// ログを読み込むサブコマンドで共通して使用します
+type sourceFlags struct {
+	flagSet        *flag.FlagSet
+	tableFlag      *string
+	formatFlag     *string
+	fromFlag       *string
+	toFlag         *string
+	bucketFlag     *string
+	prefixFlag     *string
+	accountFlag    *string
+	regionFlag     *string
+	profileFlag    *string
+	roleARNFlag    *string
+	sessionFlag    *string
+	externalIDFlag *string
+	endpointFlag   *string
+	urlStyleFlag   *string
+	dbFlag         *string
+	noDerived      *bool
+	rawFlag        *bool
+	engineFlag     *string
+	confirmSize    *string
+	yesFlag        *bool
+	dryRunFlag     *bool
+	printSQLFlag   *bool
+	// loadConfig は名前付きのソースを定義した設定ファイルを読み込みます
+	loadConfig func() (*config.Config, error)
+	// args はフラグ以外の引数です
+	args []string
+	// set は明示的に指定されたフラグの名前です
+	set map[string]bool
 }
 
 // NewCLI は新しいCLIインスタンスを作成します
 func NewCLI(args []string) *CLI {
 	cli := &CLI{
-		args: args,
+		flagSet:    flag.NewFlagSet("dalv", flag.ContinueOnError),
+		args:       args,
+		loadConfig: config.Load,
 	}
-	
+	fs := cli.flagSet
+	fs.Usage = cli.printHelp
+
 	// フラグの定義
-	cli.helpFlag = flag.Bool("help", false, "ヘルプ情報を表示します")
-	flag.BoolVar(cli.helpFlag, "h", false, "ヘルプ情報を表示します (短縮形)")
-	
-	cli.versionFlag = flag.Bool("version", false, "バージョン情報を表示します")
-	flag.BoolVar(cli.versionFlag, "v", false, "バージョン情報を表示します (短縮形)")
-	
-	cli.tableFlag = flag.String("table", "", "作成するテーブル名 (デフォルト: 自動生成)")
-	flag.StringVar(cli.tableFlag, "t", "", "作成するテーブル名 (短縮形)")
-	
+	cli.helpFlag = fs.Bool("help", false, "ヘルプ情報を表示します")
+	fs.BoolVar(cli.helpFlag, "h", false, "ヘルプ情報を表示します (短縮形)")
+
+	cli.versionFlag = fs.Bool("version", false, "バージョン情報を表示します")
+	fs.BoolVar(cli.versionFlag, "v", false, "バージョン情報を表示します (短縮形)")
+
+	cli.queryFlag = fs.String("query", "", "実行するSQL (指定するとクエリ実行後に終了します)")
+	fs.StringVar(cli.queryFlag, "q", "", "実行するSQL (短縮形)")
+
+	cli.fileFlag = fs.String("file", "", "実行するSQLファイルのパス (指定するとクエリ実行後に終了します)")
+	fs.StringVar(cli.fileFlag, "f", "", "実行するSQLファイルのパス (短縮形)")
+
+	cli.outputFlag = fs.String("output", "", "クエリ結果の出力先ファイル (デフォルト: 標準出力)")
+	fs.StringVar(cli.outputFlag, "o", "", "クエリ結果の出力先ファイル (短縮形)")
+	cli.outputFormatFlag = fs.String("output-format", "", fmt.Sprintf("クエリ結果の出力形式 (%s。デフォルト: -o の拡張子から推定、推定できない場合は table)", strings.Join(duckdb.OutputFormatNames(), "|")))
+
+	cli.source = cli.newSourceFlags(fs, "to")
+
 	return cli
 }
 
+// newSourceFlags はログの読み込み元を指定するフラグを定義します
+// toFlagName は時間範囲の終了日時を指定するフラグ名です
+func (c *CLI) newSourceFlags(fs *flag.FlagSet, toFlagName string) *sourceFlags {
+	s := &sourceFlags{
+		flagSet: fs,
+		loadConfig: func() (*config.Config, error) {
+			return c.loadConfig()
+		},
+	}
+
+	s.tableFlag = fs.String("table", "", "作成するテーブル名 (デフォルト: 自動生成)")
+	fs.StringVar(s.tableFlag, "t", "", "作成するテーブル名 (短縮形)")
+
+	s.formatFlag = fs.String("format", schema.AutoFormatName, fmt.Sprintf("ログ形式 (%s|%s)。%s の場合はパスとログの1行目から自動判定します", schema.AutoFormatName, strings.Join(schema.Names(), "|"), schema.AutoFormatName))
+
+	s.fromFlag = fs.String("from", "", "読み込むログの開始日時 (UTC。例: 2025-03-30T22:00, yesterday)")
+	s.toFlag = fs.String(toFlagName, "", "読み込むログの終了日時 (UTC。日付のみの場合はその日の終わりまで。デフォルト: 現在時刻)")
+	s.bucketFlag = fs.String("bucket", "", "ELBログが保存されているS3バケット名 (--from と併用)")
+	s.prefixFlag = fs.String("prefix", "", "ELBログのS3プレフィックス (AWSLogs/ より前の部分)")
+	s.accountFlag = fs.String("account", "", "ELBのAWSアカウントID")
+	s.regionFlag = fs.String("region", "", "AWSのリージョン (ELBログのパスの生成とS3の接続に使用)")
+	s.profileFlag = fs.String("profile", "", "S3の認証情報に使用するAWSのプロファイル (~/.aws/config)")
+	s.roleARNFlag = fs.String("role-arn", "", "S3の読み込みに引き受けるIAMロールのARN (別アカウントのバケットなど)")
+	s.sessionFlag = fs.String("role-session-name", "", "ロールを引き受ける際のセッション名 (--role-arn と併用)")
+	s.externalIDFlag = fs.String("external-id", "", "ロールを引き受ける際の外部ID (--role-arn と併用)")
+	s.endpointFlag = fs.String("s3-endpoint", "", "S3互換のストレージのエンドポイント (例: s3.example.com:9000。http:// で始まる場合はSSLを使用しません。デフォルト: AWSのS3)")
+	s.urlStyleFlag = fs.String("url-style", "", fmt.Sprintf("S3のURLのスタイル (%s。デフォルト: --s3-endpoint を指定した場合は %s、それ以外は %s)", strings.Join(duckdb.URLStyles, "|"), duckdb.URLStylePath, duckdb.URLStyleVirtualHost))
+	s.dbFlag = fs.String("db", "", "読み込んだログをキャッシュするDuckDBのデータベースファイル (パス・サイズ・更新日時が一致するオブジェクトは再利用します)")
+	s.rawFlag = fs.Bool("raw", false, "-1 や - をNULLに変換せず、派生カラムも追加せずにログをそのまま読み込みます")
+	s.confirmSize = fs.String("confirm-size", defaultConfirmSize, "読み込むオブジェクトの合計サイズ (圧縮後) がこの値を超える場合に確認します (例: 500MB, 10GB。0 の場合は確認しない)")
+	s.yesFlag = fs.Bool("yes", false, "合計サイズが --confirm-size を超えても確認せずに読み込みます")
+	fs.BoolVar(s.yesFlag, "y", false, "合計サイズが --confirm-size を超えても確認せずに読み込みます (短縮形)")
+	s.dryRunFlag = fs.Bool("dry-run", false, "DuckDBを起動せずに、生成したSQL (秘密情報は伏せます)、読み込むパス、ログ形式、推定オブジェクト数を表示して終了します")
+	s.printSQLFlag = fs.Bool("print-sql", false, "実行前にテーブルの作成に使用するSQL (秘密情報は伏せます) を標準エラー出力に表示します")
+	s.noDerived = fs.Bool("no-derived", false, "request カラムを分解した http_method, url_path や client_ip, client_port などの派生カラムを追加しません")
+	s.engineFlag = newEngineFlag(fs)
+
+	return s
+}
+
+// newEngineFlag はDuckDBを実行するエンジンを指定するフラグを定義します
+func newEngineFlag(fs *flag.FlagSet) *string {
+	return fs.String("engine", duckdb.EngineAuto, fmt.Sprintf("DuckDBの実行方法 (%s)。%s はPATHのDuckDBのCLIを、%s はdalvに組み込んだDuckDBを使用し、%s の場合はCLIがあればCLIを使用します",
+		strings.Join(duckdb.EngineNames, "|"), duckdb.EngineCLI, duckdb.EngineEmbedded, duckdb.EngineAuto))
+}
+
+// parseEngine はエンジンの名前を検証します
+func parseEngine(name string) (string, error) {
+	if !slices.Contains(duckdb.EngineNames, name) {
+		return "", fmt.Errorf("未対応のエンジンです: %s (%s のいずれかを指定してください)", name, strings.Join(duckdb.EngineNames, ", "))
+	}
+	return name, nil
+}
+
 // Parse はコマンドライン引数を解析します
-func (c *CLI) Parse() (s3Path string, tableName string, err error) {
-	flag.Parse()
-	
+// ヘルプまたはバージョンを表示した場合は nil を返します
+func (c *CLI) Parse() (*Options, error) {
+	// サブコマンドの場合
+	if len(c.args) > 0 {
+		switch c.args[0] {
+		case CommandCache:
+			return c.parseCache(c.args[1:], time.Now())
+		case CommandExport:
+			return c.parseExport(c.args[1:])
+		case CommandReport:
+			return c.parseReport(c.args[1:])
+		case CommandLatency:
+			return c.parseLatency(c.args[1:])
+		case CommandChart:
+			return c.parseChart(c.args[1:])
+		case CommandServe:
+			return c.parseServe(c.args[1:])
+		case CommandMCP:
+			return c.parseMCP(c.args[1:])
+		}
+	}
+
+	if err := c.source.parseArgs(c.args); err != nil {
+		return nil, err
+	}
+
 	// ヘルプフラグが指定された場合
 	if *c.helpFlag {
 		c.printHelp()
-		return "", "", nil
+		return nil, nil
 	}
-	
+
 	// バージョンフラグが指定された場合
 	if *c.versionFlag {
 		c.printVersion()
-		return "", "", nil
+		return nil, nil
+	}
+
+	// ログの読み込み元の取得
+	opts, err := c.source.parse()
+	if err != nil {
+		return nil, err
+	}
+
+	// 実行するクエリの取得
+	query, err := c.parseQuery()
+	if err != nil {
+		return nil, err
+	}
+	opts.Query = query
+
+	// クエリ結果の出力設定の取得
+	output, err := c.parseOutput(opts.Interactive())
+	if err != nil {
+		return nil, err
+	}
+	opts.Output = output
+
+	return opts, nil
+}
+
+// parseOutput は -o と --output-format で指定された出力設定を取得します
+// 出力形式が指定されていない場合は出力先ファイルの拡張子から推定します
+func (c *CLI) parseOutput(interactive bool) (duckdb.Output, error) {
+	if interactive {
+		if *c.outputFlag != "" || *c.outputFormatFlag != "" {
+			return duckdb.Output{}, fmt.Errorf("-o と --output-format は -q または -f と併用してください")
+		}
+		return duckdb.Output{}, nil
 	}
-	
+	return parseOutput(*c.outputFlag, *c.outputFormatFlag)
+}
+
+// parseOutput は出力先ファイルと出力形式の名前から出力設定を作成します
+func parseOutput(path string, formatName string) (duckdb.Output, error) {
+	output := duckdb.Output{Path: path}
+	switch {
+	case formatName != "":
+		format, err := duckdb.ParseOutputFormat(formatName)
+		if err != nil {
+			return output, err
+		}
+		output.Format = format
+	case output.Path != "":
+		output.Format = duckdb.OutputFormatFromPath(output.Path)
+	}
+	if output.Format == "" {
+		output.Format = duckdb.OutputTable
+	}
+
+	if output.Format == duckdb.OutputParquet && output.Path == "" {
+		return output, fmt.Errorf("Parquet形式で出力する場合は -o で出力先ファイルを指定してください")
+	}
+
+	return output, nil
+}
+
+// parseArgs はフラグと引数を解析します
+// ソース名やパスの後にフラグを指定できるように、フラグ以外の引数を取り除きながら解析を続けます
+// -- 以降はすべてフラグ以外の引数として扱います
+func (s *sourceFlags) parseArgs(args []string) error {
+	s.args = nil
+	for {
+		if err := s.flagSet.Parse(args); err != nil {
+			return err
+		}
+		rest := s.flagSet.Args()
+		if len(rest) == 0 {
+			break
+		}
+		// flag パッケージは -- を取り除くため、直前の引数で判定する
+		if consumed := len(args) - len(rest); consumed > 0 && args[consumed-1] == "--" {
+			s.args = append(s.args, rest...)
+			break
+		}
+		s.args = append(s.args, rest[0])
+		args = rest[1:]
+	}
+
+	s.set = make(map[string]bool)
+	s.flagSet.Visit(func(f *flag.Flag) {
+		s.set[f.Name] = true
+	})
+	return nil
+}
+
+// parse はログの読み込み元を指定するフラグと引数を解析します
+func (s *sourceFlags) parse() (*Options, error) {
+	// 設定ファイルのソース、環境変数、フラグの統合
+	src, args, err := s.resolve()
+	if err != nil {
+		return nil, err
+	}
+
+	// ログ形式の取得 (自動判定の場合は nil)
+	var format *schema.Format
+	if src.Format != "" && src.Format != schema.AutoFormatName {
+		f, err := schema.Get(src.Format)
+		if err != nil {
+			return nil, err
+		}
+		format = f
+	}
+
+	// 時間範囲の取得
+	var timeRange *source.TimeRange
+	if *s.fromFlag != "" || *s.toFlag != "" {
+		tr, err := source.ParseTimeRange(*s.fromFlag, *s.toFlag, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		timeRange = tr
+	}
+
 	// S3パスの取得
-	args := flag.Args()
-	if len(args) < 1 {
-		return "", "", fmt.Errorf("S3パスが指定されていません。使用方法: dalv [options] <s3-path>")
+	paths, err := parsePaths(args, src, format, timeRange)
+	if err != nil {
+		return nil, err
+	}
+
+	engine, err := parseEngine(*s.engineFlag)
+	if err != nil {
+		return nil, err
+	}
+
+	// S3の認証情報と接続先の取得
+	endpoint, useSSL, err := duckdb.ParseEndpoint(src.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	s3 := duckdb.S3Config{
+		Profile:         src.Profile,
+		Region:          src.Region,
+		RoleARN:         src.RoleARN,
+		RoleSessionName: src.RoleSessionName,
+		ExternalID:      src.ExternalID,
+		Endpoint:        endpoint,
+		URLStyle:        src.URLStyle,
+		DisableSSL:      !useSSL,
+	}
+	if err := s3.Validate(); err != nil {
+		return nil, err
+	}
+
+	confirmSize, err := parseSize(*s.confirmSize)
+	if err != nil {
+		return nil, fmt.Errorf("--confirm-size の指定が不正です: %w", err)
 	}
-	
-	// テーブル名の取得
-	tableName = *c.tableFlag
-	
-	return args[0], tableName, nil
+
+	return &Options{
+		Paths:            paths,
+		TableName:        src.Table,
+		Format:           format,
+		TimeRange:        timeRange,
+		DBPath:           *s.dbFlag,
+		NoDerivedColumns: *s.noDerived || *s.rawFlag,
+		Raw:              *s.rawFlag,
+		Engine:           engine,
+		S3:               s3,
+		ConfirmSize:      confirmSize,
+		AssumeYes:        *s.yesFlag,
+		DryRun:           *s.dryRunFlag,
+		PrintSQL:         *s.printSQLFlag,
+	}, nil
+}
+
+// resolve はログの読み込み元の設定を決定し、パスとして扱う引数を返します
+// 引数が1つで設定ファイルに同じ名前のソースがある場合は、そのソースを使用します
+// 存在するファイルやディレクトリはパスとして扱い、設定ファイルを読み込みません
+// 各項目は設定ファイル、環境変数、フラグの順に優先します
+func (s *sourceFlags) resolve() (config.Source, []string, error) {
+	var src config.Source
+	args := s.args
+	if len(args) == 1 && isSourceArg(args[0]) {
+		cfg, err := s.loadConfig()
+		if err != nil {
+			return src, nil, err
+		}
+		if named, ok := cfg.Source(args[0]); ok {
+			src, args = named, nil
+		}
+	}
+
+	env := config.FromEnv()
+	if len(args) > 0 || len(src.Paths) > 0 {
+		// パスを引数または名前付きのソースで指定した場合は環境変数の保存先を使用しない
+		env = env.WithoutLocation()
+	}
+	src = src.Override(env)
+
+	return src.Override(s.flagSource()), args, nil
+}
+
+// isSourceArg は引数を名前付きのソースとして解決するかどうかを返します
+// access.log のようにソースの名前と同じ形式のファイル名もあるため、存在するパスはソースとして扱いません
+func isSourceArg(arg string) bool {
+	if !config.IsSourceName(arg) {
+		return false
+	}
+	_, err := os.Stat(arg)
+	return err != nil
+}
+
+// flagSource はフラグで指定された読み込み元の項目を返します
+func (s *sourceFlags) flagSource() config.Source {
+	src := config.Source{
+		Bucket:          *s.bucketFlag,
+		Prefix:          *s.prefixFlag,
+		Account:         *s.accountFlag,
+		Region:          *s.regionFlag,
+		Table:           *s.tableFlag,
+		Profile:         *s.profileFlag,
+		RoleARN:         *s.roleARNFlag,
+		RoleSessionName: *s.sessionFlag,
+		ExternalID:      *s.externalIDFlag,
+		Endpoint:        *s.endpointFlag,
+		URLStyle:        *s.urlStyleFlag,
+	}
+	// --format はデフォルト値が auto のため、明示的に指定した場合のみ使用する
+	if s.set["format"] {
+		src.Format = *s.formatFlag
+	}
+	return src
+}
+
+// parsePaths は引数、ソースのパス、またはバケットなどの指定から読み込むS3パスを決定します
+// 引数には複数のS3パスを指定でき、すべて1つのテーブルに読み込みます
+// ローカルのファイル、glob、file:// URI、ディレクトリも指定できます
+func parsePaths(args []string, src config.Source, format *schema.Format, timeRange *source.TimeRange) ([]string, error) {
+	location := &source.ELBLocation{
+		Bucket:  src.Bucket,
+		Prefix:  src.Prefix,
+		Account: src.Account,
+		Region:  src.Region,
+	}
+	locationSpecified := src.HasLocation()
+
+	if len(args) == 0 {
+		args = src.Paths
+	}
+	if len(args) >= 1 {
+		if locationSpecified {
+			return nil, fmt.Errorf("S3パスと --bucket/--prefix/--account は同時に指定できません")
+		}
+		var paths []string
+		for _, arg := range args {
+			expanded, err := source.ExpandPath(arg)
+			if err != nil {
+				return nil, err
+			}
+			paths = append(paths, expanded...)
+		}
+		return paths, nil
+	}
+
+	if !locationSpecified {
+		return nil, fmt.Errorf("S3パスが指定されていません。使用方法: dalv [options] <path> [<path>...] または dalv [options] <source>")
+	}
+
+	// --bucket などの指定から日付ディレクトリのglobを生成
+	if err := location.Validate(); err != nil {
+		return nil, err
+	}
+	if timeRange == nil {
+		return nil, fmt.Errorf("--bucket/--account/--region を指定する場合は --from も指定してください")
+	}
+	if format == schema.CloudFront {
+		return nil, fmt.Errorf("--bucket/--account/--region によるパスの生成はELBログ (alb, nlb, clb) のみ対応しています")
+	}
+
+	return location.Globs(timeRange), nil
+}
+
+// parseQuery は -q または -f で指定されたSQLを取得します
+func (c *CLI) parseQuery() (string, error) {
+	if *c.queryFlag != "" && *c.fileFlag != "" {
+		return "", fmt.Errorf("-q と -f は同時に指定できません")
+	}
+
+	if *c.fileFlag == "" {
+		return *c.queryFlag, nil
+	}
+
+	data, err := os.ReadFile(*c.fileFlag)
+	if err != nil {
+		return "", fmt.Errorf("SQLファイルの読み込みに失敗しました: %w", err)
+	}
+	if len(data) == 0 {
+		return "", fmt.Errorf("SQLファイルが空です: %s", *c.fileFlag)
+	}
+
+	return string(data), nil
 }
 
 // printHelp はヘルプ情報を表示します
 func (c *CLI) printHelp() {
 	fmt.Println("dalv - AWS ALB S3ログをDuckDBでクエリするツール")
 	fmt.Println()
-	fmt.Println("使用方法: dalv [options] <s3-path>")
+	fmt.Println("使用方法: dalv [options] <path> [<path>...]")
+	fmt.Println("          dalv [options] --bucket <bucket> --account <id> --region <region> --from <time> [--to <time>]")
+	fmt.Println("          dalv [options] <source> [--from <time>] [--to <time>]")
+	fmt.Println("          dalv export --to <dir> [--partition-by <columns>] [options] <path> [<path>...]")
+	fmt.Println("          dalv report <name> [--param name=value] [options] <path> [<path>...]")
+	fmt.Println("          dalv latency [--by <columns>] [--interval <間隔>] [options] <path> [<path>...]")
+	fmt.Println("          dalv chart [--interval <間隔>] [--split-by <column>] [options] <path> [<path>...]")
+	fmt.Println("          dalv serve [--listen <addr>] [--token <token>] [options] <path> [<path>...]")
+	fmt.Println("          dalv mcp [--max-rows <n>] [options] <path> [<path>...]")
+	fmt.Println("          dalv cache ls|prune --db <path> [options]")
 	fmt.Println()
 	fmt.Println("引数:")
-	fmt.Println("  <s3-path>  AWS ALBログが保存されているS3パス")
+	fmt.Println("  <path>     ログが保存されているS3パスまたはローカルパス (デフォルトはALBログ)")
 	fmt.Println("             例: s3://bucket/path/to/logs/*.log.gz")
+	fmt.Println("                 ./logs/*.log.gz, file:///var/log/alb/, ./downloaded-logs/")
+	fmt.Println("             複数指定した場合は1つのテーブルに読み込みます")
+	fmt.Println("             各行の読み込み元ファイルは source_path カラムで確認できます")
+	fmt.Println("  <source>   設定ファイルで定義した名前付きのソース (例: prod-api)")
+	fmt.Println()
+	fmt.Println("設定ファイル:")
+	fmt.Printf("  ~/.config/dalv/config.yaml (%s で変更可能) とカレントディレクトリの %s を読み込みます\n", config.PathEnv, config.LocalFileName)
+	fmt.Println("  sources の下にソースごとの paths または bucket, prefix, account と")
+	fmt.Println("  region, format, table, profile, role_arn, role_session_name, external_id,")
+	fmt.Println("  s3_endpoint, url_style を定義できます (同じ名前のソースは .dalv.yaml を優先)")
+	fmt.Println("  各項目は 設定ファイル < 環境変数 < フラグ の順に優先します")
+	fmt.Printf("  環境変数: %s\n", strings.Join([]string{config.BucketEnv, config.PrefixEnv, config.AccountEnv, config.RegionEnv, config.FormatEnv, config.TableEnv, config.ProfileEnv, config.EndpointEnv}, ", "))
+	fmt.Println()
+	fmt.Println("対応ログ形式:")
+	for _, name := range schema.Names() {
+		format, _ := schema.Get(name)
+		fmt.Printf("  %-10s %s\n", name, format.Description)
+	}
 	fmt.Println()
 	fmt.Println("オプション:")
-	flag.PrintDefaults()
+	c.flagSet.PrintDefaults()
 }
 
 // printVersion はバージョン情報を表示します
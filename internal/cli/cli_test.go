@@ -2,129 +2,1077 @@ package cli
 
 import (
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/naotama2002/dalv/internal/config"
+	"github.com/naotama2002/dalv/internal/duckdb"
+	"github.com/naotama2002/dalv/internal/schema"
 )
 
-// CLIのモック実装
-type mockCLI struct {
-	args        []string
-	helpFlag    bool
-	versionFlag bool
-	tableValue  string
+func TestCLIWithTableFlag(t *testing.T) {
+	// テーブル名指定のテスト
+	opts, err := NewCLI([]string{"-t", "test_table", "s3://bucket/path"}).Parse()
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+
+	if len(opts.Paths) != 1 || opts.Paths[0] != "s3://bucket/path" {
+		t.Fatalf("Expected Paths to be [s3://bucket/path], got %v", opts.Paths)
+	}
+
+	if opts.TableName != "test_table" {
+		t.Fatalf("Expected TableName to be 'test_table', got '%s'", opts.TableName)
+	}
 }
 
-func (m *mockCLI) Parse() (string, string, error) {
-	// 引数が空の場合はエラー
-	if len(m.args) == 0 {
-		return "", "", fmt.Errorf("S3パスが指定されていません")
+func TestCLIWithoutTableFlag(t *testing.T) {
+	// テーブル名指定なしのテスト
+	opts, err := NewCLI([]string{"s3://bucket/path"}).Parse()
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+
+	if len(opts.Paths) != 1 || opts.Paths[0] != "s3://bucket/path" {
+		t.Fatalf("Expected Paths to be [s3://bucket/path], got %v", opts.Paths)
 	}
 
-	// ヘルプフラグが指定された場合
-	if m.helpFlag {
-		return "", "", nil
+	if opts.TableName != "" {
+		t.Fatalf("Expected TableName to be empty, got '%s'", opts.TableName)
 	}
+}
 
-	// バージョンフラグが指定された場合
-	if m.versionFlag {
-		return "", "", nil
+func TestCLIWithHelpFlag(t *testing.T) {
+	// ヘルプを表示した場合はオプションを返さない
+	opts, err := NewCLI([]string{"-h"}).Parse()
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
 	}
 
-	// 最後の引数をS3パスとして扱う
-	s3Path := m.args[len(m.args)-1]
-	return s3Path, m.tableValue, nil
+	if opts != nil {
+		t.Fatalf("Expected nil options, got %+v", opts)
+	}
 }
 
-func TestCLIWithTableFlag(t *testing.T) {
-	// テーブル名指定のテスト
-	mock := &mockCLI{
-		args:       []string{"-t", "test_table", "s3://bucket/path"},
-		tableValue: "test_table",
+func TestCLIWithVersionFlag(t *testing.T) {
+	// バージョンを表示した場合はオプションを返さない
+	opts, err := NewCLI([]string{"-v"}).Parse()
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+
+	if opts != nil {
+		t.Fatalf("Expected nil options, got %+v", opts)
 	}
+}
 
-	s3Path, tableName, err := mock.Parse()
+func TestCLIWithNoArgs(t *testing.T) {
+	// 引数なしのテスト
+	_, err := NewCLI([]string{}).Parse()
+	if err == nil {
+		t.Fatal("Expected error for no args, got nil")
+	}
+}
+
+func TestParseWithQueryFlag(t *testing.T) {
+	// -q で指定したSQLが取得できることを確認
+	opts, err := NewCLI([]string{"-q", "SELECT 1", "s3://bucket/path"}).Parse()
 	if err != nil {
 		t.Fatalf("Parse returned error: %v", err)
 	}
 
-	if s3Path != "s3://bucket/path" {
-		t.Fatalf("Expected s3Path to be 's3://bucket/path', got '%s'", s3Path)
+	if opts.Query != "SELECT 1" {
+		t.Errorf("Expected Query to be 'SELECT 1', got '%s'", opts.Query)
 	}
 
-	if tableName != "test_table" {
-		t.Fatalf("Expected tableName to be 'test_table', got '%s'", tableName)
+	if opts.Interactive() {
+		t.Error("Expected non-interactive mode when query is specified")
 	}
 }
 
-func TestCLIWithoutTableFlag(t *testing.T) {
-	// テーブル名指定なしのテスト
-	mock := &mockCLI{
-		args: []string{"s3://bucket/path"},
+func TestParseWithFileFlag(t *testing.T) {
+	// -f で指定したSQLファイルの内容が取得できることを確認
+	sqlFile := filepath.Join(t.TempDir(), "query.sql")
+	if err := os.WriteFile(sqlFile, []byte("SELECT 2;"), 0644); err != nil {
+		t.Fatalf("Failed to write SQL file: %v", err)
 	}
 
-	s3Path, tableName, err := mock.Parse()
+	opts, err := NewCLI([]string{"-f", sqlFile, "s3://bucket/path"}).Parse()
 	if err != nil {
 		t.Fatalf("Parse returned error: %v", err)
 	}
 
-	if s3Path != "s3://bucket/path" {
-		t.Fatalf("Expected s3Path to be 's3://bucket/path', got '%s'", s3Path)
+	if opts.Query != "SELECT 2;" {
+		t.Errorf("Expected Query to be 'SELECT 2;', got '%s'", opts.Query)
 	}
+}
 
-	if tableName != "" {
-		t.Fatalf("Expected tableName to be empty, got '%s'", tableName)
+func TestParseWithQueryAndFileFlags(t *testing.T) {
+	// -q と -f の同時指定はエラー
+	_, err := NewCLI([]string{"-q", "SELECT 1", "-f", "query.sql", "s3://bucket/path"}).Parse()
+	if err == nil {
+		t.Fatal("Expected error when both -q and -f are specified, got nil")
 	}
 }
 
-func TestCLIWithHelpFlag(t *testing.T) {
-	// ヘルプフラグのテスト
-	mock := &mockCLI{
-		args:     []string{"-h"},
-		helpFlag: true,
+func TestParseInteractive(t *testing.T) {
+	// クエリ指定なしの場合はインタラクティブモード
+	opts, err := NewCLI([]string{"-t", "test_table", "s3://bucket/path"}).Parse()
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+
+	if len(opts.Paths) != 1 || opts.Paths[0] != "s3://bucket/path" || opts.TableName != "test_table" {
+		t.Errorf("Unexpected options: %+v", opts)
+	}
+
+	if !opts.Interactive() {
+		t.Error("Expected interactive mode when no query is specified")
 	}
+}
 
-	s3Path, tableName, err := mock.Parse()
+func TestParseFormat(t *testing.T) {
+	// --format を指定しない場合は自動判定 (nil)
+	opts, err := NewCLI([]string{"s3://bucket/path"}).Parse()
 	if err != nil {
 		t.Fatalf("Parse returned error: %v", err)
 	}
+	if opts.Format != nil {
+		t.Errorf("Expected Format to be nil for auto detection, got '%s'", opts.Format.Name)
+	}
 
-	if s3Path != "" {
-		t.Fatalf("Expected s3Path to be empty, got '%s'", s3Path)
+	// --format を指定した場合はそのログ形式
+	opts, err = NewCLI([]string{"--format", "nlb", "s3://bucket/path"}).Parse()
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if opts.Format == nil || opts.Format.Name != "nlb" {
+		t.Errorf("Expected Format to be 'nlb', got %v", opts.Format)
 	}
 
-	if tableName != "" {
-		t.Fatalf("Expected tableName to be empty, got '%s'", tableName)
+	// 未対応のログ形式はエラー
+	if _, err := NewCLI([]string{"--format", "unknown", "s3://bucket/path"}).Parse(); err == nil {
+		t.Error("Expected error for unknown format, got nil")
 	}
 }
 
-func TestCLIWithVersionFlag(t *testing.T) {
-	// バージョンフラグのテスト
-	mock := &mockCLI{
-		args:        []string{"-v"},
-		versionFlag: true,
+func TestParseWithTimeRangeLocation(t *testing.T) {
+	// --bucket などと --from/--to から日付ディレクトリのglobを生成
+	opts, err := NewCLI([]string{
+		"--bucket", "my-bucket", "--account", "123456789012", "--region", "ap-northeast-1",
+		"--from", "2025-03-30T22:00", "--to", "2025-04-01",
+	}).Parse()
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
 	}
 
-	s3Path, tableName, err := mock.Parse()
+	base := "s3://my-bucket/AWSLogs/123456789012/elasticloadbalancing/ap-northeast-1"
+	expected := []string{
+		base + "/2025/03/30/*.log*",
+		base + "/2025/03/31/*.log*",
+		base + "/2025/04/01/*.log*",
+		base + "/2025/04/02/*.log*",
+	}
+	if strings.Join(opts.Paths, ",") != strings.Join(expected, ",") {
+		t.Errorf("Unexpected paths: %v", opts.Paths)
+	}
+
+	if opts.TimeRange == nil {
+		t.Fatal("Expected TimeRange to be set")
+	}
+}
+
+func TestParseWithTimeRangeAndPath(t *testing.T) {
+	// S3パスと --from を併用した場合は時間範囲での絞り込みのみ
+	opts, err := NewCLI([]string{"--from", "2025-03-03T10:00", "--to", "2025-03-03T11:00", "s3://bucket/path/*.log.gz"}).Parse()
 	if err != nil {
 		t.Fatalf("Parse returned error: %v", err)
 	}
 
-	if s3Path != "" {
-		t.Fatalf("Expected s3Path to be empty, got '%s'", s3Path)
+	if len(opts.Paths) != 1 || opts.Paths[0] != "s3://bucket/path/*.log.gz" {
+		t.Errorf("Unexpected paths: %v", opts.Paths)
 	}
 
-	if tableName != "" {
-		t.Fatalf("Expected tableName to be empty, got '%s'", tableName)
+	if opts.TimeRange == nil {
+		t.Error("Expected TimeRange to be set")
 	}
 }
 
-func TestCLIWithNoArgs(t *testing.T) {
-	// 引数なしのテスト
-	mock := &mockCLI{
-		args: []string{},
+func TestParseWithLocationErrors(t *testing.T) {
+	testCases := [][]string{
+		// --from がない
+		{"--bucket", "b", "--account", "1", "--region", "r"},
+		// --account がない
+		{"--bucket", "b", "--region", "r", "--from", "2025-03-03"},
+		// S3パスとの同時指定
+		{"--bucket", "b", "--account", "1", "--region", "r", "--from", "2025-03-03", "s3://bucket/path"},
+		// CloudFrontは未対応
+		{"--format", "cloudfront", "--bucket", "b", "--account", "1", "--region", "r", "--from", "2025-03-03"},
 	}
 
-	_, _, err := mock.Parse()
-	if err == nil {
-		t.Fatal("Expected error for no args, got nil")
+	for _, args := range testCases {
+		if _, err := NewCLI(args).Parse(); err == nil {
+			t.Errorf("Expected error for args %v, got nil", args)
+		}
+	}
+}
+
+// testConfig はソースの解決のテストで使用する設定ファイルの内容です
+const testConfig = `
+sources:
+  prod-api:
+    bucket: prod-logs
+    prefix: api
+    account: "123456789012"
+    region: ap-northeast-1
+    format: alb
+    table: api_logs
+    profile: prod
+  local:
+    paths: [s3://local-bucket/logs/*.log.gz]
+    format: nlb
+`
+
+// newConfigCLI は設定ファイルの代わりに testConfig を読み込むCLIを作成します
+func newConfigCLI(t *testing.T, args ...string) *CLI {
+	t.Helper()
+	cfg, err := config.Parse([]byte(testConfig))
+	if err != nil {
+		t.Fatalf("config.Parse returned error: %v", err)
+	}
+	c := NewCLI(args)
+	c.loadConfig = func() (*config.Config, error) { return cfg, nil }
+	return c
+}
+
+func TestParseWithSource(t *testing.T) {
+	// ソース名の後にフラグを指定できる
+	opts, err := newConfigCLI(t, "prod-api", "--from", "2025-03-30T22:00", "--to", "2025-03-31").Parse()
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+
+	base := "s3://prod-logs/api/AWSLogs/123456789012/elasticloadbalancing/ap-northeast-1"
+	if len(opts.Paths) != 3 || opts.Paths[0] != base+"/2025/03/30/*.log*" {
+		t.Errorf("Unexpected paths: %v", opts.Paths)
+	}
+	if opts.Format != schema.ALB {
+		t.Errorf("Expected format alb, got %v", opts.Format)
+	}
+	if opts.TableName != "api_logs" {
+		t.Errorf("Expected TableName 'api_logs', got '%s'", opts.TableName)
+	}
+	if opts.S3.Profile != "prod" {
+		t.Errorf("Expected Profile 'prod', got '%s'", opts.S3.Profile)
+	}
+
+	// パスを定義したソース
+	opts, err = newConfigCLI(t, "-q", "SELECT 1", "local").Parse()
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if len(opts.Paths) != 1 || opts.Paths[0] != "s3://local-bucket/logs/*.log.gz" {
+		t.Errorf("Unexpected paths: %v", opts.Paths)
+	}
+	if opts.Format != schema.NLB {
+		t.Errorf("Expected format nlb, got %v", opts.Format)
+	}
+
+	// 設定ファイルにないソースはパスとして扱う
+	opts, err = newConfigCLI(t, "unknown-source").Parse()
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if len(opts.Paths) != 1 || opts.Paths[0] != "unknown-source" || opts.Format != nil {
+		t.Errorf("Expected unknown source to be treated as a path, got %v", opts.Paths)
+	}
+}
+
+func TestParseSourcePrecedence(t *testing.T) {
+	// 設定ファイル < 環境変数 < フラグ の順に優先する
+	t.Setenv(config.RegionEnv, "us-east-1")
+	t.Setenv(config.TableEnv, "env_logs")
+	t.Setenv(config.ProfileEnv, "env-profile")
+
+	opts, err := newConfigCLI(t, "prod-api", "--from", "2025-03-30", "-t", "flag_logs", "--format", "auto").Parse()
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if !strings.Contains(opts.Paths[0], "/elasticloadbalancing/us-east-1/") {
+		t.Errorf("Expected region from environment, got %v", opts.Paths)
+	}
+	if opts.TableName != "flag_logs" {
+		t.Errorf("Expected TableName from flag, got '%s'", opts.TableName)
+	}
+	if opts.S3.Profile != "env-profile" {
+		t.Errorf("Expected Profile from environment, got '%s'", opts.S3.Profile)
+	}
+	if opts.Format != nil {
+		t.Errorf("Expected explicit --format auto to override config, got %v", opts.Format)
+	}
+
+	// パスを指定した場合は環境変数の保存先を使用しない
+	opts, err = newConfigCLI(t, "s3://bucket/path").Parse()
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if len(opts.Paths) != 1 || opts.Paths[0] != "s3://bucket/path" {
+		t.Errorf("Unexpected paths: %v", opts.Paths)
+	}
+	if opts.TableName != "env_logs" {
+		t.Errorf("Expected TableName from environment, got '%s'", opts.TableName)
+	}
+
+	// パスを定義したソースでも環境変数の保存先を使用しない
+	t.Setenv(config.BucketEnv, "env-bucket")
+	t.Setenv(config.AccountEnv, "123456789012")
+	opts, err = newConfigCLI(t, "-q", "SELECT 1", "local").Parse()
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if len(opts.Paths) != 1 || opts.Paths[0] != "s3://local-bucket/logs/*.log.gz" {
+		t.Errorf("Unexpected paths: %v", opts.Paths)
+	}
+	if opts.S3.Region != "us-east-1" {
+		t.Errorf("Expected Region from environment, got '%s'", opts.S3.Region)
+	}
+}
+
+func TestParseWithSourceErrors(t *testing.T) {
+	testCases := [][]string{
+		// パスを定義したソースと --bucket の同時指定
+		{"--bucket", "b", "local"},
+		// 保存先を定義したソースで --from がない
+		{"prod-api"},
+	}
+
+	for _, args := range testCases {
+		if _, err := newConfigCLI(t, args...).Parse(); err == nil {
+			t.Errorf("Expected error for args %v, got nil", args)
+		}
+	}
+
+	// 設定ファイルの読み込みに失敗した場合
+	c := NewCLI([]string{"prod-api"})
+	c.loadConfig = func() (*config.Config, error) { return nil, fmt.Errorf("broken") }
+	if _, err := c.Parse(); err == nil || err.Error() != "broken" {
+		t.Errorf("Expected config error, got %v", err)
+	}
+}
+
+func TestParseExistingPathIsNotSource(t *testing.T) {
+	// ソースの名前と同じ形式でも存在するファイルはパスとして扱い、設定ファイルを読み込まない
+	dir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Getwd returned error: %v", err)
+	}
+	tmp := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmp, "access.log"), []byte(""), 0o644); err != nil {
+		t.Fatalf("WriteFile returned error: %v", err)
+	}
+	if err := os.Chdir(tmp); err != nil {
+		t.Fatalf("Chdir returned error: %v", err)
+	}
+	t.Cleanup(func() { os.Chdir(dir) })
+
+	c := NewCLI([]string{"--format", "alb", "access.log"})
+	c.loadConfig = func() (*config.Config, error) { return nil, fmt.Errorf("broken") }
+	opts, err := c.Parse()
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if len(opts.Paths) != 1 || opts.Paths[0] != "access.log" {
+		t.Errorf("Expected access.log to be used as a path, got %v", opts.Paths)
+	}
+}
+
+func TestParseWithS3Config(t *testing.T) {
+	// --region はS3パスと併用でき、S3の接続に使用する
+	opts, err := NewCLI([]string{
+		"--profile", "prod", "--region", "us-west-2",
+		"--role-arn", "arn:aws:iam::123456789012:role/log-reader", "--role-session-name", "dalv", "--external-id", "ext",
+		"--s3-endpoint", "s3.example.com", "--url-style", "path",
+		"s3://bucket/path/*.log.gz",
+	}).Parse()
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+
+	expected := duckdb.S3Config{
+		Profile:         "prod",
+		Region:          "us-west-2",
+		RoleARN:         "arn:aws:iam::123456789012:role/log-reader",
+		RoleSessionName: "dalv",
+		ExternalID:      "ext",
+		Endpoint:        "s3.example.com",
+		URLStyle:        duckdb.URLStylePath,
+	}
+	if opts.S3 != expected {
+		t.Errorf("Expected S3 config %+v, got %+v", expected, opts.S3)
+	}
+
+	// --bucket などで指定した場合はELBのリージョンをS3の接続にも使用する
+	opts, err = NewCLI([]string{"--bucket", "b", "--account", "123456789012", "--region", "eu-west-1", "--from", "2025-03-03"}).Parse()
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if opts.S3.Region != "eu-west-1" {
+		t.Errorf("Expected region 'eu-west-1', got '%s'", opts.S3.Region)
+	}
+}
+
+func TestParseWithS3Endpoint(t *testing.T) {
+	// http:// のエンドポイントはSSLを使用しない (MinIOなどのローカル環境向け)
+	t.Setenv(config.EndpointEnv, "http://localhost:9000")
+	opts, err := NewCLI([]string{"s3://dalv-test/alb/"}).Parse()
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if opts.S3.Endpoint != "localhost:9000" || !opts.S3.DisableSSL {
+		t.Errorf("Expected endpoint without SSL, got %+v", opts.S3)
+	}
+
+	// フラグは環境変数より優先する
+	opts, err = NewCLI([]string{"--s3-endpoint", "https://s3.example.com", "s3://dalv-test/alb/"}).Parse()
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if opts.S3.Endpoint != "s3.example.com" || opts.S3.DisableSSL {
+		t.Errorf("Expected endpoint with SSL, got %+v", opts.S3)
+	}
+}
+
+func TestParseWithS3ConfigErrors(t *testing.T) {
+	testCases := [][]string{
+		{"--role-arn", "log-reader", "s3://bucket/path"},
+		{"--external-id", "ext", "s3://bucket/path"},
+		{"--s3-endpoint", "ftp://s3.example.com", "s3://bucket/path"},
+		{"--s3-endpoint", "http://localhost:9000/path", "s3://bucket/path"},
+		{"--url-style", "virtual", "s3://bucket/path"},
+	}
+
+	for _, args := range testCases {
+		if _, err := NewCLI(args).Parse(); err == nil {
+			t.Errorf("Expected error for args %v, got nil", args)
+		}
+	}
+}
+
+func TestParseInterspersedFlags(t *testing.T) {
+	// パスの後のフラグも解析し、-- 以降はパスとして扱う
+	dir := t.TempDir()
+	dashed := filepath.Join(dir, "-q.log")
+	if err := os.WriteFile(dashed, []byte("log"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	opts, err := NewCLI([]string{"s3://bucket/a", "-q", "SELECT 1", "s3://bucket/b", "--", dashed}).Parse()
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if opts.Query != "SELECT 1" {
+		t.Errorf("Expected query 'SELECT 1', got '%s'", opts.Query)
+	}
+	if len(opts.Paths) != 3 || opts.Paths[1] != "s3://bucket/b" {
+		t.Errorf("Unexpected paths: %v", opts.Paths)
+	}
+}
+
+func TestParseWithMultiplePaths(t *testing.T) {
+	// 複数のS3パスをすべて取得できることを確認
+	opts, err := NewCLI([]string{
+		"-t", "test_table",
+		"s3://bucket-a/AWSLogs/111111111111/elasticloadbalancing/ap-northeast-1/2025/03/03/*.log.gz",
+		"s3://bucket-b/AWSLogs/222222222222/elasticloadbalancing/us-east-1/2025/03/03/*.log.gz",
+	}).Parse()
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+
+	if len(opts.Paths) != 2 {
+		t.Fatalf("Expected 2 paths, got %d: %v", len(opts.Paths), opts.Paths)
+	}
+
+	if !strings.HasPrefix(opts.Paths[0], "s3://bucket-a/") || !strings.HasPrefix(opts.Paths[1], "s3://bucket-b/") {
+		t.Errorf("Unexpected paths: %v", opts.Paths)
+	}
+}
+
+func TestParseWithLocalPaths(t *testing.T) {
+	// file:// URI はローカルパスに変換し、ディレクトリはglobに展開する
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "app.log.gz"), nil, 0644); err != nil {
+		t.Fatalf("Failed to create log file: %v", err)
+	}
+
+	opts, err := NewCLI([]string{"file:///var/log/alb/app.log.gz", dir}).Parse()
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+
+	expected := []string{"/var/log/alb/app.log.gz", filepath.Join(dir, "**", "*.gz")}
+	if strings.Join(opts.Paths, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected paths %v, got %v", expected, opts.Paths)
+	}
+}
+
+func TestParseWithDB(t *testing.T) {
+	cli := NewCLI([]string{"--db", "cache.duckdb", "s3://bucket/path/*.log.gz"})
+	opts, err := cli.Parse()
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if opts.DBPath != "cache.duckdb" {
+		t.Errorf("Expected DBPath to be 'cache.duckdb', got '%s'", opts.DBPath)
+	}
+	if opts.Command != "" {
+		t.Errorf("Expected no command, got '%s'", opts.Command)
+	}
+}
+
+func TestParseConfirmSize(t *testing.T) {
+	// デフォルトでは10GBを超える場合に確認する
+	opts, err := NewCLI([]string{"s3://bucket/path/*.log.gz"}).Parse()
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if opts.ConfirmSize != 10<<30 || opts.AssumeYes {
+		t.Errorf("Expected default confirm size 10GiB without --yes, got %d, %v", opts.ConfirmSize, opts.AssumeYes)
+	}
+
+	opts, err = NewCLI([]string{"--confirm-size", "500MB", "-y", "s3://bucket/path/*.log.gz"}).Parse()
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if opts.ConfirmSize != 500<<20 || !opts.AssumeYes {
+		t.Errorf("Expected confirm size 500MiB with --yes, got %d, %v", opts.ConfirmSize, opts.AssumeYes)
+	}
+
+	if _, err := NewCLI([]string{"--confirm-size", "ten", "s3://bucket/path/*.log.gz"}).Parse(); err == nil {
+		t.Error("Expected error for invalid --confirm-size, got nil")
+	}
+}
+
+func TestParseDryRun(t *testing.T) {
+	opts, err := NewCLI([]string{"s3://bucket/path/*.log.gz"}).Parse()
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if opts.DryRun || opts.PrintSQL {
+		t.Errorf("Expected --dry-run and --print-sql to be disabled by default, got %v, %v", opts.DryRun, opts.PrintSQL)
+	}
+
+	opts, err = NewCLI([]string{"--dry-run", "s3://bucket/path/*.log.gz", "--print-sql"}).Parse()
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if !opts.DryRun || !opts.PrintSQL {
+		t.Errorf("Expected --dry-run and --print-sql to be enabled, got %v, %v", opts.DryRun, opts.PrintSQL)
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected int64
+	}{
+		{"0", 0},
+		{"512", 512},
+		{"512B", 512},
+		{"1k", 1 << 10},
+		{"1.5KB", 1536},
+		{"500MB", 500 << 20},
+		{"10GB", 10 << 30},
+		{"10 GiB", 10 << 30},
+		{"2T", 2 << 40},
+	}
+
+	for _, tc := range testCases {
+		actual, err := parseSize(tc.input)
+		if err != nil {
+			t.Errorf("parseSize(%q) returned error: %v", tc.input, err)
+			continue
+		}
+		if actual != tc.expected {
+			t.Errorf("parseSize(%q) = %d, expected %d", tc.input, actual, tc.expected)
+		}
+	}
+
+	for _, input := range []string{"", "GB", "-1GB", "10XB", "1e30GB"} {
+		if _, err := parseSize(input); err == nil {
+			t.Errorf("parseSize(%q): expected error, got nil", input)
+		}
+	}
+}
+
+func TestParseEngine(t *testing.T) {
+	// 指定しない場合は auto
+	opts, err := NewCLI([]string{"s3://bucket/path/*.log.gz"}).Parse()
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if opts.Engine != "auto" {
+		t.Errorf("Expected Engine to be 'auto', got '%s'", opts.Engine)
+	}
+
+	opts, err = NewCLI([]string{"--engine", "embedded", "s3://bucket/path/*.log.gz"}).Parse()
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if opts.Engine != "embedded" {
+		t.Errorf("Expected Engine to be 'embedded', got '%s'", opts.Engine)
+	}
+
+	if _, err := NewCLI([]string{"--engine", "sqlite", "s3://bucket/path/*.log.gz"}).Parse(); err == nil {
+		t.Error("Parse should return error for unsupported engine")
+	}
+}
+
+func TestParseCache(t *testing.T) {
+	now := time.Date(2025, 3, 30, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		args         []string
+		action       string
+		loadedBefore time.Time
+	}{
+		{[]string{"ls", "--db", "cache.duckdb"}, CacheActionList, time.Time{}},
+		{[]string{"prune", "--db", "cache.duckdb", "--all"}, CacheActionPrune, time.Time{}},
+		{[]string{"prune", "--db", "cache.duckdb", "--older-than", "7d"}, CacheActionPrune, now.AddDate(0, 0, -7)},
+		{[]string{"prune", "--db", "cache.duckdb", "--older-than", "12h"}, CacheActionPrune, now.Add(-12 * time.Hour)},
+	}
+
+	for _, tc := range testCases {
+		opts, err := NewCLI(nil).parseCache(tc.args, now)
+		if err != nil {
+			t.Errorf("parseCache(%v) returned error: %v", tc.args, err)
+			continue
+		}
+		if opts.Command != CommandCache || opts.Cache.Action != tc.action || opts.Cache.DBPath != "cache.duckdb" {
+			t.Errorf("parseCache(%v) returned unexpected options: %+v", tc.args, opts.Cache)
+		}
+		if !opts.Cache.LoadedBefore.Equal(tc.loadedBefore) {
+			t.Errorf("parseCache(%v) LoadedBefore = %v, expected %v", tc.args, opts.Cache.LoadedBefore, tc.loadedBefore)
+		}
+	}
+}
+
+func TestParseCacheErrors(t *testing.T) {
+	testCases := [][]string{
+		// 操作の指定なし
+		{"cache"},
+		// 未対応の操作
+		{"cache", "rm", "--db", "cache.duckdb"},
+		// --db の指定なし
+		{"cache", "ls"},
+		// prune の条件の指定なし
+		{"cache", "prune", "--db", "cache.duckdb"},
+		// --older-than と --all の同時指定
+		{"cache", "prune", "--db", "cache.duckdb", "--older-than", "7d", "--all"},
+		// 不正な期間
+		{"cache", "prune", "--db", "cache.duckdb", "--older-than", "a week"},
+		// ls で prune のオプションを指定
+		{"cache", "ls", "--db", "cache.duckdb", "--all"},
+	}
+
+	for _, args := range testCases {
+		if _, err := NewCLI(args).Parse(); err == nil {
+			t.Errorf("Parse(%v) should return error", args)
+		}
+	}
+}
+
+func TestParseExport(t *testing.T) {
+	cli := NewCLI([]string{"export", "--to", "./out", "--partition-by", "date, elb", "--compression", "ZSTD", "--from", "2025-03-30", "--until", "2025-03-31", "s3://bucket/path/*.log.gz"})
+	opts, err := cli.Parse()
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+
+	if opts.Command != CommandExport {
+		t.Errorf("Expected command to be '%s', got '%s'", CommandExport, opts.Command)
+	}
+	if opts.Export.Destination != "./out" || opts.Export.Compression != "zstd" {
+		t.Errorf("Unexpected export options: %+v", opts.Export)
+	}
+	if strings.Join(opts.Export.PartitionBy, ",") != "date,elb" {
+		t.Errorf("Expected partition columns to be date,elb, got %v", opts.Export.PartitionBy)
+	}
+	if opts.TimeRange == nil || opts.TimeRange.To.Day() != 1 {
+		t.Errorf("Expected time range to end at 2025-04-01, got %v", opts.TimeRange)
+	}
+	if len(opts.Paths) != 1 || opts.Paths[0] != "s3://bucket/path/*.log.gz" {
+		t.Errorf("Unexpected paths: %v", opts.Paths)
+	}
+}
+
+func TestParseExportErrors(t *testing.T) {
+	testCases := [][]string{
+		// 出力先の指定なし
+		{"export", "s3://bucket/path/*.log.gz"},
+		// 未対応の圧縮形式
+		{"export", "--to", "./out", "--compression", "lz4", "s3://bucket/path/*.log.gz"},
+		// パスの指定なし
+		{"export", "--to", "./out"},
+	}
+
+	for _, args := range testCases {
+		if _, err := NewCLI(args).Parse(); err == nil {
+			t.Errorf("Parse(%v) should return error", args)
+		}
+	}
+}
+
+func TestParseColumnList(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"date,elb", "date,elb"},
+		{" date , elb ,", "date,elb"},
+		{"none", ""},
+		{"", ""},
+	}
+
+	for _, tc := range testCases {
+		if got := strings.Join(parseColumnList(tc.input), ","); got != tc.expected {
+			t.Errorf("parseColumnList(%q) = %q, expected %q", tc.input, got, tc.expected)
+		}
+	}
+}
+
+func TestParseOutput(t *testing.T) {
+	testCases := []struct {
+		args   []string
+		format duckdb.OutputFormat
+		path   string
+	}{
+		{[]string{"-q", "SELECT 1", "s3://bucket/path"}, duckdb.OutputTable, ""},
+		{[]string{"-q", "SELECT 1", "--output-format", "csv", "s3://bucket/path"}, duckdb.OutputCSV, ""},
+		{[]string{"-q", "SELECT 1", "-o", "results.ndjson", "s3://bucket/path"}, duckdb.OutputNDJSON, "results.ndjson"},
+		{[]string{"-q", "SELECT 1", "-o", "results.txt", "--output-format", "tsv", "s3://bucket/path"}, duckdb.OutputTSV, "results.txt"},
+		{[]string{"-q", "SELECT 1", "-o", "results.txt", "s3://bucket/path"}, duckdb.OutputTable, "results.txt"},
+		{[]string{"-q", "SELECT 1", "-o", "results.parquet", "s3://bucket/path"}, duckdb.OutputParquet, "results.parquet"},
+	}
+
+	for _, tc := range testCases {
+		opts, err := NewCLI(tc.args).Parse()
+		if err != nil {
+			t.Errorf("Parse(%v) returned error: %v", tc.args, err)
+			continue
+		}
+		if opts.Output.Format != tc.format || opts.Output.Path != tc.path {
+			t.Errorf("Parse(%v) output = %+v, expected format %q and path %q", tc.args, opts.Output, tc.format, tc.path)
+		}
+	}
+}
+
+func TestParseOutputErrors(t *testing.T) {
+	testCases := [][]string{
+		// インタラクティブモードでは指定できない
+		{"-o", "results.csv", "s3://bucket/path"},
+		// 未対応の出力形式
+		{"-q", "SELECT 1", "--output-format", "xml", "s3://bucket/path"},
+		// Parquetは出力先ファイルが必要
+		{"-q", "SELECT 1", "--output-format", "parquet", "s3://bucket/path"},
+	}
+
+	for _, args := range testCases {
+		if _, err := NewCLI(args).Parse(); err == nil {
+			t.Errorf("Parse(%v) should return error", args)
+		}
+	}
+}
+
+func TestParseWithNoDerived(t *testing.T) {
+	opts, err := NewCLI([]string{"s3://bucket/path"}).Parse()
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if opts.NoDerivedColumns {
+		t.Error("Expected derived columns to be enabled by default")
+	}
+
+	opts, err = NewCLI([]string{"--no-derived", "s3://bucket/path"}).Parse()
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if !opts.NoDerivedColumns {
+		t.Error("Expected derived columns to be disabled")
+	}
+}
+
+func TestParseWithRaw(t *testing.T) {
+	opts, err := NewCLI([]string{"--raw", "s3://bucket/path"}).Parse()
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+
+	// --raw は派生カラムも追加しない
+	if !opts.Raw || !opts.NoDerivedColumns {
+		t.Errorf("Expected raw load without derived columns, got Raw=%v NoDerivedColumns=%v", opts.Raw, opts.NoDerivedColumns)
+	}
+}
+
+func TestParseReport(t *testing.T) {
+	cli := NewCLI([]string{"report", "top-5xx-paths", "--param", "limit=5", "--reports-dir", "./reports", "--output-format", "markdown", "s3://bucket/path/*.log.gz"})
+	opts, err := cli.Parse()
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+
+	if opts.Command != CommandReport {
+		t.Errorf("Expected command to be '%s', got '%s'", CommandReport, opts.Command)
+	}
+	if opts.Report.Name != "top-5xx-paths" || opts.Report.Dir != "./reports" || opts.Report.List {
+		t.Errorf("Unexpected report options: %+v", opts.Report)
+	}
+	if opts.Report.Params["limit"] != "5" {
+		t.Errorf("Expected limit param to be 5, got %v", opts.Report.Params)
+	}
+	if opts.Output.Format != duckdb.OutputMarkdown {
+		t.Errorf("Expected output format to be markdown, got %q", opts.Output.Format)
+	}
+	if len(opts.Paths) != 1 || opts.Paths[0] != "s3://bucket/path/*.log.gz" {
+		t.Errorf("Unexpected paths: %v", opts.Paths)
+	}
+}
+
+func TestParseReportList(t *testing.T) {
+	opts, err := NewCLI([]string{"report", "--list"}).Parse()
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if opts.Command != CommandReport || !opts.Report.List {
+		t.Errorf("Expected report list options, got %+v", opts.Report)
+	}
+}
+
+func TestParseReportErrors(t *testing.T) {
+	testCases := [][]string{
+		// レポート名の指定なし
+		{"report", "s3://bucket/path/*.log.gz"},
+		// パラメータの形式が不正
+		{"report", "overview", "--param", "limit", "s3://bucket/path/*.log.gz"},
+		// 派生カラムを使用しない
+		{"report", "overview", "--raw", "s3://bucket/path/*.log.gz"},
+		{"report", "overview", "--no-derived", "s3://bucket/path/*.log.gz"},
+		// 未対応の出力形式
+		{"report", "overview", "--output-format", "csv", "s3://bucket/path/*.log.gz"},
+		// --list とレポート名
+		{"report", "overview", "--list"},
+		// パスの指定なし
+		{"report", "overview"},
+	}
+
+	for _, args := range testCases {
+		if _, err := NewCLI(args).Parse(); err == nil {
+			t.Errorf("Parse(%v) should return error", args)
+		}
+	}
+}
+
+func TestParseLatency(t *testing.T) {
+	cli := NewCLI([]string{"latency", "--by", "target_group_arn,url_path", "--interval", "5m", "--percentiles", "p50,99,99.9", "s3://bucket/path/*.log.gz"})
+	opts, err := cli.Parse()
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+
+	if opts.Command != CommandLatency {
+		t.Errorf("Expected command to be '%s', got '%s'", CommandLatency, opts.Command)
+	}
+	if strings.Join(opts.Latency.Dimensions, ",") != "target_group_arn,url_path" {
+		t.Errorf("Unexpected dimensions: %v", opts.Latency.Dimensions)
+	}
+	if opts.Latency.Interval != 5*time.Minute {
+		t.Errorf("Expected interval to be 5m, got %v", opts.Latency.Interval)
+	}
+	if fmt.Sprint(opts.Latency.Percentiles) != "[50 99 99.9]" {
+		t.Errorf("Unexpected percentiles: %v", opts.Latency.Percentiles)
+	}
+	if opts.Latency.Limit != 20 || opts.Output.Format != duckdb.OutputTable {
+		t.Errorf("Unexpected defaults: limit=%d, output=%q", opts.Latency.Limit, opts.Output.Format)
+	}
+}
+
+func TestParseLatencyErrors(t *testing.T) {
+	testCases := [][]string{
+		// 範囲外のパーセンタイル
+		{"latency", "--percentiles", "50,100", "s3://bucket/path/*.log.gz"},
+		// 不正な間隔
+		{"latency", "--interval", "soon", "s3://bucket/path/*.log.gz"},
+		// 負の件数
+		{"latency", "--limit", "-1", "s3://bucket/path/*.log.gz"},
+		// センチネル値をNULLに変換しない
+		{"latency", "--raw", "s3://bucket/path/*.log.gz"},
+		// 未対応の出力形式
+		{"latency", "-o", "latency.csv", "s3://bucket/path/*.log.gz"},
+	}
+
+	for _, args := range testCases {
+		if _, err := NewCLI(args).Parse(); err == nil {
+			t.Errorf("Parse(%v) should return error", args)
+		}
+	}
+}
+
+func TestParseChart(t *testing.T) {
+	cli := NewCLI([]string{"chart", "--interval", "1h", "--split-by", "target_group_arn", "--style", "bar", "--ascii", "s3://bucket/path/*.log.gz"})
+	opts, err := cli.Parse()
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+
+	if opts.Command != CommandChart {
+		t.Errorf("Expected command to be '%s', got '%s'", CommandChart, opts.Command)
+	}
+	if opts.Chart.Interval != time.Hour || opts.Chart.SplitBy != "target_group_arn" || opts.Chart.Top != 5 {
+		t.Errorf("Unexpected chart options: %+v", opts.Chart)
+	}
+	if opts.Chart.Display.Style != "bar" || !opts.Chart.Display.ASCII || opts.Chart.Display.Width != 60 {
+		t.Errorf("Unexpected display options: %+v", opts.Chart.Display)
+	}
+}
+
+func TestParseChartErrors(t *testing.T) {
+	testCases := [][]string{
+		// 不正な間隔
+		{"chart", "--interval", "0s", "s3://bucket/path/*.log.gz"},
+		// 未対応の形式
+		{"chart", "--style", "pie", "s3://bucket/path/*.log.gz"},
+		// 狭すぎる幅
+		{"chart", "--width", "5", "s3://bucket/path/*.log.gz"},
+		// センチネル値をNULLに変換しない
+		{"chart", "--raw", "s3://bucket/path/*.log.gz"},
+	}
+
+	for _, args := range testCases {
+		if _, err := NewCLI(args).Parse(); err == nil {
+			t.Errorf("Parse(%v) should return error", args)
+		}
+	}
+}
+
+func TestParseServe(t *testing.T) {
+	t.Setenv(ServeTokenEnv, "env-token")
+
+	opts, err := NewCLI([]string{"serve", "s3://bucket/path/*.log.gz"}).Parse()
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if opts.Command != CommandServe {
+		t.Errorf("Expected command to be '%s', got '%s'", CommandServe, opts.Command)
+	}
+	if opts.Serve.Listen != "127.0.0.1:8080" || opts.Serve.Timeout != 30*time.Second || opts.Serve.MaxBytes != 64<<20 {
+		t.Errorf("Unexpected serve options: %+v", opts.Serve)
+	}
+	// トークンは環境変数から読み込む
+	if opts.Serve.Token != "env-token" {
+		t.Errorf("Expected token from environment, got '%s'", opts.Serve.Token)
+	}
+
+	opts, err = NewCLI([]string{"serve", "--listen", ":9090", "--token", "flag-token", "--timeout", "5s", "--max-bytes", "1GB", "s3://bucket/path/*.log.gz"}).Parse()
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if opts.Serve.Listen != ":9090" || opts.Serve.Token != "flag-token" || opts.Serve.Timeout != 5*time.Second || opts.Serve.MaxBytes != 1<<30 {
+		t.Errorf("Unexpected serve options: %+v", opts.Serve)
+	}
+}
+
+func TestParseServeHelpHidesToken(t *testing.T) {
+	t.Setenv(ServeTokenEnv, "secret-token")
+
+	// ヘルプはflagの出力先とは別に標準出力にも書き込むため両方を取得する
+	stdout, stderr := os.Stdout, os.Stderr
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Pipe returned error: %v", err)
+	}
+	os.Stdout, os.Stderr = w, w
+	opts, err := NewCLI([]string{"serve", "-h"}).Parse()
+	os.Stdout, os.Stderr = stdout, stderr
+	w.Close()
+	output, readErr := io.ReadAll(r)
+	if readErr != nil {
+		t.Fatalf("ReadAll returned error: %v", readErr)
+	}
+
+	if err != nil || opts != nil {
+		t.Fatalf("Expected help to return nil options, got %+v, %v", opts, err)
+	}
+	if !strings.Contains(string(output), "-token") {
+		t.Fatalf("Expected help output, got %q", output)
+	}
+	if strings.Contains(string(output), "secret-token") {
+		t.Errorf("Help output must not contain the token: %q", output)
+	}
+}
+
+func TestParseServeErrors(t *testing.T) {
+	testCases := [][]string{
+		// ポートのないアドレス
+		{"serve", "--listen", "localhost", "s3://bucket/path/*.log.gz"},
+		// 負のタイムアウト
+		{"serve", "--timeout", "-1s", "s3://bucket/path/*.log.gz"},
+		// 不正なサイズ
+		{"serve", "--max-bytes", "lots", "s3://bucket/path/*.log.gz"},
+		// パスがない
+		{"serve"},
+	}
+
+	for _, args := range testCases {
+		if _, err := NewCLI(args).Parse(); err == nil {
+			t.Errorf("Parse(%v) should return error", args)
+		}
+	}
+}
+
+func TestServeOptionsIsLoopback(t *testing.T) {
+	testCases := map[string]bool{
+		"127.0.0.1:8080": true,
+		"localhost:8080": true,
+		"[::1]:8080":     true,
+		":8080":          false,
+		"0.0.0.0:8080":   false,
+		"10.0.0.1:8080":  false,
+	}
+	for listen, expected := range testCases {
+		opts := &ServeOptions{Listen: listen}
+		if got := opts.IsLoopback(); got != expected {
+			t.Errorf("IsLoopback(%q) = %v, expected %v", listen, got, expected)
+		}
+	}
+}
+
+func TestParseMCP(t *testing.T) {
+	opts, err := NewCLI([]string{"mcp", "--max-rows", "50", "--timeout", "10s", "-t", "logs", "s3://bucket/path/*.log.gz"}).Parse()
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if opts.Command != CommandMCP {
+		t.Errorf("Expected command to be '%s', got '%s'", CommandMCP, opts.Command)
+	}
+	if opts.MCP.MaxRows != 50 || opts.MCP.Timeout != 10*time.Second || opts.TableName != "logs" {
+		t.Errorf("Unexpected mcp options: %+v, table %s", opts.MCP, opts.TableName)
+	}
+
+	testCases := [][]string{
+		// 0行
+		{"mcp", "--max-rows", "0", "s3://bucket/path/*.log.gz"},
+		// 負のタイムアウト
+		{"mcp", "--timeout", "-1s", "s3://bucket/path/*.log.gz"},
+		// パスがない
+		{"mcp"},
+	}
+	for _, args := range testCases {
+		if _, err := NewCLI(args).Parse(); err == nil {
+			t.Errorf("Parse(%v) should return error", args)
+		}
 	}
 }
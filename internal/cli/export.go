@@ -0,0 +1,107 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// CommandExport はログをParquetにエクスポートするサブコマンドです
+const CommandExport = "export"
+
+// exportCompressions はエクスポートで指定できる圧縮形式です
+var exportCompressions = []string{"snappy", "zstd", "gzip", "uncompressed"}
+
+// ExportOptions は export サブコマンドの解析結果です
+type ExportOptions struct {
+	// Destination は出力先のディレクトリまたはS3パスです (パーティション分割しない場合は .parquet ファイル)
+	Destination string
+	// PartitionBy はHive形式でパーティション分割するカラムです
+	PartitionBy []string
+	// Compression はParquetの圧縮形式です
+	Compression string
+}
+
+// parseExport は export サブコマンドの引数を解析します
+// ヘルプを表示した場合は nil を返します
+// --to は出力先の指定に使用するため、時間範囲の終了日時は --until で指定します
+func (c *CLI) parseExport(args []string) (*Options, error) {
+	fs := flag.NewFlagSet("dalv export", flag.ContinueOnError)
+	fs.Usage = func() { printExportHelp(fs) }
+
+	helpFlag := fs.Bool("help", false, "ヘルプ情報を表示します")
+	fs.BoolVar(helpFlag, "h", false, "ヘルプ情報を表示します (短縮形)")
+	toFlag := fs.String("to", "", "出力先のディレクトリまたはS3パス (例: ./out, s3://bucket/parquet/alb/)")
+	partitionByFlag := fs.String("partition-by", "date", "Hive形式でパーティション分割するカラム (カンマ区切り。none で分割しない)")
+	compressionFlag := fs.String("compression", "snappy", fmt.Sprintf("Parquetの圧縮形式 (%s)", strings.Join(exportCompressions, "|")))
+	src := c.newSourceFlags(fs, "until")
+
+	if err := src.parseArgs(args); err != nil {
+		return nil, err
+	}
+	if *helpFlag {
+		printExportHelp(fs)
+		return nil, nil
+	}
+	if *toFlag == "" {
+		return nil, fmt.Errorf("--to で出力先を指定してください")
+	}
+
+	compression := strings.ToLower(*compressionFlag)
+	if !contains(exportCompressions, compression) {
+		return nil, fmt.Errorf("未対応の圧縮形式です: %s (対応形式: %s)", *compressionFlag, strings.Join(exportCompressions, ", "))
+	}
+
+	opts, err := src.parse()
+	if err != nil {
+		return nil, err
+	}
+
+	opts.Command = CommandExport
+	opts.Export = &ExportOptions{
+		Destination: *toFlag,
+		PartitionBy: parseColumnList(*partitionByFlag),
+		Compression: compression,
+	}
+	return opts, nil
+}
+
+// parseColumnList はカンマ区切りのカラム名を解析します
+// none または空文字列の場合は nil を返します
+func parseColumnList(s string) []string {
+	if s == "" || s == "none" {
+		return nil
+	}
+
+	var columns []string
+	for _, c := range strings.Split(s, ",") {
+		if c = strings.TrimSpace(c); c != "" {
+			columns = append(columns, c)
+		}
+	}
+	return columns
+}
+
+// contains はスライスに値が含まれるかどうかを返します
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// printExportHelp は export サブコマンドのヘルプ情報を表示します
+func printExportHelp(fs *flag.FlagSet) {
+	fmt.Println("dalv export - ログをHive形式でパーティション分割したParquetにエクスポートします")
+	fmt.Println()
+	fmt.Println("使用方法: dalv export --to <dir> [--partition-by <columns>] [options] <path> [<path>...]")
+	fmt.Println("          dalv export --to <dir> --bucket <bucket> --account <id> --region <region> --from <time> [--until <time>]")
+	fmt.Println()
+	fmt.Println("出力先にはローカルのディレクトリまたはS3パスを指定できます")
+	fmt.Println("タイムスタンプから求めた date カラムを追加して出力します")
+	fmt.Println()
+	fmt.Println("オプション:")
+	fs.PrintDefaults()
+}
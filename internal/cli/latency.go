@@ -0,0 +1,136 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/naotama2002/dalv/internal/duckdb"
+)
+
+// CommandLatency は処理時間のパーセンタイルを集計するサブコマンドです
+const CommandLatency = "latency"
+
+// LatencyOptions は latency サブコマンドの解析結果です
+type LatencyOptions struct {
+	// Dimensions は集計の単位にするカラムです (空の場合は全体で集計)
+	Dimensions []string
+	// Interval は時間帯ごとに集計する間隔です (0の場合は時間帯で分けない)
+	Interval time.Duration
+	// Percentiles は計算するパーセンタイルです
+	Percentiles []float64
+	// Limit は表示するグループ数の上限です (0の場合は制限なし)
+	Limit int
+}
+
+// parseLatency は latency サブコマンドの引数を解析します
+// ヘルプを表示した場合は nil を返します
+func (c *CLI) parseLatency(args []string) (*Options, error) {
+	fs := flag.NewFlagSet("dalv latency", flag.ContinueOnError)
+	fs.Usage = func() { printLatencyHelp(fs) }
+
+	helpFlag := fs.Bool("help", false, "ヘルプ情報を表示します")
+	fs.BoolVar(helpFlag, "h", false, "ヘルプ情報を表示します (短縮形)")
+	byFlag := fs.String("by", "", "集計の単位にするカラム (カンマ区切り。例: target_group_arn, url_path,http_method)")
+	intervalFlag := fs.String("interval", "", "時間帯ごとに集計する間隔 (例: 1m, 5m, 1h, 1d)")
+	percentilesFlag := fs.String("percentiles", formatPercentiles(duckdb.DefaultPercentiles), "計算するパーセンタイル (カンマ区切り)")
+	limitFlag := fs.Int("limit", 20, "表示するグループ数の上限 (リクエスト数の多い順。--interval を指定した場合は制限なし。0 で制限なし)")
+	outputFlag := fs.String("output", "", "結果の出力先ファイル (デフォルト: 標準出力)")
+	fs.StringVar(outputFlag, "o", "", "結果の出力先ファイル (短縮形)")
+	outputFormatFlag := fs.String("output-format", "", fmt.Sprintf("結果の出力形式 (%s|%s。デフォルト: -o の拡張子から推定、推定できない場合は %s)", duckdb.OutputTable, duckdb.OutputMarkdown, duckdb.OutputTable))
+	src := c.newSourceFlags(fs, "to")
+
+	if err := src.parseArgs(args); err != nil {
+		return nil, err
+	}
+	if *helpFlag {
+		printLatencyHelp(fs)
+		return nil, nil
+	}
+	if *src.rawFlag {
+		return nil, fmt.Errorf("latency では -1 をNULLに変換する必要があるため --raw は指定できません")
+	}
+
+	latency := &LatencyOptions{
+		Dimensions: parseColumnList(*byFlag),
+		Limit:      *limitFlag,
+	}
+	if *intervalFlag != "" {
+		d, err := parseAge(*intervalFlag)
+		if err != nil || d < time.Second {
+			return nil, fmt.Errorf("集計の間隔の形式が不正です: %s (例: 1m, 5m, 1h, 1d)", *intervalFlag)
+		}
+		latency.Interval = d
+	}
+	percentiles, err := parsePercentiles(*percentilesFlag)
+	if err != nil {
+		return nil, err
+	}
+	latency.Percentiles = percentiles
+	if latency.Limit < 0 {
+		return nil, fmt.Errorf("--limit には0以上の値を指定してください: %d", latency.Limit)
+	}
+
+	output, err := parseOutput(*outputFlag, *outputFormatFlag)
+	if err != nil {
+		return nil, err
+	}
+	if output.Format != duckdb.OutputTable && output.Format != duckdb.OutputMarkdown {
+		return nil, fmt.Errorf("latency の結果は %s または %s 形式でのみ出力できます: %s", duckdb.OutputTable, duckdb.OutputMarkdown, output.Format)
+	}
+
+	opts, err := src.parse()
+	if err != nil {
+		return nil, err
+	}
+
+	opts.Command = CommandLatency
+	opts.Latency = latency
+	opts.Output = output
+	return opts, nil
+}
+
+// parsePercentiles はカンマ区切りのパーセンタイルを解析します
+func parsePercentiles(s string) ([]float64, error) {
+	var percentiles []float64
+	for _, v := range strings.Split(s, ",") {
+		v = strings.TrimPrefix(strings.TrimSpace(v), "p")
+		if v == "" {
+			continue
+		}
+		p, err := strconv.ParseFloat(v, 64)
+		if err != nil || p <= 0 || p >= 100 {
+			return nil, fmt.Errorf("パーセンタイルの形式が不正です: %s (0より大きく100より小さい値。例: 50,90,99,99.9)", v)
+		}
+		percentiles = append(percentiles, p)
+	}
+	if len(percentiles) == 0 {
+		return nil, fmt.Errorf("--percentiles にパーセンタイルを指定してください")
+	}
+	return percentiles, nil
+}
+
+// formatPercentiles はパーセンタイルをカンマ区切りの文字列にします
+func formatPercentiles(percentiles []float64) string {
+	values := make([]string, 0, len(percentiles))
+	for _, p := range percentiles {
+		values = append(values, strconv.FormatFloat(p, 'f', -1, 64))
+	}
+	return strings.Join(values, ",")
+}
+
+// printLatencyHelp は latency サブコマンドのヘルプ情報を表示します
+func printLatencyHelp(fs *flag.FlagSet) {
+	fmt.Println("dalv latency - 処理時間のパーセンタイルと内訳を集計します")
+	fmt.Println()
+	fmt.Println("使用方法: dalv latency [--by <columns>] [--interval <間隔>] [--percentiles <values>] [options] <path> [<path>...]")
+	fmt.Println()
+	fmt.Println("リクエスト・ターゲット・レスポンスの処理時間の合計のパーセンタイルと、処理時間の内訳を表示します")
+	fmt.Println("--by には url_path や http_method などの派生カラムも指定できます")
+	fmt.Println("対応ログ形式: alb, clb")
+	fmt.Println()
+	fmt.Println("オプション:")
+	fs.PrintDefaults()
+}
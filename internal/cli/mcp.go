@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"time"
+)
+
+// CommandMCP は標準入出力でMCPサーバーを起動するサブコマンドです
+const CommandMCP = "mcp"
+
+// MCPOptions は mcp サブコマンドの解析結果です
+type MCPOptions struct {
+	// MaxRows はツールが返す結果の行数の上限です
+	MaxRows int
+	// Timeout は1回のツールの呼び出しの実行時間の上限です (0の場合は制限なし)
+	Timeout time.Duration
+}
+
+// parseMCP は mcp サブコマンドの引数を解析します
+// ヘルプを表示した場合は nil を返します
+func (c *CLI) parseMCP(args []string) (*Options, error) {
+	fs := flag.NewFlagSet("dalv mcp", flag.ContinueOnError)
+	fs.Usage = func() { printMCPHelp(fs) }
+
+	helpFlag := fs.Bool("help", false, "ヘルプ情報を表示します")
+	fs.BoolVar(helpFlag, "h", false, "ヘルプ情報を表示します (短縮形)")
+	maxRowsFlag := fs.Int("max-rows", 100, "ツールが返す結果の行数の上限")
+	timeoutFlag := fs.Duration("timeout", 30*time.Second, "1回のツールの呼び出しの実行時間の上限 (0 で制限なし)")
+	src := c.newSourceFlags(fs, "to")
+
+	if err := src.parseArgs(args); err != nil {
+		return nil, err
+	}
+	if *helpFlag {
+		printMCPHelp(fs)
+		return nil, nil
+	}
+
+	if *maxRowsFlag < 1 {
+		return nil, fmt.Errorf("--max-rows には1以上の値を指定してください: %d", *maxRowsFlag)
+	}
+	if *timeoutFlag < 0 {
+		return nil, fmt.Errorf("--timeout には0以上の値を指定してください: %s", *timeoutFlag)
+	}
+
+	opts, err := src.parse()
+	if err != nil {
+		return nil, err
+	}
+
+	opts.Command = CommandMCP
+	opts.MCP = &MCPOptions{
+		MaxRows: *maxRowsFlag,
+		Timeout: *timeoutFlag,
+	}
+	return opts, nil
+}
+
+// printMCPHelp は mcp サブコマンドのヘルプ情報を表示します
+func printMCPHelp(fs *flag.FlagSet) {
+	fmt.Println("dalv mcp - ログを読み込んだテーブルを調査するMCPサーバーを標準入出力で起動します")
+	fmt.Println()
+	fmt.Println("使用方法: dalv mcp [--max-rows <n>] [--timeout <時間>] [options] <path> [<path>...]")
+	fmt.Println()
+	fmt.Println("ログを1度だけ読み込み、以下のツールを提供します")
+	fmt.Println("  list_tables       テーブルの一覧と行数")
+	fmt.Println("  describe_schema   テーブルのカラム名と型")
+	fmt.Println("  run_query         読み取り専用のSQLを1つ実行 (結果は --max-rows 行まで)")
+	fmt.Println("  summarize_errors  4xx/5xxのステータスコード別の件数、時間帯、パス")
+	fmt.Println()
+	fmt.Println("クエリは読み取り専用で実行し、ファイルやネットワークにはアクセスできません")
+	fmt.Println("ログは標準エラー出力に出力します")
+	fmt.Println()
+	fmt.Println("オプション:")
+	fs.PrintDefaults()
+}
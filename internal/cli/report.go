@@ -0,0 +1,131 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/naotama2002/dalv/internal/duckdb"
+)
+
+// CommandReport は定義済みのSQLレポートを実行するサブコマンドです
+const CommandReport = "report"
+
+// ReportOptions は report サブコマンドの解析結果です
+type ReportOptions struct {
+	// Name は実行するレポートの名前です
+	Name string
+	// List はレポートの一覧を表示するかどうかです
+	List bool
+	// Params はレポートのパラメータに指定する値です
+	Params map[string]string
+	// Dir はユーザー定義のレポートを読み込むディレクトリです (空の場合はデフォルトのディレクトリ)
+	Dir string
+}
+
+// paramFlag は name=value 形式で複数回指定できるフラグです
+type paramFlag map[string]string
+
+// String はフラグの値を文字列で返します
+func (p paramFlag) String() string {
+	pairs := make([]string, 0, len(p))
+	for name, value := range p {
+		pairs = append(pairs, name+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// Set は name=value 形式の値を追加します
+func (p paramFlag) Set(s string) error {
+	name, value, ok := strings.Cut(s, "=")
+	if !ok || strings.TrimSpace(name) == "" {
+		return fmt.Errorf("パラメータは name=value 形式で指定してください: %s", s)
+	}
+	p[strings.TrimSpace(name)] = value
+	return nil
+}
+
+// parseReport は report サブコマンドの引数を解析します
+// ヘルプを表示した場合は nil を返します
+// レポート名はオプションより前に指定します
+func (c *CLI) parseReport(args []string) (*Options, error) {
+	fs := flag.NewFlagSet("dalv report", flag.ContinueOnError)
+	fs.Usage = func() { printReportHelp(fs) }
+
+	helpFlag := fs.Bool("help", false, "ヘルプ情報を表示します")
+	fs.BoolVar(helpFlag, "h", false, "ヘルプ情報を表示します (短縮形)")
+	listFlag := fs.Bool("list", false, "レポートの一覧を表示します")
+	params := paramFlag{}
+	fs.Var(params, "param", "レポートのパラメータ (name=value。複数回指定できます)")
+	dirFlag := fs.String("reports-dir", "", "ユーザー定義のレポート (*.sql) を読み込むディレクトリ (デフォルト: ~/.config/dalv/reports)")
+	outputFlag := fs.String("output", "", "レポートの出力先ファイル (デフォルト: 標準出力)")
+	fs.StringVar(outputFlag, "o", "", "レポートの出力先ファイル (短縮形)")
+	outputFormatFlag := fs.String("output-format", "", fmt.Sprintf("レポートの出力形式 (%s|%s。デフォルト: -o の拡張子から推定、推定できない場合は %s)", duckdb.OutputTable, duckdb.OutputMarkdown, duckdb.OutputTable))
+	src := c.newSourceFlags(fs, "to")
+
+	var name string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+
+	if err := src.parseArgs(args); err != nil {
+		return nil, err
+	}
+	if *helpFlag {
+		printReportHelp(fs)
+		return nil, nil
+	}
+
+	report := &ReportOptions{
+		Name:   name,
+		List:   *listFlag,
+		Params: params,
+		Dir:    *dirFlag,
+	}
+	if report.List {
+		if name != "" || len(src.args) > 0 {
+			return nil, fmt.Errorf("--list にはレポート名やパスを指定できません")
+		}
+		return &Options{Command: CommandReport, Report: report}, nil
+	}
+	if name == "" {
+		return nil, fmt.Errorf("レポート名が指定されていません。使用方法: dalv report <name> [options] <path> (一覧は dalv report --list)")
+	}
+	if *src.rawFlag || *src.noDerived {
+		return nil, fmt.Errorf("レポートは派生カラムを使用するため --raw と --no-derived は指定できません")
+	}
+
+	output, err := parseOutput(*outputFlag, *outputFormatFlag)
+	if err != nil {
+		return nil, err
+	}
+	if output.Format != duckdb.OutputTable && output.Format != duckdb.OutputMarkdown {
+		return nil, fmt.Errorf("レポートは %s または %s 形式でのみ出力できます: %s", duckdb.OutputTable, duckdb.OutputMarkdown, output.Format)
+	}
+
+	opts, err := src.parse()
+	if err != nil {
+		return nil, err
+	}
+
+	opts.Command = CommandReport
+	opts.Report = report
+	opts.Output = output
+	return opts, nil
+}
+
+// printReportHelp は report サブコマンドのヘルプ情報を表示します
+func printReportHelp(fs *flag.FlagSet) {
+	fmt.Println("dalv report - 定義済みのSQLレポートを実行します")
+	fmt.Println()
+	fmt.Println("使用方法: dalv report <name> [--param name=value] [options] <path> [<path>...]")
+	fmt.Println("          dalv report --list [--reports-dir <dir>]")
+	fmt.Println()
+	fmt.Println("ログを読み込んだテーブルに対してレポートの各セクションのSQLを実行し、見出しとともに表示します")
+	fmt.Println("--reports-dir のディレクトリにある *.sql ファイルはユーザー定義のレポートとして追加されます")
+	fmt.Println()
+	fmt.Println("オプション:")
+	fs.PrintDefaults()
+}
@@ -0,0 +1,114 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"time"
+)
+
+// CommandServe はログを読み込んだテーブルにSQLを実行するHTTP APIサーバーを起動するサブコマンドです
+const CommandServe = "serve"
+
+// ServeTokenEnv はHTTP APIのトークンを指定する環境変数です
+const ServeTokenEnv = "DALV_SERVE_TOKEN"
+
+// ServeOptions は serve サブコマンドの解析結果です
+type ServeOptions struct {
+	// Listen は待ち受けるアドレスです
+	Listen string
+	// Token はリクエストに必要なBearerトークンです (空の場合は認証しない)
+	Token string
+	// Timeout は1つのクエリの実行時間の上限です (0の場合は制限なし)
+	Timeout time.Duration
+	// MaxBytes は1つのクエリの結果のレスポンスの最大サイズ (バイト) です (0の場合は制限なし)
+	MaxBytes int64
+}
+
+// defaultServeMaxBytes は1つのクエリの結果のレスポンスのデフォルトの最大サイズです
+const defaultServeMaxBytes = "64MB"
+
+// parseServe は serve サブコマンドの引数を解析します
+// ヘルプを表示した場合は nil を返します
+func (c *CLI) parseServe(args []string) (*Options, error) {
+	fs := flag.NewFlagSet("dalv serve", flag.ContinueOnError)
+	fs.Usage = func() { printServeHelp(fs) }
+
+	helpFlag := fs.Bool("help", false, "ヘルプ情報を表示します")
+	fs.BoolVar(helpFlag, "h", false, "ヘルプ情報を表示します (短縮形)")
+	listenFlag := fs.String("listen", "127.0.0.1:8080", "待ち受けるアドレス (例: :8080, 127.0.0.1:8080)")
+	tokenFlag := fs.String("token", "", fmt.Sprintf("リクエストに必要なBearerトークン (指定しない場合は環境変数 %s)", ServeTokenEnv))
+	timeoutFlag := fs.Duration("timeout", 30*time.Second, "1つのクエリの実行時間の上限 (0 で制限なし)")
+	maxBytesFlag := fs.String("max-bytes", defaultServeMaxBytes, "1つのクエリの結果の最大サイズ。超えた場合は 413 を返します (例: 10MB, 1GB。0 で制限なし)")
+	src := c.newSourceFlags(fs, "to")
+
+	if err := src.parseArgs(args); err != nil {
+		return nil, err
+	}
+	if *helpFlag {
+		printServeHelp(fs)
+		return nil, nil
+	}
+
+	if _, _, err := net.SplitHostPort(*listenFlag); err != nil {
+		return nil, fmt.Errorf("待ち受けるアドレスの形式が不正です: %s (例: :8080, 127.0.0.1:8080)", *listenFlag)
+	}
+	if *timeoutFlag < 0 {
+		return nil, fmt.Errorf("--timeout には0以上の値を指定してください: %s", *timeoutFlag)
+	}
+	maxBytes, err := parseSize(*maxBytesFlag)
+	if err != nil {
+		return nil, fmt.Errorf("--max-bytes: %w", err)
+	}
+
+	opts, err := src.parse()
+	if err != nil {
+		return nil, err
+	}
+
+	// 環境変数はヘルプのデフォルト値に表示しないよう、解析後に読み込む
+	token := *tokenFlag
+	if token == "" {
+		token = os.Getenv(ServeTokenEnv)
+	}
+
+	opts.Command = CommandServe
+	opts.Serve = &ServeOptions{
+		Listen:   *listenFlag,
+		Token:    token,
+		Timeout:  *timeoutFlag,
+		MaxBytes: maxBytes,
+	}
+	return opts, nil
+}
+
+// IsLoopback は待ち受けるアドレスがループバックアドレスに限定されているかどうかを返します
+func (o *ServeOptions) IsLoopback() bool {
+	host, _, err := net.SplitHostPort(o.Listen)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// printServeHelp は serve サブコマンドのヘルプ情報を表示します
+func printServeHelp(fs *flag.FlagSet) {
+	fmt.Println("dalv serve - ログを読み込んだテーブルにSQLを実行するHTTP APIサーバーを起動します")
+	fmt.Println()
+	fmt.Println("使用方法: dalv serve [--listen <addr>] [--token <token>] [--timeout <時間>] [--max-bytes <サイズ>] [options] <path> [<path>...]")
+	fmt.Println()
+	fmt.Println("ログを1度だけ読み込み、以下のエンドポイントでSQLを受け付けます")
+	fmt.Println("  POST /query   SQLを実行します (本文: SQL または {\"sql\": \"...\", \"format\": \"json\"})")
+	fmt.Println("                形式: json, ndjson, csv, arrow (本文の format、?format=、Acceptヘッダーの順に決定)")
+	fmt.Println("  GET  /health  サーバーの状態とテーブル名を返します (認証不要)")
+	fmt.Println()
+	fmt.Println("クエリは読み取り専用で実行し、ファイルやネットワークにはアクセスできません")
+	fmt.Println()
+	fmt.Println("オプション:")
+	fs.PrintDefaults()
+}
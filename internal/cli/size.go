@@ -0,0 +1,40 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// defaultConfirmSize は読み込む前に確認する合計サイズのデフォルトのしきい値です
+const defaultConfirmSize = "10GB"
+
+// sizeUnits はサイズの単位と倍率です (1024倍ごと。長い単位から順に判定します)
+var sizeUnits = []struct {
+	suffix     string
+	multiplier int64
+}{
+	{"TIB", 1 << 40}, {"GIB", 1 << 30}, {"MIB", 1 << 20}, {"KIB", 1 << 10},
+	{"TB", 1 << 40}, {"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10},
+	{"T", 1 << 40}, {"G", 1 << 30}, {"M", 1 << 20}, {"K", 1 << 10},
+	{"B", 1},
+}
+
+// parseSize は 500MB や 10GB のようなサイズを解析してバイト数を返します
+// 単位は 1024 倍ごとで、単位を省略した場合はバイトとして扱います
+func parseSize(s string) (int64, error) {
+	value := strings.ToUpper(strings.TrimSpace(s))
+	multiplier := int64(1)
+	for _, u := range sizeUnits {
+		if v, ok := strings.CutSuffix(value, u.suffix); ok {
+			value, multiplier = strings.TrimSpace(v), u.multiplier
+			break
+		}
+	}
+
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil || n < 0 || n*float64(multiplier) > float64(1<<62) {
+		return 0, fmt.Errorf("サイズの形式が不正です: %s (例: 500MB, 10GB)", s)
+	}
+	return int64(n * float64(multiplier)), nil
+}
@@ -0,0 +1,247 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// PathEnv はユーザーの設定ファイルのパスを指定する環境変数です
+	PathEnv = "DALV_CONFIG"
+	// LocalFileName はカレントディレクトリから読み込むプロジェクトの設定ファイルの名前です
+	LocalFileName = ".dalv.yaml"
+)
+
+// ソースの項目を指定する環境変数です
+// 設定ファイルより優先し、コマンドラインのフラグで上書きされます
+const (
+	BucketEnv  = "DALV_BUCKET"
+	PrefixEnv  = "DALV_PREFIX"
+	AccountEnv = "DALV_ACCOUNT"
+	RegionEnv  = "DALV_REGION"
+	FormatEnv  = "DALV_FORMAT"
+	TableEnv   = "DALV_TABLE"
+	ProfileEnv = "DALV_PROFILE"
+	// EndpointEnv はS3互換のストレージのエンドポイントです (MinIOやLocalStackでの開発向け)
+	EndpointEnv = "DALV_S3_ENDPOINT"
+)
+
+// sourceNamePattern はソースの名前として使用できる文字列です
+var sourceNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// Source は名前付きのログの読み込み元です
+// 空の項目は指定されていないものとして扱います
+type Source struct {
+	// Paths はログを読み込むS3パスまたはローカルパスです (Bucket などとは同時に指定できません)
+	Paths []string `yaml:"paths"`
+	// Bucket はELBログが保存されているS3バケット名です
+	Bucket string `yaml:"bucket"`
+	// Prefix はELBログのS3プレフィックス (AWSLogs/ より前の部分) です
+	Prefix string `yaml:"prefix"`
+	// Account はELBのAWSアカウントIDです
+	Account string `yaml:"account"`
+	// Region はAWSのリージョンです (ELBログのパスの生成とS3の接続に使用)
+	Region string `yaml:"region"`
+	// Format はログ形式の名前です
+	Format string `yaml:"format"`
+	// Table は作成するテーブル名です
+	Table string `yaml:"table"`
+	// Profile はS3の認証情報に使用するAWSのプロファイルです
+	Profile string `yaml:"profile"`
+	// RoleARN はS3の読み込みに引き受けるIAMロールのARNです
+	RoleARN string `yaml:"role_arn"`
+	// RoleSessionName はロールを引き受ける際のセッション名です
+	RoleSessionName string `yaml:"role_session_name"`
+	// ExternalID はロールを引き受ける際の外部IDです
+	ExternalID string `yaml:"external_id"`
+	// Endpoint はS3互換のストレージのエンドポイントです (http:// で始まる場合はSSLを使用しない)
+	Endpoint string `yaml:"s3_endpoint"`
+	// URLStyle はS3のURLのスタイルです (vhost, path)
+	URLStyle string `yaml:"url_style"`
+}
+
+// Override は other で指定された項目で上書きしたソースを返します
+func (s Source) Override(other Source) Source {
+	if len(other.Paths) > 0 {
+		s.Paths = other.Paths
+	}
+	override := func(dst *string, v string) {
+		if v != "" {
+			*dst = v
+		}
+	}
+	override(&s.Bucket, other.Bucket)
+	override(&s.Prefix, other.Prefix)
+	override(&s.Account, other.Account)
+	override(&s.Region, other.Region)
+	override(&s.Format, other.Format)
+	override(&s.Table, other.Table)
+	override(&s.Profile, other.Profile)
+	override(&s.RoleARN, other.RoleARN)
+	override(&s.RoleSessionName, other.RoleSessionName)
+	override(&s.ExternalID, other.ExternalID)
+	override(&s.Endpoint, other.Endpoint)
+	override(&s.URLStyle, other.URLStyle)
+	return s
+}
+
+// FromEnv は環境変数で指定されたソースの項目を返します
+func FromEnv() Source {
+	return Source{
+		Bucket:   os.Getenv(BucketEnv),
+		Prefix:   os.Getenv(PrefixEnv),
+		Account:  os.Getenv(AccountEnv),
+		Region:   os.Getenv(RegionEnv),
+		Format:   os.Getenv(FormatEnv),
+		Table:    os.Getenv(TableEnv),
+		Profile:  os.Getenv(ProfileEnv),
+		Endpoint: os.Getenv(EndpointEnv),
+	}
+}
+
+// WithoutLocation はELBログの保存先の項目を除いたソースを返します
+// リージョンはS3の接続にも使用するため除きません
+func (s Source) WithoutLocation() Source {
+	s.Bucket, s.Prefix, s.Account = "", "", ""
+	return s
+}
+
+// HasLocation はELBログの保存先 (バケット、プレフィックス、アカウント) が指定されているかを返します
+func (s Source) HasLocation() bool {
+	return s.Bucket != "" || s.Prefix != "" || s.Account != ""
+}
+
+// Config は設定ファイルの内容です
+type Config struct {
+	// Sources は名前付きのログの読み込み元です
+	Sources map[string]Source `yaml:"sources"`
+	// Files は読み込んだ設定ファイルのパスです (読み込んだ順)
+	Files []string `yaml:"-"`
+}
+
+// Source は名前を指定してソースを返します
+func (c *Config) Source(name string) (Source, bool) {
+	s, ok := c.Sources[name]
+	return s, ok
+}
+
+// SourceNames はソースの名前を昇順で返します
+func (c *Config) SourceNames() []string {
+	names := make([]string, 0, len(c.Sources))
+	for name := range c.Sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// merge は other のソースを項目ごとに上書きして追加します
+// other のソースが保存先 (paths または bucket, prefix, account) を指定している場合は、
+// 異なるファイルの paths と bucket などが混ざらないように、保存先の項目をまとめて置き換えます
+// region はS3の接続にも使用するため、other が bucket, prefix, account を指定した場合のみ置き換えます
+func (c *Config) merge(other *Config) {
+	if c.Sources == nil {
+		c.Sources = make(map[string]Source)
+	}
+	for name, s := range other.Sources {
+		base := c.Sources[name]
+		if len(s.Paths) > 0 || s.HasLocation() {
+			base.Paths, base.Bucket, base.Prefix, base.Account = nil, "", "", ""
+		}
+		if s.HasLocation() {
+			base.Region = ""
+		}
+		c.Sources[name] = base.Override(s)
+	}
+	c.Files = append(c.Files, other.Files...)
+}
+
+// IsSourceName はソースの名前として使用できる文字列かどうかを返します
+// パス区切り文字やglobを含む文字列はパスとして扱うため、ソースの名前にはできません
+func IsSourceName(s string) bool {
+	return sourceNamePattern.MatchString(s)
+}
+
+// DefaultPath はユーザーの設定ファイルのデフォルトのパスを返します
+// ホームディレクトリを取得できない場合は空文字列を返します
+func DefaultPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "dalv", "config.yaml")
+}
+
+// Load はユーザーの設定ファイルとカレントディレクトリの .dalv.yaml を読み込みます
+// 同じ名前のソースは .dalv.yaml の項目で上書きします (保存先の扱いは merge を参照)
+// ユーザーの設定ファイルは DALV_CONFIG で指定でき、指定したファイルが存在しない場合はエラーを返します
+func Load() (*Config, error) {
+	cfg := &Config{Sources: make(map[string]Source)}
+
+	path, required := os.Getenv(PathEnv), true
+	if path == "" {
+		path, required = DefaultPath(), false
+	}
+	for _, p := range []struct {
+		path     string
+		required bool
+	}{{path, required}, {LocalFileName, false}} {
+		if p.path == "" {
+			continue
+		}
+		loaded, err := LoadFile(p.path)
+		if errors.Is(err, os.ErrNotExist) && !p.required {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		cfg.merge(loaded)
+	}
+	return cfg, nil
+}
+
+// LoadFile は設定ファイルを読み込みます
+func LoadFile(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("設定ファイルの読み込みに失敗しました: %w", err)
+	}
+
+	cfg, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("設定ファイル %s: %w", path, err)
+	}
+	cfg.Files = []string{path}
+	return cfg, nil
+}
+
+// Parse はYAMLの設定を解析します
+// 未知の項目はエラーにします
+func Parse(data []byte) (*Config, error) {
+	cfg := &Config{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("YAMLの解析に失敗しました: %w", err)
+	}
+
+	for _, name := range cfg.SourceNames() {
+		if !IsSourceName(name) {
+			return nil, fmt.Errorf("ソースの名前は英数字で始まり、英数字と _ . - のみを含む必要があります: %s", name)
+		}
+		s := cfg.Sources[name]
+		if len(s.Paths) > 0 && s.HasLocation() {
+			return nil, fmt.Errorf("ソース %s: paths と bucket/prefix/account は同時に指定できません", name)
+		}
+	}
+	return cfg, nil
+}
@@ -0,0 +1,215 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	cfg, err := Parse([]byte(`
+sources:
+  prod-api:
+    bucket: prod-logs
+    account: "123456789012"
+    region: ap-northeast-1
+    format: alb
+    profile: prod
+  local:
+    paths: [./logs/*.log.gz]
+`))
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+
+	if names := cfg.SourceNames(); strings.Join(names, ",") != "local,prod-api" {
+		t.Errorf("Unexpected source names: %v", names)
+	}
+	s, ok := cfg.Source("prod-api")
+	if !ok {
+		t.Fatal("Expected source prod-api to be defined")
+	}
+	if s.Bucket != "prod-logs" || s.Account != "123456789012" || s.Profile != "prod" || !s.HasLocation() {
+		t.Errorf("Unexpected source: %+v", s)
+	}
+	if s, _ := cfg.Source("local"); len(s.Paths) != 1 || s.HasLocation() {
+		t.Errorf("Unexpected source: %+v", s)
+	}
+
+	// 空のファイルはソースなし
+	cfg, err = Parse(nil)
+	if err != nil || len(cfg.Sources) != 0 {
+		t.Errorf("Expected empty config, got %+v (%v)", cfg, err)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	testCases := map[string]string{
+		"unknown field":       "sources:\n  prod:\n    bukcet: b\n",
+		"invalid name":        "sources:\n  \"prod/api\":\n    bucket: b\n",
+		"paths with location": "sources:\n  prod:\n    paths: [s3://b/*.log]\n    bucket: b\n",
+		"invalid yaml":        "sources: [",
+	}
+
+	for name, data := range testCases {
+		if _, err := Parse([]byte(data)); err == nil {
+			t.Errorf("%s: expected error, got nil", name)
+		}
+	}
+}
+
+func TestSourceOverride(t *testing.T) {
+	base := Source{Bucket: "b", Region: "ap-northeast-1", Format: "alb"}
+	s := base.Override(Source{Region: "us-east-1", Table: "logs"})
+
+	expected := Source{Bucket: "b", Region: "us-east-1", Format: "alb", Table: "logs"}
+	if s.Bucket != expected.Bucket || s.Region != expected.Region || s.Format != expected.Format || s.Table != expected.Table {
+		t.Errorf("Expected %+v, got %+v", expected, s)
+	}
+	if s.WithoutLocation().HasLocation() || s.WithoutLocation().Region != "us-east-1" {
+		t.Errorf("Expected location except region to be removed, got %+v", s.WithoutLocation())
+	}
+}
+
+func TestIsSourceName(t *testing.T) {
+	testCases := map[string]bool{
+		"prod-api":          true,
+		"prod_api.v2":       true,
+		"-q":                false,
+		"./logs":            false,
+		"s3://bucket/*.log": false,
+		"logs/*.log.gz":     false,
+		"":                  false,
+	}
+
+	for name, expected := range testCases {
+		if got := IsSourceName(name); got != expected {
+			t.Errorf("IsSourceName(%q): expected %v, got %v", name, expected, got)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	userConfig := filepath.Join(dir, "config.yaml")
+	writeFile(t, userConfig, "sources:\n  prod:\n    bucket: user-bucket\n    region: ap-northeast-1\n    profile: prod\n  staging:\n    bucket: staging-bucket\n")
+
+	// カレントディレクトリの .dalv.yaml で同じ名前のソースの項目を上書きする
+	project := t.TempDir()
+	writeFile(t, filepath.Join(project, LocalFileName), "sources:\n  prod:\n    paths: [s3://project-bucket/logs/]\n")
+	chdir(t, project)
+	t.Setenv(PathEnv, userConfig)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	prod, _ := cfg.Source("prod")
+	// 保存先はまとめて置き換え、S3の接続に使用するリージョンなどの項目は残す
+	if len(prod.Paths) != 1 || prod.Bucket != "" || prod.Region != "ap-northeast-1" || prod.Profile != "prod" {
+		t.Errorf("Unexpected merged source: %+v", prod)
+	}
+	if _, ok := cfg.Source("staging"); !ok {
+		t.Error("Expected source staging to be defined")
+	}
+	if len(cfg.Files) != 2 {
+		t.Errorf("Expected 2 loaded files, got %v", cfg.Files)
+	}
+}
+
+func TestLoadReplacesLocation(t *testing.T) {
+	dir := t.TempDir()
+	userConfig := filepath.Join(dir, "config.yaml")
+	writeFile(t, userConfig, "sources:\n"+
+		"  prod:\n    paths: [s3://user-bucket/logs/]\n    format: clb\n"+
+		"  staging:\n    bucket: staging-bucket\n    prefix: alb\n    account: \"123456789012\"\n    region: us-east-1\n"+
+		"  dev:\n    bucket: dev-bucket\n    account: \"123456789012\"\n")
+
+	// 一方のファイルの paths と他方のファイルの bucket などを混ぜない
+	project := t.TempDir()
+	writeFile(t, filepath.Join(project, LocalFileName), "sources:\n"+
+		"  prod:\n    bucket: project-bucket\n    account: \"210987654321\"\n    region: eu-west-1\n"+
+		"  staging:\n    paths: [./logs/]\n"+
+		"  dev:\n    region: ap-northeast-1\n")
+	chdir(t, project)
+	t.Setenv(PathEnv, userConfig)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+
+	expected := map[string]Source{
+		"prod": {Bucket: "project-bucket", Account: "210987654321", Region: "eu-west-1", Format: "clb"},
+		// paths のみを指定した場合はS3の接続に使用するリージョンを残す
+		"staging": {Paths: []string{"./logs/"}, Region: "us-east-1"},
+		// 保存先を指定しない場合は項目ごとに上書きする
+		"dev": {Bucket: "dev-bucket", Account: "123456789012", Region: "ap-northeast-1"},
+	}
+	for name, want := range expected {
+		got, _ := cfg.Source(name)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Source %s = %+v, expected %+v", name, got, want)
+		}
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	chdir(t, t.TempDir())
+
+	// DALV_CONFIG で指定したファイルは必須
+	t.Setenv(PathEnv, filepath.Join(t.TempDir(), "missing.yaml"))
+	if _, err := Load(); err == nil {
+		t.Error("Expected error for missing DALV_CONFIG file, got nil")
+	}
+
+	// 不正な設定ファイルはパスを含むエラーにする
+	broken := filepath.Join(t.TempDir(), "broken.yaml")
+	writeFile(t, broken, "sources: [")
+	t.Setenv(PathEnv, broken)
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), broken) {
+		t.Errorf("Expected error to contain the file path, got %v", err)
+	}
+}
+
+func TestLoadWithoutFiles(t *testing.T) {
+	// デフォルトの設定ファイルと .dalv.yaml がなくてもエラーにしない
+	chdir(t, t.TempDir())
+	t.Setenv(PathEnv, "")
+	t.Setenv("HOME", t.TempDir())
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+	if len(cfg.Sources) != 0 || len(cfg.Files) != 0 {
+		t.Errorf("Expected empty config, got %+v", cfg)
+	}
+}
+
+// writeFile はテスト用のファイルを作成します
+func writeFile(t *testing.T, path string, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// chdir はテストの間だけカレントディレクトリを変更します
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := os.Chdir(wd); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
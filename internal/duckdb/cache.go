@@ -0,0 +1,228 @@
+package duckdb
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/naotama2002/dalv/internal/schema"
+	"github.com/naotama2002/dalv/internal/source"
+)
+
+const (
+	// cacheObjectsTable は読み込み済みオブジェクトを管理するテーブル名です
+	cacheObjectsTable = "_dalv_objects"
+	// cacheTablePrefix はログ形式ごとのキャッシュテーブル名のプレフィックスです
+	cacheTablePrefix = "_dalv_cache_"
+	// sessionFilesTable はセッションで読み込み対象となるオブジェクトの一時テーブル名です
+	sessionFilesTable = "_dalv_session_files"
+)
+
+// Object はログファイル (S3オブジェクトまたはローカルファイル) です
+type Object struct {
+	// Path はオブジェクトのパスです
+	Path string
+	// Size はオブジェクトのサイズ (バイト) です
+	Size int64
+	// LastModified はDuckDBが返すオブジェクトの更新日時です
+	LastModified string
+}
+
+// Key はキャッシュのキーを返します
+// キーはオブジェクトの内容から求めたものではなく、パス・サイズ・更新日時のメタデータです
+// read_blob はETagを返さないため、更新日時 (秒単位) の同じ秒に同じサイズで上書きされたオブジェクトは
+// 変更を検出できず、古いキャッシュを使用します
+func (o Object) Key() string {
+	return fmt.Sprintf("%s\x00%d\x00%s", o.Path, o.Size, o.LastModified)
+}
+
+// CacheStats はキャッシュの利用状況です
+type CacheStats struct {
+	// Objects は読み込み対象のオブジェクトです
+	Objects []Object
+	// NewObjects はキャッシュになく新たに読み込むオブジェクトです
+	NewObjects []Object
+}
+
+// CachedCount はキャッシュを再利用するオブジェクトの数を返します
+func (s *CacheStats) CachedCount() int {
+	return len(s.Objects) - len(s.NewObjects)
+}
+
+// NewCacheStats は読み込み対象のオブジェクトとキャッシュ済みのオブジェクトから利用状況を作成します
+func NewCacheStats(objects []Object, cached []Object) *CacheStats {
+	cachedKeys := make(map[string]bool, len(cached))
+	for _, o := range cached {
+		cachedKeys[o.Key()] = true
+	}
+
+	stats := &CacheStats{Objects: objects}
+	for _, o := range objects {
+		if !cachedKeys[o.Key()] {
+			stats.NewObjects = append(stats.NewObjects, o)
+		}
+	}
+	return stats
+}
+
+// cacheTableName はログ形式ごとのキャッシュテーブル名を返します
+func cacheTableName(format *schema.Format) string {
+	return cacheTablePrefix + format.Name
+}
+
+// GenerateListObjectsSQL はパスに一致するオブジェクトの一覧を取得するSQLを生成します
+// read_blob はcontentカラムを参照しない限りオブジェクトの内容を読み込みません
+func (g *SQLGenerator) GenerateListObjectsSQL(paths []string) string {
+	return fmt.Sprintf(`SELECT DISTINCT filename AS path, size, last_modified
+FROM read_blob(%s)
+ORDER BY path;`, pathListLiteral(paths))
+}
+
+// GenerateCacheSchemaSQL はキャッシュ用のテーブルを作成するSQLを生成します
+// 登録済みのすべてのログ形式のキャッシュテーブルを作成します
+func (g *SQLGenerator) GenerateCacheSchemaSQL() string {
+	var b strings.Builder
+	b.WriteString("-- キャッシュ用テーブルの作成\n")
+	fmt.Fprintf(&b, `CREATE TABLE IF NOT EXISTS %s (
+    path VARCHAR,
+    format VARCHAR,
+    size BIGINT,
+    last_modified TIMESTAMP,
+    loaded_at TIMESTAMP,
+    row_count BIGINT,
+    PRIMARY KEY (path, format)
+);`, cacheObjectsTable)
+
+	for _, name := range schema.Names() {
+		format, _ := schema.Get(name)
+		columns := make([]string, 0, len(format.Columns)+1)
+		for _, c := range format.Columns {
+			columns = append(columns, fmt.Sprintf("    %s %s", c.Name, c.Type))
+		}
+		columns = append(columns, "    filename VARCHAR")
+		fmt.Fprintf(&b, "\nCREATE TABLE IF NOT EXISTS %s (\n%s\n);", cacheTableName(format), strings.Join(columns, ",\n"))
+	}
+
+	return b.String()
+}
+
+// GenerateCachedObjectsSQL はキャッシュ済みのオブジェクトの一覧を取得するSQLを生成します
+func (g *SQLGenerator) GenerateCachedObjectsSQL() string {
+	return fmt.Sprintf(`SELECT path, size, last_modified
+FROM %s
+WHERE format = %s
+ORDER BY path;`, cacheObjectsTable, QuoteLiteral(g.format.Name))
+}
+
+// GenerateCachedLoadSQL はキャッシュにない新しいオブジェクトのみを読み込み、
+// 読み込み対象のオブジェクトを参照するビューを作成するSQLを生成します
+func (g *SQLGenerator) GenerateCachedLoadSQL(tableName string, stats *CacheStats) string {
+	// テーブル名が指定されていない場合は生成
+	if tableName == "" {
+		tableName = g.GenerateTableName()
+	}
+
+	var sqls []string
+	newPaths := objectPaths(stats.NewObjects)
+	if source.AnyRemote(newPaths) {
+		sqls = append(sqls, g.GenerateAWSConfigSQL())
+	}
+	sqls = append(sqls, g.GenerateCacheSchemaSQL())
+
+	cacheTable := cacheTableName(g.format)
+	if len(stats.NewObjects) > 0 {
+		sqls = append(sqls, fmt.Sprintf(`-- 新しいオブジェクトをキャッシュに読み込み
+BEGIN TRANSACTION;
+DELETE FROM %s WHERE filename IN %s;
+INSERT INTO %s
+SELECT *
+FROM %s;
+INSERT OR REPLACE INTO %s
+SELECT o.path, %s, o.size, o.last_modified, now(), (SELECT count(*) FROM %s c WHERE c.filename = o.path)
+FROM %s;
+COMMIT;`, cacheTable, valueListLiteral(newPaths),
+			cacheTable, g.generateReadCSVSQL(newPaths),
+			cacheObjectsTable, QuoteLiteral(g.format.Name), cacheTable, objectValuesLiteral(stats.NewObjects)))
+	}
+
+	if extensionSQL := g.GenerateExtensionSQL(); extensionSQL != "" {
+		sqls = append(sqls, extensionSQL)
+	}
+	sqls = append(sqls, fmt.Sprintf(`-- 読み込み対象のオブジェクトを参照するビューを作成
+CREATE TEMP TABLE %s AS
+SELECT path
+FROM %s;
+CREATE TEMP VIEW %s AS
+%s;`, sessionFilesTable, objectValuesLiteral(stats.Objects),
+		Identifier(tableName), g.generateSelectSQL(cacheTable, fmt.Sprintf("filename IN (SELECT path FROM %s)", sessionFilesTable))))
+
+	return strings.Join(sqls, "\n\n")
+}
+
+// GenerateListCacheSQL はキャッシュの内容を表示するSQLを生成します
+func (g *SQLGenerator) GenerateListCacheSQL() string {
+	return fmt.Sprintf(`SELECT format, count(*) AS objects, sum(row_count) AS rows, sum(size) AS total_bytes, min(loaded_at) AS first_loaded_at, max(loaded_at) AS last_loaded_at
+FROM %s
+GROUP BY format
+ORDER BY format;
+SELECT format, path, size, last_modified, loaded_at, row_count
+FROM %s
+ORDER BY format, path;`, cacheObjectsTable, cacheObjectsTable)
+}
+
+// GeneratePruneCacheSQL はキャッシュからオブジェクトを削除するSQLを生成します
+// loadedBefore がゼロ値の場合はすべてのオブジェクトを削除します
+// 最後に削除したオブジェクトの数を返します
+func (g *SQLGenerator) GeneratePruneCacheSQL(loadedBefore time.Time) string {
+	condition := ""
+	if !loadedBefore.IsZero() {
+		condition = fmt.Sprintf("\nWHERE loaded_at < TIMESTAMP '%s'", loadedBefore.UTC().Format("2006-01-02 15:04:05"))
+	}
+
+	var deletes []string
+	for _, name := range schema.Names() {
+		format, _ := schema.Get(name)
+		deletes = append(deletes, fmt.Sprintf("DELETE FROM %s WHERE filename IN (SELECT path FROM _dalv_prune WHERE format = %s);", cacheTableName(format), QuoteLiteral(format.Name)))
+	}
+
+	return fmt.Sprintf(`BEGIN TRANSACTION;
+CREATE TEMP TABLE _dalv_prune AS
+SELECT path, format
+FROM %s%s;
+%s
+DELETE FROM %s o USING _dalv_prune p WHERE o.path = p.path AND o.format = p.format;
+COMMIT;
+CHECKPOINT;
+SELECT count(*) AS pruned FROM _dalv_prune;`, cacheObjectsTable, condition, strings.Join(deletes, "\n"), cacheObjectsTable)
+}
+
+// objectPaths はオブジェクトのパスの一覧を返します
+func objectPaths(objects []Object) []string {
+	paths := make([]string, 0, len(objects))
+	for _, o := range objects {
+		paths = append(paths, o.Path)
+	}
+	return paths
+}
+
+// valueListLiteral は文字列の一覧をIN句で使用できる形式で表現します
+func valueListLiteral(values []string) string {
+	items := make([]string, 0, len(values))
+	for _, v := range values {
+		items = append(items, QuoteLiteral(v))
+	}
+	return "(" + strings.Join(items, ", ") + ")"
+}
+
+// objectValuesLiteral はオブジェクトの一覧を (path, size, last_modified) のVALUES句で表現します
+// 空のVALUES句は構文エラーになるため、オブジェクトがない場合は同じカラムの空の結果を返します
+func objectValuesLiteral(objects []Object) string {
+	if len(objects) == 0 {
+		return "(SELECT NULL::VARCHAR, NULL::BIGINT, NULL::TIMESTAMP WHERE false) o(path, size, last_modified)"
+	}
+	rows := make([]string, 0, len(objects))
+	for _, o := range objects {
+		rows = append(rows, fmt.Sprintf("    (%s, %d, TIMESTAMP %s)", QuoteLiteral(o.Path), o.Size, QuoteLiteral(o.LastModified)))
+	}
+	return fmt.Sprintf("(VALUES\n%s\n) o(path, size, last_modified)", strings.Join(rows, ",\n"))
+}
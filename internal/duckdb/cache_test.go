@@ -0,0 +1,134 @@
+package duckdb
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewCacheStats(t *testing.T) {
+	objects := []Object{
+		{Path: "s3://bucket/a.log.gz", Size: 100, LastModified: "2025-03-30 00:00:00"},
+		{Path: "s3://bucket/b.log.gz", Size: 200, LastModified: "2025-03-30 00:05:00"},
+		{Path: "s3://bucket/c.log.gz", Size: 300, LastModified: "2025-03-30 00:10:00"},
+	}
+	cached := []Object{
+		{Path: "s3://bucket/a.log.gz", Size: 100, LastModified: "2025-03-30 00:00:00"},
+		// サイズが変わったオブジェクトは読み込み直す
+		{Path: "s3://bucket/b.log.gz", Size: 150, LastModified: "2025-03-30 00:05:00"},
+		// 読み込み対象外のオブジェクトは無視する
+		{Path: "s3://bucket/z.log.gz", Size: 100, LastModified: "2025-03-29 00:00:00"},
+	}
+
+	stats := NewCacheStats(objects, cached)
+
+	if len(stats.NewObjects) != 2 {
+		t.Fatalf("Expected 2 new objects, got %d: %v", len(stats.NewObjects), stats.NewObjects)
+	}
+	if stats.NewObjects[0].Path != "s3://bucket/b.log.gz" || stats.NewObjects[1].Path != "s3://bucket/c.log.gz" {
+		t.Errorf("Unexpected new objects: %v", stats.NewObjects)
+	}
+	if stats.CachedCount() != 1 {
+		t.Errorf("Expected 1 cached object, got %d", stats.CachedCount())
+	}
+}
+
+func TestGenerateCachedLoadSQL(t *testing.T) {
+	generator := NewSQLGenerator()
+	objects := []Object{
+		{Path: "s3://bucket/a.log.gz", Size: 100, LastModified: "2025-03-30 00:00:00"},
+		{Path: "s3://bucket/b.log.gz", Size: 200, LastModified: "2025-03-30 00:05:00"},
+	}
+	stats := &CacheStats{Objects: objects, NewObjects: objects[1:]}
+
+	sql := generator.GenerateCachedLoadSQL("test_table", stats)
+
+	// 新しいオブジェクトのみを読み込む
+	if !strings.Contains(sql, "read_csv(\n    's3://bucket/b.log.gz',") {
+		t.Errorf("Generated SQL does not read only new objects:\n%s", sql)
+	}
+	if !strings.Contains(sql, "INSERT INTO _dalv_cache_alb") || !strings.Contains(sql, "INSERT OR REPLACE INTO _dalv_objects") {
+		t.Errorf("Generated SQL does not update cache tables:\n%s", sql)
+	}
+
+	// S3から読み込むためAWSの設定を含む
+	if !strings.Contains(sql, "CREATE SECRET") {
+		t.Errorf("Generated SQL does not contain AWS config:\n%s", sql)
+	}
+
+	// 読み込み対象のすべてのオブジェクトをビューで参照する
+	if !strings.Contains(sql, "CREATE TEMP VIEW test_table AS") {
+		t.Errorf("Generated SQL does not create view:\n%s", sql)
+	}
+	if !strings.Contains(sql, "('s3://bucket/a.log.gz', 100, TIMESTAMP '2025-03-30 00:00:00')") {
+		t.Errorf("Generated SQL does not reference cached objects:\n%s", sql)
+	}
+}
+
+func TestGenerateCachedLoadSQLWithoutNewObjects(t *testing.T) {
+	generator := NewSQLGenerator()
+	objects := []Object{
+		{Path: "s3://bucket/a.log.gz", Size: 100, LastModified: "2025-03-30 00:00:00"},
+	}
+	stats := &CacheStats{Objects: objects}
+
+	sql := generator.GenerateCachedLoadSQL("test_table", stats)
+
+	// すべてキャッシュ済みの場合はS3にアクセスしない
+	if strings.Contains(sql, "read_csv") {
+		t.Errorf("Generated SQL should not read logs when all objects are cached:\n%s", sql)
+	}
+	if strings.Contains(sql, "CREATE SECRET") {
+		t.Errorf("Generated SQL should not contain AWS config when all objects are cached:\n%s", sql)
+	}
+}
+
+func TestGenerateCachedLoadSQLWithoutObjects(t *testing.T) {
+	generator := NewSQLGenerator()
+
+	sql := generator.GenerateCachedLoadSQL("test_table", &CacheStats{})
+
+	// オブジェクトがない場合も空のVALUES句を生成しない
+	if strings.Contains(sql, "(VALUES\n\n)") {
+		t.Errorf("Generated SQL contains empty VALUES clause:\n%s", sql)
+	}
+	if !strings.Contains(sql, "WHERE false) o(path, size, last_modified)") {
+		t.Errorf("Generated SQL does not select an empty object list:\n%s", sql)
+	}
+}
+
+func TestGeneratePruneCacheSQL(t *testing.T) {
+	generator := NewSQLGenerator()
+
+	sql := generator.GeneratePruneCacheSQL(time.Time{})
+	if strings.Contains(sql, "WHERE loaded_at") {
+		t.Errorf("Generated SQL should prune all objects:\n%s", sql)
+	}
+
+	loadedBefore := time.Date(2025, 3, 30, 0, 0, 0, 0, time.UTC)
+	sql = generator.GeneratePruneCacheSQL(loadedBefore)
+	if !strings.Contains(sql, "WHERE loaded_at < TIMESTAMP '2025-03-30 00:00:00'") {
+		t.Errorf("Generated SQL does not contain loaded_at condition:\n%s", sql)
+	}
+
+	// すべてのログ形式のキャッシュテーブルから削除する
+	for _, table := range []string{"_dalv_cache_alb", "_dalv_cache_clb", "_dalv_cache_cloudfront", "_dalv_cache_nlb"} {
+		if !strings.Contains(sql, "DELETE FROM "+table) {
+			t.Errorf("Generated SQL does not prune %s:\n%s", table, sql)
+		}
+	}
+}
+
+func TestParseObjects(t *testing.T) {
+	objects, err := parseObjects([][]string{{"s3://bucket/a.log.gz", "100", "2025-03-30 00:00:00"}})
+	if err != nil {
+		t.Fatalf("parseObjects returned error: %v", err)
+	}
+	if len(objects) != 1 || objects[0].Size != 100 || objects[0].LastModified != "2025-03-30 00:00:00" {
+		t.Errorf("Unexpected objects: %v", objects)
+	}
+
+	if _, err := parseObjects([][]string{{"s3://bucket/a.log.gz", "x", "2025-03-30 00:00:00"}}); err == nil {
+		t.Error("parseObjects should return error for invalid size")
+	}
+}
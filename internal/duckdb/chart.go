@@ -0,0 +1,192 @@
+package duckdb
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// chartTimeLayout はグラフのデータの時間帯をDuckDBから受け取る形式です
+const chartTimeLayout = "2006-01-02T15:04:05"
+
+// ChartConfig は時系列グラフのデータの集計設定です
+type ChartConfig struct {
+	// Interval は集計する時間帯の間隔です
+	Interval time.Duration
+	// SplitBy は系列を分けるカラムです (空の場合は全体で1つの系列)
+	SplitBy string
+	// MaxSeries は系列を分ける場合の系列数の上限です (リクエスト数の多い順。0の場合は制限なし)
+	MaxSeries int
+}
+
+// ChartPoint は時系列グラフの1つの時間帯の値です
+type ChartPoint struct {
+	// Series は系列の名前です (系列を分けない場合は空)
+	Series string
+	// Bucket は時間帯の開始日時です
+	Bucket time.Time
+	// Requests はリクエスト数です
+	Requests int64
+	// Errors4xx はステータスコードが4xxのリクエスト数です
+	Errors4xx int64
+	// Errors5xx はステータスコードが5xxのリクエスト数です
+	Errors5xx int64
+	// P99 は処理時間の合計のp99 (秒) です (計算できない場合はNaN)
+	P99 float64
+}
+
+// ChartData は時系列グラフのデータです
+type ChartData struct {
+	// Points は系列・時間帯順の値です
+	Points []ChartPoint
+	// Interval は集計した時間帯の間隔です
+	Interval time.Duration
+	// HasStatusCodes はログ形式がステータスコードを記録しているかどうかです
+	HasStatusCodes bool
+	// HasLatency はログ形式が処理時間を記録しているかどうかです
+	HasLatency bool
+}
+
+// ValidateChartConfig は時系列グラフの集計設定がログ形式に対して有効かを検証します
+func (g *SQLGenerator) ValidateChartConfig(cfg ChartConfig) error {
+	if cfg.Interval < time.Second || cfg.Interval%time.Second != 0 {
+		return fmt.Errorf("集計の間隔は1秒以上の秒単位で指定してください: %s", cfg.Interval)
+	}
+	if cfg.SplitBy != "" {
+		if _, ok := g.dimensionExpr(cfg.SplitBy); !ok {
+			return fmt.Errorf("%sログに系列を分けるために使用できるカラムがありません: %s", g.format.Label, cfg.SplitBy)
+		}
+	}
+	if cfg.MaxSeries < 0 {
+		return fmt.Errorf("系列数の上限には0以上の値を指定してください: %d", cfg.MaxSeries)
+	}
+	return nil
+}
+
+// GenerateChartSQL は時間帯ごとのリクエスト数・エラー数・p99を集計するSQLを生成します
+// ステータスコードや処理時間を記録していないログ形式ではNULLを返します
+func (g *SQLGenerator) GenerateChartSQL(tableName string, cfg ChartConfig) string {
+	series := "''"
+	if cfg.SplitBy != "" {
+		expr, _ := g.dimensionExpr(cfg.SplitBy)
+		series = fmt.Sprintf("coalesce(CAST(%s AS VARCHAR), '-')", expr)
+	}
+	status := "NULL"
+	if g.format.StatusCodeColumn != "" {
+		status = g.format.StatusCodeColumn
+	}
+	totalTime := "NULL"
+	if len(g.format.ProcessingTimeColumns) > 0 {
+		totalTime = strings.Join(g.format.ProcessingTimeColumns, " + ")
+	}
+
+	limit := ""
+	if cfg.MaxSeries > 0 {
+		limit = fmt.Sprintf("\n    LIMIT %d", cfg.MaxSeries)
+	}
+
+	return fmt.Sprintf(`WITH points AS (
+    SELECT
+        %s AS series,
+        time_bucket(INTERVAL '%d seconds', %s) AS bucket,
+        %s AS status,
+        %s AS total_time
+    FROM %s
+), top_series AS (
+    SELECT series
+    FROM points
+    GROUP BY series
+    ORDER BY count(*) DESC%s
+)
+SELECT
+    series,
+    strftime(bucket, '%%Y-%%m-%%dT%%H:%%M:%%S') AS bucket_start,
+    count(*) AS requests,
+    count(*) FILTER (WHERE status BETWEEN 400 AND 499) AS errors_4xx,
+    count(*) FILTER (WHERE status >= 500) AS errors_5xx,
+    quantile_cont(total_time, 0.99) AS p99
+FROM points
+WHERE series IN (SELECT series FROM top_series)
+GROUP BY ALL
+ORDER BY series, bucket_start;`, series, int64(cfg.Interval/time.Second), g.format.TimestampExpr, status, totalTime, Identifier(tableName), limit)
+}
+
+// ChartData はログを読み込んだ後に時系列グラフのデータを集計します
+func (e *Executor) ChartData(paths []string, tableName string, cfg ChartConfig) (*ChartData, error) {
+	if err := e.sqlGenerator.ValidateChartConfig(cfg); err != nil {
+		return nil, err
+	}
+
+	// テーブル名が指定されていない場合は生成
+	if tableName == "" {
+		tableName = e.sqlGenerator.GenerateTableName()
+	}
+
+	loadSQL, err := e.loadSQL(paths, tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	b.WriteString(".bail on\n")
+	b.WriteString(".mode trash\n")
+	b.WriteString(loadSQL)
+	b.WriteString("\n\n.mode csv\n.headers on\n")
+	b.WriteString(e.sqlGenerator.GenerateChartSQL(tableName, cfg))
+	b.WriteString("\n")
+
+	rows, err := e.queryCSV(e.dbPath, b.String())
+	if err != nil {
+		return nil, fmt.Errorf("グラフのデータの集計に失敗しました: %w", err)
+	}
+	points, err := parseChartPoints(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ChartData{
+		Points:         points,
+		Interval:       cfg.Interval,
+		HasStatusCodes: e.sqlGenerator.format.StatusCodeColumn != "",
+		HasLatency:     len(e.sqlGenerator.format.ProcessingTimeColumns) > 0,
+	}, nil
+}
+
+// parseChartPoints は series, bucket_start, requests, errors_4xx, errors_5xx, p99 の行を変換します
+func parseChartPoints(rows [][]string) ([]ChartPoint, error) {
+	points := make([]ChartPoint, 0, len(rows))
+	for _, row := range rows {
+		if len(row) != 6 {
+			return nil, fmt.Errorf("グラフのデータの形式が不正です: %v", row)
+		}
+
+		bucket, err := time.Parse(chartTimeLayout, row[1])
+		if err != nil {
+			return nil, fmt.Errorf("グラフのデータの時間帯の形式が不正です: %w", err)
+		}
+		var counts [3]int64
+		for i := range counts {
+			if counts[i], err = strconv.ParseInt(row[2+i], 10, 64); err != nil {
+				return nil, fmt.Errorf("グラフのデータのリクエスト数の形式が不正です: %w", err)
+			}
+		}
+		p99 := math.NaN()
+		if row[5] != "" {
+			if p99, err = strconv.ParseFloat(row[5], 64); err != nil {
+				return nil, fmt.Errorf("グラフのデータのp99の形式が不正です: %w", err)
+			}
+		}
+
+		points = append(points, ChartPoint{
+			Series:    row[0],
+			Bucket:    bucket,
+			Requests:  counts[0],
+			Errors4xx: counts[1],
+			Errors5xx: counts[2],
+			P99:       p99,
+		})
+	}
+	return points, nil
+}
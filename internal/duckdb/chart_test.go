@@ -0,0 +1,82 @@
+package duckdb
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/naotama2002/dalv/internal/schema"
+)
+
+func TestGenerateChartSQL(t *testing.T) {
+	generator := NewSQLGeneratorWithConfig(Config{Format: schema.ALB})
+	sql := generator.GenerateChartSQL("alb_logs", ChartConfig{Interval: 5 * time.Minute, SplitBy: "elb", MaxSeries: 3})
+
+	expectedParts := []string{
+		"coalesce(CAST(elb AS VARCHAR), '-') AS series",
+		"time_bucket(INTERVAL '300 seconds', timestamp) AS bucket",
+		"elb_status_code AS status",
+		"request_processing_time + target_processing_time + response_processing_time AS total_time",
+		"FROM alb_logs",
+		"ORDER BY count(*) DESC\n    LIMIT 3",
+		"strftime(bucket, '%Y-%m-%dT%H:%M:%S') AS bucket_start",
+		"quantile_cont(total_time, 0.99) AS p99",
+	}
+	for _, part := range expectedParts {
+		if !strings.Contains(sql, part) {
+			t.Errorf("Expected SQL to contain %q, got:\n%s", part, sql)
+		}
+	}
+
+	// ステータスコードと処理時間を記録していないログ形式
+	generator = NewSQLGeneratorWithConfig(Config{Format: schema.NLB})
+	sql = generator.GenerateChartSQL("nlb_logs", ChartConfig{Interval: time.Hour})
+	for _, part := range []string{"'' AS series", "NULL AS status", "NULL AS total_time", "time_bucket(INTERVAL '3600 seconds', time)"} {
+		if !strings.Contains(sql, part) {
+			t.Errorf("Expected SQL to contain %q, got:\n%s", part, sql)
+		}
+	}
+	if strings.Contains(sql, "LIMIT") {
+		t.Errorf("Unexpected LIMIT without max series:\n%s", sql)
+	}
+}
+
+func TestValidateChartConfig(t *testing.T) {
+	generator := NewSQLGeneratorWithConfig(Config{Format: schema.ALB})
+	if err := generator.ValidateChartConfig(ChartConfig{Interval: time.Minute, SplitBy: "target_group_arn"}); err != nil {
+		t.Errorf("ValidateChartConfig returned error: %v", err)
+	}
+
+	for _, cfg := range []ChartConfig{
+		{},
+		{Interval: time.Minute, SplitBy: "backend_ip_port"},
+		{Interval: time.Minute, MaxSeries: -1},
+	} {
+		if err := generator.ValidateChartConfig(cfg); err == nil {
+			t.Errorf("ValidateChartConfig(%+v) should return error", cfg)
+		}
+	}
+}
+
+func TestParseChartPoints(t *testing.T) {
+	points, err := parseChartPoints([][]string{
+		{"app/a", "2025-03-30T22:05:00", "10", "1", "2", "0.25"},
+		{"", "2025-03-30T22:10:00", "3", "0", "0", ""},
+	})
+	if err != nil {
+		t.Fatalf("parseChartPoints returned error: %v", err)
+	}
+
+	expected := ChartPoint{Series: "app/a", Bucket: time.Date(2025, 3, 30, 22, 5, 0, 0, time.UTC), Requests: 10, Errors4xx: 1, Errors5xx: 2, P99: 0.25}
+	if points[0] != expected {
+		t.Errorf("Expected %+v, got %+v", expected, points[0])
+	}
+	if !math.IsNaN(points[1].P99) {
+		t.Errorf("Expected p99 to be NaN, got %v", points[1].P99)
+	}
+
+	if _, err := parseChartPoints([][]string{{"app/a", "yesterday", "1", "0", "0", ""}}); err == nil {
+		t.Error("parseChartPoints should return error for invalid bucket")
+	}
+}
@@ -0,0 +1,134 @@
+// Package duckdbtest はDuckDBを起動せずにエンジンの呼び出しを確認するためのテスト用のエンジンを提供します
+package duckdbtest
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/naotama2002/dalv/internal/duckdb"
+)
+
+const (
+	// MethodRun は Engine.Run の呼び出しです
+	MethodRun = "run"
+	// MethodQuery は Engine.Query の呼び出しです
+	MethodQuery = "query"
+	// MethodInteractive は Engine.Interactive の呼び出しです
+	MethodInteractive = "interactive"
+)
+
+// Call はエンジンの呼び出しの記録です
+type Call struct {
+	// Method は呼び出されたメソッドです
+	Method string
+	// Request は実行を依頼された内容です
+	Request duckdb.RunRequest
+}
+
+// Response は呼び出しに対して返す結果です
+type Response struct {
+	// Output は Run で stdout に書き込む内容です
+	Output string
+	// Result は Query で返す結果です (nil の場合は空の結果)
+	Result *duckdb.Result
+	// Err は返すエラーです
+	Err error
+}
+
+// Engine は呼び出しを記録し、設定した結果を返すエンジンです
+// 並行して呼び出すことができます
+type Engine struct {
+	// CheckErr は Check で返すエラーです
+	CheckErr error
+	// Respond は呼び出しに対する結果を返す関数です (nil の場合は何も出力しません)
+	Respond func(call Call) Response
+
+	mu    sync.Mutex
+	calls []Call
+}
+
+var _ duckdb.Engine = (*Engine)(nil)
+
+// NewEngine は何も出力しないエンジンを作成します
+func NewEngine() *Engine {
+	return &Engine{}
+}
+
+// Name はエンジンの名前を返します
+func (e *Engine) Name() string {
+	return "fake"
+}
+
+// Check は CheckErr を返します
+func (e *Engine) Check() error {
+	return e.CheckErr
+}
+
+// Run は呼び出しを記録し、結果の Output を stdout に書き込みます
+func (e *Engine) Run(ctx context.Context, req duckdb.RunRequest, stdout io.Writer) error {
+	resp := e.record(MethodRun, req)
+	if resp.Output != "" {
+		if _, err := io.WriteString(stdout, resp.Output); err != nil {
+			return err
+		}
+	}
+	return resp.Err
+}
+
+// Query は呼び出しを記録し、結果の Result を返します
+func (e *Engine) Query(ctx context.Context, req duckdb.RunRequest) (*duckdb.Result, error) {
+	resp := e.record(MethodQuery, req)
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+	if resp.Result == nil {
+		return &duckdb.Result{}, nil
+	}
+	return resp.Result, nil
+}
+
+// Interactive は呼び出しを記録します
+func (e *Engine) Interactive(req duckdb.RunRequest) error {
+	return e.record(MethodInteractive, req).Err
+}
+
+// Calls は記録した呼び出しを順に返します
+func (e *Engine) Calls() []Call {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]Call(nil), e.calls...)
+}
+
+// Last は最後の呼び出しを返します
+// 呼び出されていない場合は false を返します
+func (e *Engine) Last() (Call, bool) {
+	calls := e.Calls()
+	if len(calls) == 0 {
+		return Call{}, false
+	}
+	return calls[len(calls)-1], true
+}
+
+// Script は呼び出しで実行するスクリプトとクエリを連結して返します
+func (c Call) Script() string {
+	if c.Request.Query == "" {
+		return c.Request.Script
+	}
+	return strings.TrimRight(c.Request.Script, "\n") + "\n" + c.Request.Query
+}
+
+// record は呼び出しを記録し、Respond の結果を返します
+func (e *Engine) record(method string, req duckdb.RunRequest) Response {
+	call := Call{Method: method, Request: req}
+	e.mu.Lock()
+	e.calls = append(e.calls, call)
+	respond := e.Respond
+	e.mu.Unlock()
+
+	if respond == nil {
+		return Response{}
+	}
+	return respond(call)
+}
@@ -0,0 +1,98 @@
+package duckdb
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+const (
+	// EngineAuto はDuckDBのCLIがあればCLIを、なければ組み込みのDuckDBを使用します
+	EngineAuto = "auto"
+	// EngineCLI はPATHにあるDuckDBのCLIを起動して実行します
+	EngineCLI = "cli"
+	// EngineEmbedded はdalvに組み込んだDuckDBでプロセス内で実行します
+	EngineEmbedded = "embedded"
+)
+
+// EngineNames は指定できるエンジンの名前です
+var EngineNames = []string{EngineAuto, EngineCLI, EngineEmbedded}
+
+// Engine はDuckDBのスクリプトやクエリを実行するエンジンです
+type Engine interface {
+	// Name はエンジンの名前を返します
+	Name() string
+	// Check はエンジンを使用できるかを確認します
+	Check() error
+	// Run はスクリプトとクエリを非インタラクティブに実行し、結果を stdout に書き込みます
+	Run(ctx context.Context, req RunRequest, stdout io.Writer) error
+	// Query はスクリプトを実行した後にクエリを実行し、結果を型付きの値で返します
+	Query(ctx context.Context, req RunRequest) (*Result, error)
+	// Interactive はスクリプトを実行した後にインタラクティブなコンソールを起動します
+	Interactive(req RunRequest) error
+}
+
+// RunRequest はエンジンで実行する内容です
+type RunRequest struct {
+	// DBPath はデータベースファイルのパスです (空の場合はインメモリデータベース)
+	DBPath string
+	// ReadOnly はデータベースファイルを読み取り専用で開くかどうかです
+	ReadOnly bool
+	// Script はドットコマンドを含むDuckDB CLIのスクリプトです
+	Script string
+	// Query はスクリプトの後に実行するSQLです
+	// ドットコマンドとしては解釈しません
+	Query string
+	// Sensitive はスクリプトに外部IDなどの秘密情報を含むかどうかです
+	// 秘密情報を含むスクリプトはディスクに書き込みません
+	Sensitive bool
+}
+
+// Result は型付きのクエリ結果です
+type Result struct {
+	// Columns は結果のカラムです
+	Columns []ResultColumn
+	// Rows は結果の行です
+	Rows [][]any
+}
+
+// NewEngine は名前を指定してエンジンを作成します
+// auto の場合はPATHにDuckDBのCLIがあればCLIを、なければ組み込みのDuckDBを使用します
+func NewEngine(name string) (Engine, error) {
+	switch name {
+	case "", EngineAuto:
+		if _, err := exec.LookPath(duckDBCommand); err == nil {
+			return NewCLIEngine(), nil
+		}
+		engine, err := NewEmbeddedEngine()
+		if err != nil {
+			return nil, fmt.Errorf("DuckDBがインストールされておらず、組み込みのDuckDBも使用できません: %w", err)
+		}
+		return engine, nil
+	case EngineCLI:
+		return NewCLIEngine(), nil
+	case EngineEmbedded:
+		return NewEmbeddedEngine()
+	default:
+		return nil, fmt.Errorf("未対応のエンジンです: %s (%s のいずれかを指定してください)", name, strings.Join(EngineNames, ", "))
+	}
+}
+
+// checkQuery はクエリが空でなく、ドットコマンドを含まないことを検証します
+func checkQuery(query string) error {
+	if strings.TrimSpace(query) == "" {
+		return &QueryError{Message: "クエリが空です"}
+	}
+	items, err := parseScript(query)
+	if err != nil {
+		return &QueryError{Message: err.Error(), Err: err}
+	}
+	for _, item := range items {
+		if item.Command != "" {
+			return &QueryError{Message: "ドットコマンドは実行できません"}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+package duckdb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// duckDBCommand はDuckDBのCLIのコマンド名です
+const duckDBCommand = "duckdb"
+
+// cliEngine はPATHにあるDuckDBのCLIを起動して実行するエンジンです
+type cliEngine struct{}
+
+// NewCLIEngine はDuckDBのCLIを使用するエンジンを作成します
+func NewCLIEngine() Engine {
+	return &cliEngine{}
+}
+
+// Name はエンジンの名前を返します
+func (c *cliEngine) Name() string {
+	return EngineCLI
+}
+
+// Check はDuckDBのCLIがインストールされているかを確認します
+func (c *cliEngine) Check() error {
+	cmd := exec.Command(duckDBCommand, "--version")
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("DuckDBがインストールされていないか、実行できません: %w", err)
+	}
+	return nil
+}
+
+// Run はDuckDBを非インタラクティブに起動し、スクリプトを標準入力から実行します
+// クエリを指定した場合はエラーメッセージを QueryError で返し、指定しない場合は標準エラー出力にそのまま表示します
+// ctx がキャンセルされた場合はDuckDBを終了します
+func (c *cliEngine) Run(ctx context.Context, req RunRequest, stdout io.Writer) error {
+	args, stdin, err := c.commandInput(req)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.CommandContext(ctx, duckDBCommand, args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	cmd.Stdout = stdout
+	if req.Query == "" {
+		cmd.Stderr = os.Stderr
+		return cmd.Run()
+	}
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return fmt.Errorf("クエリが中断されました: %w", ctxErr)
+		}
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return &QueryError{Message: strings.TrimSpace(stderr.String()), Err: err}
+		}
+		return fmt.Errorf("DuckDBの実行に失敗しました: %w", err)
+	}
+	return nil
+}
+
+// commandInput はDuckDBコマンドの引数と標準入力に渡すスクリプトを返します
+// クエリは .mode などのドットコマンドの後に追加するため、ドットコマンドを含まないことを検証します
+// データベースファイルはオプションの後に指定する必要があります
+func (c *cliEngine) commandInput(req RunRequest) ([]string, string, error) {
+	var args []string
+	if req.ReadOnly {
+		args = append(args, "-readonly")
+	}
+	args = append(args, "-batch", "-bail")
+	if req.DBPath != "" {
+		args = append(args, req.DBPath)
+	}
+
+	if req.Query == "" {
+		return args, req.Script, nil
+	}
+	if err := checkQuery(req.Query); err != nil {
+		return nil, "", err
+	}
+	var b strings.Builder
+	if req.Script != "" {
+		b.WriteString(strings.TrimRight(req.Script, "\n"))
+		b.WriteString("\n")
+	}
+	b.WriteString(terminateStatement(req.Query))
+	b.WriteString("\n")
+	return args, b.String(), nil
+}
+
+// Query はJSON形式で出力したクエリ結果を型付きの値に変換して返します
+// カラムの型はJSONの値から推定するため、Type は空になります
+func (c *cliEngine) Query(ctx context.Context, req RunRequest) (*Result, error) {
+	if err := checkQuery(req.Query); err != nil {
+		return nil, err
+	}
+	req.Script = strings.TrimRight(req.Script, "\n") + "\n.mode json\n.headers on\n"
+
+	var out bytes.Buffer
+	if err := c.Run(ctx, req, &out); err != nil {
+		return nil, err
+	}
+	return parseJSONResult(&out)
+}
+
+// Interactive はスクリプトを -init で指定してDuckDBのコンソールを起動します
+// スクリプトは一時ディレクトリの init.sql から渡します (渡し方は initScript を参照)
+func (c *cliEngine) Interactive(req RunRequest) error {
+	tempDir, err := os.MkdirTemp("", "dalv-")
+	if err != nil {
+		return fmt.Errorf("一時ディレクトリの作成に失敗しました: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	sqlFilePath := filepath.Join(tempDir, "init.sql")
+	done, err := initScript(sqlFilePath, req)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	args := []string{"-init", sqlFilePath}
+	if req.ReadOnly {
+		args = append([]string{"-readonly"}, args...)
+	}
+	if req.DBPath != "" {
+		args = append(args, req.DBPath)
+	}
+	cmd := exec.Command(duckDBCommand, args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// parseJSONResult はDuckDBがJSON形式で出力したオブジェクトの配列を結果に変換します
+// カラムの順序を保つため、オブジェクトのキーを出現順に読み込みます
+// 数値は整数として表せる場合は int64、それ以外は float64 に変換します
+func parseJSONResult(r io.Reader) (*Result, error) {
+	result := &Result{}
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+
+	// 結果が0行の場合、DuckDBは何も出力しない
+	if _, err := decoder.Token(); err == io.EOF {
+		return result, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("クエリ結果の読み込みに失敗しました: %w", err)
+	}
+
+	for decoder.More() {
+		if _, err := decoder.Token(); err != nil {
+			return nil, fmt.Errorf("クエリ結果の読み込みに失敗しました: %w", err)
+		}
+		var row []any
+		for i := 0; decoder.More(); i++ {
+			key, err := decoder.Token()
+			if err != nil {
+				return nil, fmt.Errorf("クエリ結果の読み込みに失敗しました: %w", err)
+			}
+			var value any
+			if err := decoder.Decode(&value); err != nil {
+				return nil, fmt.Errorf("クエリ結果の読み込みに失敗しました: %w", err)
+			}
+			if len(result.Rows) == 0 {
+				result.Columns = append(result.Columns, ResultColumn{Name: fmt.Sprint(key)})
+			}
+			row = append(row, jsonNumberValue(value))
+		}
+		if _, err := decoder.Token(); err != nil {
+			return nil, fmt.Errorf("クエリ結果の読み込みに失敗しました: %w", err)
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	return result, nil
+}
+
+// jsonNumberValue は json.Number を int64 または float64 に変換します
+func jsonNumberValue(v any) any {
+	n, ok := v.(json.Number)
+	if !ok {
+		return v
+	}
+	if i, err := n.Int64(); err == nil {
+		return i
+	}
+	if f, err := n.Float64(); err == nil {
+		return f
+	}
+	return n.String()
+}
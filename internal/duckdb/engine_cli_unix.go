@@ -0,0 +1,53 @@
+//go:build !windows
+
+package duckdb
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"syscall"
+	"time"
+)
+
+// initScriptPollInterval はDuckDBが init.sql を開くのを待つ間隔です
+const initScriptPollInterval = 10 * time.Millisecond
+
+// initScript は名前付きパイプ (FIFO) を作成し、DuckDBが開いた時にスクリプトを書き込みます
+// スクリプトはパイプを経由して渡すため、外部IDなどの秘密情報をディスクに書き込みません
+// 返す関数はDuckDBの終了後に呼び出し、スクリプトの書き込みを終了させます
+func initScript(path string, req RunRequest) (func(), error) {
+	if err := syscall.Mkfifo(path, 0600); err != nil {
+		return nil, fmt.Errorf("SQLファイルの作成に失敗しました: %w", err)
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			// 読み込み側が開くまでは ENXIO になるため、DuckDBが開くか終了するまで待つ
+			f, err := os.OpenFile(path, os.O_WRONLY|syscall.O_NONBLOCK, 0)
+			if err == nil {
+				// DuckDBが読み込まずに終了した場合は書き込みがエラーになる
+				_, _ = io.WriteString(f, req.Script)
+				f.Close()
+				return
+			}
+			if !errors.Is(err, syscall.ENXIO) {
+				return
+			}
+			select {
+			case <-stop:
+				return
+			case <-time.After(initScriptPollInterval):
+			}
+		}
+	}()
+
+	return func() {
+		close(stop)
+		<-done
+	}, nil
+}
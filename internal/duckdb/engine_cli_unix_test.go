@@ -0,0 +1,57 @@
+//go:build !windows
+
+package duckdb
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCLIEngineInteractiveKeepsInitScriptOffDisk(t *testing.T) {
+	// -init で渡されたファイルの種類と内容を記録するDuckDBの代わりのコマンド
+	dir := t.TempDir()
+	record := filepath.Join(dir, "record")
+	fake := "#!/bin/sh\n" +
+		"if [ -p \"$2\" ]; then echo fifo > \"" + record + "\"; else echo file > \"" + record + "\"; fi\n" +
+		"cat \"$2\" >> \"" + record + "\"\n"
+	if err := os.WriteFile(filepath.Join(dir, duckDBCommand), []byte(fake), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	generator := NewSQLGeneratorWithConfig(Config{S3: S3Config{RoleARN: "arn:aws:iam::123456789012:role/r", ExternalID: "ext-1234"}})
+	script := generator.GenerateCompleteSQL([]string{"s3://bucket/path/*.log.gz"}, "alb_logs")
+	if err := NewCLIEngine().Interactive(RunRequest{Script: script, Sensitive: true}); err != nil {
+		t.Fatalf("Interactive returned error: %v", err)
+	}
+
+	recorded, err := os.ReadFile(record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// init.sql は名前付きパイプで、外部IDを含むスクリプトはディスクに書き込まない
+	kind, content, _ := strings.Cut(string(recorded), "\n")
+	if kind != "fifo" {
+		t.Errorf("Expected init.sql to be a named pipe, got %s", kind)
+	}
+	if content != script {
+		t.Errorf("Expected DuckDB to read the script through init.sql, got:\n%s", content)
+	}
+}
+
+func TestCLIEngineInteractiveWithoutReadingInitScript(t *testing.T) {
+	// DuckDBが init.sql を読み込まずに、または途中まで読み込んで終了しても、書き込みを待ち続けない
+	for _, fake := range []string{"#!/bin/sh\nexit 0\n", "#!/bin/sh\nhead -c 10 \"$2\" > /dev/null\n"} {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, duckDBCommand), []byte(fake), 0755); err != nil {
+			t.Fatal(err)
+		}
+		t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+		if err := NewCLIEngine().Interactive(RunRequest{Script: strings.Repeat("SELECT 1;\n", 100000)}); err != nil {
+			t.Fatalf("Interactive returned error: %v", err)
+		}
+	}
+}
@@ -0,0 +1,20 @@
+//go:build windows
+
+package duckdb
+
+import (
+	"fmt"
+	"os"
+)
+
+// initScript はスクリプトを -init で指定するファイルに書き込みます
+// Windowsでは名前付きパイプをファイルとして渡せないため、秘密情報を含むスクリプトはディスクに書き込まずにエラーを返します
+func initScript(path string, req RunRequest) (func(), error) {
+	if req.Sensitive {
+		return nil, fmt.Errorf("WindowsではDuckDBのCLIのコンソールに外部ID (--external-id) を渡せません。-q でクエリを実行するか、--engine embedded を使用してください")
+	}
+	if err := os.WriteFile(path, []byte(req.Script), 0600); err != nil {
+		return nil, fmt.Errorf("SQLファイルの作成に失敗しました: %w", err)
+	}
+	return func() {}, nil
+}
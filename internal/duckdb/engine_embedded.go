@@ -0,0 +1,428 @@
+//go:build cgo
+
+package duckdb
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/marcboeker/go-duckdb"
+)
+
+// silentStatementTypes はDuckDBのCLIと同様に結果を表示しない文の種類です
+// テーブルの作成や変更した行数などの結果は表示しません
+var silentStatementTypes = map[duckdb.StmtType]bool{
+	duckdb.STATEMENT_TYPE_INSERT:       true,
+	duckdb.STATEMENT_TYPE_UPDATE:       true,
+	duckdb.STATEMENT_TYPE_DELETE:       true,
+	duckdb.STATEMENT_TYPE_CREATE:       true,
+	duckdb.STATEMENT_TYPE_CREATE_FUNC:  true,
+	duckdb.STATEMENT_TYPE_ALTER:        true,
+	duckdb.STATEMENT_TYPE_DROP:         true,
+	duckdb.STATEMENT_TYPE_SET:          true,
+	duckdb.STATEMENT_TYPE_VARIABLE_SET: true,
+	duckdb.STATEMENT_TYPE_TRANSACTION:  true,
+	duckdb.STATEMENT_TYPE_ATTACH:       true,
+	duckdb.STATEMENT_TYPE_DETACH:       true,
+	duckdb.STATEMENT_TYPE_LOAD:         true,
+	duckdb.STATEMENT_TYPE_COPY:         true,
+	duckdb.STATEMENT_TYPE_EXPORT:       true,
+	duckdb.STATEMENT_TYPE_VACUUM:       true,
+	duckdb.STATEMENT_TYPE_ANALYZE:      true,
+	duckdb.STATEMENT_TYPE_PREPARE:      true,
+}
+
+// errQuit は .quit または .exit で実行を終了したことを表します
+var errQuit = errors.New("quit")
+
+// embeddedEngine はdalvに組み込んだDuckDB (go-duckdb) でプロセス内で実行するエンジンです
+// DuckDBのCLIのスクリプトで使用するドットコマンドのうち、.bail, .mode, .headers, .print を解釈します
+type embeddedEngine struct{}
+
+// NewEmbeddedEngine は組み込みのDuckDBを使用するエンジンを作成します
+func NewEmbeddedEngine() (Engine, error) {
+	return &embeddedEngine{}, nil
+}
+
+// Name はエンジンの名前を返します
+func (e *embeddedEngine) Name() string {
+	return EngineEmbedded
+}
+
+// Check はインメモリデータベースを開けるかを確認します
+func (e *embeddedEngine) Check() error {
+	s, err := openSession(context.Background(), RunRequest{}, io.Discard)
+	if err != nil {
+		return err
+	}
+	return s.close()
+}
+
+// Run はスクリプトとクエリを1つの接続で順に実行し、結果を stdout に書き込みます
+// ctx がキャンセルされた場合は実行中のクエリを中断します
+func (e *embeddedEngine) Run(ctx context.Context, req RunRequest, stdout io.Writer) error {
+	if req.Query != "" {
+		if err := checkQuery(req.Query); err != nil {
+			return err
+		}
+	}
+
+	s, err := openSession(ctx, req, stdout)
+	if err != nil {
+		return err
+	}
+	defer s.close()
+
+	if err := s.runScript(ctx, req.Script); err != nil {
+		if errors.Is(err, errQuit) {
+			return nil
+		}
+		return err
+	}
+	if req.Query == "" {
+		return nil
+	}
+	return s.runSQL(ctx, req.Query, s.newRowWriter)
+}
+
+// Query はスクリプトを実行した後にクエリを実行し、最後の文の結果を返します
+// 値はDuckDBの型に対応するGoの型 (int64, float64, string, time.Time など) で返します
+func (e *embeddedEngine) Query(ctx context.Context, req RunRequest) (*Result, error) {
+	if err := checkQuery(req.Query); err != nil {
+		return nil, err
+	}
+
+	s, err := openSession(ctx, req, io.Discard)
+	if err != nil {
+		return nil, err
+	}
+	defer s.close()
+
+	if err := s.runScript(ctx, req.Script); err != nil {
+		return nil, err
+	}
+	result := &Result{}
+	err = s.runSQL(ctx, req.Query, func(columns []ResultColumn) (rowWriter, error) {
+		*result = Result{Columns: columns}
+		return &resultWriter{result: result}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Interactive はスクリプトを実行した後に、標準入力からSQLとドットコマンドを読み込んで実行します
+// 入力が終端に達するか .quit または .exit で終了します
+func (e *embeddedEngine) Interactive(req RunRequest) error {
+	ctx := context.Background()
+	s, err := openSession(ctx, req, os.Stdout)
+	if err != nil {
+		return err
+	}
+	defer s.close()
+
+	if err := s.runScript(ctx, req.Script); err != nil {
+		if errors.Is(err, errQuit) {
+			return nil
+		}
+		return err
+	}
+	return s.console(ctx, os.Stdin, os.Stderr)
+}
+
+// embeddedSession は1つの接続と .mode などの出力の設定を保持します
+type embeddedSession struct {
+	db      *sql.DB
+	conn    *sql.Conn
+	stdout  io.Writer
+	mode    string
+	headers bool
+}
+
+// openSession はデータベースを開き、接続を確立します
+// データベースファイルを指定しない場合はインメモリデータベースを使用します
+func openSession(ctx context.Context, req RunRequest, stdout io.Writer) (*embeddedSession, error) {
+	dsn := req.DBPath
+	if dsn != "" && req.ReadOnly {
+		dsn += "?access_mode=read_only"
+	}
+	db, err := sql.Open("duckdb", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("データベースを開けませんでした: %w", err)
+	}
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("データベースを開けませんでした: %w", err)
+	}
+	return &embeddedSession{db: db, conn: conn, stdout: stdout, mode: "duckbox", headers: true}, nil
+}
+
+// close は接続とデータベースを閉じます
+func (s *embeddedSession) close() error {
+	s.conn.Close()
+	return s.db.Close()
+}
+
+// runScript はスクリプトのドットコマンドとSQLの文を順に実行します
+// いずれかが失敗した場合はその時点で終了します (.bail on と同様)
+func (s *embeddedSession) runScript(ctx context.Context, script string) error {
+	items, err := parseScript(script)
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		if err := s.runItem(ctx, item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runItem はドットコマンドまたはSQLの文を1つ実行します
+func (s *embeddedSession) runItem(ctx context.Context, item scriptItem) error {
+	if item.Command == "" {
+		return s.exec(ctx, item.SQL, s.newRowWriter)
+	}
+	return s.command(item)
+}
+
+// command はドットコマンドを実行します
+func (s *embeddedSession) command(item scriptItem) error {
+	switch item.Command {
+	case "bail":
+		// 組み込みのDuckDBでは常にエラーで終了する
+		return nil
+	case "mode":
+		if len(item.Args) != 1 || !renderModes[item.Args[0]] {
+			return fmt.Errorf("未対応の出力モードです: %s", item)
+		}
+		s.mode = item.Args[0]
+	case "headers":
+		if len(item.Args) != 1 || (item.Args[0] != "on" && item.Args[0] != "off") {
+			return fmt.Errorf(".headers には on または off を指定してください: %s", item)
+		}
+		s.headers = item.Args[0] == "on"
+	case "print":
+		if _, err := fmt.Fprintln(s.stdout, strings.Join(item.Args, " ")); err != nil {
+			return err
+		}
+	case "quit", "exit":
+		return errQuit
+	default:
+		return fmt.Errorf("組み込みのDuckDBでは未対応のドットコマンドです: %s", item)
+	}
+	return nil
+}
+
+// runSQL はドットコマンドを含まないSQLを文ごとに実行します
+func (s *embeddedSession) runSQL(ctx context.Context, query string, newWriter func([]ResultColumn) (rowWriter, error)) error {
+	items, rest, err := parseScriptPrefix(query)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(rest) != "" {
+		items = append(items, scriptItem{SQL: rest})
+	}
+	for _, item := range items {
+		if err := s.exec(ctx, item.SQL, newWriter); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newRowWriter は現在の .mode と .headers の設定で stdout に書き込む rowWriter を作成します
+func (s *embeddedSession) newRowWriter(columns []ResultColumn) (rowWriter, error) {
+	return newRowWriter(s.stdout, s.mode, s.headers, columns)
+}
+
+// exec はSQLの文を1つ実行し、結果を newWriter で作成した rowWriter に書き込みます
+// 結果を表示しない種類の文や、カラムのない結果は書き込みません
+func (s *embeddedSession) exec(ctx context.Context, query string, newWriter func([]ResultColumn) (rowWriter, error)) error {
+	return s.conn.Raw(func(driverConn any) error {
+		conn, ok := driverConn.(driver.ConnPrepareContext)
+		if !ok {
+			return fmt.Errorf("DuckDBのドライバーが文の準備に対応していません")
+		}
+		stmt, err := conn.PrepareContext(ctx, query)
+		if err != nil {
+			return queryError(ctx, err)
+		}
+		defer stmt.Close()
+
+		rows, err := stmt.(driver.StmtQueryContext).QueryContext(ctx, nil)
+		if err != nil {
+			return queryError(ctx, err)
+		}
+		defer rows.Close()
+
+		names := rows.Columns()
+		columns := make([]ResultColumn, len(names))
+		typeNames, _ := rows.(driver.RowsColumnTypeDatabaseTypeName)
+		for i, name := range names {
+			columns[i] = ResultColumn{Name: name}
+			if typeNames != nil {
+				columns[i].Type = typeNames.ColumnTypeDatabaseTypeName(i)
+			}
+		}
+		if len(columns) == 0 || silentResult(stmt, columns) {
+			return nil
+		}
+
+		w, err := newWriter(columns)
+		if err != nil {
+			return err
+		}
+		values := make([]driver.Value, len(columns))
+		for {
+			if err := rows.Next(values); err == io.EOF {
+				break
+			} else if err != nil {
+				return queryError(ctx, err)
+			}
+			row := make([]any, len(values))
+			for i, v := range values {
+				row[i] = normalizeValue(v, columns[i].Type)
+			}
+			if err := w.WriteRow(row); err != nil {
+				return err
+			}
+		}
+		return w.Close()
+	})
+}
+
+// silentResult は文の結果を表示しないかどうかを返します
+// CHECKPOINT などの結果の種類で判別できない文は、成功したかどうかのみの結果を表示しません
+func silentResult(stmt driver.Stmt, columns []ResultColumn) bool {
+	typed, ok := stmt.(*duckdb.Stmt)
+	if !ok {
+		return false
+	}
+	stmtType, err := typed.StatementType()
+	if err != nil {
+		return false
+	}
+	if silentStatementTypes[stmtType] {
+		return true
+	}
+	return stmtType != duckdb.STATEMENT_TYPE_SELECT && len(columns) == 1 &&
+		columns[0] == ResultColumn{Name: "Success", Type: "BOOLEAN"}
+}
+
+// console は r から1行ずつ読み込み、文が完結するたびに実行します
+// エラーは errOut に表示して入力の読み込みを続けます
+func (s *embeddedSession) console(ctx context.Context, r io.Reader, errOut io.Writer) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10<<20)
+
+	pending := ""
+	for {
+		prompt := "D "
+		if pending != "" {
+			prompt = "‣ "
+		}
+		fmt.Fprint(s.stdout, prompt)
+		if !scanner.Scan() {
+			fmt.Fprintln(s.stdout)
+			return scanner.Err()
+		}
+
+		items, rest, err := parseScriptPrefix(pending + scanner.Text() + "\n")
+		if err != nil {
+			fmt.Fprintf(errOut, "Error: %v\n", err)
+			pending = ""
+			continue
+		}
+		pending = rest
+		for _, item := range items {
+			err := s.runItem(ctx, item)
+			if errors.Is(err, errQuit) {
+				return nil
+			}
+			if err != nil {
+				var queryErr *QueryError
+				if errors.As(err, &queryErr) {
+					fmt.Fprintln(errOut, queryErr.Message)
+				} else {
+					fmt.Fprintf(errOut, "Error: %v\n", err)
+				}
+				break
+			}
+		}
+	}
+}
+
+// queryError はDuckDBのエラーを QueryError に変換します
+// ctx がキャンセルされた場合は中断されたことを表すエラーを返します
+func queryError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return fmt.Errorf("クエリが中断されました: %w", ctxErr)
+	}
+	return &QueryError{Message: err.Error(), Err: err}
+}
+
+// normalizeValue はgo-duckdb固有の型の値を標準の型の値に変換します
+// DECIMAL は float64、INTERVAL は表示用の文字列、UUID は文字列、MAP は map[any]any に変換します
+func normalizeValue(v any, typ string) any {
+	switch x := v.(type) {
+	case duckdb.Decimal:
+		return x.Float64()
+	case duckdb.Interval:
+		return formatInterval(x.Months, x.Days, x.Micros)
+	case duckdb.UUID:
+		return x.String()
+	case duckdb.Map:
+		m := make(map[any]any, len(x))
+		for k, e := range x {
+			m[normalizeValue(k, "")] = normalizeValue(e, "")
+		}
+		return m
+	case []byte:
+		if typ == "UUID" && len(x) == 16 {
+			var u duckdb.UUID
+			copy(u[:], x)
+			return u.String()
+		}
+		return x
+	case []any:
+		list := make([]any, len(x))
+		for i, e := range x {
+			list[i] = normalizeValue(e, "")
+		}
+		return list
+	case map[string]any:
+		m := make(map[string]any, len(x))
+		for k, e := range x {
+			m[k] = normalizeValue(e, "")
+		}
+		return m
+	default:
+		return v
+	}
+}
+
+// resultWriter はクエリ結果の行を Result に追加します
+type resultWriter struct {
+	result *Result
+}
+
+// WriteRow は1行を追加します
+func (r *resultWriter) WriteRow(row []any) error {
+	r.result.Rows = append(r.result.Rows, row)
+	return nil
+}
+
+// Close は何もしません
+func (r *resultWriter) Close() error {
+	return nil
+}
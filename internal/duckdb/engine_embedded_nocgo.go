@@ -0,0 +1,11 @@
+//go:build !cgo
+
+package duckdb
+
+import "fmt"
+
+// NewEmbeddedEngine は組み込みのDuckDBを使用するエンジンを作成します
+// 組み込みのDuckDBはcgoを有効にしてビルドした場合のみ使用できます
+func NewEmbeddedEngine() (Engine, error) {
+	return nil, fmt.Errorf("組み込みのDuckDBを使用するには CGO_ENABLED=1 でビルドしてください")
+}
@@ -0,0 +1,159 @@
+//go:build cgo
+
+package duckdb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestEmbeddedEngineRun(t *testing.T) {
+	engine, err := NewEmbeddedEngine()
+	if err != nil {
+		t.Fatalf("NewEmbeddedEngine returned error: %v", err)
+	}
+
+	// テーブルの作成や変更の結果は表示せず、.mode と .print を解釈する
+	script := `.bail on
+.mode trash
+CREATE TABLE t AS SELECT * FROM (VALUES (1, 'a'), (2, 'b')) v(id, name);
+SELECT * FROM t;
+.mode csv
+.headers on
+INSERT INTO t VALUES (3, 'c');
+CHECKPOINT;
+.print "== t =="
+SELECT * FROM t ORDER BY id;
+`
+	var out bytes.Buffer
+	if err := engine.Run(context.Background(), RunRequest{Script: script}, &out); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	expected := "== t ==\nid,name\n1,a\n2,b\n3,c\n"
+	if out.String() != expected {
+		t.Errorf("Expected output %q, got %q", expected, out.String())
+	}
+}
+
+func TestEmbeddedEngineRunErrors(t *testing.T) {
+	engine, _ := NewEmbeddedEngine()
+
+	// SQLのエラーは QueryError で返す
+	err := engine.Run(context.Background(), RunRequest{Script: "SELECT * FROM missing;"}, &bytes.Buffer{})
+	var queryErr *QueryError
+	if !errors.As(err, &queryErr) {
+		t.Errorf("Run should return QueryError, got %v", err)
+	}
+
+	// 対応していないドットコマンドはエラー
+	if err := engine.Run(context.Background(), RunRequest{Script: ".shell ls\n"}, &bytes.Buffer{}); err == nil {
+		t.Error("Run should return error for unsupported dot command")
+	}
+	if err := engine.Run(context.Background(), RunRequest{Query: ".shell ls"}, &bytes.Buffer{}); err == nil {
+		t.Error("Run should return error for dot command in query")
+	}
+
+	// 中断された場合は QueryError ではなく中断のエラーを返す
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = engine.Run(ctx, RunRequest{Query: "SELECT 1"}, &bytes.Buffer{})
+	if err == nil || errors.As(err, &queryErr) {
+		t.Errorf("Run should return cancellation error, got %v", err)
+	}
+}
+
+func TestEmbeddedEngineReadOnly(t *testing.T) {
+	engine, _ := NewEmbeddedEngine()
+	dbPath := filepath.Join(t.TempDir(), "shared.duckdb")
+
+	if err := engine.Run(context.Background(), RunRequest{DBPath: dbPath, Script: "CREATE TABLE t AS SELECT 1 AS id;"}, &bytes.Buffer{}); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+
+	var out bytes.Buffer
+	req := RunRequest{DBPath: dbPath, ReadOnly: true, Script: ".mode list\n.headers off\n", Query: "SELECT count(*) FROM t"}
+	if err := engine.Run(context.Background(), req, &out); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if out.String() != "1\n" {
+		t.Errorf("Expected output %q, got %q", "1\n", out.String())
+	}
+
+	// 読み取り専用で開いたデータベースには書き込めない
+	req.Query = "INSERT INTO t VALUES (2)"
+	var queryErr *QueryError
+	if err := engine.Run(context.Background(), req, &bytes.Buffer{}); !errors.As(err, &queryErr) {
+		t.Errorf("Run should return QueryError for write in read-only database, got %v", err)
+	}
+}
+
+func TestEmbeddedEngineQuery(t *testing.T) {
+	engine, _ := NewEmbeddedEngine()
+
+	result, err := engine.Query(context.Background(), RunRequest{
+		Script: "CREATE TABLE t AS SELECT 200 AS status, TIMESTAMP '2025-03-30 12:00:00' AS ts, 1.50::DECIMAL(10,2) AS price, INTERVAL 5 MINUTE AS d;",
+		Query:  "SELECT * FROM t",
+	})
+	if err != nil {
+		t.Fatalf("Query returned error: %v", err)
+	}
+
+	expectedColumns := []ResultColumn{
+		{Name: "status", Type: "INTEGER"},
+		{Name: "ts", Type: "TIMESTAMP"},
+		{Name: "price", Type: "DECIMAL(10,2)"},
+		{Name: "d", Type: "INTERVAL"},
+	}
+	if len(result.Columns) != len(expectedColumns) {
+		t.Fatalf("Expected columns %v, got %v", expectedColumns, result.Columns)
+	}
+	for i, c := range expectedColumns {
+		if result.Columns[i] != c {
+			t.Errorf("Expected column %v, got %v", c, result.Columns[i])
+		}
+	}
+
+	if len(result.Rows) != 1 {
+		t.Fatalf("Expected 1 row, got %d", len(result.Rows))
+	}
+	row := result.Rows[0]
+	if row[0] != int32(200) {
+		t.Errorf("Expected status int32(200), got %#v", row[0])
+	}
+	if ts, ok := row[1].(time.Time); !ok || !ts.Equal(time.Date(2025, 3, 30, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected timestamp: %#v", row[1])
+	}
+	if row[2] != 1.5 {
+		t.Errorf("Expected price 1.5, got %#v", row[2])
+	}
+	if row[3] != "00:05:00" {
+		t.Errorf("Expected interval 00:05:00, got %#v", row[3])
+	}
+}
+
+func TestEmbeddedSessionConsole(t *testing.T) {
+	var out, errOut bytes.Buffer
+	s, err := openSession(context.Background(), RunRequest{}, &out)
+	if err != nil {
+		t.Fatalf("openSession returned error: %v", err)
+	}
+	defer s.close()
+
+	// 複数行の文は完結してから実行し、エラーの後も入力を読み続ける
+	input := ".mode list\n.headers off\nSELECT\n42;\nSELEC 1;\nSELECT 'ok';\n.quit\nSELECT 'not reached';\n"
+	if err := s.console(context.Background(), bytes.NewBufferString(input), &errOut); err != nil {
+		t.Fatalf("console returned error: %v", err)
+	}
+
+	expected := "D D D ‣ 42\nD D ok\nD "
+	if out.String() != expected {
+		t.Errorf("Expected output %q, got %q", expected, out.String())
+	}
+	if errOut.Len() == 0 {
+		t.Error("Expected syntax error to be reported")
+	}
+}
@@ -0,0 +1,115 @@
+package duckdb
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewEngine(t *testing.T) {
+	engine, err := NewEngine(EngineCLI)
+	if err != nil {
+		t.Fatalf("NewEngine(%q) returned error: %v", EngineCLI, err)
+	}
+	if engine.Name() != EngineCLI {
+		t.Errorf("Expected engine %q, got %q", EngineCLI, engine.Name())
+	}
+
+	if _, err := NewEngine("sqlite"); err == nil {
+		t.Error("NewEngine should return error for unsupported engine")
+	}
+}
+
+func TestNewExecutorDefaultEngine(t *testing.T) {
+	// エンジンを指定しない場合はDuckDBのCLIを使用する
+	executor := NewExecutor()
+	if executor.engine.Name() != EngineCLI {
+		t.Errorf("Expected default engine %q, got %q", EngineCLI, executor.engine.Name())
+	}
+}
+
+func TestCheckQuery(t *testing.T) {
+	for _, query := range []string{"SELECT 1", "SELECT '.shell ls'", "SELECT 1;\nSELECT 2;"} {
+		if err := checkQuery(query); err != nil {
+			t.Errorf("checkQuery(%q) returned error: %v", query, err)
+		}
+	}
+
+	for _, query := range []string{"", "  \n", ".shell ls", "SELECT 1;\n.read /etc/passwd"} {
+		err := checkQuery(query)
+		var queryErr *QueryError
+		if !errors.As(err, &queryErr) {
+			t.Errorf("checkQuery(%q) should return QueryError, got %v", query, err)
+		}
+	}
+}
+
+func TestCLIEngineCommandInput(t *testing.T) {
+	engine := &cliEngine{}
+
+	// スクリプトのみの場合は標準入力にそのまま渡す
+	args, stdin, err := engine.commandInput(RunRequest{Script: ".mode csv\nSELECT 1;\n"})
+	if err != nil {
+		t.Fatalf("commandInput returned error: %v", err)
+	}
+	if !reflect.DeepEqual(args, []string{"-batch", "-bail"}) {
+		t.Errorf("Unexpected args without database: %v", args)
+	}
+	if stdin != ".mode csv\nSELECT 1;\n" {
+		t.Errorf("Unexpected stdin: %q", stdin)
+	}
+
+	// データベースファイルはオプションの後に指定し、クエリはスクリプトの後に追加する
+	args, stdin, err = engine.commandInput(RunRequest{
+		DBPath:   "cache.duckdb",
+		ReadOnly: true,
+		Script:   ".mode json\n",
+		Query:    "SELECT 1",
+	})
+	if err != nil {
+		t.Fatalf("commandInput returned error: %v", err)
+	}
+	if !reflect.DeepEqual(args, []string{"-readonly", "-batch", "-bail", "cache.duckdb"}) {
+		t.Errorf("Unexpected args with database: %v", args)
+	}
+	if stdin != ".mode json\nSELECT 1;\n" {
+		t.Errorf("Unexpected stdin: %q", stdin)
+	}
+
+	// クエリにドットコマンドが含まれる場合はエラー
+	if _, _, err := engine.commandInput(RunRequest{Query: "SELECT 1;\n.shell ls"}); err == nil {
+		t.Error("commandInput should return error for dot command in query")
+	}
+}
+
+func TestParseJSONResult(t *testing.T) {
+	out := `[{"status":200,"path":"/a","rate":0.5,"tags":["x"]},
+{"status":502,"path":null,"rate":1,"tags":[]}]
+`
+	result, err := parseJSONResult(strings.NewReader(out))
+	if err != nil {
+		t.Fatalf("parseJSONResult returned error: %v", err)
+	}
+
+	expectedColumns := []ResultColumn{{Name: "status"}, {Name: "path"}, {Name: "rate"}, {Name: "tags"}}
+	if !reflect.DeepEqual(result.Columns, expectedColumns) {
+		t.Errorf("Expected columns %v, got %v", expectedColumns, result.Columns)
+	}
+	expectedRows := [][]any{
+		{int64(200), "/a", 0.5, []any{"x"}},
+		{int64(502), nil, int64(1), []any{}},
+	}
+	if !reflect.DeepEqual(result.Rows, expectedRows) {
+		t.Errorf("Expected rows %#v, got %#v", expectedRows, result.Rows)
+	}
+
+	// 結果が0行の場合、DuckDBは何も出力しない
+	result, err = parseJSONResult(strings.NewReader(""))
+	if err != nil {
+		t.Fatalf("parseJSONResult returned error for empty output: %v", err)
+	}
+	if len(result.Columns) != 0 || len(result.Rows) != 0 {
+		t.Errorf("Expected empty result, got %v", result)
+	}
+}
@@ -0,0 +1,84 @@
+package duckdb
+
+import (
+	"fmt"
+	"time"
+)
+
+// ErrorSummaryConfig はエラーの概要の集計設定です
+type ErrorSummaryConfig struct {
+	// Interval はエラーが多い時間帯を集計する間隔です
+	Interval time.Duration
+	// Limit は各セクションに表示する行数の上限です
+	Limit int
+}
+
+// ValidateErrorSummaryConfig はエラーの概要の集計設定がログ形式に対して有効かを検証します
+func (g *SQLGenerator) ValidateErrorSummaryConfig(cfg ErrorSummaryConfig) error {
+	if g.format.StatusCodeColumn == "" {
+		return fmt.Errorf("%sログはステータスコードを記録していないため、エラーの概要を集計できません", g.format.Label)
+	}
+	if cfg.Interval < time.Second || cfg.Interval%time.Second != 0 {
+		return fmt.Errorf("集計の間隔は1秒以上の秒単位で指定してください: %s", cfg.Interval)
+	}
+	if cfg.Limit <= 0 {
+		return fmt.Errorf("表示する行数には1以上の値を指定してください: %d", cfg.Limit)
+	}
+	return nil
+}
+
+// GenerateErrorSummarySQL はステータスコードが4xx/5xxのリクエストの概要を集計するセクションを生成します
+// ステータスコード別の件数、エラーが多い時間帯、エラーが多いパス (パスを記録しているログ形式のみ) を集計します
+func (g *SQLGenerator) GenerateErrorSummarySQL(tableName string, cfg ErrorSummaryConfig) []ReportSection {
+	status := g.format.StatusCodeColumn
+	table := Identifier(tableName)
+
+	sections := []ReportSection{
+		{
+			Title: "ステータスコード別のエラー数",
+			SQL: fmt.Sprintf(`SELECT
+    %s AS status_code,
+    count(*) AS requests,
+    round(100.0 * count(*) / (SELECT count(*) FROM %s), 2) AS percent,
+    min(%s) AS first_seen,
+    max(%s) AS last_seen
+FROM %s
+WHERE %s >= 400
+GROUP BY ALL
+ORDER BY requests DESC
+LIMIT %d;`, status, table, g.format.TimestampExpr, g.format.TimestampExpr, table, status, cfg.Limit),
+		},
+		{
+			Title: "エラーが多い時間帯",
+			SQL: fmt.Sprintf(`SELECT
+    time_bucket(INTERVAL '%d seconds', %s) AS bucket,
+    count(*) AS requests,
+    count(*) FILTER (WHERE %s BETWEEN 400 AND 499) AS errors_4xx,
+    count(*) FILTER (WHERE %s >= 500) AS errors_5xx,
+    round(100.0 * count(*) FILTER (WHERE %s >= 500) / count(*), 2) AS error_rate_5xx
+FROM %s
+GROUP BY ALL
+HAVING errors_4xx + errors_5xx > 0
+ORDER BY errors_5xx DESC, errors_4xx DESC
+LIMIT %d;`, int64(cfg.Interval/time.Second), g.format.TimestampExpr, status, status, status, table, cfg.Limit),
+		},
+	}
+
+	if g.format.PathColumn != "" {
+		path, _ := g.dimensionExpr(g.format.PathColumn)
+		sections = append(sections, ReportSection{
+			Title: "エラーが多いパス",
+			SQL: fmt.Sprintf(`SELECT
+    %s AS path,
+    count(*) FILTER (WHERE %s BETWEEN 400 AND 499) AS errors_4xx,
+    count(*) FILTER (WHERE %s >= 500) AS errors_5xx,
+    count(*) AS requests
+FROM %s
+GROUP BY ALL
+HAVING errors_4xx + errors_5xx > 0
+ORDER BY errors_5xx DESC, errors_4xx DESC
+LIMIT %d;`, path, status, status, table, cfg.Limit),
+		})
+	}
+	return sections
+}
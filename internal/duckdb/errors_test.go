@@ -0,0 +1,62 @@
+package duckdb
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/naotama2002/dalv/internal/schema"
+)
+
+func TestGenerateErrorSummarySQL(t *testing.T) {
+	generator := NewSQLGeneratorWithConfig(Config{Format: schema.ALB})
+	sections := generator.GenerateErrorSummarySQL("alb_logs", ErrorSummaryConfig{Interval: time.Minute, Limit: 5})
+	if len(sections) != 3 {
+		t.Fatalf("Expected 3 sections, got %d", len(sections))
+	}
+
+	expectedParts := [][]string{
+		{"elb_status_code AS status_code", "(SELECT count(*) FROM alb_logs)", "WHERE elb_status_code >= 400", "LIMIT 5;"},
+		{"time_bucket(INTERVAL '60 seconds', timestamp) AS bucket", "count(*) FILTER (WHERE elb_status_code >= 500) AS errors_5xx", "LIMIT 5;"},
+		{"url_path AS path", "HAVING errors_4xx + errors_5xx > 0"},
+	}
+	for i, parts := range expectedParts {
+		for _, part := range parts {
+			if !strings.Contains(sections[i].SQL, part) {
+				t.Errorf("Expected section %d to contain %q, got:\n%s", i, part, sections[i].SQL)
+			}
+		}
+	}
+
+	// 派生カラムを追加しない場合はパスを式で計算する
+	generator = NewSQLGeneratorWithConfig(Config{Format: schema.ALB, NoDerivedColumns: true})
+	sections = generator.GenerateErrorSummarySQL("alb_logs", ErrorSummaryConfig{Interval: time.Minute, Limit: 5})
+	if !strings.Contains(sections[2].SQL, "regexp_extract(") {
+		t.Errorf("Expected path expression, got:\n%s", sections[2].SQL)
+	}
+}
+
+func TestValidateErrorSummaryConfig(t *testing.T) {
+	generator := NewSQLGeneratorWithConfig(Config{Format: schema.CloudFront})
+	if err := generator.ValidateErrorSummaryConfig(ErrorSummaryConfig{Interval: time.Minute, Limit: 10}); err != nil {
+		t.Errorf("ValidateErrorSummaryConfig returned error: %v", err)
+	}
+
+	testCases := []struct {
+		format *schema.Format
+		cfg    ErrorSummaryConfig
+	}{
+		// ステータスコードを記録していないログ形式
+		{schema.NLB, ErrorSummaryConfig{Interval: time.Minute, Limit: 10}},
+		// 秒未満の間隔
+		{schema.ALB, ErrorSummaryConfig{Interval: 500 * time.Millisecond, Limit: 10}},
+		// 行数が0
+		{schema.ALB, ErrorSummaryConfig{Interval: time.Minute}},
+	}
+	for _, tc := range testCases {
+		generator := NewSQLGeneratorWithConfig(Config{Format: tc.format})
+		if err := generator.ValidateErrorSummaryConfig(tc.cfg); err == nil {
+			t.Errorf("ValidateErrorSummaryConfig(%s, %+v) should return error", tc.format.Name, tc.cfg)
+		}
+	}
+}
@@ -1,70 +1,409 @@
 package duckdb
 
 import (
+	"bytes"
+	"context"
+	"encoding/csv"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"os"
-	"os/exec"
-	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/naotama2002/dalv/internal/source"
 )
 
 // Executor はDuckDBを実行するための構造体です
 type Executor struct {
 	sqlGenerator *SQLGenerator
+	engine       Engine
+	stdout       io.Writer
+	dbPath       string
+	output       Output
+	cacheStats   *CacheStats
+	// extensionsDetected は派生カラムで使用する拡張機能を確認済みかどうかです
+	extensionsDetected bool
 }
 
 // NewExecutor は新しいDuckDB実行者を作成します
 func NewExecutor() *Executor {
+	return NewExecutorWithConfig(Config{})
+}
+
+// NewExecutorWithConfig は設定を指定して新しいDuckDB実行者を作成します
+func NewExecutorWithConfig(cfg Config) *Executor {
+	engine := cfg.Engine
+	if engine == nil {
+		engine = NewCLIEngine()
+	}
+	stdout := cfg.Stdout
+	if stdout == nil {
+		stdout = os.Stdout
+	}
 	return &Executor{
-		sqlGenerator: NewSQLGenerator(),
+		sqlGenerator:       NewSQLGeneratorWithConfig(cfg),
+		engine:             engine,
+		stdout:             stdout,
+		dbPath:             cfg.DBPath,
+		output:             cfg.Output,
+		extensionsDetected: cfg.Extensions != nil,
 	}
 }
 
+// SQLGenerator はログ形式と読み込み設定を反映したSQL生成器を返します
+func (e *Executor) SQLGenerator() *SQLGenerator {
+	return e.sqlGenerator
+}
+
 // ExecuteDuckDB はDuckDBを実行します
-func (e *Executor) ExecuteDuckDB(s3Path string, tableName string) error {
+func (e *Executor) ExecuteDuckDB(paths []string, tableName string) error {
 	// テーブル名が指定されていない場合は生成
 	if tableName == "" {
-		tableName = e.sqlGenerator.generateTableName()
+		tableName = e.sqlGenerator.GenerateTableName()
 	}
 
 	// SQLを生成
-	sql := e.sqlGenerator.GenerateCompleteSQL(s3Path, tableName)
+	loadSQL, err := e.loadSQL(paths, tableName)
+	if err != nil {
+		return err
+	}
+	sql := fmt.Sprintf("%s\n\n%s", loadSQL, e.sqlGenerator.GenerateLoadedMessageSQL(tableName))
+
+	// エンジンのコンソールを起動
+	sensitive := e.loadsRemote(paths) && e.sqlGenerator.s3.HasSecrets()
+	if err := e.engine.Interactive(RunRequest{DBPath: e.dbPath, Script: sql, Sensitive: sensitive}); err != nil {
+		return fmt.Errorf("DuckDBの実行に失敗しました: %w", err)
+	}
 
-	// 一時ファイルを作成
-	tempDir, err := ioutil.TempDir("", "dalv-")
+	return nil
+}
+
+// ExecuteQuery はテーブルを作成した後にSQLを非インタラクティブに実行し、結果を標準出力に表示します
+// DuckDBのCLIが異常終了した場合は *exec.ExitError をラップしたエラーを返します
+func (e *Executor) ExecuteQuery(paths []string, tableName string, query string) error {
+	// テーブル名が指定されていない場合は生成
+	if tableName == "" {
+		tableName = e.sqlGenerator.GenerateTableName()
+	}
+
+	// スクリプトを生成
+	loadSQL, err := e.loadSQL(paths, tableName)
 	if err != nil {
-		return fmt.Errorf("一時ディレクトリの作成に失敗しました: %w", err)
+		return err
 	}
-	defer os.RemoveAll(tempDir)
+	script := e.generateQueryScript(loadSQL, query)
 
-	// SQLを一時ファイルに書き込み
-	sqlFilePath := filepath.Join(tempDir, "init.sql")
-	err = ioutil.WriteFile(sqlFilePath, []byte(sql), 0644)
+	stdout, closeOutput, err := e.openOutput()
 	if err != nil {
-		return fmt.Errorf("SQLファイルの作成に失敗しました: %w", err)
+		return err
 	}
+	defer closeOutput()
 
 	// DuckDBコマンドを実行
-	cmd := exec.Command("duckdb", "-init", sqlFilePath)
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	// 結果はDuckDBの出力をそのまま書き込むため、大きな結果もメモリに保持しない
+	if err := e.runScript(script, stdout); err != nil {
+		return fmt.Errorf("クエリの実行に失敗しました: %w", err)
+	}
 
-	// コマンドを実行
-	err = cmd.Run()
+	return nil
+}
+
+// Query はテーブルを作成した後にクエリを実行し、結果を型付きの値で返します
+// 値の型はエンジンによって異なります (CLIの場合はJSONで表せる型のみ)
+func (e *Executor) Query(paths []string, tableName string, query string) (*Result, error) {
+	// テーブル名が指定されていない場合は生成
+	if tableName == "" {
+		tableName = e.sqlGenerator.GenerateTableName()
+	}
+
+	loadSQL, err := e.loadSQL(paths, tableName)
 	if err != nil {
-		return fmt.Errorf("DuckDBの実行に失敗しました: %w", err)
+		return nil, err
 	}
+	script := fmt.Sprintf(".bail on\n.mode trash\n%s\n", loadSQL)
 
-	return nil
+	result, err := e.engine.Query(context.Background(), RunRequest{DBPath: e.dbPath, Script: script, Query: query})
+	if err != nil {
+		return nil, fmt.Errorf("クエリの実行に失敗しました: %w", err)
+	}
+	return result, nil
 }
 
-// CheckDuckDBInstallation はDuckDBがインストールされているかを確認します
-func (e *Executor) CheckDuckDBInstallation() error {
-	cmd := exec.Command("duckdb", "--version")
-	err := cmd.Run()
+// openOutput は結果の出力先を開きます
+// 出力先のファイルが指定されていない場合は標準出力を返します (Parquetの場合はDuckDBが直接書き込む)
+func (e *Executor) openOutput() (io.Writer, func(), error) {
+	if e.output.Path == "" || e.output.Format == OutputParquet {
+		return e.stdout, func() {}, nil
+	}
+
+	file, err := os.Create(e.output.Path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("出力ファイルの作成に失敗しました: %w", err)
+	}
+	return file, func() { file.Close() }, nil
+}
+
+// SampleFirstLine はログの1行目を取得します
+// ログ形式の自動判定に使用します
+func (e *Executor) SampleFirstLine(path string) (string, error) {
+	var b strings.Builder
+	b.WriteString(".bail on\n")
+	b.WriteString(".mode trash\n")
+	if source.IsRemote(path) {
+		b.WriteString(e.sqlGenerator.GenerateAWSConfigSQL())
+		b.WriteString("\n\n")
+	}
+	b.WriteString(".mode list\n.headers off\n")
+	b.WriteString(e.sqlGenerator.GenerateSampleLineSQL(path))
+	b.WriteString("\n")
+
+	var out bytes.Buffer
+	if err := e.runScript(b.String(), &out); err != nil {
+		return "", fmt.Errorf("ログの1行目の取得に失敗しました: %w", err)
+	}
+
+	line := strings.TrimSpace(out.String())
+	if line == "" {
+		return "", fmt.Errorf("ログが見つかりません: %s", path)
+	}
+	return line, nil
+}
+
+// ListObjects はパスに一致するオブジェクトの一覧を取得します
+// S3へのアクセスに失敗した場合は原因に応じて ErrAccessDenied などをラップしたエラーを返します
+func (e *Executor) ListObjects(paths []string) ([]Object, error) {
+	var b strings.Builder
+	b.WriteString(".bail on\n")
+	b.WriteString(".mode trash\n")
+	if source.AnyRemote(paths) {
+		b.WriteString(e.sqlGenerator.GenerateAWSConfigSQL())
+		b.WriteString("\n\n")
+	}
+	b.WriteString(".mode csv\n.headers on\n")
+
+	// オブジェクトの一覧はデータベースファイルを開かずに取得する
+	// エラーメッセージから原因を判定するため、一覧を取得するSQLはクエリとして実行する
+	rows, err := e.queryCSVRequest(RunRequest{Script: b.String(), Query: e.sqlGenerator.GenerateListObjectsSQL(paths)})
 	if err != nil {
-		return fmt.Errorf("DuckDBがインストールされていないか、実行できません: %w", err)
+		return nil, fmt.Errorf("オブジェクトの一覧の取得に失敗しました: %w", classifyListError(err))
+	}
+	return parseObjects(rows)
+}
+
+// PrepareCache はキャッシュを確認し、新たに読み込むオブジェクトを決定します
+// --db が指定されていない場合はエラーを返します
+func (e *Executor) PrepareCache(paths []string) (*CacheStats, error) {
+	if e.dbPath == "" {
+		return nil, fmt.Errorf("キャッシュを使用するにはデータベースファイルを指定してください")
+	}
+
+	objects, err := e.ListObjects(paths)
+	if err != nil {
+		return nil, err
+	}
+	return e.PrepareCacheForObjects(objects)
+}
+
+// PrepareCacheForObjects は取得済みのオブジェクトの一覧でキャッシュを確認し、新たに読み込むオブジェクトを決定します
+// Preflight で取得した一覧を再利用し、オブジェクトの一覧を2回取得しないようにします
+func (e *Executor) PrepareCacheForObjects(objects []Object) (*CacheStats, error) {
+	if e.dbPath == "" {
+		return nil, fmt.Errorf("キャッシュを使用するにはデータベースファイルを指定してください")
+	}
+
+	script := fmt.Sprintf(".bail on\n.mode trash\n%s\n\n.mode csv\n.headers on\n%s\n",
+		e.sqlGenerator.GenerateCacheSchemaSQL(), e.sqlGenerator.GenerateCachedObjectsSQL())
+	rows, err := e.queryCSV(e.dbPath, script)
+	if err != nil {
+		return nil, fmt.Errorf("キャッシュの確認に失敗しました: %w", err)
+	}
+	cached, err := parseObjects(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	e.cacheStats = NewCacheStats(objects, cached)
+	return e.cacheStats, nil
+}
+
+// ListCache はキャッシュの内容を表示します
+func (e *Executor) ListCache(stdout io.Writer) error {
+	if _, err := os.Stat(e.dbPath); err != nil {
+		return fmt.Errorf("データベースファイルが見つかりません: %s", e.dbPath)
+	}
+
+	script := fmt.Sprintf(".bail on\n.mode trash\n%s\n\n.mode duckbox\n%s\n",
+		e.sqlGenerator.GenerateCacheSchemaSQL(), e.sqlGenerator.GenerateListCacheSQL())
+	if err := e.runScriptOn(e.dbPath, script, stdout); err != nil {
+		return fmt.Errorf("キャッシュの表示に失敗しました: %w", err)
 	}
 	return nil
 }
+
+// PruneCache はキャッシュからオブジェクトを削除し、削除したオブジェクトの数を返します
+// loadedBefore がゼロ値の場合はすべてのオブジェクトを削除します
+func (e *Executor) PruneCache(loadedBefore time.Time) (int, error) {
+	if _, err := os.Stat(e.dbPath); err != nil {
+		return 0, fmt.Errorf("データベースファイルが見つかりません: %s", e.dbPath)
+	}
+
+	script := fmt.Sprintf(".bail on\n.mode trash\n%s\n\n.mode csv\n.headers on\n%s\n",
+		e.sqlGenerator.GenerateCacheSchemaSQL(), e.sqlGenerator.GeneratePruneCacheSQL(loadedBefore))
+	rows, err := e.queryCSV(e.dbPath, script)
+	if err != nil {
+		return 0, fmt.Errorf("キャッシュの削除に失敗しました: %w", err)
+	}
+	if len(rows) != 1 || len(rows[0]) != 1 {
+		return 0, fmt.Errorf("キャッシュの削除結果を取得できませんでした")
+	}
+
+	pruned, err := strconv.Atoi(rows[0][0])
+	if err != nil {
+		return 0, fmt.Errorf("キャッシュの削除結果を取得できませんでした: %w", err)
+	}
+	return pruned, nil
+}
+
+// DetectExtensions は派生カラムで使用する拡張機能のうち、インストール済みのものを返します
+// 拡張機能はダウンロードせず、確認に失敗した場合は使用できないものとして扱います
+func (e *Executor) DetectExtensions() []string {
+	required := e.sqlGenerator.RequiredExtensions()
+	if len(required) == 0 {
+		return nil
+	}
+
+	script := fmt.Sprintf(".bail on\n.mode csv\n.headers on\n%s\n", e.sqlGenerator.GenerateInstalledExtensionsSQL(required))
+	rows, err := e.queryCSV("", script)
+	if err != nil {
+		return nil
+	}
+
+	var extensions []string
+	for _, row := range rows {
+		if len(row) == 1 {
+			extensions = append(extensions, row[0])
+		}
+	}
+	return extensions
+}
+
+// loadSQL はテーブルを作成するSQLを返します
+// データベースファイルが指定されている場合はキャッシュを使用します
+// 派生カラムで使用する拡張機能は初回の呼び出し時に確認します
+func (e *Executor) loadSQL(paths []string, tableName string) (string, error) {
+	e.ensureExtensions()
+
+	if e.dbPath == "" {
+		return e.sqlGenerator.GenerateLoadSQL(paths, tableName), nil
+	}
+
+	if e.cacheStats == nil {
+		if _, err := e.PrepareCache(paths); err != nil {
+			return "", err
+		}
+	}
+	return e.sqlGenerator.GenerateCachedLoadSQL(tableName, e.cacheStats), nil
+}
+
+// ensureExtensions は派生カラムで使用する拡張機能を確認していない場合に確認します
+func (e *Executor) ensureExtensions() {
+	if !e.extensionsDetected {
+		e.sqlGenerator.setExtensions(e.DetectExtensions())
+		e.extensionsDetected = true
+	}
+}
+
+// CompleteSQL はテーブルの作成に使用するSQL (GenerateCompleteSQL) を、外部IDなどの秘密情報を伏せて返します
+// キャッシュを使用する場合も、キャッシュを使用しない場合のSQLを返します
+func (e *Executor) CompleteSQL(paths []string, tableName string) string {
+	e.ensureExtensions()
+	redacted := *e.sqlGenerator
+	redacted.s3 = redacted.s3.Redacted()
+	return redacted.GenerateCompleteSQL(paths, tableName)
+}
+
+// loadsRemote はテーブルの作成時にS3からログを読み込むかどうかを返します
+// loadSQL を呼び出した後に使用します
+func (e *Executor) loadsRemote(paths []string) bool {
+	if e.dbPath == "" {
+		return source.AnyRemote(paths)
+	}
+	return source.AnyRemote(objectPaths(e.cacheStats.NewObjects))
+}
+
+// runScript はDuckDBを非インタラクティブに起動し、スクリプトを標準入力から実行します
+func (e *Executor) runScript(script string, stdout io.Writer) error {
+	return e.runScriptOn(e.dbPath, script, stdout)
+}
+
+// runScriptOn はデータベースファイルを指定してスクリプトをエンジンで非インタラクティブに実行します
+// dbPath が空の場合はインメモリデータベースを使用します
+func (e *Executor) runScriptOn(dbPath string, script string, stdout io.Writer) error {
+	return e.engine.Run(context.Background(), RunRequest{DBPath: dbPath, Script: script}, stdout)
+}
+
+// queryCSV はスクリプトを実行し、CSV形式で出力された結果をヘッダー行を除いて返します
+func (e *Executor) queryCSV(dbPath string, script string) ([][]string, error) {
+	return e.queryCSVRequest(RunRequest{DBPath: dbPath, Script: script})
+}
+
+// queryCSVRequest はリクエストを実行し、CSV形式で出力された結果をヘッダー行を除いて返します
+func (e *Executor) queryCSVRequest(req RunRequest) ([][]string, error) {
+	var out bytes.Buffer
+	if err := e.engine.Run(context.Background(), req, &out); err != nil {
+		return nil, err
+	}
+
+	records, err := csv.NewReader(&out).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("DuckDBの出力の解析に失敗しました: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	return records[1:], nil
+}
+
+// parseObjects は path, size, last_modified の行をオブジェクトに変換します
+func parseObjects(rows [][]string) ([]Object, error) {
+	objects := make([]Object, 0, len(rows))
+	for _, row := range rows {
+		if len(row) != 3 {
+			return nil, fmt.Errorf("オブジェクトの一覧の形式が不正です: %v", row)
+		}
+		size, err := strconv.ParseInt(row[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("オブジェクトのサイズが不正です: %s: %w", row[1], err)
+		}
+		objects = append(objects, Object{Path: row[0], Size: size, LastModified: row[2]})
+	}
+	return objects, nil
+}
+
+// generateQueryScript は非インタラクティブ実行用のDuckDBスクリプトを生成します
+// テーブル作成時の出力は破棄し、ユーザーのクエリ結果のみを表示します
+func (e *Executor) generateQueryScript(loadSQL string, query string) string {
+	var b strings.Builder
+	b.WriteString(".bail on\n")
+	b.WriteString(".mode trash\n")
+	b.WriteString(loadSQL)
+	b.WriteString("\n\n")
+	b.WriteString(e.sqlGenerator.generateOutputSQL(query, e.output))
+	b.WriteString("\n")
+	return b.String()
+}
+
+// terminateStatement はSQLの末尾にセミコロンがなければ付与します
+func terminateStatement(query string) string {
+	query = strings.TrimSpace(query)
+	if !strings.HasSuffix(query, ";") {
+		query += ";"
+	}
+	return query
+}
+
+// CheckDuckDBInstallation はエンジンでDuckDBを実行できるかを確認します
+func (e *Executor) CheckDuckDBInstallation() error {
+	return e.engine.Check()
+}
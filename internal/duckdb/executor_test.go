@@ -0,0 +1,71 @@
+package duckdb
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateQueryScript(t *testing.T) {
+	executor := NewExecutor()
+	loadSQL := executor.sqlGenerator.GenerateLoadSQL([]string{"s3://bucket/path/*.log.gz"}, "test_table")
+	script := executor.generateQueryScript(loadSQL, "SELECT COUNT(*) FROM test_table")
+
+	// ロード時の出力は破棄し、クエリ前に表示モードを戻す
+	trashIndex := strings.Index(script, ".mode trash")
+	createIndex := strings.Index(script, "CREATE TABLE test_table")
+	duckboxIndex := strings.Index(script, ".mode duckbox")
+	queryIndex := strings.Index(script, "SELECT COUNT(*) FROM test_table;")
+
+	if trashIndex < 0 || createIndex < 0 || duckboxIndex < 0 || queryIndex < 0 {
+		t.Fatalf("Generated script is missing required elements:\n%s", script)
+	}
+
+	if !(trashIndex < createIndex && createIndex < duckboxIndex && duckboxIndex < queryIndex) {
+		t.Errorf("Generated script elements are in unexpected order:\n%s", script)
+	}
+
+	// インタラクティブモードのメッセージは含まない
+	if strings.Contains(script, "ALBログが正常にロードされました") {
+		t.Error("Generated script should not contain interactive mode message")
+	}
+}
+
+func TestTerminateStatement(t *testing.T) {
+	testCases := []struct {
+		query    string
+		expected string
+	}{
+		{"SELECT 1", "SELECT 1;"},
+		{"SELECT 1;", "SELECT 1;"},
+		{"  SELECT 1;\n\n", "SELECT 1;"},
+		{"SELECT 1; SELECT 2", "SELECT 1; SELECT 2;"},
+	}
+
+	for _, tc := range testCases {
+		if got := terminateStatement(tc.query); got != tc.expected {
+			t.Errorf("terminateStatement(%q) = %q, expected %q", tc.query, got, tc.expected)
+		}
+	}
+}
+
+func TestCompleteSQL(t *testing.T) {
+	executor := NewExecutorWithConfig(Config{
+		Extensions: []string{},
+		S3:         S3Config{RoleARN: "arn:aws:iam::123456789012:role/r", ExternalID: "ext-1234"},
+	})
+	sql := executor.CompleteSQL([]string{"s3://bucket/path/*.log.gz"}, "alb_logs")
+
+	// 外部IDは伏せて表示する
+	if strings.Contains(sql, "ext-1234") {
+		t.Errorf("Expected external ID to be redacted:\n%s", sql)
+	}
+	for _, expected := range []string{"ASSUME_ROLE_EXTERNAL_ID '********'", "CREATE TABLE alb_logs AS", "'s3://bucket/path/*.log.gz'"} {
+		if !strings.Contains(sql, expected) {
+			t.Errorf("Expected SQL to contain %q:\n%s", expected, sql)
+		}
+	}
+	// 実行に使用するSQLは伏せない
+	if !strings.Contains(executor.sqlGenerator.GenerateAWSConfigSQL(), "'ext-1234'") {
+		t.Error("Expected generator used for execution to keep the external ID")
+	}
+}
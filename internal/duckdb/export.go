@@ -0,0 +1,162 @@
+package duckdb
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/naotama2002/dalv/internal/source"
+)
+
+// DateColumn はエクスポート時に追加する日付カラムの名前です
+// パーティション分割のキーとして使用します
+const DateColumn = "date"
+
+// ExportConfig はParquetへのエクスポートの設定です
+type ExportConfig struct {
+	// Destination は出力先のディレクトリです (パーティション分割しない場合は .parquet ファイル)
+	Destination string
+	// PartitionBy はHive形式でパーティション分割するカラムです
+	PartitionBy []string
+	// Compression はParquetの圧縮形式です (空の場合はDuckDBのデフォルト)
+	Compression string
+}
+
+// ExportColumns はエクスポートで出力されるカラムの一覧を返します
+func (g *SQLGenerator) ExportColumns() []string {
+	columns := make([]string, 0, len(g.format.Columns)+len(g.format.DerivedColumns)+2)
+	for _, c := range g.format.Columns {
+		columns = append(columns, c.Name)
+	}
+	columns = append(columns, SourcePathColumn)
+	for _, d := range g.derivedColumns() {
+		columns = append(columns, d.Name)
+	}
+	if !g.hasDateColumn() {
+		columns = append(columns, DateColumn)
+	}
+	return columns
+}
+
+// hasDateColumn はログ形式に日付カラムがあるかどうかを返します
+func (g *SQLGenerator) hasDateColumn() bool {
+	_, ok := g.format.Column(DateColumn)
+	return ok
+}
+
+// ValidateExportConfig はエクスポートの設定がログ形式に対して有効かを検証します
+func (g *SQLGenerator) ValidateExportConfig(cfg ExportConfig) error {
+	if cfg.Destination == "" {
+		return fmt.Errorf("エクスポート先が指定されていません")
+	}
+
+	if len(cfg.PartitionBy) == 0 && !strings.HasSuffix(cfg.Destination, ".parquet") {
+		return fmt.Errorf("パーティション分割しない場合はエクスポート先に .parquet ファイルを指定してください: %s", cfg.Destination)
+	}
+
+	columns := g.ExportColumns()
+	for _, p := range cfg.PartitionBy {
+		found := false
+		for _, c := range columns {
+			if p == c {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%sログにパーティション分割に使用できるカラムがありません: %s", g.format.Label, p)
+		}
+	}
+
+	return nil
+}
+
+// GenerateExportSQL はテーブルをParquetにエクスポートするSQLを生成します
+// 日付カラムがないログ形式ではタイムスタンプから date カラムを追加します
+func (g *SQLGenerator) GenerateExportSQL(tableName string, cfg ExportConfig) string {
+	// 拡張機能の型 (INET など) はParquetで表現できないため文字列に変換する
+	columns := "*"
+	if extColumns := g.extensionColumns(); len(extColumns) > 0 {
+		replaces := make([]string, 0, len(extColumns))
+		for _, d := range extColumns {
+			replaces = append(replaces, fmt.Sprintf("CAST(%s AS VARCHAR) AS %s", d.Name, d.Name))
+		}
+		columns = fmt.Sprintf("* REPLACE (%s)", strings.Join(replaces, ", "))
+	}
+	if !g.hasDateColumn() {
+		columns += fmt.Sprintf(", CAST(%s AS DATE) AS %s", g.format.TimestampExpr, DateColumn)
+	}
+	selectSQL := fmt.Sprintf("SELECT %s\nFROM %s", columns, Identifier(tableName))
+
+	options := []string{"FORMAT PARQUET"}
+	if len(cfg.PartitionBy) > 0 {
+		options = append(options, fmt.Sprintf("PARTITION_BY (%s)", strings.Join(cfg.PartitionBy, ", ")))
+		// 既存のディレクトリにも出力できるようにする
+		options = append(options, "OVERWRITE_OR_IGNORE")
+	}
+	if cfg.Compression != "" {
+		options = append(options, fmt.Sprintf("COMPRESSION %s", strings.ToUpper(cfg.Compression)))
+	}
+
+	return fmt.Sprintf(`-- %sログをParquetにエクスポート
+COPY (
+%s
+) TO %s (%s);`, g.format.Label, selectSQL, QuoteLiteral(cfg.Destination), strings.Join(options, ", "))
+}
+
+// Export はログを読み込んだ後にParquetにエクスポートし、エクスポートした行数を返します
+func (e *Executor) Export(paths []string, tableName string, cfg ExportConfig) (int64, error) {
+	if err := e.sqlGenerator.ValidateExportConfig(cfg); err != nil {
+		return 0, err
+	}
+
+	// テーブル名が指定されていない場合は生成
+	if tableName == "" {
+		tableName = e.sqlGenerator.GenerateTableName()
+	}
+
+	// ローカルの出力先のディレクトリを作成
+	if !source.IsRemote(cfg.Destination) {
+		dir := cfg.Destination
+		if len(cfg.PartitionBy) == 0 {
+			dir = filepath.Dir(dir)
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return 0, fmt.Errorf("エクスポート先のディレクトリの作成に失敗しました: %w", err)
+		}
+	}
+
+	loadSQL, err := e.loadSQL(paths, tableName)
+	if err != nil {
+		return 0, err
+	}
+
+	var b strings.Builder
+	b.WriteString(".bail on\n")
+	b.WriteString(".mode trash\n")
+	// S3に出力する場合は読み込み元がローカルでもAWSの設定が必要
+	if source.IsRemote(cfg.Destination) && !e.loadsRemote(paths) {
+		b.WriteString(e.sqlGenerator.GenerateAWSConfigSQL())
+		b.WriteString("\n\n")
+	}
+	b.WriteString(loadSQL)
+	b.WriteString("\n\n")
+	b.WriteString(e.sqlGenerator.GenerateExportSQL(tableName, cfg))
+	fmt.Fprintf(&b, "\n\n.mode csv\n.headers on\nSELECT count(*) AS exported_rows FROM %s;\n", Identifier(tableName))
+
+	rows, err := e.queryCSV(e.dbPath, b.String())
+	if err != nil {
+		return 0, fmt.Errorf("エクスポートに失敗しました: %w", err)
+	}
+	if len(rows) != 1 || len(rows[0]) != 1 {
+		return 0, fmt.Errorf("エクスポートした行数を取得できませんでした")
+	}
+
+	count, err := strconv.ParseInt(rows[0][0], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("エクスポートした行数を取得できませんでした: %w", err)
+	}
+	return count, nil
+}
@@ -0,0 +1,87 @@
+package duckdb
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/naotama2002/dalv/internal/schema"
+)
+
+func TestGenerateExportSQL(t *testing.T) {
+	generator := NewSQLGenerator()
+	sql := generator.GenerateExportSQL("test_table", ExportConfig{
+		Destination: "./out",
+		PartitionBy: []string{"date", "elb"},
+		Compression: "zstd",
+	})
+
+	expected := []string{
+		"SELECT *, CAST(timestamp AS DATE) AS date\nFROM test_table",
+		"TO './out'",
+		"FORMAT PARQUET",
+		"PARTITION_BY (date, elb)",
+		"OVERWRITE_OR_IGNORE",
+		"COMPRESSION ZSTD",
+	}
+	for _, e := range expected {
+		if !strings.Contains(sql, e) {
+			t.Errorf("Generated SQL does not contain %q:\n%s", e, sql)
+		}
+	}
+}
+
+func TestGenerateExportSQLWithoutPartition(t *testing.T) {
+	generator := NewSQLGenerator()
+	sql := generator.GenerateExportSQL("test_table", ExportConfig{Destination: "./out/alb.parquet"})
+
+	if strings.Contains(sql, "PARTITION_BY") || strings.Contains(sql, "COMPRESSION") {
+		t.Errorf("Generated SQL should not contain partition or compression options:\n%s", sql)
+	}
+}
+
+func TestGenerateExportSQLWithDateColumn(t *testing.T) {
+	// CloudFrontログは date カラムを持つため追加しない
+	generator := NewSQLGeneratorWithConfig(Config{Format: schema.CloudFront})
+	sql := generator.GenerateExportSQL("test_table", ExportConfig{Destination: "./out", PartitionBy: []string{"date"}})
+
+	if strings.Contains(sql, "AS DATE") {
+		t.Errorf("Generated SQL should not add date column for CloudFront logs:\n%s", sql)
+	}
+}
+
+func TestValidateExportConfig(t *testing.T) {
+	generator := NewSQLGenerator()
+
+	testCases := []struct {
+		cfg     ExportConfig
+		wantErr bool
+	}{
+		{ExportConfig{Destination: "./out", PartitionBy: []string{"date", "elb"}}, false},
+		{ExportConfig{Destination: "s3://bucket/parquet/", PartitionBy: []string{"source_path"}}, false},
+		{ExportConfig{Destination: "./out/alb.parquet"}, false},
+		// 出力先の指定なし
+		{ExportConfig{PartitionBy: []string{"date"}}, true},
+		// パーティション分割しない場合はファイルを指定する
+		{ExportConfig{Destination: "./out"}, true},
+		// 存在しないカラム
+		{ExportConfig{Destination: "./out", PartitionBy: []string{"listener"}}, true},
+	}
+
+	for _, tc := range testCases {
+		err := generator.ValidateExportConfig(tc.cfg)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("ValidateExportConfig(%+v) error = %v, wantErr %v", tc.cfg, err, tc.wantErr)
+		}
+	}
+}
+
+func TestGenerateExportSQLWithINET(t *testing.T) {
+	// INET型はParquetで表現できないため文字列に変換する
+	generator := NewSQLGeneratorWithConfig(Config{Extensions: []string{"inet"}})
+	sql := generator.GenerateExportSQL("test_table", ExportConfig{Destination: "./out", PartitionBy: []string{"date"}})
+
+	expected := "SELECT * REPLACE (CAST(client_ip AS VARCHAR) AS client_ip, CAST(target_ip AS VARCHAR) AS target_ip), CAST(timestamp AS DATE) AS date"
+	if !strings.Contains(sql, expected) {
+		t.Errorf("Generated SQL does not convert INET columns:\n%s", sql)
+	}
+}
@@ -0,0 +1,170 @@
+package duckdb
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// TotalTimeColumn はリクエスト・ターゲット・レスポンスの処理時間の合計のカラム名です
+	TotalTimeColumn = "total_time"
+	// BucketColumn は時間帯ごとに集計する場合の時間帯のカラム名です
+	BucketColumn = "bucket"
+)
+
+// DefaultPercentiles はレイテンシ分析でデフォルトで計算するパーセンタイルです
+var DefaultPercentiles = []float64{50, 90, 99, 99.9}
+
+// LatencyConfig はレイテンシ分析の設定です
+type LatencyConfig struct {
+	// Dimensions は集計の単位にするカラムです (空の場合は全体で集計)
+	Dimensions []string
+	// Interval は時間帯ごとに集計する間隔です (0の場合は時間帯で分けない)
+	Interval time.Duration
+	// Percentiles は計算するパーセンタイルです (空の場合は DefaultPercentiles)
+	Percentiles []float64
+	// Limit は時間帯で分けない場合に表示するグループ数の上限です (0の場合は制限なし)
+	Limit int
+}
+
+// PercentileColumnName はパーセンタイルのカラム名を返します (例: 50 は p50、99.9 は p999)
+func PercentileColumnName(p float64) string {
+	return "p" + strings.ReplaceAll(strconv.FormatFloat(p, 'f', -1, 64), ".", "")
+}
+
+// ValidateLatencyConfig はレイテンシ分析の設定がログ形式に対して有効かを検証します
+func (g *SQLGenerator) ValidateLatencyConfig(cfg LatencyConfig) error {
+	if len(g.format.ProcessingTimeColumns) != 3 {
+		return fmt.Errorf("%sログは処理時間を記録していないためレイテンシ分析に対応していません", g.format.Label)
+	}
+
+	for _, d := range cfg.Dimensions {
+		if _, ok := g.dimensionExpr(d); !ok {
+			return fmt.Errorf("%sログに集計の単位に使用できるカラムがありません: %s", g.format.Label, d)
+		}
+		if d == BucketColumn || d == TotalTimeColumn {
+			return fmt.Errorf("%s は集計の単位に使用できません", d)
+		}
+	}
+	for _, p := range cfg.Percentiles {
+		if p <= 0 || p >= 100 {
+			return fmt.Errorf("パーセンタイルは0より大きく100より小さい値を指定してください: %v", p)
+		}
+	}
+	if cfg.Interval < 0 || cfg.Interval%time.Second != 0 {
+		return fmt.Errorf("集計の間隔は秒単位で指定してください: %s", cfg.Interval)
+	}
+	if cfg.Limit < 0 {
+		return fmt.Errorf("表示するグループ数には0以上の値を指定してください: %d", cfg.Limit)
+	}
+	return nil
+}
+
+// dimensionExpr は集計の単位にするカラムの値のSQL式を返します
+// 派生カラムを追加しない場合でも、派生カラムの名前を指定したときは派生カラムの式で計算します
+func (g *SQLGenerator) dimensionExpr(name string) (string, bool) {
+	if _, ok := g.format.Column(name); ok || name == SourcePathColumn {
+		return name, true
+	}
+	for _, d := range g.format.DerivedColumns {
+		if d.Name != name {
+			continue
+		}
+		if g.derived {
+			return name, true
+		}
+		return d.Expr, true
+	}
+	return "", false
+}
+
+// GenerateLatencySQL はレイテンシ分析のセクションを生成します
+// 合計処理時間のパーセンタイルと、リクエスト・ターゲット・レスポンスの処理時間の内訳を集計します
+// いずれかの処理時間がNULL (ALBの -1) のリクエストは合計処理時間の計算から除外します
+func (g *SQLGenerator) GenerateLatencySQL(tableName string, cfg LatencyConfig) []ReportSection {
+	percentiles := cfg.Percentiles
+	if len(percentiles) == 0 {
+		percentiles = DefaultPercentiles
+	}
+	times := g.format.ProcessingTimeColumns
+
+	// 集計の単位と処理時間を計算する共通テーブル式
+	columns := make([]string, 0, len(cfg.Dimensions)+len(times)+2)
+	groups := make([]string, 0, len(cfg.Dimensions)+1)
+	if cfg.Interval > 0 {
+		columns = append(columns, fmt.Sprintf("time_bucket(INTERVAL '%d seconds', %s) AS %s", int64(cfg.Interval/time.Second), g.format.TimestampExpr, BucketColumn))
+		groups = append(groups, BucketColumn)
+	}
+	for _, d := range cfg.Dimensions {
+		expr, _ := g.dimensionExpr(d)
+		if expr != d {
+			expr = fmt.Sprintf("%s AS %s", expr, d)
+		}
+		columns = append(columns, expr)
+		groups = append(groups, d)
+	}
+	columns = append(columns, fmt.Sprintf("%s AS %s", strings.Join(times, " + "), TotalTimeColumn))
+	columns = append(columns, times...)
+	with := fmt.Sprintf("WITH latency AS (\n    SELECT\n        %s\n    FROM %s\n)", strings.Join(columns, ",\n        "), Identifier(tableName))
+
+	// 時間帯ごとの場合は時系列順、それ以外はリクエスト数の多い順に表示する
+	orderBy := "requests DESC"
+	limit := ""
+	if cfg.Interval > 0 {
+		orderBy = strings.Join(groups, ", ")
+	} else if cfg.Limit > 0 {
+		limit = fmt.Sprintf("\nLIMIT %d", cfg.Limit)
+	}
+
+	percentileColumns := []string{"count(*) AS requests", fmt.Sprintf("count(*) FILTER (WHERE %s IS NULL) AS incomplete", TotalTimeColumn)}
+	for _, p := range percentiles {
+		// 99.9/100 などの浮動小数点数の誤差を含めないように有効桁数を指定する
+		fraction := strconv.FormatFloat(p/100, 'g', 12, 64)
+		percentileColumns = append(percentileColumns, fmt.Sprintf("round(quantile_cont(%s, %s), 3) AS %s", TotalTimeColumn, fraction, PercentileColumnName(p)))
+	}
+	percentileColumns = append(percentileColumns, fmt.Sprintf("round(max(%s), 3) AS max", TotalTimeColumn))
+
+	breakdownColumns := []string{"count(*) AS requests"}
+	for _, t := range times {
+		name := strings.TrimSuffix(t, "_processing_time")
+		breakdownColumns = append(breakdownColumns,
+			fmt.Sprintf("round(avg(%s), 3) AS %s_avg", t, name),
+			fmt.Sprintf("round(quantile_cont(%s, 0.99), 3) AS %s_p99", t, name),
+		)
+	}
+	for _, t := range times {
+		name := strings.TrimSuffix(t, "_processing_time")
+		breakdownColumns = append(breakdownColumns,
+			fmt.Sprintf("round(100.0 * sum(%s) FILTER (WHERE %s IS NOT NULL) / sum(%s), 1) AS %s_percent", t, TotalTimeColumn, TotalTimeColumn, name))
+	}
+
+	query := func(selectColumns []string) string {
+		return fmt.Sprintf("%s\nSELECT\n    %s\nFROM latency\nGROUP BY ALL\nORDER BY %s%s;",
+			with, strings.Join(append(append([]string{}, groups...), selectColumns...), ",\n    "), orderBy, limit)
+	}
+
+	return []ReportSection{
+		{Title: "合計処理時間のパーセンタイル (秒)", SQL: query(percentileColumns)},
+		{Title: "処理時間の内訳 (秒)", SQL: query(breakdownColumns)},
+	}
+}
+
+// ExecuteLatency はログを読み込んだ後にレイテンシ分析の結果を出力します
+func (e *Executor) ExecuteLatency(paths []string, tableName string, cfg LatencyConfig) error {
+	if err := e.sqlGenerator.ValidateLatencyConfig(cfg); err != nil {
+		return err
+	}
+
+	// テーブル名が指定されていない場合は生成
+	if tableName == "" {
+		tableName = e.sqlGenerator.GenerateTableName()
+	}
+
+	title := fmt.Sprintf("%sログのレイテンシ", e.sqlGenerator.format.Label)
+	if len(cfg.Dimensions) > 0 {
+		title += fmt.Sprintf(" (%s 別)", strings.Join(cfg.Dimensions, ", "))
+	}
+	return e.ExecuteReport(paths, tableName, title, e.sqlGenerator.GenerateLatencySQL(tableName, cfg))
+}
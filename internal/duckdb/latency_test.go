@@ -0,0 +1,117 @@
+package duckdb
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/naotama2002/dalv/internal/schema"
+)
+
+func TestPercentileColumnName(t *testing.T) {
+	testCases := []struct {
+		percentile float64
+		expected   string
+	}{
+		{50, "p50"},
+		{99, "p99"},
+		{99.9, "p999"},
+		{99.99, "p9999"},
+	}
+
+	for _, tc := range testCases {
+		if got := PercentileColumnName(tc.percentile); got != tc.expected {
+			t.Errorf("PercentileColumnName(%v) = %q, expected %q", tc.percentile, got, tc.expected)
+		}
+	}
+}
+
+func TestGenerateLatencySQL(t *testing.T) {
+	generator := NewSQLGeneratorWithConfig(Config{Format: schema.ALB})
+	sections := generator.GenerateLatencySQL("alb_logs", LatencyConfig{
+		Dimensions: []string{"target_group_arn", "url_path"},
+		Limit:      10,
+	})
+	if len(sections) != 2 {
+		t.Fatalf("Expected 2 sections, got %d", len(sections))
+	}
+
+	expectedParts := []string{
+		"request_processing_time + target_processing_time + response_processing_time AS total_time",
+		"FROM alb_logs\n)",
+		"target_group_arn,\n    url_path,\n    count(*) AS requests",
+		"round(quantile_cont(total_time, 0.5), 3) AS p50",
+		"round(quantile_cont(total_time, 0.999), 3) AS p999",
+		"count(*) FILTER (WHERE total_time IS NULL) AS incomplete",
+		"ORDER BY requests DESC\nLIMIT 10;",
+	}
+	for _, part := range expectedParts {
+		if !strings.Contains(sections[0].SQL, part) {
+			t.Errorf("Expected percentile SQL to contain %q, got:\n%s", part, sections[0].SQL)
+		}
+	}
+
+	for _, part := range []string{"round(avg(target_processing_time), 3) AS target_avg", "AS response_p99", "AS request_percent"} {
+		if !strings.Contains(sections[1].SQL, part) {
+			t.Errorf("Expected breakdown SQL to contain %q, got:\n%s", part, sections[1].SQL)
+		}
+	}
+}
+
+func TestGenerateLatencySQLWithInterval(t *testing.T) {
+	generator := NewSQLGeneratorWithConfig(Config{Format: schema.CLB, NoDerivedColumns: true})
+	sections := generator.GenerateLatencySQL("clb_logs", LatencyConfig{
+		Dimensions:  []string{"http_method"},
+		Interval:    5 * time.Minute,
+		Percentiles: []float64{95},
+		Limit:       10,
+	})
+	sql := sections[0].SQL
+
+	expectedParts := []string{
+		"time_bucket(INTERVAL '300 seconds', timestamp) AS bucket",
+		"request_processing_time + backend_processing_time + response_processing_time AS total_time",
+		"AS p95",
+		// 時間帯ごとの場合は時系列順で件数を制限しない
+		"ORDER BY bucket, http_method;",
+	}
+	for _, part := range expectedParts {
+		if !strings.Contains(sql, part) {
+			t.Errorf("Expected SQL to contain %q, got:\n%s", part, sql)
+		}
+	}
+
+	// 派生カラムを追加しない場合は派生カラムの式で計算する
+	if !strings.Contains(sql, "AS http_method,") || strings.Contains(sql, "\n        http_method,") {
+		t.Errorf("Expected http_method to be computed from request, got:\n%s", sql)
+	}
+	if strings.Contains(sql, "p50") || strings.Contains(sql, "LIMIT") {
+		t.Errorf("Unexpected default percentile or limit in SQL:\n%s", sql)
+	}
+}
+
+func TestValidateLatencyConfig(t *testing.T) {
+	alb := NewSQLGeneratorWithConfig(Config{Format: schema.ALB})
+	if err := alb.ValidateLatencyConfig(LatencyConfig{Dimensions: []string{"elb", "url_path", "target_ip"}, Interval: time.Minute}); err != nil {
+		t.Errorf("ValidateLatencyConfig returned error: %v", err)
+	}
+
+	testCases := []struct {
+		generator *SQLGenerator
+		cfg       LatencyConfig
+	}{
+		// 処理時間を記録していないログ形式
+		{NewSQLGeneratorWithConfig(Config{Format: schema.NLB}), LatencyConfig{}},
+		// 存在しないカラム
+		{alb, LatencyConfig{Dimensions: []string{"backend_ip"}}},
+		// 範囲外のパーセンタイル
+		{alb, LatencyConfig{Percentiles: []float64{100}}},
+		// 秒未満の間隔
+		{alb, LatencyConfig{Interval: 1500 * time.Millisecond}},
+	}
+	for _, tc := range testCases {
+		if err := tc.generator.ValidateLatencyConfig(tc.cfg); err == nil {
+			t.Errorf("ValidateLatencyConfig(%+v) should return error for %s", tc.cfg, tc.generator.format.Name)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package duckdb
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// OutputFormat は非インタラクティブ実行時のクエリ結果の出力形式です
+type OutputFormat string
+
+const (
+	// OutputTable は罫線付きの表形式です (デフォルト)
+	OutputTable OutputFormat = "table"
+	// OutputCSV はCSV形式です
+	OutputCSV OutputFormat = "csv"
+	// OutputTSV はTSV形式です
+	OutputTSV OutputFormat = "tsv"
+	// OutputJSON はオブジェクトの配列のJSON形式です
+	OutputJSON OutputFormat = "json"
+	// OutputNDJSON は1行に1オブジェクトのJSON形式です
+	OutputNDJSON OutputFormat = "ndjson"
+	// OutputMarkdown はMarkdownの表形式です
+	OutputMarkdown OutputFormat = "markdown"
+	// OutputParquet はParquet形式です (ファイルへの出力のみ)
+	OutputParquet OutputFormat = "parquet"
+)
+
+// outputModes は出力形式に対応するDuckDB CLIの .mode の値です
+// Parquetは .mode ではなく COPY で出力します
+var outputModes = map[OutputFormat]string{
+	OutputTable:    "duckbox",
+	OutputCSV:      "csv",
+	OutputTSV:      "tabs",
+	OutputJSON:     "json",
+	OutputNDJSON:   "jsonlines",
+	OutputMarkdown: "markdown",
+}
+
+// outputExtensions は出力ファイルの拡張子から推定する出力形式です
+var outputExtensions = map[string]OutputFormat{
+	".csv":      OutputCSV,
+	".tsv":      OutputTSV,
+	".json":     OutputJSON,
+	".ndjson":   OutputNDJSON,
+	".jsonl":    OutputNDJSON,
+	".md":       OutputMarkdown,
+	".markdown": OutputMarkdown,
+	".parquet":  OutputParquet,
+}
+
+// Output は非インタラクティブ実行時のクエリ結果の出力設定です
+type Output struct {
+	// Format は出力形式です (空の場合は表形式)
+	Format OutputFormat
+	// Path は出力先のファイルです (空の場合は標準出力)
+	Path string
+}
+
+// OutputFormatNames は対応している出力形式の名前を返します
+func OutputFormatNames() []string {
+	names := []string{string(OutputParquet)}
+	for f := range outputModes {
+		names = append(names, string(f))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseOutputFormat は出力形式の名前を解析します
+func ParseOutputFormat(name string) (OutputFormat, error) {
+	f := OutputFormat(strings.ToLower(name))
+	if _, ok := outputModes[f]; ok || f == OutputParquet {
+		return f, nil
+	}
+	return "", fmt.Errorf("未対応の出力形式です: %s (対応形式: %s)", name, strings.Join(OutputFormatNames(), ", "))
+}
+
+// OutputFormatFromPath は出力ファイルの拡張子から出力形式を推定します
+// 推定できない場合は空文字列を返します
+func OutputFormatFromPath(path string) OutputFormat {
+	return outputExtensions[strings.ToLower(filepath.Ext(path))]
+}
+
+// generateOutputSQL は出力形式に応じてクエリ結果を出力するスクリプトを生成します
+func (g *SQLGenerator) generateOutputSQL(query string, output Output) string {
+	query = terminateStatement(query)
+
+	if output.Format == OutputParquet {
+		return fmt.Sprintf("COPY (\n%s\n) TO %s (FORMAT PARQUET);", strings.TrimSuffix(query, ";"), QuoteLiteral(output.Path))
+	}
+
+	mode, ok := outputModes[output.Format]
+	if !ok {
+		mode = outputModes[OutputTable]
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, ".mode %s\n", mode)
+	if mode != outputModes[OutputTable] {
+		b.WriteString(".headers on\n")
+	}
+	b.WriteString(query)
+	return b.String()
+}
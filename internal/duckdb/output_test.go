@@ -0,0 +1,83 @@
+package duckdb
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseOutputFormat(t *testing.T) {
+	testCases := []struct {
+		name     string
+		expected OutputFormat
+		wantErr  bool
+	}{
+		{"csv", OutputCSV, false},
+		{"NDJSON", OutputNDJSON, false},
+		{"parquet", OutputParquet, false},
+		{"xml", "", true},
+	}
+
+	for _, tc := range testCases {
+		got, err := ParseOutputFormat(tc.name)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("ParseOutputFormat(%q) error = %v, wantErr %v", tc.name, err, tc.wantErr)
+			continue
+		}
+		if got != tc.expected {
+			t.Errorf("ParseOutputFormat(%q) = %q, expected %q", tc.name, got, tc.expected)
+		}
+	}
+}
+
+func TestOutputFormatFromPath(t *testing.T) {
+	testCases := []struct {
+		path     string
+		expected OutputFormat
+	}{
+		{"results.csv", OutputCSV},
+		{"results.TSV", OutputTSV},
+		{"out/results.jsonl", OutputNDJSON},
+		{"report.md", OutputMarkdown},
+		{"results.parquet", OutputParquet},
+		{"results.txt", ""},
+	}
+
+	for _, tc := range testCases {
+		if got := OutputFormatFromPath(tc.path); got != tc.expected {
+			t.Errorf("OutputFormatFromPath(%q) = %q, expected %q", tc.path, got, tc.expected)
+		}
+	}
+}
+
+func TestGenerateOutputSQL(t *testing.T) {
+	generator := NewSQLGenerator()
+
+	testCases := []struct {
+		output   Output
+		expected string
+	}{
+		{Output{}, ".mode duckbox\nSELECT 1;"},
+		{Output{Format: OutputCSV}, ".mode csv\n.headers on\nSELECT 1;"},
+		{Output{Format: OutputTSV}, ".mode tabs\n.headers on\nSELECT 1;"},
+		{Output{Format: OutputNDJSON}, ".mode jsonlines\n.headers on\nSELECT 1;"},
+		{Output{Format: OutputParquet, Path: "results.parquet"}, "COPY (\nSELECT 1\n) TO 'results.parquet' (FORMAT PARQUET);"},
+	}
+
+	for _, tc := range testCases {
+		if got := generator.generateOutputSQL("SELECT 1", tc.output); got != tc.expected {
+			t.Errorf("generateOutputSQL(%+v) = %q, expected %q", tc.output, got, tc.expected)
+		}
+	}
+}
+
+func TestGenerateQueryScriptWithOutput(t *testing.T) {
+	executor := NewExecutorWithConfig(Config{Output: Output{Format: OutputJSON}})
+	script := executor.generateQueryScript("CREATE TABLE t AS SELECT 1;", "SELECT * FROM t")
+
+	if !strings.Contains(script, ".mode json\n") {
+		t.Errorf("Generated script does not switch to JSON mode:\n%s", script)
+	}
+	if strings.Contains(script, ".mode duckbox") {
+		t.Errorf("Generated script should not use duckbox mode:\n%s", script)
+	}
+}
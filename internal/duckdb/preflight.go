@@ -0,0 +1,186 @@
+package duckdb
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// オブジェクトの一覧の取得で判定するエラーの原因です
+var (
+	// ErrNoObjects はパスに一致するオブジェクトがないことを表します
+	ErrNoObjects = errors.New("パスに一致するオブジェクトがありません")
+	// ErrAccessDenied はS3へのアクセスが拒否されたことを表します
+	ErrAccessDenied = errors.New("S3へのアクセスが拒否されました (AccessDenied)。認証情報 (--profile, --role-arn) とバケットポリシーを確認してください")
+	// ErrNoSuchBucket はS3バケットが存在しないことを表します
+	ErrNoSuchBucket = errors.New("S3バケットが存在しません (NoSuchBucket)。バケット名とリージョンを確認してください")
+	// ErrInvalidCredentials はS3の認証情報が無効であることを表します
+	ErrInvalidCredentials = errors.New("S3の認証情報が無効です。アクセスキーの有効期限とプロファイルを確認してください")
+	// ErrAssumeRoleUnsupported はDuckDBのaws拡張機能がロールの引き受けに対応していないことを表します
+	ErrAssumeRoleUnsupported = errors.New("DuckDBのaws拡張機能が --role-arn, --role-session-name, --external-id に対応していません。" +
+		"DuckDBを更新するか、~/.aws/config のプロファイルに role_arn と source_profile を設定して --profile で指定してください")
+)
+
+// listErrorPatterns はDuckDBのエラーメッセージに含まれる文字列と原因の対応です
+// S3のエラーコードを優先し、エラーコードを含まない場合はHTTPのステータスコードで判定します
+// バケットが存在しないと判定するのはエラーコードが NoSuchBucket の場合のみです
+// aws拡張機能が対応していないパラメータやチェーンはCREATE SECRETの時点で失敗するため、最初に判定します
+var listErrorPatterns = []struct {
+	patterns []string
+	err      error
+}{
+	{[]string{"parameter 'assume_role", "chain string: 'sts'"}, ErrAssumeRoleUnsupported},
+	{[]string{"NoSuchBucket"}, ErrNoSuchBucket},
+	{[]string{"InvalidAccessKeyId", "SignatureDoesNotMatch", "ExpiredToken", "InvalidToken"}, ErrInvalidCredentials},
+	{[]string{"AccessDenied", "HTTP 403", "403 (Forbidden)"}, ErrAccessDenied},
+	// NoSuchBucket 以外の404は存在しないオブジェクトのキーを指定した場合にも返るため、一致するオブジェクトがないものとして扱う
+	{[]string{"NoSuchKey", "HTTP 404", "404 (Not Found)"}, ErrNoObjects},
+}
+
+// classifyListError はオブジェクトの一覧の取得に失敗したエラーの原因を判定します
+// 原因を判定できた場合は原因のエラーと元のエラーの両方をラップしたエラーを返します
+// DuckDBはパラメータ名を小文字にしてエラーメッセージに含めるため、大文字と小文字を区別せずに判定します
+func classifyListError(err error) error {
+	message := strings.ToLower(err.Error())
+	for _, p := range listErrorPatterns {
+		for _, pattern := range p.patterns {
+			if strings.Contains(message, strings.ToLower(pattern)) {
+				return fmt.Errorf("%w: %w", p.err, err)
+			}
+		}
+	}
+	return err
+}
+
+// ObjectSummary はパスに一致するオブジェクトの集計です
+type ObjectSummary struct {
+	// Objects はパスに一致するオブジェクトです
+	Objects []Object
+	// TotalSize はオブジェクトの合計サイズ (圧縮後のバイト数) です
+	TotalSize int64
+	// EmptyPaths は一致するオブジェクトがなかったパスです
+	// DuckDBは一致するファイルのないパスを含むとログの読み込みに失敗するため、読み込む前に除外します
+	EmptyPaths []string
+}
+
+// NewObjectSummary はオブジェクトの件数と合計サイズを集計します
+func NewObjectSummary(objects []Object) *ObjectSummary {
+	summary := &ObjectSummary{Objects: objects}
+	for _, o := range objects {
+		summary.TotalSize += o.Size
+	}
+	return summary
+}
+
+// String は件数と合計サイズを表示用の文字列で返します
+func (s *ObjectSummary) String() string {
+	return fmt.Sprintf("%d 件のオブジェクト (合計 %s)", len(s.Objects), FormatSize(s.TotalSize))
+}
+
+// Preflight はDuckDBでログを読み込む前に、パスに一致するオブジェクトを確認します
+// 一致するオブジェクトがない場合は ErrNoObjects を、S3へのアクセスに失敗した場合は原因を判定したエラーを返します
+// 一部のパスのみ一致するオブジェクトがない場合は、そのパスを EmptyPaths に含めます
+func (e *Executor) Preflight(paths []string) (*ObjectSummary, error) {
+	objects, err := e.ListObjects(paths)
+	if err != nil {
+		return nil, err
+	}
+	if len(objects) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNoObjects, strings.Join(paths, ", "))
+	}
+	summary := NewObjectSummary(objects)
+	summary.EmptyPaths = emptyPaths(paths, objects)
+	return summary, nil
+}
+
+// NonEmptyPaths はパスから EmptyPaths を除いたパスを順に返します
+func (s *ObjectSummary) NonEmptyPaths(paths []string) []string {
+	if len(s.EmptyPaths) == 0 {
+		return paths
+	}
+	empty := make(map[string]bool, len(s.EmptyPaths))
+	for _, p := range s.EmptyPaths {
+		empty[p] = true
+	}
+	var result []string
+	for _, p := range paths {
+		if !empty[p] {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// emptyPaths は一致するオブジェクトがないパスを返します
+// DuckDBはオブジェクトのパスをグロブと同じ接頭辞で返すため、グロブと照合して判定します
+// いずれのパスにも一致しないオブジェクトがある場合は照合できないものとし、パスを除外しないように nil を返します
+func emptyPaths(paths []string, objects []Object) []string {
+	matched := make([]bool, len(paths))
+	for _, o := range objects {
+		found := false
+		for i, p := range paths {
+			if matchGlob(p, o.Path) {
+				matched[i] = true
+				found = true
+			}
+		}
+		if !found {
+			return nil
+		}
+	}
+
+	var empty []string
+	for i, p := range paths {
+		if !matched[i] {
+			empty = append(empty, p)
+		}
+	}
+	return empty
+}
+
+// matchGlob はオブジェクトのパスがDuckDBのグロブに一致するかどうかを判定します
+// ** は0個以上のディレクトリに一致し、それ以外は / で区切った要素ごとに path.Match で照合します
+func matchGlob(pattern string, name string) bool {
+	return matchGlobSegments(strings.Split(filepath.ToSlash(pattern), "/"), strings.Split(filepath.ToSlash(name), "/"))
+}
+
+// matchGlobSegments は / で区切ったグロブとパスの要素を先頭から照合します
+func matchGlobSegments(pattern []string, name []string) bool {
+	if len(pattern) == 0 {
+		return len(name) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(name); i++ {
+			if matchGlobSegments(pattern[1:], name[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(name) == 0 {
+		return false
+	}
+	if ok, err := path.Match(pattern[0], name[0]); err != nil || !ok {
+		return false
+	}
+	return matchGlobSegments(pattern[1:], name[1:])
+}
+
+// sizeUnits はサイズの表示に使用する単位です (1024倍ごと)
+var sizeUnits = []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB"}
+
+// FormatSize はバイト数を KiB や GiB などの単位で表示用の文字列に変換します
+func FormatSize(size int64) string {
+	value := float64(size)
+	unit := 0
+	for value >= 1024 && unit < len(sizeUnits)-1 {
+		value /= 1024
+		unit++
+	}
+	if unit == 0 {
+		return fmt.Sprintf("%d %s", size, sizeUnits[unit])
+	}
+	return fmt.Sprintf("%.1f %s", value, sizeUnits[unit])
+}
@@ -0,0 +1,122 @@
+package duckdb
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestClassifyListError(t *testing.T) {
+	testCases := []struct {
+		message  string
+		expected error
+	}{
+		{`HTTP Error: HTTP GET error on 'https://bucket.s3.amazonaws.com/?prefix=logs' (HTTP 403)`, ErrAccessDenied},
+		{"IO Error: <Code>AccessDenied</Code><Message>Access Denied</Message>", ErrAccessDenied},
+		{"IO Error: <Code>NoSuchBucket</Code> (HTTP 404)", ErrNoSuchBucket},
+		// NoSuchBucket を含まない404はオブジェクトのキーの誤りの場合もあるため、一致するオブジェクトがないものとする
+		{`HTTP Error: HTTP GET error on 'https://bucket.s3.amazonaws.com/logs/typo.log.gz' (HTTP 404)`, ErrNoObjects},
+		{"IO Error: <Code>NoSuchKey</Code> (HTTP 404)", ErrNoObjects},
+		{"HTTP Error: Unable to connect to URL 's3://bucket/logs/typo.log.gz': 404 (Not Found)", ErrNoObjects},
+		{"IO Error: <Code>ExpiredToken</Code> (HTTP 400)", ErrInvalidCredentials},
+		// 認証情報のエラーコードはHTTPのステータスコードより優先する
+		{"IO Error: <Code>InvalidAccessKeyId</Code> (HTTP 403)", ErrInvalidCredentials},
+		// ロールの引き受けに対応していないaws拡張機能ではCREATE SECRETが失敗する
+		{"Binder Error: Unknown parameter 'assume_role_arn' for secret type 's3' with provider 'credential_chain'", ErrAssumeRoleUnsupported},
+		{"Invalid Input Error: Unknown provider found while parsing AWS credential chain string: 'sts'", ErrAssumeRoleUnsupported},
+	}
+
+	for _, tc := range testCases {
+		original := &QueryError{Message: tc.message}
+		err := classifyListError(original)
+		if !errors.Is(err, tc.expected) {
+			t.Errorf("classifyListError(%q) = %v, expected %v", tc.message, err, tc.expected)
+		}
+		// 元のエラーも確認できる
+		var queryErr *QueryError
+		if !errors.As(err, &queryErr) {
+			t.Errorf("classifyListError(%q): expected original error to be wrapped", tc.message)
+		}
+	}
+
+	// 原因を判定できない場合は元のエラーをそのまま返す
+	original := errors.New("IO Error: No files found")
+	if err := classifyListError(original); err != original {
+		t.Errorf("Expected original error, got %v", err)
+	}
+}
+
+func TestNewObjectSummary(t *testing.T) {
+	summary := NewObjectSummary([]Object{
+		{Path: "s3://bucket/a.log.gz", Size: 1024},
+		{Path: "s3://bucket/b.log.gz", Size: 2048},
+	})
+	if summary.TotalSize != 3072 {
+		t.Errorf("Expected total size 3072, got %d", summary.TotalSize)
+	}
+	if summary.String() != "2 件のオブジェクト (合計 3.0 KiB)" {
+		t.Errorf("Unexpected summary: %s", summary)
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	testCases := []struct {
+		size     int64
+		expected string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1024, "1.0 KiB"},
+		{1536, "1.5 KiB"},
+		{10 << 30, "10.0 GiB"},
+		{3 << 50, "3.0 PiB"},
+	}
+
+	for _, tc := range testCases {
+		if actual := FormatSize(tc.size); actual != tc.expected {
+			t.Errorf("FormatSize(%d) = %s, expected %s", tc.size, actual, tc.expected)
+		}
+	}
+}
+
+func TestMatchGlob(t *testing.T) {
+	testCases := []struct {
+		pattern  string
+		name     string
+		expected bool
+	}{
+		{"s3://bucket/logs/*.log.gz", "s3://bucket/logs/a.log.gz", true},
+		{"s3://bucket/logs/*.log.gz", "s3://bucket/logs/sub/a.log.gz", false},
+		// ** は0個以上のディレクトリに一致する
+		{"s3://bucket/logs/**/*.log.gz", "s3://bucket/logs/a.log.gz", true},
+		{"s3://bucket/logs/**/*.log.gz", "s3://bucket/logs/2025/03/a.log.gz", true},
+		{"s3://bucket/logs/**", "s3://bucket/logs/2025/a.log.gz", true},
+		{"./logs/*.log", "./logs/a.log", true},
+		{"s3://bucket/logs/a.log.gz", "s3://bucket/logs/a.log.gz", true},
+		{"s3://bucket/logs/2025/03/0[12]/*", "s3://bucket/logs/2025/03/03/a.log.gz", false},
+	}
+
+	for _, tc := range testCases {
+		if actual := matchGlob(tc.pattern, tc.name); actual != tc.expected {
+			t.Errorf("matchGlob(%q, %q) = %v, expected %v", tc.pattern, tc.name, actual, tc.expected)
+		}
+	}
+}
+
+func TestEmptyPaths(t *testing.T) {
+	paths := []string{"s3://bucket/logs/2025/03/01/*", "s3://bucket/logs/2025/03/02/*"}
+	objects := []Object{{Path: "s3://bucket/logs/2025/03/01/a.log.gz"}}
+
+	summary := &ObjectSummary{EmptyPaths: emptyPaths(paths, objects)}
+	if len(summary.EmptyPaths) != 1 || summary.EmptyPaths[0] != paths[1] {
+		t.Fatalf("Expected %s to be empty, got %v", paths[1], summary.EmptyPaths)
+	}
+	if nonEmpty := summary.NonEmptyPaths(paths); len(nonEmpty) != 1 || nonEmpty[0] != paths[0] {
+		t.Errorf("Expected only %s, got %v", paths[0], nonEmpty)
+	}
+
+	// グロブと照合できないオブジェクトがある場合はパスを除外しない
+	objects = append(objects, Object{Path: "logs/2025/03/02/a.log.gz"})
+	if empty := emptyPaths(paths, objects); empty != nil {
+		t.Errorf("Expected no empty paths for unmatched objects, got %v", empty)
+	}
+}
@@ -0,0 +1,61 @@
+package duckdb
+
+import (
+	"regexp"
+	"strings"
+)
+
+// simpleIdentifierPattern は引用符で囲まずに使用できる識別子のパターンです
+var simpleIdentifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// reservedKeywords はDuckDBの予約語です (duckdb_keywords() の reserved)
+// 予約語は引用符で囲まなければ識別子として使用できません
+var reservedKeywords = map[string]bool{
+	"all": true, "analyse": true, "analyze": true, "and": true, "any": true, "array": true,
+	"as": true, "asc": true, "asymmetric": true, "both": true, "case": true, "cast": true,
+	"check": true, "collate": true, "column": true, "constraint": true, "create": true,
+	"default": true, "deferrable": true, "desc": true, "describe": true, "distinct": true,
+	"do": true, "else": true, "end": true, "except": true, "false": true, "fetch": true,
+	"for": true, "foreign": true, "from": true, "grant": true, "group": true, "having": true,
+	"in": true, "initially": true, "intersect": true, "into": true, "lateral": true,
+	"leading": true, "limit": true, "not": true, "null": true, "offset": true, "on": true,
+	"only": true, "or": true, "order": true, "pivot": true, "pivot_longer": true,
+	"pivot_wider": true, "placing": true, "primary": true, "qualify": true, "references": true,
+	"returning": true, "select": true, "show": true, "some": true, "summarize": true,
+	"symmetric": true, "table": true, "then": true, "to": true, "trailing": true, "true": true,
+	"union": true, "unique": true, "unpivot": true, "using": true, "variadic": true,
+	"when": true, "where": true, "window": true, "with": true,
+}
+
+// QuoteLiteral は文字列をSQLの文字列リテラルとして表現します
+// 単一引用符は2つ重ねてエスケープします
+func QuoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// QuoteIdentifier は名前を二重引用符で囲んだSQLの識別子として表現します
+// 二重引用符は2つ重ねてエスケープします
+func QuoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// Identifier は名前をSQLの識別子として表現します
+// 引用符で囲まずに使用できる名前はそのまま返し、それ以外は二重引用符で囲みます
+func Identifier(name string) string {
+	if IsSimpleIdentifier(name) {
+		return name
+	}
+	return QuoteIdentifier(name)
+}
+
+// IsSimpleIdentifier は名前が引用符で囲まずに識別子として使用できるかを返します
+// 英字またはアンダースコアで始まり、英数字とアンダースコアのみを含む予約語でない名前が該当します
+func IsSimpleIdentifier(name string) bool {
+	return simpleIdentifierPattern.MatchString(name) && !IsReservedKeyword(name)
+}
+
+// IsReservedKeyword は名前がDuckDBの予約語かどうかを返します
+// 大文字と小文字は区別しません
+func IsReservedKeyword(name string) bool {
+	return reservedKeywords[strings.ToLower(name)]
+}
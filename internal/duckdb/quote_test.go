@@ -0,0 +1,46 @@
+package duckdb
+
+import "testing"
+
+func TestQuoteLiteral(t *testing.T) {
+	testCases := []struct {
+		value    string
+		expected string
+	}{
+		{"s3://bucket/path/*.log.gz", "'s3://bucket/path/*.log.gz'"},
+		{"", "''"},
+		{"it's", "'it''s'"},
+		{"'; DROP TABLE t; --", "'''; DROP TABLE t; --'"},
+	}
+
+	for _, tc := range testCases {
+		if got := QuoteLiteral(tc.value); got != tc.expected {
+			t.Errorf("QuoteLiteral(%q) = %q, expected %q", tc.value, got, tc.expected)
+		}
+	}
+}
+
+func TestIdentifier(t *testing.T) {
+	testCases := []struct {
+		name     string
+		expected string
+	}{
+		// 引用符が不要な名前はそのまま使用する
+		{"alb_logs", "alb_logs"},
+		{"_Logs2025", "_Logs2025"},
+		// それ以外は二重引用符で囲む
+		{"my-logs", `"my-logs"`},
+		{"x; DROP TABLE y", `"x; DROP TABLE y"`},
+		{`a"b`, `"a""b"`},
+		{"1logs", `"1logs"`},
+		{"select", `"select"`},
+		{"Order", `"Order"`},
+		{"", `""`},
+	}
+
+	for _, tc := range testCases {
+		if got := Identifier(tc.name); got != tc.expected {
+			t.Errorf("Identifier(%q) = %q, expected %q", tc.name, got, tc.expected)
+		}
+	}
+}
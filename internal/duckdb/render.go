@@ -0,0 +1,564 @@
+package duckdb
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// renderModes は組み込みのDuckDBで対応している .mode の値です
+var renderModes = map[string]bool{
+	"trash":     true,
+	"list":      true,
+	"csv":       true,
+	"tabs":      true,
+	"json":      true,
+	"jsonlines": true,
+	"markdown":  true,
+	"duckbox":   true,
+}
+
+// numericTypePrefixes は右寄せで表示する数値型のDuckDBの型名の接頭辞です
+var numericTypePrefixes = []string{
+	"TINYINT", "SMALLINT", "INTEGER", "BIGINT", "HUGEINT",
+	"UTINYINT", "USMALLINT", "UINTEGER", "UBIGINT", "UHUGEINT",
+	"FLOAT", "DOUBLE", "DECIMAL",
+}
+
+// rowWriter はクエリ結果を .mode に合わせた形式で書き込みます
+type rowWriter interface {
+	// WriteRow は1行を書き込みます
+	WriteRow(row []any) error
+	// Close は残りの出力を書き込みます
+	Close() error
+}
+
+// newRowWriter は .mode と .headers の設定に合わせた rowWriter を作成します
+// duckbox と markdown はカラムの幅を揃えるため、すべての行を受け取った後に書き込みます
+func newRowWriter(w io.Writer, mode string, headers bool, columns []ResultColumn) (rowWriter, error) {
+	switch mode {
+	case "trash":
+		return &delimitedWriter{w: io.Discard, columns: columns, separator: "|"}, nil
+	case "list":
+		return newDelimitedWriter(w, columns, "|", headers)
+	case "tabs":
+		return newDelimitedWriter(w, columns, "\t", headers)
+	case "csv":
+		return newCSVWriter(w, columns, headers)
+	case "json":
+		return &jsonWriter{w: w, columns: columns, array: true}, nil
+	case "jsonlines":
+		return &jsonWriter{w: w, columns: columns}, nil
+	case "markdown":
+		return &tableWriter{w: w, columns: columns, markdown: true}, nil
+	case "duckbox":
+		return &tableWriter{w: w, columns: columns}, nil
+	default:
+		return nil, fmt.Errorf("未対応の出力モードです: %s", mode)
+	}
+}
+
+// delimitedWriter は区切り文字で値を区切って1行ずつ書き込みます (list, tabs)
+type delimitedWriter struct {
+	w         io.Writer
+	columns   []ResultColumn
+	separator string
+}
+
+// newDelimitedWriter は delimitedWriter を作成し、必要に応じてヘッダー行を書き込みます
+func newDelimitedWriter(w io.Writer, columns []ResultColumn, separator string, headers bool) (*delimitedWriter, error) {
+	d := &delimitedWriter{w: w, columns: columns, separator: separator}
+	if headers {
+		names := make([]string, len(columns))
+		for i, c := range columns {
+			names[i] = c.Name
+		}
+		if _, err := fmt.Fprintln(w, strings.Join(names, separator)); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+// WriteRow は1行を書き込みます
+func (d *delimitedWriter) WriteRow(row []any) error {
+	values := make([]string, len(row))
+	for i, v := range row {
+		values[i] = formatValue(v, d.columns[i].Type, "")
+	}
+	_, err := fmt.Fprintln(d.w, strings.Join(values, d.separator))
+	return err
+}
+
+// Close は何もしません
+func (d *delimitedWriter) Close() error {
+	return nil
+}
+
+// csvWriter はCSV形式で1行ずつ書き込みます
+type csvWriter struct {
+	w       *csv.Writer
+	columns []ResultColumn
+}
+
+// newCSVWriter は csvWriter を作成し、必要に応じてヘッダー行を書き込みます
+func newCSVWriter(w io.Writer, columns []ResultColumn, headers bool) (*csvWriter, error) {
+	c := &csvWriter{w: csv.NewWriter(w), columns: columns}
+	if headers {
+		names := make([]string, len(columns))
+		for i, col := range columns {
+			names[i] = col.Name
+		}
+		if err := c.w.Write(names); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// WriteRow は1行を書き込みます
+func (c *csvWriter) WriteRow(row []any) error {
+	values := make([]string, len(row))
+	for i, v := range row {
+		values[i] = formatValue(v, c.columns[i].Type, "")
+	}
+	return c.w.Write(values)
+}
+
+// Close はバッファに残った行を書き込みます
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// jsonWriter は1行を1つのJSONオブジェクトとして書き込みます
+// array の場合はDuckDBのCLIと同様に [ と ] で囲み、結果が0行の場合は何も書き込みません
+type jsonWriter struct {
+	w       io.Writer
+	columns []ResultColumn
+	array   bool
+	count   int
+}
+
+// WriteRow は1行を書き込みます
+func (j *jsonWriter) WriteRow(row []any) error {
+	var b bytes.Buffer
+	switch {
+	case !j.array:
+	case j.count == 0:
+		b.WriteString("[")
+	default:
+		b.WriteString(",\n")
+	}
+
+	b.WriteString("{")
+	for i, v := range row {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		if err := writeJSON(&b, j.columns[i].Name); err != nil {
+			return err
+		}
+		b.WriteString(":")
+		if err := writeJSON(&b, jsonValue(v, j.columns[i].Type)); err != nil {
+			return err
+		}
+	}
+	b.WriteString("}")
+	if !j.array {
+		b.WriteString("\n")
+	}
+	j.count++
+
+	_, err := j.w.Write(b.Bytes())
+	return err
+}
+
+// Close は配列の閉じ括弧を書き込みます
+func (j *jsonWriter) Close() error {
+	if !j.array || j.count == 0 {
+		return nil
+	}
+	_, err := io.WriteString(j.w, "]\n")
+	return err
+}
+
+// writeJSON は v をHTMLの文字をエスケープせずにJSONで書き込みます
+func writeJSON(b *bytes.Buffer, v any) error {
+	encoder := json.NewEncoder(b)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return err
+	}
+	// Encode が末尾に付ける改行を除く
+	b.Truncate(b.Len() - 1)
+	return nil
+}
+
+// jsonValue はJSONに書き込む値を返します
+// 数値と真偽値はそのまま、それ以外の値は表示用の文字列にします
+func jsonValue(v any, typ string) any {
+	switch x := v.(type) {
+	case nil, bool, string:
+		return x
+	case int8, int16, int32, int64, int, uint8, uint16, uint32, uint64, uint:
+		return x
+	case *big.Int:
+		return json.Number(x.String())
+	case float32, float64:
+		s := formatValue(x, typ, "")
+		if f, _ := strconv.ParseFloat(s, 64); math.IsNaN(f) || math.IsInf(f, 0) {
+			return s
+		}
+		return json.Number(s)
+	default:
+		return formatValue(x, typ, "")
+	}
+}
+
+// tableWriter はすべての行を受け取った後に、カラムの幅を揃えた表を書き込みます (duckbox, markdown)
+type tableWriter struct {
+	w        io.Writer
+	columns  []ResultColumn
+	markdown bool
+	rows     [][]string
+}
+
+// WriteRow は1行を表示用の文字列にして保持します
+func (t *tableWriter) WriteRow(row []any) error {
+	null := "NULL"
+	if t.markdown {
+		null = ""
+	}
+	values := make([]string, len(row))
+	for i, v := range row {
+		values[i] = formatValue(v, t.columns[i].Type, null)
+	}
+	t.rows = append(t.rows, values)
+	return nil
+}
+
+// Close は表を書き込みます
+func (t *tableWriter) Close() error {
+	if len(t.columns) == 0 {
+		return nil
+	}
+
+	widths := make([]int, len(t.columns))
+	types := make([]string, len(t.columns))
+	numeric := make([]bool, len(t.columns))
+	for i, c := range t.columns {
+		types[i] = strings.ToLower(c.Type)
+		numeric[i] = isNumericType(c.Type)
+		widths[i] = displayWidth(c.Name)
+		if !t.markdown {
+			widths[i] = max(widths[i], displayWidth(types[i]))
+		}
+	}
+	for _, row := range t.rows {
+		for i, v := range row {
+			widths[i] = max(widths[i], displayWidth(v))
+		}
+	}
+
+	var b strings.Builder
+	if t.markdown {
+		t.writeMarkdown(&b, widths, numeric)
+	} else {
+		t.writeDuckbox(&b, widths, types, numeric)
+	}
+	_, err := io.WriteString(t.w, b.String())
+	return err
+}
+
+// writeMarkdown はMarkdownの表を書き込みます
+func (t *tableWriter) writeMarkdown(b *strings.Builder, widths []int, numeric []bool) {
+	line := func(cells []string, align func(i int) bool) {
+		b.WriteString("|")
+		for i, cell := range cells {
+			b.WriteString(" " + pad(cell, widths[i], align(i)) + " |")
+		}
+		b.WriteString("\n")
+	}
+
+	names := make([]string, len(t.columns))
+	for i, c := range t.columns {
+		names[i] = c.Name
+	}
+	line(names, func(int) bool { return false })
+
+	b.WriteString("|")
+	for i, w := range widths {
+		if numeric[i] {
+			b.WriteString(strings.Repeat("-", w+1) + ":|")
+		} else {
+			b.WriteString(strings.Repeat("-", w+2) + "|")
+		}
+	}
+	b.WriteString("\n")
+
+	for _, row := range t.rows {
+		line(row, func(i int) bool { return numeric[i] })
+	}
+}
+
+// writeDuckbox はDuckDBのCLIの duckbox と同様の罫線付きの表を書き込みます
+// ヘッダーにはカラム名と型を表示します
+func (t *tableWriter) writeDuckbox(b *strings.Builder, widths []int, types []string, numeric []bool) {
+	border := func(left, middle, right string) {
+		b.WriteString(left)
+		for i, w := range widths {
+			if i > 0 {
+				b.WriteString(middle)
+			}
+			b.WriteString(strings.Repeat("─", w+2))
+		}
+		b.WriteString(right + "\n")
+	}
+	line := func(cells []string, format func(i int, cell string) string) {
+		b.WriteString("│")
+		for i, cell := range cells {
+			b.WriteString(" " + format(i, cell) + " │")
+		}
+		b.WriteString("\n")
+	}
+	center := func(i int, cell string) string { return centered(cell, widths[i]) }
+
+	names := make([]string, len(t.columns))
+	for i, c := range t.columns {
+		names[i] = c.Name
+	}
+	border("┌", "┬", "┐")
+	line(names, center)
+	line(types, center)
+	border("├", "┼", "┤")
+	for _, row := range t.rows {
+		line(row, func(i int, cell string) string { return pad(cell, widths[i], numeric[i]) })
+	}
+	border("└", "┴", "┘")
+}
+
+// pad は文字列を幅に合わせて空白で埋めます
+func pad(s string, width int, right bool) string {
+	n := width - displayWidth(s)
+	if n <= 0 {
+		return s
+	}
+	if right {
+		return strings.Repeat(" ", n) + s
+	}
+	return s + strings.Repeat(" ", n)
+}
+
+// centered は文字列を幅の中央に配置します
+func centered(s string, width int) string {
+	n := width - displayWidth(s)
+	if n <= 0 {
+		return s
+	}
+	return strings.Repeat(" ", n/2) + s + strings.Repeat(" ", n-n/2)
+}
+
+// displayWidth は端末に表示したときの文字列の幅を返します
+// 日本語などの全角文字は2文字分の幅として数えます
+func displayWidth(s string) int {
+	width := 0
+	for _, r := range s {
+		if isWide(r) {
+			width += 2
+		} else {
+			width++
+		}
+	}
+	return width
+}
+
+// isWide は文字が全角で表示されるかどうかを返します
+func isWide(r rune) bool {
+	return r >= 0x1100 && (r <= 0x115f ||
+		(r >= 0x2e80 && r <= 0xa4cf && r != 0x303f) ||
+		(r >= 0xac00 && r <= 0xd7a3) ||
+		(r >= 0xf900 && r <= 0xfaff) ||
+		(r >= 0xfe30 && r <= 0xfe4f) ||
+		(r >= 0xff00 && r <= 0xff60) ||
+		(r >= 0xffe0 && r <= 0xffe6) ||
+		(r >= 0x1f300 && r <= 0x1f64f) ||
+		(r >= 0x20000 && r <= 0x3fffd))
+}
+
+// isNumericType はDuckDBの型が数値型かどうかを返します
+func isNumericType(typ string) bool {
+	for _, prefix := range numericTypePrefixes {
+		if strings.HasPrefix(typ, prefix) && !strings.HasSuffix(typ, "]") {
+			return true
+		}
+	}
+	return false
+}
+
+// formatValue は値をDuckDBのCLIと同様の表示用の文字列にします
+// NULL は null の文字列で表示します
+func formatValue(v any, typ string, null string) string {
+	switch x := v.(type) {
+	case nil:
+		return null
+	case string:
+		return x
+	case bool:
+		return strconv.FormatBool(x)
+	case float32:
+		return formatFloat(float64(x), typ, 32)
+	case float64:
+		return formatFloat(x, typ, 64)
+	case time.Time:
+		return formatTime(x, typ)
+	case []byte:
+		return formatBlob(x)
+	case []any:
+		values := make([]string, len(x))
+		for i, e := range x {
+			values[i] = formatNested(e)
+		}
+		return "[" + strings.Join(values, ", ") + "]"
+	case map[string]any:
+		keys := make([]string, 0, len(x))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		values := make([]string, len(keys))
+		for i, k := range keys {
+			values[i] = fmt.Sprintf("'%s': %s", k, formatNested(x[k]))
+		}
+		return "{" + strings.Join(values, ", ") + "}"
+	case map[any]any:
+		values := make([]string, 0, len(x))
+		for k, e := range x {
+			values = append(values, fmt.Sprintf("%s=%s", formatNested(k), formatNested(e)))
+		}
+		sort.Strings(values)
+		return "{" + strings.Join(values, ", ") + "}"
+	default:
+		return fmt.Sprint(x)
+	}
+}
+
+// formatNested はリストや構造体の要素の値を表示用の文字列にします
+func formatNested(v any) string {
+	if s, ok := v.(string); ok {
+		return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+	}
+	return formatValue(v, "", "NULL")
+}
+
+// formatFloat は浮動小数点数を表示用の文字列にします
+// DECIMAL の場合は型の小数点以下の桁数で表示します
+func formatFloat(f float64, typ string, bitSize int) string {
+	if scale, ok := decimalScale(typ); ok {
+		return strconv.FormatFloat(f, 'f', scale, 64)
+	}
+	switch {
+	case math.IsNaN(f):
+		return "nan"
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	}
+	if abs := math.Abs(f); abs != 0 && (abs < 1e-4 || abs >= 1e16) {
+		return strconv.FormatFloat(f, 'e', -1, bitSize)
+	}
+	// 整数の値も浮動小数点数であることが分かるように 1.0 のように表示する
+	s := strconv.FormatFloat(f, 'f', -1, bitSize)
+	if !strings.Contains(s, ".") {
+		s += ".0"
+	}
+	return s
+}
+
+// decimalScale は DECIMAL(width,scale) の型名から小数点以下の桁数を返します
+func decimalScale(typ string) (int, bool) {
+	if !strings.HasPrefix(typ, "DECIMAL(") || !strings.HasSuffix(typ, ")") {
+		return 0, false
+	}
+	_, scale, found := strings.Cut(strings.TrimSuffix(typ, ")"), ",")
+	if !found {
+		return 0, false
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(scale))
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
+
+// formatTime は日付・時刻を型に合わせて表示用の文字列にします
+func formatTime(t time.Time, typ string) string {
+	switch typ {
+	case "DATE":
+		return t.Format("2006-01-02")
+	case "TIME":
+		return t.Format("15:04:05.999999")
+	case "TIMESTAMPTZ", "TIMESTAMP WITH TIME ZONE":
+		return t.Format("2006-01-02 15:04:05.999999-07")
+	case "TIMESTAMP_NS":
+		return t.Format("2006-01-02 15:04:05.999999999")
+	default:
+		return t.Format("2006-01-02 15:04:05.999999")
+	}
+}
+
+// formatBlob はバイト列を表示用の文字列にします
+// 表示できないバイトは \xFF の形式で表示します
+func formatBlob(data []byte) string {
+	var b strings.Builder
+	for _, c := range data {
+		if c >= 0x20 && c < 0x7f && c != '\\' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, `\x%02X`, c)
+		}
+	}
+	return b.String()
+}
+
+// formatInterval は INTERVAL をDuckDBのCLIと同様の表示用の文字列にします (例: 1 year 2 months 3 days 04:05:06)
+func formatInterval(months int32, days int32, micros int64) string {
+	var parts []string
+	unit := func(n int64, singular string) {
+		if n == 0 {
+			return
+		}
+		s := fmt.Sprintf("%d %s", n, singular)
+		if n != 1 && n != -1 {
+			s += "s"
+		}
+		parts = append(parts, s)
+	}
+	unit(int64(months/12), "year")
+	unit(int64(months%12), "month")
+	unit(int64(days), "day")
+
+	if micros != 0 || len(parts) == 0 {
+		sign := ""
+		if micros < 0 {
+			sign = "-"
+			micros = -micros
+		}
+		d := time.Duration(micros) * time.Microsecond
+		s := fmt.Sprintf("%s%02d:%02d:%02d", sign, int64(d/time.Hour), int64(d/time.Minute%60), int64(d/time.Second%60))
+		if frac := micros % 1e6; frac != 0 {
+			s += strings.TrimRight(fmt.Sprintf(".%06d", frac), "0")
+		}
+		parts = append(parts, s)
+	}
+	return strings.Join(parts, " ")
+}
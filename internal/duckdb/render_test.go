@@ -0,0 +1,154 @@
+package duckdb
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// renderColumns はテスト用の結果のカラムです
+var renderColumns = []ResultColumn{
+	{Name: "status", Type: "INTEGER"},
+	{Name: "path", Type: "VARCHAR"},
+	{Name: "rate", Type: "DOUBLE"},
+}
+
+// renderRows はテスト用の結果の行です
+var renderRows = [][]any{
+	{int32(200), "/index.html", 0.5},
+	{int32(502), "/api/<id>", nil},
+}
+
+func TestRowWriter(t *testing.T) {
+	testCases := []struct {
+		mode     string
+		headers  bool
+		expected string
+	}{
+		{"trash", true, ""},
+		{"list", false, "200|/index.html|0.5\n502|/api/<id>|\n"},
+		{"tabs", true, "status\tpath\trate\n200\t/index.html\t0.5\n502\t/api/<id>\t\n"},
+		{"csv", true, "status,path,rate\n200,/index.html,0.5\n502,/api/<id>,\n"},
+		{"json", true, `[{"status":200,"path":"/index.html","rate":0.5},` + "\n" + `{"status":502,"path":"/api/<id>","rate":null}]` + "\n"},
+		{"jsonlines", true, `{"status":200,"path":"/index.html","rate":0.5}` + "\n" + `{"status":502,"path":"/api/<id>","rate":null}` + "\n"},
+		{"markdown", true, "| status | path        | rate |\n" +
+			"|-------:|-------------|-----:|\n" +
+			"|    200 | /index.html |  0.5 |\n" +
+			"|    502 | /api/<id>   |      |\n"},
+		{"duckbox", true, "┌─────────┬─────────────┬────────┐\n" +
+			"│ status  │    path     │  rate  │\n" +
+			"│ integer │   varchar   │ double │\n" +
+			"├─────────┼─────────────┼────────┤\n" +
+			"│     200 │ /index.html │    0.5 │\n" +
+			"│     502 │ /api/<id>   │   NULL │\n" +
+			"└─────────┴─────────────┴────────┘\n"},
+	}
+
+	for _, tc := range testCases {
+		var out bytes.Buffer
+		w, err := newRowWriter(&out, tc.mode, tc.headers, renderColumns)
+		if err != nil {
+			t.Fatalf("newRowWriter(%q) returned error: %v", tc.mode, err)
+		}
+		for _, row := range renderRows {
+			if err := w.WriteRow(row); err != nil {
+				t.Fatalf("WriteRow(%q) returned error: %v", tc.mode, err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close(%q) returned error: %v", tc.mode, err)
+		}
+		if out.String() != tc.expected {
+			t.Errorf("Mode %q: expected\n%s\ngot\n%s", tc.mode, tc.expected, out.String())
+		}
+	}
+
+	if _, err := newRowWriter(&bytes.Buffer{}, "html", true, renderColumns); err == nil {
+		t.Error("newRowWriter should return error for unsupported mode")
+	}
+}
+
+func TestJSONWriterEmpty(t *testing.T) {
+	// 結果が0行の場合はDuckDBのCLIと同様に何も書き込まない
+	var out bytes.Buffer
+	w, _ := newRowWriter(&out, "json", true, renderColumns)
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("Expected no output, got %q", out.String())
+	}
+}
+
+func TestDuckboxWideCharacters(t *testing.T) {
+	// 全角文字は2文字分の幅で罫線を揃える
+	var out bytes.Buffer
+	w, _ := newRowWriter(&out, "duckbox", true, []ResultColumn{{Name: "message", Type: "VARCHAR"}})
+	w.WriteRow([]any{"読み込み完了"})
+	w.Close()
+
+	expected := "┌──────────────┐\n" +
+		"│   message    │\n" +
+		"│   varchar    │\n" +
+		"├──────────────┤\n" +
+		"│ 読み込み完了 │\n" +
+		"└──────────────┘\n"
+	if out.String() != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, out.String())
+	}
+}
+
+func TestFormatValue(t *testing.T) {
+	ts := time.Date(2025, 3, 30, 12, 34, 56, 500000000, time.UTC)
+
+	testCases := []struct {
+		value    any
+		typ      string
+		expected string
+	}{
+		{nil, "INTEGER", "NULL"},
+		{true, "BOOLEAN", "true"},
+		{int64(42), "BIGINT", "42"},
+		{big.NewInt(12345678901234), "HUGEINT", "12345678901234"},
+		{1.0, "DOUBLE", "1.0"},
+		{0.125, "DOUBLE", "0.125"},
+		{1.5e20, "DOUBLE", "1.5e+20"},
+		{1.5, "DECIMAL(10,2)", "1.50"},
+		{ts, "TIMESTAMP", "2025-03-30 12:34:56.5"},
+		{ts, "TIMESTAMPTZ", "2025-03-30 12:34:56.5+00"},
+		{ts, "DATE", "2025-03-30"},
+		{[]byte("a\x00"), "BLOB", `a\x00`},
+		{[]any{int32(1), "x", nil}, "VARCHAR[]", "[1, 'x', NULL]"},
+		{map[string]any{"b": int32(2), "a": "x"}, "STRUCT", "{'a': 'x', 'b': 2}"},
+		{map[any]any{"k": int32(1)}, "MAP", "{'k'=1}"},
+	}
+
+	for _, tc := range testCases {
+		if got := formatValue(tc.value, tc.typ, "NULL"); got != tc.expected {
+			t.Errorf("formatValue(%#v, %q) = %q, expected %q", tc.value, tc.typ, got, tc.expected)
+		}
+	}
+}
+
+func TestFormatInterval(t *testing.T) {
+	testCases := []struct {
+		months   int32
+		days     int32
+		micros   int64
+		expected string
+	}{
+		{0, 0, 0, "00:00:00"},
+		{0, 0, 5 * 60 * 1000000, "00:05:00"},
+		{0, 0, 1500000, "00:00:01.5"},
+		{14, 1, 0, "1 year 2 months 1 day"},
+		{0, 3, 3600 * 1000000, "3 days 01:00:00"},
+		{0, 0, -90 * 1000000, "-00:01:30"},
+	}
+
+	for _, tc := range testCases {
+		if got := formatInterval(tc.months, tc.days, tc.micros); got != tc.expected {
+			t.Errorf("formatInterval(%d, %d, %d) = %q, expected %q", tc.months, tc.days, tc.micros, got, tc.expected)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package duckdb
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ReportSection はレポートの1つのセクションです
+type ReportSection struct {
+	// Title はセクションの見出しです
+	Title string
+	// SQL はセクションに表示する結果を取得するSQLです
+	SQL string
+}
+
+// ExecuteReport はログを読み込んだ後にレポートの各セクションのSQLを実行し、見出しとともに結果を出力します
+func (e *Executor) ExecuteReport(paths []string, tableName string, title string, sections []ReportSection) error {
+	if e.output.Format != "" && e.output.Format != OutputTable && e.output.Format != OutputMarkdown {
+		return fmt.Errorf("レポートは %s または %s 形式でのみ出力できます: %s", OutputTable, OutputMarkdown, e.output.Format)
+	}
+
+	// テーブル名が指定されていない場合は生成
+	if tableName == "" {
+		tableName = e.sqlGenerator.GenerateTableName()
+	}
+
+	loadSQL, err := e.loadSQL(paths, tableName)
+	if err != nil {
+		return err
+	}
+	script := e.generateReportScript(loadSQL, title, sections)
+
+	stdout, closeOutput, err := e.openOutput()
+	if err != nil {
+		return err
+	}
+	defer closeOutput()
+
+	if err := e.runScript(script, stdout); err != nil {
+		return fmt.Errorf("レポートの実行に失敗しました: %w", err)
+	}
+	return nil
+}
+
+// generateReportScript はレポートの各セクションの見出しと結果を出力するスクリプトを生成します
+// Markdown形式の場合は見出しもMarkdownで出力します
+func (e *Executor) generateReportScript(loadSQL string, title string, sections []ReportSection) string {
+	heading, sectionHeading := "%s", "== %s =="
+	if e.output.Format == OutputMarkdown {
+		heading, sectionHeading = "# %s", "## %s"
+	}
+
+	var b strings.Builder
+	b.WriteString(".bail on\n")
+	b.WriteString(".mode trash\n")
+	b.WriteString(loadSQL)
+	b.WriteString("\n\n")
+	fmt.Fprintf(&b, ".print %s\n", printArg(fmt.Sprintf(heading, title)))
+	for _, s := range sections {
+		fmt.Fprintf(&b, ".print \"\"\n.print %s\n", printArg(fmt.Sprintf(sectionHeading, s.Title)))
+		b.WriteString(e.sqlGenerator.generateOutputSQL(s.SQL, e.output))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// printArg は .print に渡す文字列をダブルクォートで囲みます
+func printArg(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
@@ -0,0 +1,44 @@
+package duckdb
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateReportScript(t *testing.T) {
+	sections := []ReportSection{
+		{Title: "ステータスコード", SQL: "SELECT elb_status_code, count(*) FROM t GROUP BY 1"},
+		{Title: "\"クライアント\"", SQL: "SELECT client_ip FROM t;"},
+	}
+
+	executor := NewExecutor()
+	script := executor.generateReportScript("CREATE TABLE t AS SELECT 1;", "overview", sections)
+
+	expectedParts := []string{
+		".bail on\n.mode trash\nCREATE TABLE t AS SELECT 1;",
+		".print \"overview\"\n",
+		".print \"\"\n.print \"== ステータスコード ==\"\n.mode duckbox\nSELECT elb_status_code, count(*) FROM t GROUP BY 1;\n",
+		".print \"== \\\"クライアント\\\" ==\"\n.mode duckbox\nSELECT client_ip FROM t;\n",
+	}
+	for _, part := range expectedParts {
+		if !strings.Contains(script, part) {
+			t.Errorf("Expected script to contain %q, got:\n%s", part, script)
+		}
+	}
+
+	// Markdown形式の場合は見出しもMarkdownで出力する
+	executor = NewExecutorWithConfig(Config{Output: Output{Format: OutputMarkdown}})
+	script = executor.generateReportScript("CREATE TABLE t AS SELECT 1;", "overview", sections)
+	for _, part := range []string{".print \"# overview\"\n", ".print \"## ステータスコード\"\n.mode markdown\n.headers on\n"} {
+		if !strings.Contains(script, part) {
+			t.Errorf("Expected script to contain %q, got:\n%s", part, script)
+		}
+	}
+}
+
+func TestExecuteReportUnsupportedOutput(t *testing.T) {
+	executor := NewExecutorWithConfig(Config{Output: Output{Format: OutputCSV}})
+	if err := executor.ExecuteReport([]string{"s3://bucket/path/*.log.gz"}, "t", "overview", nil); err == nil {
+		t.Error("ExecuteReport should return error for csv output")
+	}
+}
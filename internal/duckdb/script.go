@@ -0,0 +1,180 @@
+package duckdb
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// scriptItem はスクリプトの1つのドットコマンドまたはSQLの文です
+type scriptItem struct {
+	// Command はドットコマンドの名前です (. を除く。SQLの場合は空)
+	Command string
+	// Args はドットコマンドの引数です
+	Args []string
+	// SQL はSQLの文です (末尾のセミコロンを含む)
+	SQL string
+}
+
+// String はスクリプトでの表記を返します
+func (i scriptItem) String() string {
+	if i.Command == "" {
+		return i.SQL
+	}
+	return strings.TrimSpace("." + i.Command + " " + strings.Join(i.Args, " "))
+}
+
+// parseScript はDuckDB CLIのスクリプトをドットコマンドとSQLの文に分割します
+// 文の途中でない行の先頭が . の場合はドットコマンドとして扱います
+// 末尾のセミコロンで終わっていないSQLも1つの文として返します
+func parseScript(script string) ([]scriptItem, error) {
+	items, rest, err := parseScriptPrefix(script)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(rest) != "" {
+		items = append(items, scriptItem{SQL: strings.TrimSpace(rest)})
+	}
+	return items, nil
+}
+
+// parseScriptPrefix は完結しているドットコマンドとSQLの文を返し、続きの入力が必要な部分を rest に返します
+// インタラクティブコンソールで入力が完結したかどうかの判定にも使用します
+func parseScriptPrefix(script string) (items []scriptItem, rest string, err error) {
+	for {
+		// 空白と文の外のコメントを読み飛ばす
+		script = skipSpaceAndComments(script)
+		if script == "" {
+			return items, "", nil
+		}
+
+		if script[0] == '.' {
+			line, next, _ := strings.Cut(script, "\n")
+			item, err := parseDotCommand(strings.TrimRight(line, "\r"))
+			if err != nil {
+				return nil, "", err
+			}
+			items = append(items, item)
+			script = next
+			continue
+		}
+
+		end := statementEnd(script)
+		if end < 0 {
+			return items, script, nil
+		}
+		items = append(items, scriptItem{SQL: strings.TrimSpace(script[:end+1])})
+		script = script[end+1:]
+	}
+}
+
+// skipSpaceAndComments は先頭の空白と -- または /* */ のコメントを除きます
+// 閉じられていない /* は除きません
+func skipSpaceAndComments(s string) string {
+	for {
+		s = strings.TrimLeftFunc(s, unicode.IsSpace)
+		switch {
+		case strings.HasPrefix(s, "--"):
+			_, next, found := strings.Cut(s, "\n")
+			if !found {
+				return ""
+			}
+			s = next
+		case strings.HasPrefix(s, "/*"):
+			end := strings.Index(s[2:], "*/")
+			if end < 0 {
+				return s
+			}
+			s = s[2+end+2:]
+		default:
+			return s
+		}
+	}
+}
+
+// statementEnd は文字列・引用符付きの識別子・コメントの外にある最初のセミコロンの位置を返します
+// セミコロンがない場合、または引用符やコメントが閉じられていない場合は -1 を返します
+func statementEnd(s string) int {
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\'' || c == '"':
+			// '' や "" は引用符のエスケープ
+			j := i + 1
+			for {
+				k := strings.IndexByte(s[j:], c)
+				if k < 0 {
+					return -1
+				}
+				j += k + 1
+				if j >= len(s) || s[j] != c {
+					break
+				}
+				j++
+			}
+			i = j - 1
+		case c == '-' && strings.HasPrefix(s[i:], "--"):
+			k := strings.IndexByte(s[i:], '\n')
+			if k < 0 {
+				return -1
+			}
+			i += k
+		case c == '/' && strings.HasPrefix(s[i:], "/*"):
+			k := strings.Index(s[i+2:], "*/")
+			if k < 0 {
+				return -1
+			}
+			i += 2 + k + 1
+		case c == ';':
+			return i
+		}
+	}
+	return -1
+}
+
+// parseDotCommand はドットコマンドの行を名前と引数に分割します
+// ダブルクォートで囲んだ引数では \" と \\ をエスケープとして扱います
+func parseDotCommand(line string) (scriptItem, error) {
+	var args []string
+	s := strings.TrimSpace(line[1:])
+	for {
+		s = strings.TrimLeftFunc(s, unicode.IsSpace)
+		if s == "" {
+			break
+		}
+		if s[0] != '"' {
+			end := strings.IndexFunc(s, unicode.IsSpace)
+			if end < 0 {
+				end = len(s)
+			}
+			args = append(args, s[:end])
+			s = s[end:]
+			continue
+		}
+
+		var b strings.Builder
+		closed := false
+		i := 1
+		for ; i < len(s); i++ {
+			if s[i] == '\\' && i+1 < len(s) {
+				i++
+				b.WriteByte(s[i])
+				continue
+			}
+			if s[i] == '"' {
+				closed = true
+				break
+			}
+			b.WriteByte(s[i])
+		}
+		if !closed {
+			return scriptItem{}, fmt.Errorf("ドットコマンドの引数の引用符が閉じられていません: %s", line)
+		}
+		args = append(args, b.String())
+		s = s[i+1:]
+	}
+
+	if len(args) == 0 {
+		return scriptItem{}, fmt.Errorf("ドットコマンドの名前がありません: %s", line)
+	}
+	return scriptItem{Command: args[0], Args: args[1:]}, nil
+}
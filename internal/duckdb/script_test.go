@@ -0,0 +1,87 @@
+package duckdb
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseScript(t *testing.T) {
+	script := `.bail on
+.mode trash
+-- コメント
+CREATE TABLE t AS SELECT 'a;b' AS "x;y";
+/* ブロック
+コメント */
+.print "== 見出し \"1\" =="
+SELECT 1
+-- 文の途中のコメント;
+  , '.not a command'
+FROM t;
+.mode csv
+SELECT 2`
+
+	items, err := parseScript(script)
+	if err != nil {
+		t.Fatalf("parseScript returned error: %v", err)
+	}
+	expected := []scriptItem{
+		{Command: "bail", Args: []string{"on"}},
+		{Command: "mode", Args: []string{"trash"}},
+		{SQL: `CREATE TABLE t AS SELECT 'a;b' AS "x;y";`},
+		{Command: "print", Args: []string{`== 見出し "1" ==`}},
+		{SQL: "SELECT 1\n-- 文の途中のコメント;\n  , '.not a command'\nFROM t;"},
+		{Command: "mode", Args: []string{"csv"}},
+		{SQL: "SELECT 2"},
+	}
+	if !reflect.DeepEqual(items, expected) {
+		t.Errorf("Expected items %#v, got %#v", expected, items)
+	}
+}
+
+func TestParseScriptPrefix(t *testing.T) {
+	// 完結していない文は rest に返す
+	items, rest, err := parseScriptPrefix("SELECT 1;\nSELECT 'a;\n")
+	if err != nil {
+		t.Fatalf("parseScriptPrefix returned error: %v", err)
+	}
+	if len(items) != 1 || items[0].SQL != "SELECT 1;" {
+		t.Errorf("Unexpected items: %#v", items)
+	}
+	if rest != "SELECT 'a;\n" {
+		t.Errorf("Expected rest %q, got %q", "SELECT 'a;\n", rest)
+	}
+
+	// 文の途中の . で始まる行はドットコマンドとして扱わない
+	items, rest, err = parseScriptPrefix("SELECT\n.5 AS x;\n")
+	if err != nil {
+		t.Fatalf("parseScriptPrefix returned error: %v", err)
+	}
+	if len(items) != 1 || items[0].SQL != "SELECT\n.5 AS x;" || rest != "" {
+		t.Errorf("Unexpected items %#v and rest %q", items, rest)
+	}
+}
+
+func TestParseDotCommandErrors(t *testing.T) {
+	for _, line := range []string{".", `.print "unclosed`} {
+		if _, err := parseDotCommand(line); err == nil {
+			t.Errorf("parseDotCommand(%q) should return error", line)
+		}
+	}
+}
+
+func TestScriptItemString(t *testing.T) {
+	testCases := []struct {
+		item     scriptItem
+		expected string
+	}{
+		{scriptItem{Command: "mode", Args: []string{"csv"}}, ".mode csv"},
+		{scriptItem{Command: "quit"}, ".quit"},
+		{scriptItem{SQL: "SELECT 1;"}, "SELECT 1;"},
+	}
+
+	for _, tc := range testCases {
+		if got := tc.item.String(); got != tc.expected {
+			t.Errorf("String() = %q, expected %q", got, tc.expected)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package duckdb
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// S3のURLのスタイル
+const (
+	// URLStyleVirtualHost はバケット名をホスト名に含めるURLのスタイルです (DuckDBのデフォルト)
+	URLStyleVirtualHost = "vhost"
+	// URLStylePath はバケット名をパスに含めるURLのスタイルです
+	URLStylePath = "path"
+)
+
+// maxExternalIDLength は外部IDの最大の長さです
+const maxExternalIDLength = 1224
+
+// RedactedValue は表示するSQLで秘密情報を置き換える値です
+const RedactedValue = "********"
+
+// URLStyles は指定できるURLのスタイルの一覧です
+var URLStyles = []string{URLStyleVirtualHost, URLStylePath}
+
+var (
+	// roleARNPattern はIAMロールのARNのパターンです
+	roleARNPattern = regexp.MustCompile(`^arn:aws[a-z-]*:iam::\d{12}:role/[\w+=,.@/-]+$`)
+	// roleSessionNamePattern はロールのセッション名のパターンです (AWS STSの制約)
+	roleSessionNamePattern = regexp.MustCompile(`^[\w+=,.@-]{2,64}$`)
+	// externalIDPattern は外部IDのパターンです (AWS STSの制約。長さは maxExternalIDLength で検証)
+	externalIDPattern = regexp.MustCompile(`^[\w+=,.@:/-]{2,}$`)
+)
+
+// S3Config はS3の認証情報と接続先の設定です
+// 認証情報はDuckDBのクレデンシャルチェーンで取得するため、アクセスキーなどの秘密情報はSQLに含めません
+type S3Config struct {
+	// Profile は認証情報に使用するAWSのプロファイルです (空の場合はデフォルトの認証情報)
+	Profile string
+	// Region はS3バケットのリージョンです (空の場合はクレデンシャルチェーンの設定)
+	Region string
+	// RoleARN は引き受けるIAMロールのARNです (空の場合はロールを引き受けない)
+	RoleARN string
+	// RoleSessionName はロールを引き受ける際のセッション名です
+	RoleSessionName string
+	// ExternalID はロールを引き受ける際の外部IDです
+	ExternalID string
+	// Endpoint はS3のエンドポイントのホスト名です (空の場合はAWSのS3)
+	Endpoint string
+	// URLStyle はS3のURLのスタイルです (vhost, path。空の場合はエンドポイントを指定していれば path、それ以外はDuckDBのデフォルト)
+	URLStyle string
+	// DisableSSL はエンドポイントにSSLを使用せずに接続するかどうかです
+	DisableSSL bool
+}
+
+// ParseEndpoint はS3のエンドポイントを解析し、ホスト名とSSLを使用するかどうかを返します
+// http:// で始まる場合はSSLを使用せず、https:// またはスキームがない場合はSSLを使用します
+func ParseEndpoint(endpoint string) (string, bool, error) {
+	scheme, host, found := strings.Cut(endpoint, "://")
+	if !found {
+		return endpoint, true, nil
+	}
+	switch strings.ToLower(scheme) {
+	case "http":
+		return strings.TrimSuffix(host, "/"), false, nil
+	case "https":
+		return strings.TrimSuffix(host, "/"), true, nil
+	}
+	return "", false, fmt.Errorf("S3のエンドポイントのスキームは http または https を指定してください: %s", endpoint)
+}
+
+// HasCustomEndpoint はAWS以外のS3互換のエンドポイントを使用するかどうかを返します
+func (c S3Config) HasCustomEndpoint() bool {
+	return c.Endpoint != ""
+}
+
+// HasSecrets は外部IDなどの秘密情報を含むかどうかを返します
+func (c S3Config) HasSecrets() bool {
+	return c.ExternalID != ""
+}
+
+// Redacted は外部IDなどの秘密情報を RedactedValue に置き換えた設定を返します
+// 生成したSQLを表示する場合に使用します
+func (c S3Config) Redacted() S3Config {
+	if c.ExternalID != "" {
+		c.ExternalID = RedactedValue
+	}
+	return c
+}
+
+// Validate はS3の設定を検証します
+func (c S3Config) Validate() error {
+	if c.RoleARN != "" && !roleARNPattern.MatchString(c.RoleARN) {
+		return fmt.Errorf("IAMロールのARNの形式が正しくありません (例: arn:aws:iam::123456789012:role/log-reader): %s", c.RoleARN)
+	}
+	if c.RoleARN == "" && (c.RoleSessionName != "" || c.ExternalID != "") {
+		return fmt.Errorf("--role-session-name と --external-id は --role-arn と併用してください")
+	}
+	if c.RoleSessionName != "" && !roleSessionNamePattern.MatchString(c.RoleSessionName) {
+		return fmt.Errorf("セッション名は英数字と +=,.@_- からなる2〜64文字で指定してください: %s", c.RoleSessionName)
+	}
+	// 外部IDは秘密情報として扱う場合があるため、エラーメッセージに含めない
+	if c.ExternalID != "" && (!externalIDPattern.MatchString(c.ExternalID) || len(c.ExternalID) > maxExternalIDLength) {
+		return fmt.Errorf("外部IDは英数字と +=,.@:/_- からなる2〜1224文字で指定してください")
+	}
+	if c.Endpoint != "" && (strings.Contains(c.Endpoint, "://") || strings.ContainsAny(c.Endpoint, "/ '")) {
+		return fmt.Errorf("S3のエンドポイントはパスを含まないホスト名またはURL (例: s3.example.com:9000, http://localhost:9000) で指定してください: %s", c.Endpoint)
+	}
+	if c.DisableSSL && c.Endpoint == "" {
+		return fmt.Errorf("SSLを使用しない接続は http:// で始まる --s3-endpoint を指定した場合のみ使用できます")
+	}
+	if c.URLStyle != "" && !slices.Contains(URLStyles, c.URLStyle) {
+		return fmt.Errorf("未対応のURLのスタイルです: %s (%s のいずれかを指定してください)", c.URLStyle, strings.Join(URLStyles, ", "))
+	}
+	return nil
+}
+
+// secretParams はCREATE SECRETに指定するパラメータを返します
+// プロファイルを指定した場合は共有設定ファイル (~/.aws/config) のプロファイルから認証情報を取得し、
+// ロールを指定した場合はaws拡張機能の sts チェーンでロールを引き受けます
+// sts チェーンに対応していないaws拡張機能ではCREATE SECRETが失敗し、ErrAssumeRoleUnsupported として報告します
+func (c S3Config) secretParams() []string {
+	params := []string{"TYPE S3", "PROVIDER CREDENTIAL_CHAIN"}
+	add := func(name string, value string) {
+		if value != "" {
+			params = append(params, name+" "+QuoteLiteral(value))
+		}
+	}
+	switch {
+	case c.RoleARN != "":
+		add("CHAIN", "sts")
+	case c.Profile != "":
+		add("CHAIN", "config")
+	}
+	add("PROFILE", c.Profile)
+	add("REGION", c.Region)
+	add("ASSUME_ROLE_ARN", c.RoleARN)
+	add("ASSUME_ROLE_SESSION_NAME", c.RoleSessionName)
+	add("ASSUME_ROLE_EXTERNAL_ID", c.ExternalID)
+	add("ENDPOINT", c.Endpoint)
+	// MinIOなどのS3互換のストレージはバケット名をホスト名に含めるURLに対応していないことが多いため、
+	// エンドポイントを指定した場合はパス形式をデフォルトにする
+	urlStyle := c.URLStyle
+	if urlStyle == "" && c.HasCustomEndpoint() {
+		urlStyle = URLStylePath
+	}
+	add("URL_STYLE", urlStyle)
+	if c.DisableSSL {
+		params = append(params, "USE_SSL false")
+	}
+	return params
+}
@@ -0,0 +1,54 @@
+//go:build cgo
+
+package duckdb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// TestGenerateAWSConfigSQLOnEmbeddedEngine は生成したCREATE SECRETを組み込みのDuckDBで実行し、
+// aws拡張機能がパラメータを受け付けることを確認します
+// aws拡張機能をインストールできない (ネットワークに接続できない) 環境ではスキップします
+func TestGenerateAWSConfigSQLOnEmbeddedEngine(t *testing.T) {
+	engine, _ := NewEmbeddedEngine()
+	ctx := context.Background()
+	if err := engine.Run(ctx, RunRequest{Script: "INSTALL aws;\nLOAD aws;\nINSTALL httpfs;\nLOAD httpfs;\n"}, &bytes.Buffer{}); err != nil {
+		t.Skipf("aws拡張機能を読み込めないためスキップします: %v", err)
+	}
+
+	testCases := []struct {
+		name string
+		s3   S3Config
+		// role はロールを引き受ける設定かどうかです
+		role bool
+	}{
+		{name: "default"},
+		{name: "profile", s3: S3Config{Profile: "dalv-test", Region: "ap-northeast-1"}},
+		{name: "endpoint", s3: S3Config{Endpoint: "localhost:9000", DisableSSL: true}},
+		{name: "role", s3: S3Config{RoleARN: "arn:aws:iam::123456789012:role/log-reader"}, role: true},
+		{name: "role with session", s3: S3Config{RoleARN: "arn:aws:iam::123456789012:role/log-reader", RoleSessionName: "dalv", ExternalID: "ext-1234"}, role: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			script := NewSQLGeneratorWithConfig(Config{S3: tc.s3}).GenerateAWSConfigSQL()
+			err := engine.Run(ctx, RunRequest{Script: script}, &bytes.Buffer{})
+			if err == nil {
+				return
+			}
+			// 認証情報が見つからないなどの環境によるエラーは許容し、パラメータの誤りのみを検出する
+			classified := classifyListError(err)
+			if tc.role && errors.Is(classified, ErrAssumeRoleUnsupported) {
+				t.Logf("aws拡張機能がロールの引き受けに対応していません: %v", err)
+				return
+			}
+			message := strings.ToLower(err.Error())
+			if strings.Contains(message, "unknown parameter") || strings.Contains(message, "unknown provider") || errors.Is(classified, ErrAssumeRoleUnsupported) {
+				t.Errorf("CREATE SECRET was rejected:\n%s\n%v", script, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package duckdb
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestS3ConfigValidate(t *testing.T) {
+	valid := []S3Config{
+		{},
+		{Profile: "prod", Region: "ap-northeast-1"},
+		{RoleARN: "arn:aws:iam::123456789012:role/path/log-reader", RoleSessionName: "dalv-session", ExternalID: "tenant:42"},
+		{RoleARN: "arn:aws-cn:iam::123456789012:role/log-reader"},
+		{Endpoint: "localhost:9000", URLStyle: URLStylePath},
+		{URLStyle: URLStyleVirtualHost},
+	}
+	for _, c := range valid {
+		if err := c.Validate(); err != nil {
+			t.Errorf("Validate(%+v): expected no error, got %v", c, err)
+		}
+	}
+
+	invalid := []S3Config{
+		{RoleARN: "log-reader"},
+		{RoleARN: "arn:aws:iam::123456789012:user/someone"},
+		{RoleSessionName: "dalv"},
+		{ExternalID: "ext-id"},
+		{RoleARN: "arn:aws:iam::123456789012:role/r", RoleSessionName: "a b"},
+		{RoleARN: "arn:aws:iam::123456789012:role/r", ExternalID: "x"},
+		{Endpoint: "https://s3.example.com"},
+		{DisableSSL: true},
+		{Endpoint: "s3.example.com/path"},
+		{URLStyle: "virtual"},
+	}
+	for _, c := range invalid {
+		if err := c.Validate(); err == nil {
+			t.Errorf("Validate(%+v): expected error, got nil", c)
+		}
+	}
+}
+
+func TestS3ConfigValidateHidesExternalID(t *testing.T) {
+	// 外部IDはエラーメッセージに含めない
+	c := S3Config{RoleARN: "arn:aws:iam::123456789012:role/r", ExternalID: "secret value"}
+	err := c.Validate()
+	if err == nil {
+		t.Fatal("Expected error, got nil")
+	}
+	if strings.Contains(err.Error(), "secret value") {
+		t.Errorf("Expected error not to contain the external ID, got %v", err)
+	}
+}
+
+func TestS3ConfigRedacted(t *testing.T) {
+	c := S3Config{Profile: "prod", RoleARN: "arn:aws:iam::123456789012:role/r", ExternalID: "ext-1234"}
+	redacted := c.Redacted()
+	if redacted.ExternalID != RedactedValue {
+		t.Errorf("Expected external ID to be redacted, got %q", redacted.ExternalID)
+	}
+	if redacted.Profile != c.Profile || redacted.RoleARN != c.RoleARN {
+		t.Errorf("Expected other fields to be kept, got %+v", redacted)
+	}
+	// 元の設定は変更しない
+	if c.ExternalID != "ext-1234" {
+		t.Errorf("Expected original config to be unchanged, got %q", c.ExternalID)
+	}
+	// 外部IDを指定していない場合は空のまま
+	if (S3Config{}).Redacted().ExternalID != "" {
+		t.Error("Expected empty external ID to stay empty")
+	}
+}
+
+func TestParseEndpoint(t *testing.T) {
+	testCases := []struct {
+		endpoint string
+		host     string
+		useSSL   bool
+	}{
+		{"", "", true},
+		{"s3.example.com", "s3.example.com", true},
+		{"http://localhost:9000", "localhost:9000", false},
+		{"HTTP://localhost:4566/", "localhost:4566", false},
+		{"https://s3.example.com:8443", "s3.example.com:8443", true},
+	}
+
+	for _, tc := range testCases {
+		host, useSSL, err := ParseEndpoint(tc.endpoint)
+		if err != nil {
+			t.Errorf("ParseEndpoint(%q) returned error: %v", tc.endpoint, err)
+			continue
+		}
+		if host != tc.host || useSSL != tc.useSSL {
+			t.Errorf("ParseEndpoint(%q): expected (%q, %v), got (%q, %v)", tc.endpoint, tc.host, tc.useSSL, host, useSSL)
+		}
+	}
+
+	if _, _, err := ParseEndpoint("ftp://localhost"); err == nil {
+		t.Error("Expected error for unsupported scheme, got nil")
+	}
+}
+
+func TestS3ConfigSecretParamsWithCustomEndpoint(t *testing.T) {
+	// エンドポイントを指定した場合はパス形式をデフォルトにする
+	params := strings.Join(S3Config{Endpoint: "localhost:9000", DisableSSL: true}.secretParams(), ", ")
+	expected := "ENDPOINT 'localhost:9000', URL_STYLE 'path', USE_SSL false"
+	if !strings.HasSuffix(params, expected) {
+		t.Errorf("Expected params to end with %q, got %q", expected, params)
+	}
+
+	// URLのスタイルを明示的に指定した場合はそのまま使用する
+	params = strings.Join(S3Config{Endpoint: "s3.localhost.localstack.cloud:4566", URLStyle: URLStyleVirtualHost}.secretParams(), ", ")
+	if !strings.HasSuffix(params, "URL_STYLE 'vhost'") || strings.Contains(params, "USE_SSL") {
+		t.Errorf("Unexpected params: %q", params)
+	}
+
+	// AWSのS3の場合はURLのスタイルを指定しない
+	if params := strings.Join(S3Config{}.secretParams(), ", "); strings.Contains(params, "URL_STYLE") {
+		t.Errorf("Expected no URL style for AWS, got %q", params)
+	}
+}
@@ -0,0 +1,179 @@
+package duckdb
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// readOnlySettingsSQL はクエリからファイルやネットワークにアクセスできないようにする設定です
+// 設定を固定し、クエリから元に戻せないようにします
+var readOnlySettingsSQL = []string{
+	"SET enable_external_access = false;",
+	"SET lock_configuration = true;",
+}
+
+// QueryError はDuckDBがクエリの実行に失敗した場合のエラーです
+// Message にはDuckDBのエラーメッセージを保持します
+type QueryError struct {
+	// Message はDuckDBのエラーメッセージです
+	Message string
+	// Err はDuckDBのCLIの終了エラーまたは組み込みのDuckDBのエラーです
+	Err error
+}
+
+// Error はDuckDBのエラーメッセージを返します
+// 呼び出し元で「クエリの実行に失敗しました」などの説明を付けてラップします
+func (e *QueryError) Error() string {
+	return e.Message
+}
+
+// Unwrap は元のエラーを返します
+func (e *QueryError) Unwrap() error {
+	return e.Err
+}
+
+// ResultColumn はクエリ結果のカラムの名前と型です
+type ResultColumn struct {
+	// Name はカラム名です
+	Name string
+	// Type はDuckDBのデータ型です
+	Type string
+}
+
+// SharedDatabase はログを読み込んだテーブルを保存した、複数のクエリから読み取り専用で参照するデータベースファイルです
+type SharedDatabase struct {
+	engine    Engine
+	path      string
+	tableName string
+	setupSQL  string
+}
+
+// LoadShared はログを読み込んだテーブルを path のデータベースファイルに保存します
+// --db を指定した場合はキャッシュから読み込み、読み込んだ結果を path にコピーします
+func (e *Executor) LoadShared(paths []string, tableName string, path string) (*SharedDatabase, error) {
+	// テーブル名が指定されていない場合は生成
+	if tableName == "" {
+		tableName = e.sqlGenerator.GenerateTableName()
+	}
+
+	loadSQL, err := e.loadSQL(paths, tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	b.WriteString(".bail on\n")
+	b.WriteString(".mode trash\n")
+	b.WriteString(loadSQL)
+	fmt.Fprintf(&b, `
+
+-- 読み込んだテーブルを共有するデータベースファイルにコピー
+ATTACH %s AS shared;
+CREATE OR REPLACE TABLE shared.%s AS
+SELECT *
+FROM %s;
+DETACH shared;
+`, QuoteLiteral(path), Identifier(tableName), Identifier(tableName))
+
+	if err := e.runScript(b.String(), io.Discard); err != nil {
+		return nil, fmt.Errorf("ログの読み込みに失敗しました: %w", err)
+	}
+
+	return &SharedDatabase{
+		engine:    e.engine,
+		path:      path,
+		tableName: tableName,
+		setupSQL:  e.sqlGenerator.GenerateExtensionSQL(),
+	}, nil
+}
+
+// Path はデータベースファイルのパスを返します
+func (d *SharedDatabase) Path() string {
+	return d.path
+}
+
+// TableName はログを読み込んだテーブル名を返します
+func (d *SharedDatabase) TableName() string {
+	return d.tableName
+}
+
+// Query はデータベースファイルを読み取り専用で開いてクエリを実行し、結果を w に書き込みます
+// クエリからはファイルやネットワークにアクセスできません
+// ctx がキャンセルされた場合はDuckDBを終了します
+func (d *SharedDatabase) Query(ctx context.Context, query string, format OutputFormat, w io.Writer) error {
+	mode, ok := outputModes[format]
+	if !ok {
+		return fmt.Errorf("未対応の出力形式です: %s", format)
+	}
+	return d.run(ctx, query, w, ".mode "+mode, ".headers on")
+}
+
+// Describe はクエリの結果のカラム名と型を返します
+func (d *SharedDatabase) Describe(ctx context.Context, query string) ([]ResultColumn, error) {
+	var out bytes.Buffer
+	if err := d.run(ctx, "DESCRIBE "+query, &out, ".mode tabs", ".headers off"); err != nil {
+		return nil, err
+	}
+
+	var columns []ResultColumn
+	for _, line := range strings.Split(strings.TrimRight(out.String(), "\n"), "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) < 2 {
+			continue
+		}
+		columns = append(columns, ResultColumn{Name: fields[0], Type: fields[1]})
+	}
+	return columns, nil
+}
+
+// run はデータベースファイルを読み取り専用で開き、設定を固定した後にクエリを実行します
+// ctx がキャンセルされた場合はクエリを中断します
+func (d *SharedDatabase) run(ctx context.Context, query string, w io.Writer, commands ...string) error {
+	req, err := d.runRequest(query, commands)
+	if err != nil {
+		return err
+	}
+	return d.engine.Run(ctx, req, w)
+}
+
+// runRequest はクエリを実行する内容を返します
+// クエリはドットコマンドとして解釈されないように、スクリプトと分けて渡します
+func (d *SharedDatabase) runRequest(query string, commands []string) (RunRequest, error) {
+	if err := checkQuery(query); err != nil {
+		return RunRequest{}, err
+	}
+
+	var b strings.Builder
+	if d.setupSQL != "" {
+		b.WriteString(d.setupSQL)
+		b.WriteString("\n")
+	}
+	for _, c := range readOnlySettingsSQL {
+		b.WriteString(c)
+		b.WriteString("\n")
+	}
+	for _, c := range commands {
+		b.WriteString(c)
+		b.WriteString("\n")
+	}
+	return RunRequest{
+		DBPath:   d.path,
+		ReadOnly: true,
+		Script:   b.String(),
+		Query:    strings.TrimSpace(query),
+	}, nil
+}
+
+// Remove はデータベースファイルを削除します
+func (d *SharedDatabase) Remove() error {
+	for _, p := range []string{d.path, d.path + ".wal"} {
+		if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("データベースファイルの削除に失敗しました: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package duckdb
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestSharedDatabaseRunRequest(t *testing.T) {
+	db := &SharedDatabase{path: "/tmp/serve.duckdb", tableName: "alb_logs", setupSQL: "LOAD httpfs;"}
+
+	req, err := db.runRequest("  SELECT count(*) FROM alb_logs  ", []string{".mode json"})
+	if err != nil {
+		t.Fatalf("runRequest returned error: %v", err)
+	}
+	expected := RunRequest{
+		DBPath:   "/tmp/serve.duckdb",
+		ReadOnly: true,
+		Script: "LOAD httpfs;\n" +
+			"SET enable_external_access = false;\n" +
+			"SET lock_configuration = true;\n" +
+			".mode json\n",
+		Query: "SELECT count(*) FROM alb_logs",
+	}
+	if !reflect.DeepEqual(req, expected) {
+		t.Errorf("Expected request %+v, got %+v", expected, req)
+	}
+}
+
+func TestSharedDatabaseRunRequestRejects(t *testing.T) {
+	db := &SharedDatabase{path: "/tmp/serve.duckdb", tableName: "alb_logs"}
+
+	// 空のクエリやドットコマンドは QueryError を返す
+	for _, query := range []string{"", "   ", ".shell ls", " .read /etc/passwd", "SELECT 1;\n.shell ls"} {
+		_, err := db.runRequest(query, nil)
+		var queryErr *QueryError
+		if !errors.As(err, &queryErr) {
+			t.Errorf("runRequest(%q) should return QueryError, got %v", query, err)
+		}
+	}
+}
@@ -2,96 +2,338 @@ package duckdb
 
 import (
 	"fmt"
+	"io"
+	"strings"
 	"time"
+
+	"github.com/naotama2002/dalv/internal/schema"
+	"github.com/naotama2002/dalv/internal/source"
 )
 
+// SourcePathColumn は各行の読み込み元ファイルのパスを格納するカラム名です
+const SourcePathColumn = "source_path"
+
+// Config はSQL生成およびDuckDB実行の設定です
+type Config struct {
+	// Format はログ形式です (nil の場合はALB)
+	Format *schema.Format
+	// TimeRange は読み込むログの時間範囲です (nil の場合は絞り込みなし)
+	TimeRange *source.TimeRange
+	// DBPath はキャッシュを保存するDuckDBのデータベースファイルです (空の場合はインメモリ)
+	DBPath string
+	// Output は非インタラクティブ実行時のクエリ結果の出力設定です
+	Output Output
+	// NoDerivedColumns はリクエストの分解などの派生カラムを追加しないかどうかです
+	NoDerivedColumns bool
+	// Extensions は使用できるDuckDBの拡張機能です (inet など。派生カラムの型の決定に使用)
+	Extensions []string
+	// Raw はセンチネル値 (-1 や "-") をNULLに変換せずにそのまま読み込むかどうかです
+	Raw bool
+	// Engine はスクリプトを実行するエンジンです (nil の場合はDuckDBのCLI)
+	Engine Engine
+	// Stdout は出力先のファイルを指定しない場合の結果の出力先です (nil の場合は標準出力)
+	Stdout io.Writer
+	// S3 はS3の認証情報と接続先の設定です
+	S3 S3Config
+}
+
 // SQLGenerator はDuckDBのSQLを生成するための構造体です
-type SQLGenerator struct{}
+type SQLGenerator struct {
+	format     *schema.Format
+	timeRange  *source.TimeRange
+	derived    bool
+	raw        bool
+	extensions map[string]bool
+	s3         S3Config
+}
 
 // NewSQLGenerator は新しいSQLジェネレーターを作成します
 func NewSQLGenerator() *SQLGenerator {
-	return &SQLGenerator{}
+	return NewSQLGeneratorWithConfig(Config{})
+}
+
+// NewSQLGeneratorWithConfig は設定を指定して新しいSQLジェネレーターを作成します
+func NewSQLGeneratorWithConfig(cfg Config) *SQLGenerator {
+	format := cfg.Format
+	if format == nil {
+		format = schema.Default()
+	}
+	g := &SQLGenerator{
+		format:    format,
+		timeRange: cfg.TimeRange,
+		derived:   !cfg.NoDerivedColumns,
+		raw:       cfg.Raw,
+		s3:        cfg.S3,
+	}
+	g.setExtensions(cfg.Extensions)
+	return g
+}
+
+// Format はSQL生成に使用するログ形式を返します
+func (g *SQLGenerator) Format() *schema.Format {
+	return g.format
 }
 
 // GenerateCompleteSQL はS3パスからデータを読み込むための完全なSQLを生成します
-func (g *SQLGenerator) GenerateCompleteSQL(s3Path string, tableName string) string {
+func (g *SQLGenerator) GenerateCompleteSQL(paths []string, tableName string) string {
 	// テーブル名が指定されていない場合は生成
 	if tableName == "" {
-		tableName = g.generateTableName()
+		tableName = g.GenerateTableName()
+	}
+
+	// 完全なSQLを結合
+	return fmt.Sprintf("%s\n\n%s", g.GenerateLoadSQL(paths, tableName), g.GenerateLoadedMessageSQL(tableName))
+}
+
+// GenerateLoadedMessageSQL はインタラクティブモードで読み込み完了を表示するSQLを生成します
+func (g *SQLGenerator) GenerateLoadedMessageSQL(tableName string) string {
+	message := fmt.Sprintf("%sログが正常にロードされました。以下のテーブルに対してクエリを実行できます: %s", g.format.Label, Identifier(tableName))
+	return fmt.Sprintf("-- インタラクティブモードのためのメッセージ\nSELECT %s AS message;\n", QuoteLiteral(message))
+}
+
+// GenerateLoadSQL はAWS認証設定とテーブル作成のSQLを生成します
+// インタラクティブモード用のメッセージは含みません
+// ローカルのパスのみの場合はAWS認証設定を含みません
+func (g *SQLGenerator) GenerateLoadSQL(paths []string, tableName string) string {
+	// テーブル名が指定されていない場合は生成
+	if tableName == "" {
+		tableName = g.GenerateTableName()
+	}
+
+	// ログのスキーマを定義し、データを読み込むSQL
+	createTableSQL := g.GenerateCreateTableSQL(tableName, paths)
+	if extensionSQL := g.GenerateExtensionSQL(); extensionSQL != "" {
+		createTableSQL = fmt.Sprintf("%s\n\n%s", extensionSQL, createTableSQL)
+	}
+
+	if !source.AnyRemote(paths) {
+		return createTableSQL
 	}
 
 	// AWS認証設定のSQL
 	awsConfigSQL := g.GenerateAWSConfigSQL()
 
-	// ALBログのスキーマを定義し、S3からデータを読み込むSQL
-	createTableSQL := g.GenerateCreateTableSQL(tableName, s3Path)
-
-	// 完全なSQLを結合
-	return fmt.Sprintf("%s\n\n%s\n\n-- インタラクティブモードのためのメッセージ\nSELECT 'ALBログが正常にロードされました。以下のテーブルに対してクエリを実行できます: %s' AS message;\n", awsConfigSQL, createTableSQL, tableName)
+	return fmt.Sprintf("%s\n\n%s", awsConfigSQL, createTableSQL)
 }
 
 // GenerateAWSConfigSQL はAWS認証情報を設定するSQLを生成します
+// 認証情報はDuckDBのクレデンシャルチェーンで取得し、S3の設定に応じてパラメータを追加します
 func (g *SQLGenerator) GenerateAWSConfigSQL() string {
-	return `-- AWS拡張機能のインストールと認証設定
+	return fmt.Sprintf(`-- AWS拡張機能のインストールと認証設定
 INSTALL aws;
 LOAD aws;
 INSTALL httpfs;
 LOAD httpfs;
 CREATE SECRET secret_s3 (
-    TYPE S3,
-    PROVIDER CREDENTIAL_CHAIN
-);`
+    %s
+);`, strings.Join(g.s3.secretParams(), ",\n    "))
 }
 
-// GenerateCreateTableSQL はALBログのテーブルを作成するSQLを生成します
-func (g *SQLGenerator) GenerateCreateTableSQL(tableName string, s3Path string) string {
-	return fmt.Sprintf(`-- ALBログのテーブルを作成
+// GenerateCreateTableSQL はログ形式に応じたテーブルを作成するSQLを生成します
+// 複数のパスを指定した場合はまとめて1つのテーブルに読み込み、
+// 各行の読み込み元ファイルを source_path カラムに格納します
+func (g *SQLGenerator) GenerateCreateTableSQL(tableName string, paths []string) string {
+	return fmt.Sprintf(`-- %sログのテーブルを作成
 CREATE TABLE %s AS
-SELECT *
+%s;`, g.format.Label, Identifier(tableName), g.generateSelectSQL(g.generateReadCSVSQL(paths)))
+}
+
+// generateSelectSQL は読み込み元に対してカラムの加工と時間範囲の絞り込みを行うSELECT文を生成します
+// 読み込み元は read_csv(..., filename=True) と同じカラムを持つ必要があります
+func (g *SQLGenerator) generateSelectSQL(from string, conditions ...string) string {
+	conditions = append(conditions, g.generateTimeRangeConditions()...)
+
+	columns := "* EXCLUDE (filename)"
+	if sentinels := g.nullSentinels(); len(sentinels) > 0 {
+		replaces := make([]string, 0, len(sentinels))
+		for _, s := range sentinels {
+			replaces = append(replaces, fmt.Sprintf("    %s AS %s", s.Expr(), s.Column))
+		}
+		columns += fmt.Sprintf(" REPLACE (\n%s\n)", strings.Join(replaces, ",\n"))
+	}
+	columns += fmt.Sprintf(", filename AS %s", SourcePathColumn)
+	for _, d := range g.derivedColumns() {
+		columns += fmt.Sprintf(",\n    %s AS %s", g.derivedColumnExpr(d), d.Name)
+	}
+
+	sql := fmt.Sprintf("SELECT %s\nFROM %s", columns, from)
+	if len(conditions) > 0 {
+		sql += "\nWHERE " + strings.Join(conditions, "\n  AND ")
+	}
+	return sql
+}
+
+// nullSentinels はNULLに変換するセンチネル値の一覧を返します
+func (g *SQLGenerator) nullSentinels() []schema.NullSentinel {
+	if g.raw {
+		return nil
+	}
+	return g.format.NullSentinels
+}
+
+// derivedColumns はテーブルに追加する派生カラムの一覧を返します
+func (g *SQLGenerator) derivedColumns() []schema.DerivedColumn {
+	if !g.derived {
+		return nil
+	}
+	return g.format.DerivedColumns
+}
+
+// derivedColumnExpr は派生カラムの値を計算するSQL式を返します
+// 拡張機能が必要な型は、拡張機能が使用できる場合のみ変換します
+func (g *SQLGenerator) derivedColumnExpr(d schema.DerivedColumn) string {
+	if d.Extension == "" || !g.extensions[d.Extension] {
+		return d.Expr
+	}
+	return fmt.Sprintf("TRY_CAST(%s AS %s)", d.Expr, d.Type)
+}
+
+// extensionColumns は拡張機能の型に変換する派生カラムの一覧を返します
+func (g *SQLGenerator) extensionColumns() []schema.DerivedColumn {
+	var columns []schema.DerivedColumn
+	for _, d := range g.derivedColumns() {
+		if d.Extension != "" && g.extensions[d.Extension] {
+			columns = append(columns, d)
+		}
+	}
+	return columns
+}
+
+// RequiredExtensions は派生カラムの型に必要な拡張機能の一覧を返します
+// 使用できるかどうかに関わらず、派生カラムが必要とするすべての拡張機能を返します
+func (g *SQLGenerator) RequiredExtensions() []string {
+	var extensions []string
+	seen := make(map[string]bool)
+	for _, d := range g.derivedColumns() {
+		if d.Extension != "" && !seen[d.Extension] {
+			seen[d.Extension] = true
+			extensions = append(extensions, d.Extension)
+		}
+	}
+	return extensions
+}
+
+// GenerateExtensionSQL は派生カラムで使用する拡張機能を読み込むSQLを生成します
+// 拡張機能を使用しない場合は空文字列を返します
+func (g *SQLGenerator) GenerateExtensionSQL() string {
+	var loads []string
+	for _, ext := range g.RequiredExtensions() {
+		if g.extensions[ext] {
+			loads = append(loads, fmt.Sprintf("LOAD %s;", ext))
+		}
+	}
+	if len(loads) == 0 {
+		return ""
+	}
+	return "-- 派生カラムで使用する拡張機能の読み込み\n" + strings.Join(loads, "\n")
+}
+
+// GenerateInstalledExtensionsSQL は指定した拡張機能のうちインストール済みのものを取得するSQLを生成します
+func (g *SQLGenerator) GenerateInstalledExtensionsSQL(extensions []string) string {
+	return fmt.Sprintf(`SELECT extension_name
+FROM duckdb_extensions()
+WHERE (installed OR loaded) AND extension_name IN %s;`, valueListLiteral(extensions))
+}
+
+// setExtensions は使用できる拡張機能を設定します
+func (g *SQLGenerator) setExtensions(extensions []string) {
+	g.extensions = make(map[string]bool, len(extensions))
+	for _, ext := range extensions {
+		g.extensions[ext] = true
+	}
+}
+
+// generateReadCSVSQL はログ形式に応じてファイルを読み込むread_csvの呼び出しを生成します
+func (g *SQLGenerator) generateReadCSVSQL(paths []string) string {
+	return fmt.Sprintf(`read_csv(
+    %s,
+%s
+)`, pathListLiteral(paths), g.generateReadCSVOptions())
+}
+
+// GenerateSampleLineSQL はログの1行目を加工せずに取得するSQLを生成します
+// ログ形式の自動判定に使用します
+func (g *SQLGenerator) GenerateSampleLineSQL(path string) string {
+	return fmt.Sprintf(`SELECT line
 FROM read_csv(
-    '%s',
-    columns={
-        'type': 'VARCHAR',
-        'timestamp': 'TIMESTAMP',
-        'elb': 'VARCHAR',
-        'client_ip_port': 'VARCHAR',
-        'target_ip_port': 'VARCHAR',
-        'request_processing_time': 'DOUBLE',
-        'target_processing_time': 'DOUBLE',
-        'response_processing_time': 'DOUBLE',
-        'elb_status_code': 'INTEGER',
-        'target_status_code': 'VARCHAR',
-        'received_bytes': 'BIGINT',
-        'sent_bytes': 'BIGINT',
-        'request': 'VARCHAR',
-        'user_agent': 'VARCHAR',
-        'ssl_cipher': 'VARCHAR',
-        'ssl_protocol': 'VARCHAR',
-        'target_group_arn': 'VARCHAR',
-        'trace_id': 'VARCHAR',
-        'domain_name': 'VARCHAR',
-        'chosen_cert_arn': 'VARCHAR',
-        'matched_rule_priority': 'VARCHAR',
-        'request_creation_time': 'TIMESTAMP',
-        'actions_executed': 'VARCHAR',
-        'redirect_url': 'VARCHAR',
-        'error_reason': 'VARCHAR',
-        'target_port_list': 'VARCHAR',
-        'target_status_code_list': 'VARCHAR',
-        'classification': 'VARCHAR',
-        'classification_reason': 'VARCHAR',
-        'conn_trace_id': 'VARCHAR'
-    },
-    delim=' ',
-    quote='"',
-    escape='"',
+    %s,
+    columns={'line': 'VARCHAR'},
+    delim=chr(1),
+    quote='',
+    escape='',
     header=False,
     auto_detect=False
-);`, tableName, s3Path)
+)
+LIMIT 1;`, QuoteLiteral(path))
+}
+
+// generateReadCSVOptions はログ形式に応じたread_csvのオプションを生成します
+func (g *SQLGenerator) generateReadCSVOptions() string {
+	columns := make([]string, 0, len(g.format.Columns))
+	for _, c := range g.format.Columns {
+		columns = append(columns, fmt.Sprintf("        '%s': '%s'", c.Name, c.Type))
+	}
+
+	options := []string{
+		fmt.Sprintf("    columns={\n%s\n    }", strings.Join(columns, ",\n")),
+		fmt.Sprintf("    delim='%s'", csvOptionLiteral(g.format.Delimiter)),
+		fmt.Sprintf("    quote='%s'", csvOptionLiteral(g.format.Quote)),
+		fmt.Sprintf("    escape='%s'", csvOptionLiteral(g.format.Escape)),
+		fmt.Sprintf("    header=%s", boolLiteral(g.format.Header)),
+	}
+	if g.format.SkipRows > 0 {
+		options = append(options, fmt.Sprintf("    skip=%d", g.format.SkipRows))
+	}
+	if g.format.NullString != "" {
+		options = append(options, fmt.Sprintf("    nullstr='%s'", csvOptionLiteral(g.format.NullString)))
+	}
+	options = append(options, "    filename=True", "    auto_detect=False")
+
+	return strings.Join(options, ",\n")
+}
+
+// generateTimeRangeConditions は時間範囲で絞り込む条件を生成します
+func (g *SQLGenerator) generateTimeRangeConditions() []string {
+	if g.timeRange == nil {
+		return nil
+	}
+	return []string{
+		fmt.Sprintf("%s >= TIMESTAMP '%s'", g.format.TimestampExpr, g.timeRange.From.Format("2006-01-02 15:04:05")),
+		fmt.Sprintf("%s < TIMESTAMP '%s'", g.format.TimestampExpr, g.timeRange.To.Format("2006-01-02 15:04:05")),
+	}
+}
+
+// pathListLiteral はread_csvに渡すパスを表現します
+// 複数のパスはリストとして渡します
+func pathListLiteral(paths []string) string {
+	if len(paths) == 1 {
+		return QuoteLiteral(paths[0])
+	}
+
+	items := make([]string, 0, len(paths))
+	for _, p := range paths {
+		items = append(items, "        "+QuoteLiteral(p))
+	}
+	return fmt.Sprintf("[\n%s\n    ]", strings.Join(items, ",\n"))
+}
+
+// csvOptionLiteral はread_csvのオプション値を文字列リテラルの中身として表現します
+// タブ文字はDuckDBが解釈できる \t 表記に変換します
+func csvOptionLiteral(s string) string {
+	s = strings.ReplaceAll(s, "'", "''")
+	return strings.ReplaceAll(s, "\t", `\t`)
+}
+
+// boolLiteral はread_csvのオプションで使用する真偽値を返します
+func boolLiteral(b bool) string {
+	if b {
+		return "True"
+	}
+	return "False"
 }
 
-// generateTableName は一意のテーブル名を生成します
-func (g *SQLGenerator) generateTableName() string {
+// GenerateTableName はログ形式と現在時刻から一意のテーブル名を生成します
+func (g *SQLGenerator) GenerateTableName() string {
 	timestamp := time.Now().Format("20060102_150405")
-	return fmt.Sprintf("alb_logs_%s", timestamp)
+	return fmt.Sprintf("%s_%s", g.format.TableNamePrefix, timestamp)
 }
@@ -5,6 +5,9 @@ import (
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/naotama2002/dalv/internal/schema"
+	"github.com/naotama2002/dalv/internal/source"
 )
 
 func TestNewSQLGenerator(t *testing.T) {
@@ -34,14 +37,53 @@ func TestGenerateAWSConfigSQL(t *testing.T) {
 			t.Errorf("Generated AWS config SQL does not contain '%s'", element)
 		}
 	}
+	if strings.Contains(sql, "PROFILE") {
+		t.Errorf("Expected no profile by default:\n%s", sql)
+	}
+}
+
+func TestGenerateAWSConfigSQLWithS3Config(t *testing.T) {
+	// 各パラメータは文字列リテラルとしてエスケープする
+	sql := NewSQLGeneratorWithConfig(Config{S3: S3Config{
+		Profile:         "prod's",
+		Region:          "ap-northeast-1",
+		RoleARN:         "arn:aws:iam::123456789012:role/log-reader",
+		RoleSessionName: "dalv",
+		ExternalID:      "ext-id",
+		Endpoint:        "s3.example.com:9000",
+		URLStyle:        URLStylePath,
+	}}).GenerateAWSConfigSQL()
+
+	expected := `CREATE SECRET secret_s3 (
+    TYPE S3,
+    PROVIDER CREDENTIAL_CHAIN,
+    CHAIN 'sts',
+    PROFILE 'prod''s',
+    REGION 'ap-northeast-1',
+    ASSUME_ROLE_ARN 'arn:aws:iam::123456789012:role/log-reader',
+    ASSUME_ROLE_SESSION_NAME 'dalv',
+    ASSUME_ROLE_EXTERNAL_ID 'ext-id',
+    ENDPOINT 's3.example.com:9000',
+    URL_STYLE 'path'
+);`
+	if !strings.HasSuffix(sql, expected) {
+		t.Errorf("Expected SQL to end with:\n%s\ngot:\n%s", expected, sql)
+	}
+
+	// アクセスキーなどの秘密情報はSQLに含めない
+	for _, param := range []string{"KEY_ID", "SECRET '", "SESSION_TOKEN"} {
+		if strings.Contains(sql, param) {
+			t.Errorf("Expected SQL not to contain %s:\n%s", param, sql)
+		}
+	}
 }
 
 func TestGenerateCreateTableSQL(t *testing.T) {
 	generator := NewSQLGenerator()
 	tableName := "test_table"
 	s3Path := "s3://bucket/path/to/logs/*.log.gz"
-	
-	sql := generator.GenerateCreateTableSQL(tableName, s3Path)
+
+	sql := generator.GenerateCreateTableSQL(tableName, []string{s3Path})
 
 	// 必要な要素が含まれているか確認
 	if !strings.Contains(sql, "CREATE TABLE test_table") {
@@ -77,6 +119,7 @@ func TestGenerateCreateTableSQL(t *testing.T) {
 		"quote='\"'",
 		"escape='\"'",
 		"header=False",
+		"filename=True",
 		"auto_detect=False",
 	}
 
@@ -87,12 +130,128 @@ func TestGenerateCreateTableSQL(t *testing.T) {
 	}
 }
 
+func TestGenerateCreateTableSQL_CloudFront(t *testing.T) {
+	generator := NewSQLGeneratorWithConfig(Config{Format: schema.CloudFront})
+	sql := generator.GenerateCreateTableSQL("cf_table", []string{"s3://bucket/cloudfront/*.gz"})
+
+	if !strings.Contains(sql, "CREATE TABLE cf_table") {
+		t.Error("Generated SQL does not contain CREATE TABLE statement with correct table name")
+	}
+
+	// CloudFront固有のカラムとオプションが含まれているか確認
+	requiredElements := []string{
+		"'x_edge_location': 'VARCHAR'",
+		"'sc_status': 'INTEGER'",
+		`delim='\t'`,
+		"quote=''",
+		"skip=2",
+		"nullstr='-'",
+		"auto_detect=False",
+	}
+
+	for _, element := range requiredElements {
+		if !strings.Contains(sql, element) {
+			t.Errorf("Generated SQL does not contain '%s'", element)
+		}
+	}
+
+	// ALBのカラムは含まれない
+	if strings.Contains(sql, "'elb_status_code'") {
+		t.Error("CloudFront SQL should not contain ALB columns")
+	}
+}
+
+func TestGenerateCreateTableSQL_WithTimeRange(t *testing.T) {
+	generator := NewSQLGeneratorWithConfig(Config{
+		TimeRange: &source.TimeRange{
+			From: time.Date(2025, 3, 30, 22, 0, 0, 0, time.UTC),
+			To:   time.Date(2025, 4, 3, 0, 0, 0, 0, time.UTC),
+		},
+	})
+	paths := []string{
+		"s3://bucket/AWSLogs/1/elasticloadbalancing/r/2025/03/30/*.log*",
+		"s3://bucket/AWSLogs/1/elasticloadbalancing/r/2025/03/31/*.log*",
+	}
+
+	sql := generator.GenerateCreateTableSQL("test_table", paths)
+
+	// 複数のパスはリストとして渡す
+	expectedList := "[\n        '" + paths[0] + "',\n        '" + paths[1] + "'\n    ]"
+	if !strings.Contains(sql, expectedList) {
+		t.Errorf("Generated SQL does not contain path list:\n%s", sql)
+	}
+
+	// 時間範囲で絞り込む
+	requiredElements := []string{
+		"WHERE timestamp >= TIMESTAMP '2025-03-30 22:00:00'",
+		"AND timestamp < TIMESTAMP '2025-04-03 00:00:00'",
+	}
+
+	for _, element := range requiredElements {
+		if !strings.Contains(sql, element) {
+			t.Errorf("Generated SQL does not contain '%s'", element)
+		}
+	}
+}
+
+func TestGenerateCreateTableSQL_SourcePath(t *testing.T) {
+	generator := NewSQLGenerator()
+	sql := generator.GenerateCreateTableSQL("test_table", []string{
+		"s3://bucket-a/AWSLogs/1/elasticloadbalancing/r/2025/03/03/*.log.gz",
+		"s3://bucket-b/AWSLogs/2/elasticloadbalancing/r/2025/03/03/*.log.gz",
+	})
+
+	// 読み込み元ファイルを source_path カラムとして追加する
+	if !strings.Contains(sql, "SELECT * EXCLUDE (filename)") || !strings.Contains(sql, "), filename AS source_path,\n") {
+		t.Errorf("Generated SQL does not add source_path column:\n%s", sql)
+	}
+
+	if !strings.Contains(sql, "'s3://bucket-a/") || !strings.Contains(sql, "'s3://bucket-b/") {
+		t.Error("Generated SQL does not contain all paths")
+	}
+}
+
+func TestGenerateCreateTableSQL_WithoutTimeRange(t *testing.T) {
+	generator := NewSQLGenerator()
+	sql := generator.GenerateCreateTableSQL("test_table", []string{"s3://bucket/path/*.log.gz"})
+
+	if strings.Contains(sql, "WHERE") {
+		t.Error("Generated SQL should not contain WHERE clause without time range")
+	}
+}
+
+func TestGenerateSampleLineSQL(t *testing.T) {
+	generator := NewSQLGenerator()
+	sql := generator.GenerateSampleLineSQL("s3://bucket/path/*.log.gz")
+
+	requiredElements := []string{
+		"'s3://bucket/path/*.log.gz'",
+		"columns={'line': 'VARCHAR'}",
+		"delim=chr(1)",
+		"quote=''",
+		"LIMIT 1",
+	}
+
+	for _, element := range requiredElements {
+		if !strings.Contains(sql, element) {
+			t.Errorf("Generated sample SQL does not contain '%s'", element)
+		}
+	}
+}
+
+func TestGenerateTableName_WithFormat(t *testing.T) {
+	generator := NewSQLGeneratorWithConfig(Config{Format: schema.NLB})
+	if tableName := generator.GenerateTableName(); !strings.HasPrefix(tableName, "nlb_logs_") {
+		t.Errorf("Generated table name '%s' does not have 'nlb_logs_' prefix", tableName)
+	}
+}
+
 func TestGenerateCompleteSQL(t *testing.T) {
 	generator := NewSQLGenerator()
 	tableName := "test_table"
 	s3Path := "s3://bucket/path/to/logs/*.log.gz"
-	
-	sql := generator.GenerateCompleteSQL(s3Path, tableName)
+
+	sql := generator.GenerateCompleteSQL([]string{s3Path}, tableName)
 
 	// AWS設定SQLが含まれているか確認
 	if !strings.Contains(sql, "INSTALL aws") {
@@ -114,12 +273,56 @@ func TestGenerateCompleteSQL(t *testing.T) {
 	}
 }
 
+func TestGenerateLoadSQL(t *testing.T) {
+	generator := NewSQLGenerator()
+	sql := generator.GenerateLoadSQL([]string{"s3://bucket/path/to/logs/*.log.gz"}, "test_table")
+
+	if !strings.Contains(sql, "INSTALL aws") {
+		t.Error("Load SQL does not contain AWS configuration")
+	}
+
+	if !strings.Contains(sql, "CREATE TABLE test_table") {
+		t.Error("Load SQL does not contain table creation statement")
+	}
+
+	// インタラクティブモードのメッセージは含まない
+	if strings.Contains(sql, "ALBログが正常にロードされました") {
+		t.Error("Load SQL should not contain interactive mode message")
+	}
+}
+
+func TestGenerateLoadSQL_LocalPaths(t *testing.T) {
+	generator := NewSQLGenerator()
+	sql := generator.GenerateLoadSQL([]string{"/var/log/alb/*.log.gz", "./downloaded/app.log"}, "test_table")
+
+	// ローカルのパスのみの場合はAWS拡張機能と認証設定を含まない
+	for _, element := range []string{"INSTALL aws", "LOAD httpfs", "CREATE SECRET"} {
+		if strings.Contains(sql, element) {
+			t.Errorf("Load SQL for local paths should not contain '%s'", element)
+		}
+	}
+
+	if !strings.Contains(sql, "CREATE TABLE test_table") {
+		t.Error("Load SQL does not contain table creation statement")
+	}
+}
+
+func TestGenerateLoadSQL_MixedPaths(t *testing.T) {
+	generator := NewSQLGenerator()
+	sql := generator.GenerateLoadSQL([]string{"/var/log/alb/*.log.gz", "s3://bucket/path/*.log.gz"}, "test_table")
+
+	// S3パスが含まれる場合はAWS認証設定を含む
+	if !strings.Contains(sql, "CREATE SECRET") {
+		t.Error("Load SQL for mixed paths should contain AWS configuration")
+	}
+}
+
 func TestGenerateCompleteSQL_WithEmptyTableName(t *testing.T) {
 	generator := NewSQLGenerator()
 	s3Path := "s3://bucket/path/to/logs/*.log.gz"
-	
+
 	// テーブル名を空にして呼び出し
-	sql := generator.GenerateCompleteSQL(s3Path, "")
+	sql := generator.GenerateCompleteSQL([]string{s3Path}, "")
 
 	// 自動生成されたテーブル名のパターンを確認
 	if !strings.Contains(sql, "alb_logs_") {
@@ -135,7 +338,7 @@ func TestGenerateCompleteSQL_WithEmptyTableName(t *testing.T) {
 
 func TestGenerateTableName(t *testing.T) {
 	generator := NewSQLGenerator()
-	tableName := generator.generateTableName()
+	tableName := generator.GenerateTableName()
 
 	// テーブル名のプレフィックスを確認
 	if !strings.HasPrefix(tableName, "alb_logs_") {
@@ -151,8 +354,140 @@ func TestGenerateTableName(t *testing.T) {
 	// 時刻形式が含まれているか確認
 	currentHour := time.Now().Hour()
 	hourStr := fmt.Sprintf("%02d", currentHour)
-	
+
 	if !strings.Contains(tableName, hourStr) {
 		t.Errorf("Generated table name '%s' does not contain current hour '%s'", tableName, hourStr)
 	}
 }
+
+func TestGenerateCreateTableSQL_DerivedColumns(t *testing.T) {
+	paths := []string{"s3://bucket/path/*.log.gz"}
+
+	// デフォルトでは派生カラムを追加する
+	sql := NewSQLGenerator().GenerateCreateTableSQL("test_table", paths)
+	for _, name := range []string{"http_method", "url_host", "url_port", "url_path", "url_query", "http_version"} {
+		if !strings.Contains(sql, " AS "+name+"\n") && !strings.Contains(sql, " AS "+name+",\n") {
+			t.Errorf("Generated SQL does not contain derived column %s:\n%s", name, sql)
+		}
+	}
+
+	// 無効にした場合は追加しない
+	sql = NewSQLGeneratorWithConfig(Config{NoDerivedColumns: true}).GenerateCreateTableSQL("test_table", paths)
+	if strings.Contains(sql, "http_method") {
+		t.Errorf("Generated SQL should not contain derived columns:\n%s", sql)
+	}
+}
+
+func TestGenerateCreateTableSQL_IPColumns(t *testing.T) {
+	paths := []string{"s3://bucket/path/*.log.gz"}
+
+	// inet拡張機能が使用できない場合はVARCHARのまま追加する
+	generator := NewSQLGeneratorWithConfig(Config{Extensions: []string{}})
+	sql := generator.GenerateLoadSQL(paths, "test_table")
+	if !strings.Contains(sql, " AS client_ip,\n") || !strings.Contains(sql, " AS target_port") {
+		t.Errorf("Generated SQL does not contain IP and port columns:\n%s", sql)
+	}
+	if strings.Contains(sql, "AS INET") || strings.Contains(sql, "LOAD inet") {
+		t.Errorf("Generated SQL should not use inet extension:\n%s", sql)
+	}
+
+	// inet拡張機能が使用できる場合はINET型に変換する
+	generator = NewSQLGeneratorWithConfig(Config{Extensions: []string{"inet"}})
+	sql = generator.GenerateLoadSQL(paths, "test_table")
+	if !strings.Contains(sql, "LOAD inet;") {
+		t.Errorf("Generated SQL does not load inet extension:\n%s", sql)
+	}
+	if !strings.Contains(sql, "AS INET) AS client_ip,\n") || !strings.Contains(sql, "AS INET) AS target_ip,\n") {
+		t.Errorf("Generated SQL does not cast IP columns to INET:\n%s", sql)
+	}
+	if strings.Index(sql, "LOAD inet;") > strings.Index(sql, "CREATE TABLE") {
+		t.Errorf("inet extension should be loaded before creating table:\n%s", sql)
+	}
+}
+
+func TestGenerateInstalledExtensionsSQL(t *testing.T) {
+	sql := NewSQLGenerator().GenerateInstalledExtensionsSQL([]string{"inet"})
+	if !strings.Contains(sql, "FROM duckdb_extensions()") || !strings.Contains(sql, "extension_name IN ('inet')") {
+		t.Errorf("Unexpected SQL:\n%s", sql)
+	}
+}
+
+func TestGenerateCreateTableSQL_NullSentinels(t *testing.T) {
+	paths := []string{"s3://bucket/path/*.log.gz"}
+
+	// デフォルトではセンチネル値をNULLに変換する
+	sql := NewSQLGenerator().GenerateCreateTableSQL("test_table", paths)
+	expected := []string{
+		"REPLACE (\n",
+		"nullif(request_processing_time, -1) AS request_processing_time",
+		"nullif(target_processing_time, -1) AS target_processing_time",
+		"nullif(response_processing_time, -1) AS response_processing_time",
+		"TRY_CAST(nullif(target_status_code, '-') AS INTEGER) AS target_status_code",
+		"nullif(ssl_cipher, '-') AS ssl_cipher",
+	}
+	for _, e := range expected {
+		if !strings.Contains(sql, e) {
+			t.Errorf("Generated SQL does not contain %q:\n%s", e, sql)
+		}
+	}
+
+	// --raw の場合は変換しない
+	sql = NewSQLGeneratorWithConfig(Config{Raw: true}).GenerateCreateTableSQL("test_table", paths)
+	if strings.Contains(sql, "REPLACE") {
+		t.Errorf("Generated SQL should not convert sentinel values:\n%s", sql)
+	}
+}
+
+func TestGenerateCompleteSQL_QuotesInput(t *testing.T) {
+	generator := NewSQLGenerator()
+	sql := generator.GenerateCompleteSQL([]string{"s3://bucket/it's/*.log.gz"}, "x; DROP TABLE y")
+
+	// パスは文字列リテラル、テーブル名は識別子としてエスケープする
+	if !strings.Contains(sql, "'s3://bucket/it''s/*.log.gz'") {
+		t.Errorf("Expected path to be quoted as literal:\n%s", sql)
+	}
+	if !strings.Contains(sql, `CREATE TABLE "x; DROP TABLE y" AS`) {
+		t.Errorf("Expected table name to be quoted as identifier:\n%s", sql)
+	}
+}
+
+// FuzzGenerateCompleteSQL はパスとテーブル名によらず、生成するSQLの文の構成が変わらないことを確認します
+func FuzzGenerateCompleteSQL(f *testing.F) {
+	f.Add("s3://bucket/AWSLogs/*.log.gz", "alb_logs")
+	f.Add("s3://bucket/it's/*.log.gz", "x; DROP TABLE y")
+	f.Add("./logs/a'; DROP TABLE t; --.log", `a"b`)
+	f.Add("/tmp/logs/*.log'\n.shell ls\n'", "select")
+	f.Add("./logs/*/*.log /* '", `"; COPY t TO 'x'; --`)
+	f.Add("", "")
+
+	f.Fuzz(func(t *testing.T, path string, tableName string) {
+		generator := NewSQLGenerator()
+		sql := generator.GenerateCompleteSQL([]string{path}, tableName)
+		items, err := parseScript(sql)
+		if err != nil {
+			t.Fatalf("Generated SQL could not be parsed: %v\n%s", err, sql)
+		}
+
+		// 同じ種類の無害なパスとテーブル名で生成したSQLと文の数が一致する
+		benignPath := "./logs/app.log"
+		if source.IsRemote(path) {
+			benignPath = "s3://bucket/app.log"
+		}
+		expected, _ := parseScript(generator.GenerateCompleteSQL([]string{benignPath}, "alb_logs"))
+		if len(items) != len(expected) {
+			t.Fatalf("Expected %d statements, got %d:\n%s", len(expected), len(items), sql)
+		}
+		for _, item := range items {
+			if item.Command != "" || !strings.HasSuffix(item.SQL, ";") {
+				t.Fatalf("Unexpected script item %q:\n%s", item.String(), sql)
+			}
+		}
+
+		if !strings.Contains(sql, QuoteLiteral(path)) {
+			t.Errorf("Expected path to be quoted as literal:\n%s", sql)
+		}
+		if tableName != "" && !strings.Contains(sql, fmt.Sprintf("CREATE TABLE %s AS", Identifier(tableName))) {
+			t.Errorf("Expected table name to be quoted as identifier:\n%s", sql)
+		}
+	})
+}
@@ -0,0 +1,148 @@
+package mcp
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// readOnlyKeywords は run_query で実行できる文の先頭のキーワードです
+// 値は結果の行数を制限するためにサブクエリで囲めるかどうかです
+var readOnlyKeywords = map[string]bool{
+	"SELECT":    true,
+	"WITH":      true,
+	"FROM":      true,
+	"VALUES":    true,
+	"TABLE":     true,
+	"PIVOT":     true,
+	"UNPIVOT":   true,
+	"DESCRIBE":  false,
+	"SHOW":      false,
+	"SUMMARIZE": false,
+	"EXPLAIN":   false,
+}
+
+// readOnlyStatement は実行を許可した1つの読み取り専用の文です
+type readOnlyStatement struct {
+	// SQL は末尾のセミコロンを除いた文です
+	SQL string
+	// Keyword は文の先頭のキーワードです (大文字)
+	Keyword string
+}
+
+// limitable は結果の行数をサブクエリで制限できるかどうかを返します
+func (s readOnlyStatement) limitable() bool {
+	return readOnlyKeywords[s.Keyword]
+}
+
+// withLimit は結果を limit 行までに制限した文を返します
+// 文の末尾が行コメントの場合でも閉じ括弧が無効にならないように改行で区切ります
+func (s readOnlyStatement) withLimit(limit int) string {
+	if !s.limitable() {
+		return s.SQL
+	}
+	return fmt.Sprintf("SELECT *\nFROM (\n%s\n) AS dalv_result\nLIMIT %d", s.SQL, limit)
+}
+
+// checkReadOnly はクエリが1つの読み取り専用の文であることを検証します
+// データベースも読み取り専用で開くため、これは誤った操作を実行前に分かりやすく伝えるための検証です
+func checkReadOnly(query string) (readOnlyStatement, error) {
+	statements, err := splitStatements(query)
+	if err != nil {
+		return readOnlyStatement{}, err
+	}
+	switch len(statements) {
+	case 0:
+		return readOnlyStatement{}, fmt.Errorf("SQLが指定されていません")
+	case 1:
+	default:
+		return readOnlyStatement{}, fmt.Errorf("実行できる文は1つだけです (%d 個の文が指定されました)", len(statements))
+	}
+
+	keyword := strings.ToUpper(firstKeyword(statements[0]))
+	if _, ok := readOnlyKeywords[keyword]; !ok {
+		return readOnlyStatement{}, fmt.Errorf("読み取り専用のクエリ (SELECT, WITH, FROM, DESCRIBE, SUMMARIZE など) のみ実行できます: %s", keyword)
+	}
+	return readOnlyStatement{SQL: statements[0], Keyword: keyword}, nil
+}
+
+// splitStatements はクエリを文字列・引用符付きの識別子・コメントの外にあるセミコロンで分割します
+// コメントや空白のみの文は除きます
+func splitStatements(query string) ([]string, error) {
+	var statements []string
+	start := 0
+	hasContent := false
+	for i := 0; i < len(query); i++ {
+		switch c := query[i]; {
+		case c == '\'' || c == '"':
+			// '' や "" は引用符のエスケープ
+			j := i + 1
+			for {
+				k := strings.IndexByte(query[j:], c)
+				if k < 0 {
+					return nil, fmt.Errorf("引用符 %c が閉じられていません", c)
+				}
+				j += k + 1
+				if j >= len(query) || query[j] != c {
+					break
+				}
+				j++
+			}
+			i = j - 1
+			hasContent = true
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				i = len(query)
+			} else {
+				i += end
+			}
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("コメント /* が閉じられていません")
+			}
+			i += 2 + end + 1
+		case c == ';':
+			if hasContent {
+				statements = append(statements, strings.TrimSpace(query[start:i]))
+			}
+			start = i + 1
+			hasContent = false
+		case !unicode.IsSpace(rune(c)):
+			hasContent = true
+		}
+	}
+	if hasContent {
+		statements = append(statements, strings.TrimSpace(query[start:]))
+	}
+	return statements, nil
+}
+
+// firstKeyword は文の先頭の空白・コメント・開き括弧を除いた最初の単語を返します
+func firstKeyword(statement string) string {
+	s := statement
+	for {
+		s = strings.TrimLeftFunc(s, func(r rune) bool { return unicode.IsSpace(r) || r == '(' })
+		switch {
+		case strings.HasPrefix(s, "--"):
+			end := strings.IndexByte(s, '\n')
+			if end < 0 {
+				return ""
+			}
+			s = s[end:]
+		case strings.HasPrefix(s, "/*"):
+			end := strings.Index(s, "*/")
+			if end < 0 {
+				return ""
+			}
+			s = s[end+2:]
+		default:
+			end := strings.IndexFunc(s, func(r rune) bool { return !unicode.IsLetter(r) && r != '_' })
+			if end < 0 {
+				return s
+			}
+			return s[:end]
+		}
+	}
+}
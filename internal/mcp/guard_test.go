@@ -0,0 +1,90 @@
+package mcp
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitStatements(t *testing.T) {
+	testCases := []struct {
+		query    string
+		expected []string
+	}{
+		{"SELECT 1", []string{"SELECT 1"}},
+		{"SELECT 1;", []string{"SELECT 1"}},
+		{"SELECT 1; SELECT 2;", []string{"SELECT 1", "SELECT 2"}},
+		// 文字列・識別子・コメントの中のセミコロンでは分割しない
+		{"SELECT ';' AS \"a;b\" -- c;\nFROM t", []string{"SELECT ';' AS \"a;b\" -- c;\nFROM t"}},
+		{"SELECT 'it''s;' /* ; */", []string{"SELECT 'it''s;' /* ; */"}},
+		// コメントや空白のみの文は除く
+		{"SELECT 1; -- comment\n ;  ", []string{"SELECT 1"}},
+		{"  ", nil},
+	}
+	for _, tc := range testCases {
+		statements, err := splitStatements(tc.query)
+		if err != nil {
+			t.Errorf("splitStatements(%q) returned error: %v", tc.query, err)
+			continue
+		}
+		if !reflect.DeepEqual(statements, tc.expected) {
+			t.Errorf("splitStatements(%q) = %q, expected %q", tc.query, statements, tc.expected)
+		}
+	}
+
+	for _, query := range []string{"SELECT 'abc", "SELECT \"abc", "SELECT 1 /* comment"} {
+		if _, err := splitStatements(query); err == nil {
+			t.Errorf("splitStatements(%q) should return error", query)
+		}
+	}
+}
+
+func TestCheckReadOnly(t *testing.T) {
+	allowed := map[string]string{
+		"SELECT count(*) FROM alb_logs;":               "SELECT",
+		"-- 5xx\nwith x AS (SELECT 1) SELECT * FROM x": "WITH",
+		"FROM alb_logs LIMIT 1":                        "FROM",
+		"(SELECT 1) UNION ALL (SELECT 2)":              "SELECT",
+		"/* c */ DESCRIBE alb_logs":                    "DESCRIBE",
+		"SUMMARIZE alb_logs":                           "SUMMARIZE",
+	}
+	for query, keyword := range allowed {
+		statement, err := checkReadOnly(query)
+		if err != nil {
+			t.Errorf("checkReadOnly(%q) returned error: %v", query, err)
+			continue
+		}
+		if statement.Keyword != keyword {
+			t.Errorf("checkReadOnly(%q) keyword = %s, expected %s", query, statement.Keyword, keyword)
+		}
+	}
+
+	rejected := []string{
+		"",
+		"DROP TABLE alb_logs",
+		"INSERT INTO alb_logs SELECT * FROM alb_logs",
+		"COPY alb_logs TO 'out.csv'",
+		"ATTACH 'other.duckdb'",
+		"SET enable_external_access = true",
+		"SELECT 1; DROP TABLE alb_logs",
+	}
+	for _, query := range rejected {
+		if _, err := checkReadOnly(query); err == nil {
+			t.Errorf("checkReadOnly(%q) should return error", query)
+		}
+	}
+}
+
+func TestWithLimit(t *testing.T) {
+	statement, _ := checkReadOnly("SELECT * FROM alb_logs -- all rows")
+	sql := statement.withLimit(101)
+	if !strings.Contains(sql, "FROM (\nSELECT * FROM alb_logs -- all rows\n) AS dalv_result\nLIMIT 101") {
+		t.Errorf("Unexpected limited SQL:\n%s", sql)
+	}
+
+	// サブクエリにできない文はそのまま実行する
+	statement, _ = checkReadOnly("DESCRIBE alb_logs")
+	if sql := statement.withLimit(101); sql != "DESCRIBE alb_logs" {
+		t.Errorf("Expected DESCRIBE to be unchanged, got %q", sql)
+	}
+}
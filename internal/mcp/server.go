@@ -0,0 +1,208 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/naotama2002/dalv/internal/duckdb"
+	"github.com/naotama2002/dalv/internal/schema"
+)
+
+// ProtocolVersion は対応しているMCPの最新のプロトコルバージョンです
+const ProtocolVersion = "2025-06-18"
+
+// supportedProtocolVersions は対応しているMCPのプロトコルバージョンです (新しい順)
+var supportedProtocolVersions = []string{ProtocolVersion, "2025-03-26", "2024-11-05"}
+
+// maxMessageBytes は受け付けるメッセージ (1行) の最大サイズです
+const maxMessageBytes = 10 << 20
+
+// JSON-RPCのエラーコードです
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+// Database はツールからクエリを実行するデータベースです
+type Database interface {
+	// TableName はログを読み込んだテーブル名を返します
+	TableName() string
+	// Query はクエリを読み取り専用で実行し、結果を w に書き込みます
+	Query(ctx context.Context, query string, format duckdb.OutputFormat, w io.Writer) error
+	// Describe はクエリの結果のカラム名と型を返します
+	Describe(ctx context.Context, query string) ([]duckdb.ResultColumn, error)
+}
+
+// Config はMCPサーバーの設定です
+type Config struct {
+	// Database はクエリを実行するデータベースです
+	Database Database
+	// Generator はログ形式に合わせたエラーの概要のSQLを生成します
+	Generator *duckdb.SQLGenerator
+	// MaxRows はツールが返す結果の行数の上限です
+	MaxRows int
+	// Timeout は1回のツールの呼び出しの実行時間の上限です (0の場合は制限なし)
+	Timeout time.Duration
+	// Version はサーバーのバージョンです
+	Version string
+}
+
+// Server は標準入出力でMCP (Model Context Protocol) を話すサーバーです
+// ログを読み込んだテーブルを調査するツールを提供します
+type Server struct {
+	db        Database
+	generator *duckdb.SQLGenerator
+	format    *schema.Format
+	maxRows   int
+	timeout   time.Duration
+	version   string
+	tools     []tool
+}
+
+// request はJSON-RPCのリクエストまたは通知です
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// response はJSON-RPCのレスポンスです
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+// rpcError はJSON-RPCのエラーです
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// NewServer は新しいMCPサーバーを作成します
+func NewServer(cfg Config) *Server {
+	s := &Server{
+		db:        cfg.Database,
+		generator: cfg.Generator,
+		format:    cfg.Generator.Format(),
+		maxRows:   cfg.MaxRows,
+		timeout:   cfg.Timeout,
+		version:   cfg.Version,
+	}
+	s.tools = s.newTools()
+	return s
+}
+
+// Serve は r から1行に1つのJSON-RPCメッセージを読み込み、レスポンスを w に書き込みます
+// r が終端に達した場合は nil を返します
+func (s *Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxMessageBytes)
+	encoder := json.NewEncoder(w)
+
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		resp := s.handleMessage(ctx, line)
+		if resp == nil {
+			continue
+		}
+		if err := encoder.Encode(resp); err != nil {
+			return fmt.Errorf("レスポンスの書き込みに失敗しました: %w", err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("リクエストの読み込みに失敗しました: %w", err)
+	}
+	return nil
+}
+
+// handleMessage は1つのメッセージを処理し、レスポンスを返します
+// 通知の場合は nil を返します
+func (s *Server) handleMessage(ctx context.Context, line []byte) *response {
+	var req request
+	if err := json.Unmarshal(line, &req); err != nil {
+		return errorResponse(json.RawMessage("null"), codeParseError, fmt.Sprintf("JSON-RPCのメッセージの形式が不正です: %v", err))
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		return errorResponse(idOrNull(req.ID), codeInvalidRequest, "JSON-RPC 2.0のリクエストではありません")
+	}
+
+	// 通知 (notifications/initialized など) には応答しない
+	if len(req.ID) == 0 {
+		return nil
+	}
+
+	var result any
+	var rpcErr *rpcError
+	switch req.Method {
+	case "initialize":
+		result, rpcErr = s.initialize(req.Params)
+	case "ping":
+		result = struct{}{}
+	case "tools/list":
+		result = map[string]any{"tools": s.toolDefinitions()}
+	case "tools/call":
+		result, rpcErr = s.callTool(ctx, req.Params)
+	default:
+		rpcErr = &rpcError{Code: codeMethodNotFound, Message: fmt.Sprintf("未対応のメソッドです: %s", req.Method)}
+	}
+	if rpcErr != nil {
+		return &response{JSONRPC: "2.0", ID: req.ID, Error: rpcErr}
+	}
+	return &response{JSONRPC: "2.0", ID: req.ID, Result: result}
+}
+
+// initialize はクライアントとプロトコルバージョンを合意し、サーバーの情報を返します
+// クライアントが要求したバージョンに対応していない場合は対応している最新のバージョンを返します
+func (s *Server) initialize(params json.RawMessage) (any, *rpcError) {
+	var p struct {
+		ProtocolVersion string `json:"protocolVersion"`
+	}
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, &rpcError{Code: codeInvalidParams, Message: fmt.Sprintf("initialize のパラメータの形式が不正です: %v", err)}
+		}
+	}
+	version := ProtocolVersion
+	if slices.Contains(supportedProtocolVersions, p.ProtocolVersion) {
+		version = p.ProtocolVersion
+	}
+
+	return map[string]any{
+		"protocolVersion": version,
+		"capabilities": map[string]any{
+			"tools": map[string]any{},
+		},
+		"serverInfo": map[string]any{
+			"name":    "dalv",
+			"version": s.version,
+		},
+		"instructions": fmt.Sprintf("%sを読み込んだDuckDBのテーブル %s を読み取り専用のSQLで調査できます。まず describe_schema でカラムを確認してください。",
+			s.format.Description, s.db.TableName()),
+	}, nil
+}
+
+// errorResponse はエラーのレスポンスを返します
+func errorResponse(id json.RawMessage, code int, message string) *response {
+	return &response{JSONRPC: "2.0", ID: id, Error: &rpcError{Code: code, Message: message}}
+}
+
+// idOrNull はリクエストのIDを返します (IDがない場合は null)
+func idOrNull(id json.RawMessage) json.RawMessage {
+	if len(id) == 0 {
+		return json.RawMessage("null")
+	}
+	return id
+}
@@ -0,0 +1,261 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/naotama2002/dalv/internal/duckdb"
+	"github.com/naotama2002/dalv/internal/schema"
+)
+
+// fakeDatabase は受け取ったクエリを記録し、決まった結果を返すデータベースです
+type fakeDatabase struct {
+	queries []string
+	output  string
+	columns []duckdb.ResultColumn
+	err     error
+}
+
+func (f *fakeDatabase) TableName() string {
+	return "alb_logs"
+}
+
+func (f *fakeDatabase) Query(ctx context.Context, query string, format duckdb.OutputFormat, w io.Writer) error {
+	f.queries = append(f.queries, query)
+	if format != duckdb.OutputJSON {
+		return fmt.Errorf("unexpected format: %s", format)
+	}
+	if f.err != nil {
+		return f.err
+	}
+	io.WriteString(w, f.output)
+	return nil
+}
+
+func (f *fakeDatabase) Describe(ctx context.Context, query string) ([]duckdb.ResultColumn, error) {
+	f.queries = append(f.queries, "DESCRIBE "+query)
+	return f.columns, f.err
+}
+
+func newTestServer(db *fakeDatabase) *Server {
+	return NewServer(Config{
+		Database:  db,
+		Generator: duckdb.NewSQLGeneratorWithConfig(duckdb.Config{Format: schema.ALB}),
+		MaxRows:   2,
+		Timeout:   time.Minute,
+		Version:   "test",
+	})
+}
+
+// serve はメッセージを1行ずつ送り、受け取ったレスポンスを返します
+func serve(t *testing.T, s *Server, messages ...string) []map[string]any {
+	t.Helper()
+	var out bytes.Buffer
+	if err := s.Serve(context.Background(), strings.NewReader(strings.Join(messages, "\n")+"\n"), &out); err != nil {
+		t.Fatalf("Serve returned error: %v", err)
+	}
+
+	var responses []map[string]any
+	decoder := json.NewDecoder(&out)
+	for decoder.More() {
+		var resp map[string]any
+		if err := decoder.Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		responses = append(responses, resp)
+	}
+	return responses
+}
+
+// callTool は tools/call を送り、結果のテキストと isError を返します
+func callTool(t *testing.T, s *Server, name string, arguments string) (string, bool) {
+	t.Helper()
+	responses := serve(t, s, fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":%q,"arguments":%s}}`, name, arguments))
+	if len(responses) != 1 {
+		t.Fatalf("Expected 1 response, got %d", len(responses))
+	}
+	result, ok := responses[0]["result"].(map[string]any)
+	if !ok {
+		t.Fatalf("Expected result, got %v", responses[0])
+	}
+	content := result["content"].([]any)[0].(map[string]any)
+	return content["text"].(string), result["isError"].(bool)
+}
+
+func TestInitialize(t *testing.T) {
+	s := newTestServer(&fakeDatabase{})
+	responses := serve(t, s,
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{},"clientInfo":{"name":"test","version":"1"}}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"ping"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"initialize","params":{"protocolVersion":"1999-01-01"}}`,
+	)
+
+	// 通知には応答しない
+	if len(responses) != 3 {
+		t.Fatalf("Expected 3 responses, got %d", len(responses))
+	}
+	result := responses[0]["result"].(map[string]any)
+	if result["protocolVersion"] != "2024-11-05" {
+		t.Errorf("Expected requested protocol version, got %v", result["protocolVersion"])
+	}
+	if _, ok := result["capabilities"].(map[string]any)["tools"]; !ok {
+		t.Errorf("Expected tools capability, got %v", result["capabilities"])
+	}
+	if responses[1]["id"] != float64(2) || responses[1]["result"] == nil {
+		t.Errorf("Unexpected ping response: %v", responses[1])
+	}
+	// 対応していないバージョンの場合は最新のバージョンを返す
+	if v := responses[2]["result"].(map[string]any)["protocolVersion"]; v != ProtocolVersion {
+		t.Errorf("Expected latest protocol version, got %v", v)
+	}
+}
+
+func TestJSONRPCErrors(t *testing.T) {
+	s := newTestServer(&fakeDatabase{})
+	responses := serve(t, s,
+		`{not json`,
+		`{"jsonrpc":"2.0","id":1,"method":"resources/list"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"drop_table"}}`,
+		`{"id":3,"method":"ping"}`,
+	)
+
+	expected := []float64{codeParseError, codeMethodNotFound, codeInvalidParams, codeInvalidRequest}
+	if len(responses) != len(expected) {
+		t.Fatalf("Expected %d responses, got %d", len(expected), len(responses))
+	}
+	for i, code := range expected {
+		rpcErr, ok := responses[i]["error"].(map[string]any)
+		if !ok || rpcErr["code"] != code {
+			t.Errorf("Expected error code %v for response %d, got %v", code, i, responses[i])
+		}
+	}
+}
+
+func TestToolsList(t *testing.T) {
+	s := newTestServer(&fakeDatabase{})
+	responses := serve(t, s, `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`)
+
+	tools := responses[0]["result"].(map[string]any)["tools"].([]any)
+	var names []string
+	for _, tl := range tools {
+		tool := tl.(map[string]any)
+		names = append(names, tool["name"].(string))
+		if tool["inputSchema"].(map[string]any)["type"] != "object" {
+			t.Errorf("Expected object input schema for %s", tool["name"])
+		}
+	}
+	if strings.Join(names, ",") != "list_tables,describe_schema,run_query,summarize_errors" {
+		t.Errorf("Unexpected tools: %v", names)
+	}
+}
+
+func TestRunQuery(t *testing.T) {
+	db := &fakeDatabase{output: `[{"status":502,"n":3},
+{"status":503,"n":2},
+{"status":504,"n":1}]`}
+	s := newTestServer(db)
+
+	text, isError := callTool(t, s, "run_query", `{"sql":"SELECT elb_status_code AS status, count(*) AS n FROM alb_logs GROUP BY 1;"}`)
+	if isError {
+		t.Fatalf("run_query returned error: %s", text)
+	}
+	// 上限を超えたかを判定するために1行多く取得する
+	if !strings.Contains(db.queries[0], "\n) AS dalv_result\nLIMIT 3") {
+		t.Errorf("Expected query to be limited, got:\n%s", db.queries[0])
+	}
+	if !strings.HasPrefix(text, "[\n  {\"status\":502,\"n\":3},\n  {\"status\":503,\"n\":2}\n]") || !strings.Contains(text, "先頭の 2 行") {
+		t.Errorf("Unexpected result text:\n%s", text)
+	}
+
+	// max_rows は上限より小さい場合のみ適用する
+	callTool(t, s, "run_query", `{"sql":"SELECT 1","max_rows":1}`)
+	if !strings.HasSuffix(db.queries[1], "LIMIT 2") {
+		t.Errorf("Expected max_rows to be applied, got:\n%s", db.queries[1])
+	}
+}
+
+func TestRunQueryRejected(t *testing.T) {
+	db := &fakeDatabase{}
+	s := newTestServer(db)
+
+	for _, args := range []string{
+		`{"sql":"DELETE FROM alb_logs"}`,
+		`{"sql":"SELECT 1; DROP TABLE alb_logs"}`,
+		`{"query":"SELECT 1"}`,
+	} {
+		if _, isError := callTool(t, s, "run_query", args); !isError {
+			t.Errorf("run_query(%s) should return tool error", args)
+		}
+	}
+	if len(db.queries) != 0 {
+		t.Errorf("Rejected queries should not be executed: %v", db.queries)
+	}
+
+	// DuckDBのエラーはツールのエラーとして返す
+	db.err = &duckdb.QueryError{Message: "Binder Error: column not found"}
+	text, isError := callTool(t, s, "run_query", `{"sql":"SELECT foo FROM alb_logs"}`)
+	if !isError || text != "Binder Error: column not found" {
+		t.Errorf("Expected query error, got %v %q", isError, text)
+	}
+}
+
+func TestDescribeSchema(t *testing.T) {
+	db := &fakeDatabase{columns: []duckdb.ResultColumn{{Name: "elb_status_code", Type: "INTEGER"}, {Name: "url_path", Type: "VARCHAR"}}}
+	s := newTestServer(db)
+
+	text, isError := callTool(t, s, "describe_schema", `{}`)
+	if isError {
+		t.Fatalf("describe_schema returned error: %s", text)
+	}
+	if db.queries[0] != "DESCRIBE alb_logs" {
+		t.Errorf("Expected default table, got %q", db.queries[0])
+	}
+	var result struct {
+		Columns []struct {
+			Name    string `json:"name"`
+			Derived bool   `json:"derived"`
+		} `json:"columns"`
+	}
+	if err := json.Unmarshal([]byte(text), &result); err != nil {
+		t.Fatalf("Failed to parse describe_schema result: %v", err)
+	}
+	if len(result.Columns) != 2 || result.Columns[0].Derived || !result.Columns[1].Derived {
+		t.Errorf("Unexpected columns: %+v", result.Columns)
+	}
+
+	if _, isError := callTool(t, s, "describe_schema", `{"table":"alb_logs; DROP TABLE alb_logs"}`); !isError {
+		t.Error("describe_schema should reject invalid table name")
+	}
+}
+
+func TestSummarizeErrors(t *testing.T) {
+	db := &fakeDatabase{output: `[{"status_code":502,"requests":1}]`}
+	s := newTestServer(db)
+
+	text, isError := callTool(t, s, "summarize_errors", `{"interval":"1m"}`)
+	if isError {
+		t.Fatalf("summarize_errors returned error: %s", text)
+	}
+	if len(db.queries) != 3 {
+		t.Fatalf("Expected 3 queries, got %d", len(db.queries))
+	}
+	if !strings.Contains(db.queries[1], "INTERVAL '60 seconds'") || !strings.Contains(db.queries[0], "LIMIT 2;") {
+		t.Errorf("Expected interval and limit to be applied, got:\n%s\n%s", db.queries[0], db.queries[1])
+	}
+	for _, title := range []string{"## ステータスコード別のエラー数", "## エラーが多い時間帯", "## エラーが多いパス"} {
+		if !strings.Contains(text, title) {
+			t.Errorf("Expected result to contain %q, got:\n%s", title, text)
+		}
+	}
+
+	if _, isError := callTool(t, s, "summarize_errors", `{"interval":"soon"}`); !isError {
+		t.Error("summarize_errors should reject invalid interval")
+	}
+}
@@ -0,0 +1,327 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/naotama2002/dalv/internal/duckdb"
+)
+
+// identifierPattern は describe_schema で指定できるテーブル名の形式です
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// defaultErrorInterval は summarize_errors でエラーが多い時間帯を集計するデフォルトの間隔です
+const defaultErrorInterval = 5 * time.Minute
+
+// defaultErrorLimit は summarize_errors の各集計のデフォルトの行数の上限です
+const defaultErrorLimit = 10
+
+// tool はMCPのツールです
+type tool struct {
+	// Name はツール名です
+	Name string `json:"name"`
+	// Description はツールの説明です
+	Description string `json:"description"`
+	// InputSchema は引数のJSON Schemaです
+	InputSchema map[string]any `json:"inputSchema"`
+	// handler はツールを実行し、結果のテキストを返します
+	handler func(ctx context.Context, arguments json.RawMessage) (string, error)
+}
+
+// toolResult はツールの実行結果です
+type toolResult struct {
+	Content []textContent `json:"content"`
+	IsError bool          `json:"isError"`
+}
+
+// textContent はツールの実行結果のテキストです
+type textContent struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// newTools はサーバーが提供するツールを作成します
+func (s *Server) newTools() []tool {
+	return []tool{
+		{
+			Name:        "list_tables",
+			Description: "読み込んだログのテーブルの一覧と行数・カラム数を返します。",
+			InputSchema: objectSchema(nil, nil),
+			handler:     s.listTables,
+		},
+		{
+			Name:        "describe_schema",
+			Description: "テーブルのカラム名と型を返します。派生カラム (リクエストやIP:ポートを分解したカラム) には derived: true が付きます。",
+			InputSchema: objectSchema(map[string]any{
+				"table": map[string]any{"type": "string", "description": "テーブル名 (省略時はログを読み込んだテーブル)"},
+			}, nil),
+			handler: s.describeSchema,
+		},
+		{
+			Name: "run_query",
+			Description: fmt.Sprintf("DuckDBの読み取り専用のSQLを1つ実行し、結果をJSONで返します。SELECT, WITH, FROM, DESCRIBE, SUMMARIZE などのみ実行でき、結果は最大 %d 行です。"+
+				"ファイルやネットワークにはアクセスできません。", s.maxRows),
+			InputSchema: objectSchema(map[string]any{
+				"sql":      map[string]any{"type": "string", "description": "実行するSQL"},
+				"max_rows": map[string]any{"type": "integer", "description": fmt.Sprintf("返す行数の上限 (最大 %d)", s.maxRows), "minimum": 1},
+			}, []string{"sql"}),
+			handler: s.runQuery,
+		},
+		{
+			Name:        "summarize_errors",
+			Description: "ステータスコードが4xx/5xxのリクエストについて、ステータスコード別の件数、エラーが多い時間帯、エラーが多いパスを集計します。",
+			InputSchema: objectSchema(map[string]any{
+				"interval": map[string]any{"type": "string", "description": "エラーが多い時間帯を集計する間隔 (例: 1m, 5m, 1h。デフォルト: 5m)"},
+				"limit":    map[string]any{"type": "integer", "description": "各集計の行数の上限 (デフォルト: 10)", "minimum": 1},
+			}, nil),
+			handler: s.summarizeErrors,
+		},
+	}
+}
+
+// objectSchema はオブジェクトの引数のJSON Schemaを返します
+func objectSchema(properties map[string]any, required []string) map[string]any {
+	if properties == nil {
+		properties = map[string]any{}
+	}
+	schema := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// toolDefinitions は tools/list で返すツールの定義です
+func (s *Server) toolDefinitions() []tool {
+	return s.tools
+}
+
+// callTool はツールを実行します
+// ツールの実行に失敗した場合は isError を設定した結果を返します
+func (s *Server) callTool(ctx context.Context, params json.RawMessage) (any, *rpcError) {
+	var p struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, &rpcError{Code: codeInvalidParams, Message: fmt.Sprintf("tools/call のパラメータの形式が不正です: %v", err)}
+	}
+
+	var t *tool
+	for i := range s.tools {
+		if s.tools[i].Name == p.Name {
+			t = &s.tools[i]
+		}
+	}
+	if t == nil {
+		return nil, &rpcError{Code: codeInvalidParams, Message: fmt.Sprintf("未対応のツールです: %s", p.Name)}
+	}
+
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+
+	text, err := t.handler(ctx, p.Arguments)
+	if err != nil {
+		var queryErr *duckdb.QueryError
+		switch {
+		case errors.Is(err, context.DeadlineExceeded):
+			text = fmt.Sprintf("クエリが %s 以内に完了しませんでした。条件を絞り込んでください", s.timeout)
+		case errors.As(err, &queryErr):
+			text = queryErr.Message
+		default:
+			text = err.Error()
+		}
+		return toolResult{Content: []textContent{{Type: "text", Text: text}}, IsError: true}, nil
+	}
+	return toolResult{Content: []textContent{{Type: "text", Text: text}}}, nil
+}
+
+// decodeArguments はツールの引数を解析します (引数がない場合は何もしません)
+func decodeArguments(arguments json.RawMessage, v any) error {
+	if len(arguments) == 0 || string(arguments) == "null" {
+		return nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(arguments))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("引数の形式が不正です: %w", err)
+	}
+	return nil
+}
+
+// listTables はテーブルの一覧を返します
+func (s *Server) listTables(ctx context.Context, arguments json.RawMessage) (string, error) {
+	rows, _, err := s.queryRows(ctx, `SELECT table_name, estimated_size AS rows, column_count
+FROM duckdb_tables()
+WHERE NOT internal
+ORDER BY table_name`, s.maxRows)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("ログ形式: %s\n%s", s.format.Description, formatRows(rows)), nil
+}
+
+// describeSchema はテーブルのカラム名と型を返します
+func (s *Server) describeSchema(ctx context.Context, arguments json.RawMessage) (string, error) {
+	var args struct {
+		Table string `json:"table"`
+	}
+	if err := decodeArguments(arguments, &args); err != nil {
+		return "", err
+	}
+	if args.Table == "" {
+		args.Table = s.db.TableName()
+	}
+	if !identifierPattern.MatchString(args.Table) {
+		return "", fmt.Errorf("テーブル名の形式が不正です: %s", args.Table)
+	}
+
+	columns, err := s.db.Describe(ctx, duckdb.Identifier(args.Table))
+	if err != nil {
+		return "", err
+	}
+
+	derived := make(map[string]bool)
+	for _, d := range s.format.DerivedColumns {
+		derived[d.Name] = true
+	}
+	type column struct {
+		Name    string `json:"name"`
+		Type    string `json:"type"`
+		Derived bool   `json:"derived,omitempty"`
+	}
+	result := struct {
+		Table   string   `json:"table"`
+		Columns []column `json:"columns"`
+	}{Table: args.Table}
+	for _, c := range columns {
+		result.Columns = append(result.Columns, column{Name: c.Name, Type: c.Type, Derived: derived[c.Name]})
+	}
+
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// runQuery は読み取り専用のSQLを実行し、結果を返します
+func (s *Server) runQuery(ctx context.Context, arguments json.RawMessage) (string, error) {
+	var args struct {
+		SQL     string `json:"sql"`
+		MaxRows int    `json:"max_rows"`
+	}
+	if err := decodeArguments(arguments, &args); err != nil {
+		return "", err
+	}
+	statement, err := checkReadOnly(args.SQL)
+	if err != nil {
+		return "", err
+	}
+
+	limit := s.maxRows
+	if args.MaxRows > 0 && args.MaxRows < limit {
+		limit = args.MaxRows
+	}
+
+	// 上限を超えたかを判定するために1行多く取得する
+	rows, truncated, err := s.queryRows(ctx, statement.withLimit(limit+1), limit)
+	if err != nil {
+		return "", err
+	}
+	text := formatRows(rows)
+	if truncated {
+		text += fmt.Sprintf("\n(結果が多いため先頭の %d 行のみ返しました。集計するか条件を絞り込んでください)", limit)
+	}
+	return text, nil
+}
+
+// summarizeErrors はエラーの概要を集計します
+func (s *Server) summarizeErrors(ctx context.Context, arguments json.RawMessage) (string, error) {
+	var args struct {
+		Interval string `json:"interval"`
+		Limit    int    `json:"limit"`
+	}
+	if err := decodeArguments(arguments, &args); err != nil {
+		return "", err
+	}
+
+	cfg := duckdb.ErrorSummaryConfig{Interval: defaultErrorInterval, Limit: min(defaultErrorLimit, s.maxRows)}
+	if args.Interval != "" {
+		d, err := time.ParseDuration(args.Interval)
+		if err != nil {
+			return "", fmt.Errorf("集計の間隔の形式が不正です: %s (例: 1m, 5m, 1h)", args.Interval)
+		}
+		cfg.Interval = d
+	}
+	if args.Limit > 0 {
+		cfg.Limit = min(args.Limit, s.maxRows)
+	}
+	if err := s.generator.ValidateErrorSummaryConfig(cfg); err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	for i, section := range s.generator.GenerateErrorSummarySQL(s.db.TableName(), cfg) {
+		rows, _, err := s.queryRows(ctx, section.SQL, cfg.Limit)
+		if err != nil {
+			return "", err
+		}
+		if i > 0 {
+			b.WriteString("\n\n")
+		}
+		fmt.Fprintf(&b, "## %s\n%s", section.Title, formatRows(rows))
+	}
+	return b.String(), nil
+}
+
+// queryRows はクエリをJSON形式で実行し、先頭の limit 行を返します
+// limit 行を超えた場合は truncated に true を返します
+func (s *Server) queryRows(ctx context.Context, query string, limit int) (rows []json.RawMessage, truncated bool, err error) {
+	var out bytes.Buffer
+	if err := s.db.Query(ctx, query, duckdb.OutputJSON, &out); err != nil {
+		return nil, false, err
+	}
+	// 結果が0行の場合、DuckDBは何も出力しない
+	if err := json.NewDecoder(&out).Decode(&rows); err != nil && err != io.EOF {
+		return nil, false, fmt.Errorf("クエリ結果の読み込みに失敗しました: %w", err)
+	}
+	if len(rows) > limit {
+		return rows[:limit], true, nil
+	}
+	return rows, false, nil
+}
+
+// formatRows は結果の行を1行に1つのJSONオブジェクトを並べた配列にします
+// カラムの順序を保つため、DuckDBが出力したオブジェクトをそのまま使用します
+func formatRows(rows []json.RawMessage) string {
+	if len(rows) == 0 {
+		return "[]"
+	}
+	var b strings.Builder
+	b.WriteString("[\n")
+	for i, row := range rows {
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, row); err != nil {
+			compact.Write(row)
+		}
+		b.WriteString("  ")
+		b.Write(compact.Bytes())
+		if i < len(rows)-1 {
+			b.WriteString(",")
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("]")
+	return b.String()
+}
@@ -0,0 +1,393 @@
+package report
+
+import (
+	"bufio"
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/naotama2002/dalv/internal/duckdb"
+)
+
+// TableParam はレポートのSQLでテーブル名に置き換えるパラメータです
+const TableParam = "table"
+
+// builtinFS は組み込みレポートのSQLファイルです
+//
+//go:embed reports/*.sql
+var builtinFS embed.FS
+
+// placeholderPattern はレポートのSQL中の {{name}} 形式のパラメータです
+var placeholderPattern = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// quotedPlaceholderPattern は引用符で囲まれた {{name}} 形式のパラメータです
+var quotedPlaceholderPattern = regexp.MustCompile(`['"]\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}['"]`)
+
+// paramNamePattern はパラメータ名として使用できる文字列です
+var paramNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// パラメータの型です
+// 値は型に応じて検証し、SQLに埋め込める形に変換してから置き換えます
+const (
+	// ParamString は文字列リテラル ('...') として置き換えるパラメータです (型を省略した場合)
+	ParamString = "string"
+	// ParamNumber は数値としてそのまま置き換えるパラメータです
+	ParamNumber = "number"
+	// ParamInterval は時間の間隔 (例: 5 minutes) を文字列リテラルとして置き換えるパラメータです (INTERVAL {{name}} のように使用します)
+	ParamInterval = "interval"
+	// ParamIdentifier はカラム名などの識別子として置き換えるパラメータです
+	ParamIdentifier = "identifier"
+)
+
+// ParamTypes は指定できるパラメータの型の一覧です
+var ParamTypes = []string{ParamString, ParamNumber, ParamInterval, ParamIdentifier}
+
+var (
+	// numberPattern は number 型のパラメータとして使用できる値です
+	numberPattern = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+	// intervalPattern は interval 型のパラメータとして使用できる値です (例: 5 minutes, 1 hour 30 minutes)
+	intervalPattern = regexp.MustCompile(`(?i)^[0-9]+\s*(microsecond|millisecond|second|minute|hour|day|week|month|year)s?(\s+[0-9]+\s*(microsecond|millisecond|second|minute|hour|day|week|month|year)s?)*$`)
+)
+
+// Param はレポートのパラメータ定義です
+type Param struct {
+	// Name はパラメータ名です (--param name=value で指定する名前)
+	Name string
+	// Type はパラメータの型です (ParamTypes のいずれか)
+	Type string
+	// Default は指定されなかった場合の値です
+	Default string
+	// Description はパラメータの説明です
+	Description string
+}
+
+// SQL はパラメータの値を検証し、SQLに埋め込む表現を返します
+// 文字列と時間の間隔は文字列リテラルに、識別子は必要に応じて二重引用符で囲みます
+func (p Param) SQL(value string) (string, error) {
+	switch p.Type {
+	case ParamNumber:
+		if !numberPattern.MatchString(value) {
+			return "", fmt.Errorf("パラメータ %s には数値を指定してください: %s", p.Name, value)
+		}
+		return value, nil
+	case ParamInterval:
+		if !intervalPattern.MatchString(strings.TrimSpace(value)) {
+			return "", fmt.Errorf("パラメータ %s には時間の間隔 (例: 5 minutes, 1 hour) を指定してください: %s", p.Name, value)
+		}
+		return duckdb.QuoteLiteral(strings.TrimSpace(value)), nil
+	case ParamIdentifier:
+		return duckdb.Identifier(value), nil
+	default:
+		return duckdb.QuoteLiteral(value), nil
+	}
+}
+
+// Section はレポートの1つのセクションです
+type Section struct {
+	// Title はセクションの見出しです
+	Title string
+	// SQL はセクションに表示する結果を取得するSQLです
+	SQL string
+}
+
+// Report は名前付きのSQLレポートの定義です
+type Report struct {
+	// Name はレポートの識別子です (dalv report <name> で指定する値)
+	Name string
+	// Description はレポートの説明です
+	Description string
+	// Formats はレポートが対応しているログ形式です (空の場合はすべての形式)
+	Formats []string
+	// Params はレポートのパラメータ定義です
+	Params []Param
+	// Sections はレポートのセクションです (表示順)
+	Sections []Section
+	// Source はレポートを定義したファイルです (組み込みレポートの場合は空)
+	Source string
+}
+
+// Supports はレポートがログ形式に対応しているかどうかを返します
+func (r *Report) Supports(format string) bool {
+	if len(r.Formats) == 0 {
+		return true
+	}
+	for _, f := range r.Formats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// Render はパラメータを置き換えたセクションを返します
+// params に指定されていないパラメータは定義のデフォルト値を使用します
+// 値はパラメータの型に応じて検証・引用し、テーブル名は識別子として置き換えます
+func (r *Report) Render(tableName string, params map[string]string) ([]Section, error) {
+	raw := make(map[string]string, len(r.Params))
+	for _, p := range r.Params {
+		raw[p.Name] = p.Default
+	}
+	for name, value := range params {
+		if _, ok := raw[name]; !ok {
+			return nil, fmt.Errorf("レポート %s に未定義のパラメータです: %s", r.Name, name)
+		}
+		raw[name] = value
+	}
+
+	values := map[string]string{TableParam: duckdb.Identifier(tableName)}
+	for _, p := range r.Params {
+		if raw[p.Name] == "" {
+			continue
+		}
+		value, err := p.SQL(raw[p.Name])
+		if err != nil {
+			return nil, fmt.Errorf("レポート %s: %w", r.Name, err)
+		}
+		values[p.Name] = value
+	}
+
+	sections := make([]Section, 0, len(r.Sections))
+	for _, s := range r.Sections {
+		var missing []string
+		sql := placeholderPattern.ReplaceAllStringFunc(s.SQL, func(m string) string {
+			name := placeholderPattern.FindStringSubmatch(m)[1]
+			value, ok := values[name]
+			if !ok || value == "" {
+				missing = append(missing, name)
+				return m
+			}
+			return value
+		})
+		if len(missing) > 0 {
+			return nil, fmt.Errorf("レポート %s のパラメータが指定されていません: %s", r.Name, strings.Join(missing, ", "))
+		}
+		sections = append(sections, Section{Title: s.Title, SQL: sql})
+	}
+	return sections, nil
+}
+
+// Library はレポートの一覧です
+type Library struct {
+	reports map[string]*Report
+}
+
+// NewLibrary は組み込みレポートを含むライブラリを作成します
+func NewLibrary() (*Library, error) {
+	l := &Library{reports: make(map[string]*Report)}
+
+	entries, err := fs.ReadDir(builtinFS, "reports")
+	if err != nil {
+		return nil, fmt.Errorf("組み込みレポートの読み込みに失敗しました: %w", err)
+	}
+	for _, e := range entries {
+		data, err := builtinFS.ReadFile(path.Join("reports", e.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("組み込みレポートの読み込みに失敗しました: %w", err)
+		}
+		r, err := Parse(strings.TrimSuffix(e.Name(), ".sql"), string(data))
+		if err != nil {
+			return nil, err
+		}
+		l.reports[r.Name] = r
+	}
+	return l, nil
+}
+
+// LoadDir はディレクトリ内の *.sql ファイルをユーザー定義のレポートとして追加します
+// 組み込みレポートと同じ名前のレポートは上書きします
+func (l *Library) LoadDir(dir string) error {
+	if _, err := os.Stat(dir); err != nil {
+		return fmt.Errorf("レポートディレクトリの読み込みに失敗しました: %w", err)
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.sql"))
+	if err != nil {
+		return fmt.Errorf("レポートディレクトリの読み込みに失敗しました: %w", err)
+	}
+
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("レポートの読み込みに失敗しました: %w", err)
+		}
+		r, err := Parse(strings.TrimSuffix(filepath.Base(file), ".sql"), string(data))
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		r.Source = file
+		l.reports[r.Name] = r
+	}
+	return nil
+}
+
+// Get は指定した名前のレポートを返します
+func (l *Library) Get(name string) (*Report, error) {
+	r, ok := l.reports[name]
+	if !ok {
+		return nil, fmt.Errorf("未定義のレポートです: %s (dalv report --list で一覧を表示できます)", name)
+	}
+	return r, nil
+}
+
+// Reports は名前順のレポートの一覧を返します
+func (l *Library) Reports() []*Report {
+	reports := make([]*Report, 0, len(l.reports))
+	for _, r := range l.reports {
+		reports = append(reports, r)
+	}
+	sort.Slice(reports, func(i, j int) bool { return reports[i].Name < reports[j].Name })
+	return reports
+}
+
+// Parse はSQLファイルの内容からレポートを解析します
+// 先頭のコメントでメタデータを、-- section: で始まる行でセクションを定義します
+// メタデータは最初のセクションより前のコメントのみで解析し、セクションのSQL中のコメントはSQLに含めます
+//
+//	-- name: top-5xx-paths
+//	-- description: 5xxエラーが多いパス
+//	-- formats: alb
+//	-- param: limit:number=10 表示する件数
+//	-- section: 5xxエラーが多いパス
+//	SELECT ... FROM {{table}} ... LIMIT {{limit}};
+//
+// name を省略した場合は defaultName を使用します
+func Parse(defaultName, content string) (*Report, error) {
+	r := &Report{Name: defaultName}
+
+	var current *Section
+	var body strings.Builder
+	flush := func() {
+		if current != nil {
+			current.SQL = strings.TrimSpace(body.String())
+			r.Sections = append(r.Sections, *current)
+		}
+		body.Reset()
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		key, value, ok := parseDirective(line)
+		if !ok || (current != nil && key != "section") {
+			if current != nil {
+				body.WriteString(line)
+				body.WriteString("\n")
+			} else if trimmed := strings.TrimSpace(line); trimmed != "" && !strings.HasPrefix(trimmed, "--") {
+				return nil, fmt.Errorf("レポート %s: 最初のセクションより前にSQLがあります (-- section: <見出し> の後にSQLを記述してください)", r.Name)
+			}
+			continue
+		}
+
+		switch key {
+		case "name":
+			r.Name = value
+		case "description":
+			r.Description = value
+		case "formats":
+			for _, f := range strings.Split(value, ",") {
+				if f = strings.TrimSpace(f); f != "" {
+					r.Formats = append(r.Formats, f)
+				}
+			}
+		case "param":
+			p, err := parseParam(value)
+			if err != nil {
+				return nil, fmt.Errorf("レポート %s: %w", r.Name, err)
+			}
+			r.Params = append(r.Params, p)
+		case "section":
+			flush()
+			current = &Section{Title: value}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("レポート %s の読み込みに失敗しました: %w", r.Name, err)
+	}
+	flush()
+
+	if r.Name == "" {
+		return nil, fmt.Errorf("レポートの名前が指定されていません")
+	}
+	if len(r.Sections) == 0 {
+		return nil, fmt.Errorf("レポート %s にセクションがありません (-- section: <見出し> の後にSQLを記述してください)", r.Name)
+	}
+	for _, s := range r.Sections {
+		if s.SQL == "" {
+			return nil, fmt.Errorf("レポート %s のセクション %s にSQLがありません", r.Name, s.Title)
+		}
+		// 値は型に応じて引用するため、SQLで引用符で囲むと二重に引用される
+		if m := quotedPlaceholderPattern.FindStringSubmatch(s.SQL); m != nil {
+			return nil, fmt.Errorf("レポート %s のセクション %s: パラメータ %s を引用符で囲まないでください (値は型に応じて引用されます)", r.Name, s.Title, m[1])
+		}
+	}
+	return r, nil
+}
+
+// parseDirective は -- key: value 形式のメタデータの行を解析します
+func parseDirective(line string) (string, string, bool) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(line), "--")
+	if !ok {
+		return "", "", false
+	}
+	key, value, ok := strings.Cut(rest, ":")
+	if !ok {
+		return "", "", false
+	}
+
+	key = strings.TrimSpace(key)
+	switch key {
+	case "name", "description", "formats", "param", "section":
+		return key, strings.TrimSpace(value), true
+	}
+	return "", "", false
+}
+
+// parseParam は name:type=default 説明 形式のパラメータ定義を解析します
+// 型を省略した場合は string 型になります
+// デフォルト値に空白を含める場合は説明を | で区切ります (例: interval:interval=1 minute | 集計の間隔)
+func parseParam(s string) (Param, error) {
+	name, rest, ok := strings.Cut(s, "=")
+	name, typ, typed := strings.Cut(strings.TrimSpace(name), ":")
+	name, typ = strings.TrimSpace(name), strings.TrimSpace(typ)
+	if !ok || !paramNamePattern.MatchString(name) || name == TableParam {
+		return Param{}, fmt.Errorf("パラメータの定義が不正です: %s (例: limit:number=10 表示する件数)", s)
+	}
+	if !typed {
+		typ = ParamString
+	}
+	if !slices.Contains(ParamTypes, typ) {
+		return Param{}, fmt.Errorf("パラメータ %s の型が不正です: %s (%s のいずれかを指定してください)", name, typ, strings.Join(ParamTypes, ", "))
+	}
+
+	var def, desc string
+	if d, description, ok := strings.Cut(rest, "|"); ok {
+		def, desc = d, description
+	} else if d, description, ok := strings.Cut(rest, " "); ok {
+		def, desc = d, description
+	} else {
+		def = rest
+	}
+	p := Param{Name: name, Type: typ, Default: strings.TrimSpace(def), Description: strings.TrimSpace(desc)}
+	if p.Default != "" {
+		if _, err := p.SQL(p.Default); err != nil {
+			return Param{}, fmt.Errorf("デフォルト値が不正です: %w", err)
+		}
+	}
+	return p, nil
+}
+
+// DefaultDir はユーザー定義のレポートを読み込むデフォルトのディレクトリを返します
+// ホームディレクトリを取得できない場合は空文字列を返します
+func DefaultDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "dalv", "reports")
+}
@@ -0,0 +1,265 @@
+package report
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testReport = `-- name: errors
+-- description: エラーの集計
+-- formats: alb, clb
+-- param: limit:number=10 表示する件数
+-- param: interval:interval=1 minute | 集計の間隔
+-- param: status=5xx | ステータスコードの分類
+-- param: column:identifier=elb_status_code 集計するカラム
+-- section: ステータスコード
+SELECT elb_status_code, count(*) FROM {{table}} GROUP BY 1 LIMIT {{limit}};
+-- section: 時間帯
+-- 時間帯ごとのエラー数
+SELECT time_bucket(INTERVAL {{ interval }}, timestamp), {{column}}, {{status}} AS class, count(*)
+FROM {{table}}
+GROUP BY ALL;
+`
+
+func TestParse(t *testing.T) {
+	r, err := Parse("default", testReport)
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+
+	if r.Name != "errors" || r.Description != "エラーの集計" {
+		t.Errorf("Unexpected name or description: %q, %q", r.Name, r.Description)
+	}
+	if strings.Join(r.Formats, ",") != "alb,clb" {
+		t.Errorf("Expected formats to be alb,clb, got %v", r.Formats)
+	}
+
+	expectedParams := []Param{
+		{Name: "limit", Type: ParamNumber, Default: "10", Description: "表示する件数"},
+		{Name: "interval", Type: ParamInterval, Default: "1 minute", Description: "集計の間隔"},
+		// 型を省略した場合は文字列
+		{Name: "status", Type: ParamString, Default: "5xx", Description: "ステータスコードの分類"},
+		{Name: "column", Type: ParamIdentifier, Default: "elb_status_code", Description: "集計するカラム"},
+	}
+	if len(r.Params) != len(expectedParams) {
+		t.Fatalf("Expected %d params, got %+v", len(expectedParams), r.Params)
+	}
+	for i, p := range expectedParams {
+		if r.Params[i] != p {
+			t.Errorf("Expected param %d to be %+v, got %+v", i, p, r.Params[i])
+		}
+	}
+
+	if len(r.Sections) != 2 {
+		t.Fatalf("Expected 2 sections, got %+v", r.Sections)
+	}
+	if r.Sections[0].Title != "ステータスコード" || r.Sections[0].SQL != "SELECT elb_status_code, count(*) FROM {{table}} GROUP BY 1 LIMIT {{limit}};" {
+		t.Errorf("Unexpected first section: %+v", r.Sections[0])
+	}
+	// メタデータ以外のコメントはSQLに含める
+	if !strings.HasPrefix(r.Sections[1].SQL, "-- 時間帯ごとのエラー数\nSELECT") {
+		t.Errorf("Unexpected second section SQL: %q", r.Sections[1].SQL)
+	}
+}
+
+func TestParseDefaultName(t *testing.T) {
+	r, err := Parse("mine", "-- section: 件数\nSELECT count(*) FROM {{table}};\n")
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if r.Name != "mine" || len(r.Formats) != 0 {
+		t.Errorf("Unexpected report: %+v", r)
+	}
+	if !r.Supports("nlb") {
+		t.Error("Report without formats should support all formats")
+	}
+}
+
+func TestParseDirectivesInSection(t *testing.T) {
+	// セクションのSQL中のコメントはメタデータとして解析しない
+	content := "-- name: mine\n-- section: 件数\n-- param: limit:number=10\n-- formats: nlb\nSELECT count(*) FROM {{table}};\n"
+	r, err := Parse("default", content)
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	if len(r.Params) != 0 || len(r.Formats) != 0 {
+		t.Errorf("Expected comments in section not to be parsed as metadata, got %+v", r)
+	}
+	if r.Sections[0].SQL != "-- param: limit:number=10\n-- formats: nlb\nSELECT count(*) FROM {{table}};" {
+		t.Errorf("Expected comments to be kept in SQL, got %q", r.Sections[0].SQL)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	testCases := []string{
+		// セクションなし
+		"-- name: empty\nSELECT 1;",
+		// 最初のセクションより前のSQL
+		"SELECT 1;\n-- section: 件数\nSELECT 2;",
+		// SQLのないセクション
+		"-- section: 件数\n-- section: 時間帯\nSELECT 1;",
+		// パラメータの形式が不正
+		"-- param: limit\n-- section: 件数\nSELECT 1;",
+		// テーブル名はパラメータとして定義できない
+		"-- param: table=t\n-- section: 件数\nSELECT 1;",
+		// 未知の型
+		"-- param: limit:int=10\n-- section: 件数\nSELECT {{limit}};",
+		// デフォルト値が型に合わない
+		"-- param: limit:number=ten\n-- section: 件数\nSELECT {{limit}};",
+		// 値は型に応じて引用するため、SQLで引用符で囲むことはできない
+		"-- param: interval:interval=1 minute\n-- section: 件数\nSELECT INTERVAL '{{interval}}';",
+	}
+
+	for _, content := range testCases {
+		if _, err := Parse("test", content); err == nil {
+			t.Errorf("Parse(%q) should return error", content)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	r, err := Parse("default", testReport)
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+
+	sections, err := r.Render("alb_logs", map[string]string{"interval": "5 minutes"})
+	if err != nil {
+		t.Fatalf("Render returned error: %v", err)
+	}
+	if sections[0].SQL != "SELECT elb_status_code, count(*) FROM alb_logs GROUP BY 1 LIMIT 10;" {
+		t.Errorf("Unexpected rendered SQL: %q", sections[0].SQL)
+	}
+	if !strings.Contains(sections[1].SQL, "INTERVAL '5 minutes'") {
+		t.Errorf("Expected interval param to be replaced, got %q", sections[1].SQL)
+	}
+	if !strings.Contains(sections[1].SQL, "elb_status_code, '5xx' AS class") {
+		t.Errorf("Expected identifier and string params to be replaced, got %q", sections[1].SQL)
+	}
+
+	// 文字列は文字列リテラルに、識別子は必要に応じて二重引用符で囲む
+	sections, err = r.Render("alb logs", map[string]string{"status": "5xx' OR 1=1 --", "column": `elb"status`})
+	if err != nil {
+		t.Fatalf("Render returned error: %v", err)
+	}
+	if !strings.Contains(sections[1].SQL, `"elb""status", '5xx'' OR 1=1 --' AS class`) || !strings.Contains(sections[1].SQL, `FROM "alb logs"`) {
+		t.Errorf("Expected params to be quoted, got %q", sections[1].SQL)
+	}
+
+	// 定義の SQL は変更しない
+	if !strings.Contains(r.Sections[0].SQL, "{{table}}") {
+		t.Errorf("Render should not modify report sections: %q", r.Sections[0].SQL)
+	}
+}
+
+func TestRenderErrors(t *testing.T) {
+	r, err := Parse("test", "-- section: 件数\nSELECT count(*) FROM {{table}} WHERE x = {{value}};\n")
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+
+	// 定義されていないパラメータが使われている
+	if _, err := r.Render("t", nil); err == nil {
+		t.Error("Render should return error for undefined placeholder")
+	}
+	// 定義されていないパラメータを指定
+	if _, err := r.Render("t", map[string]string{"value": "1"}); err == nil {
+		t.Error("Render should return error for unknown param")
+	}
+	// テーブル名はパラメータで上書きできない
+	if _, err := r.Render("t", map[string]string{TableParam: "other"}); err == nil {
+		t.Error("Render should return error for table param")
+	}
+
+	// 型に合わない値はSQLに埋め込まない
+	typed, err := Parse("typed", testReport)
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	for _, params := range []map[string]string{
+		{"limit": "10; DROP TABLE alb_logs"},
+		{"interval": "1 minute', timestamp) --"},
+		{"interval": "5 fortnights"},
+	} {
+		if _, err := typed.Render("alb_logs", params); err == nil {
+			t.Errorf("Render(%v) should return error", params)
+		}
+	}
+}
+
+func TestBuiltinReports(t *testing.T) {
+	library, err := NewLibrary()
+	if err != nil {
+		t.Fatalf("NewLibrary returned error: %v", err)
+	}
+
+	reports := library.Reports()
+	if len(reports) == 0 {
+		t.Fatal("Expected builtin reports")
+	}
+	for _, r := range reports {
+		if r.Description == "" {
+			t.Errorf("Builtin report %s should have description", r.Name)
+		}
+		// デフォルト値だけで実行できる
+		sections, err := r.Render("alb_logs", nil)
+		if err != nil {
+			t.Errorf("Render(%s) returned error: %v", r.Name, err)
+			continue
+		}
+		for _, s := range sections {
+			if strings.Contains(s.SQL, "{{") || !strings.Contains(s.SQL, "alb_logs") {
+				t.Errorf("Unexpected rendered SQL for %s/%s: %q", r.Name, s.Title, s.SQL)
+			}
+		}
+	}
+
+	if _, err := library.Get("top-5xx-paths"); err != nil {
+		t.Errorf("Get(top-5xx-paths) returned error: %v", err)
+	}
+	if _, err := library.Get("nosuch"); err == nil {
+		t.Error("Get(nosuch) should return error")
+	}
+}
+
+func TestLoadDir(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"mine.sql":     "-- description: 自作のレポート\n-- section: 件数\nSELECT count(*) FROM {{table}};\n",
+		"overview.sql": "-- description: 上書きした概要\n-- section: 件数\nSELECT count(*) FROM {{table}};\n",
+		"notes.txt":    "SQLファイル以外は読み込まない",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	library, err := NewLibrary()
+	if err != nil {
+		t.Fatalf("NewLibrary returned error: %v", err)
+	}
+	if err := library.LoadDir(dir); err != nil {
+		t.Fatalf("LoadDir returned error: %v", err)
+	}
+
+	mine, err := library.Get("mine")
+	if err != nil {
+		t.Fatalf("Get(mine) returned error: %v", err)
+	}
+	if mine.Source != filepath.Join(dir, "mine.sql") {
+		t.Errorf("Expected source to be the report file, got %q", mine.Source)
+	}
+
+	// 組み込みレポートと同じ名前の場合は上書きする
+	overview, _ := library.Get("overview")
+	if overview.Description != "上書きした概要" {
+		t.Errorf("Expected builtin report to be overridden, got %q", overview.Description)
+	}
+
+	if err := library.LoadDir(filepath.Join(dir, "missing")); err == nil {
+		t.Error("LoadDir should return error for missing directory")
+	}
+}
@@ -0,0 +1,14 @@
+-- name: error-rate
+-- description: 時間帯ごとのリクエスト数とエラー率
+-- formats: alb
+-- param: interval:interval=1 minute | 集計する時間の間隔 (例: 5 minutes, 1 hour)
+-- section: 時間帯ごとのエラー率
+SELECT
+    time_bucket(INTERVAL {{interval}}, timestamp) AS bucket,
+    count(*) AS requests,
+    count(*) FILTER (WHERE elb_status_code BETWEEN 400 AND 499) AS errors_4xx,
+    count(*) FILTER (WHERE elb_status_code >= 500) AS errors_5xx,
+    round(100.0 * count(*) FILTER (WHERE elb_status_code >= 500) / count(*), 2) AS error_5xx_percent
+FROM {{table}}
+GROUP BY bucket
+ORDER BY bucket;
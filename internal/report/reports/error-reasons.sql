@@ -0,0 +1,24 @@
+-- name: error-reasons
+-- description: ELBが記録したエラーの理由と分類
+-- formats: alb
+-- param: limit:number=20 表示する件数
+-- section: エラーの理由
+SELECT
+    elb_status_code,
+    error_reason,
+    count(*) AS requests
+FROM {{table}}
+WHERE elb_status_code >= 400
+GROUP BY ALL
+ORDER BY requests DESC
+LIMIT {{limit}};
+-- section: Desync緩和モードの分類
+SELECT
+    classification,
+    classification_reason,
+    count(*) AS requests
+FROM {{table}}
+WHERE classification IS NOT NULL
+GROUP BY ALL
+ORDER BY requests DESC
+LIMIT {{limit}};
@@ -0,0 +1,16 @@
+-- name: overview
+-- description: リクエスト数・期間・転送量・処理時間の概要
+-- formats: alb
+-- section: 概要
+SELECT
+    count(*) AS requests,
+    min(timestamp) AS first_request,
+    max(timestamp) AS last_request,
+    count(DISTINCT elb) AS load_balancers,
+    count(DISTINCT client_ip) AS clients,
+    sum(received_bytes) AS received_bytes,
+    sum(sent_bytes) AS sent_bytes,
+    round(avg(target_processing_time), 3) AS avg_target_time,
+    count(*) FILTER (WHERE elb_status_code >= 500) AS elb_5xx,
+    count(*) FILTER (WHERE target_status_code IS NULL) AS no_target_response
+FROM {{table}};
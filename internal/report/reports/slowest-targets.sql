@@ -0,0 +1,20 @@
+-- name: slowest-targets
+-- description: 処理時間が長いターゲット (ターゲットが応答しなかったリクエストは処理時間に含めない)
+-- formats: alb
+-- param: limit:number=10 表示する件数
+-- section: 処理時間が長いターゲット
+SELECT
+    target_group_arn,
+    target_ip,
+    target_port,
+    count(*) AS requests,
+    count(*) FILTER (WHERE target_processing_time IS NULL) AS no_response,
+    round(avg(target_processing_time), 3) AS avg_time,
+    round(quantile_cont(target_processing_time, 0.5), 3) AS p50,
+    round(quantile_cont(target_processing_time, 0.99), 3) AS p99,
+    round(max(target_processing_time), 3) AS max_time
+FROM {{table}}
+WHERE target_ip IS NOT NULL
+GROUP BY ALL
+ORDER BY p99 DESC NULLS LAST
+LIMIT {{limit}};
@@ -0,0 +1,19 @@
+-- name: status-codes
+-- description: ELBとターゲットのステータスコード別のリクエスト数
+-- formats: alb
+-- section: ELBのステータスコード
+SELECT
+    elb_status_code,
+    count(*) AS requests,
+    round(100.0 * count(*) / sum(count(*)) OVER (), 2) AS percent
+FROM {{table}}
+GROUP BY elb_status_code
+ORDER BY elb_status_code;
+-- section: ターゲットのステータスコード
+SELECT
+    target_status_code,
+    count(*) AS requests,
+    round(100.0 * count(*) / sum(count(*)) OVER (), 2) AS percent
+FROM {{table}}
+GROUP BY target_status_code
+ORDER BY target_status_code NULLS LAST;
@@ -0,0 +1,17 @@
+-- name: top-5xx-paths
+-- description: 5xxエラーが多いパス
+-- formats: alb
+-- param: limit:number=10 表示する件数
+-- section: 5xxエラーが多いパス
+SELECT
+    http_method,
+    url_host,
+    url_path,
+    count(*) FILTER (WHERE elb_status_code >= 500) AS errors_5xx,
+    count(*) AS requests,
+    round(100.0 * count(*) FILTER (WHERE elb_status_code >= 500) / count(*), 2) AS error_percent
+FROM {{table}}
+GROUP BY ALL
+HAVING errors_5xx > 0
+ORDER BY errors_5xx DESC
+LIMIT {{limit}};
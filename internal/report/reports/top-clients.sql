@@ -0,0 +1,16 @@
+-- name: top-clients
+-- description: リクエスト数が多いクライアントIP
+-- formats: alb
+-- param: limit:number=10 表示する件数
+-- section: リクエスト数が多いクライアントIP
+SELECT
+    client_ip,
+    count(*) AS requests,
+    count(*) FILTER (WHERE elb_status_code BETWEEN 400 AND 499) AS errors_4xx,
+    count(*) FILTER (WHERE elb_status_code >= 500) AS errors_5xx,
+    sum(sent_bytes) AS sent_bytes,
+    any_value(user_agent) AS user_agent
+FROM {{table}}
+GROUP BY client_ip
+ORDER BY requests DESC
+LIMIT {{limit}};
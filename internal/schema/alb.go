@@ -0,0 +1,63 @@
+package schema
+
+// ALB はApplication Load Balancerのアクセスログ形式です
+// https://docs.aws.amazon.com/elasticloadbalancing/latest/application/load-balancer-access-logs.html
+var ALB = &Format{
+	Name:            "alb",
+	Label:           "ALB",
+	Description:     "Application Load Balancer アクセスログ",
+	TableNamePrefix: "alb_logs",
+	Columns: []Column{
+		{"type", "VARCHAR"},
+		{"timestamp", "TIMESTAMP"},
+		{"elb", "VARCHAR"},
+		{"client_ip_port", "VARCHAR"},
+		{"target_ip_port", "VARCHAR"},
+		{"request_processing_time", "DOUBLE"},
+		{"target_processing_time", "DOUBLE"},
+		{"response_processing_time", "DOUBLE"},
+		{"elb_status_code", "INTEGER"},
+		{"target_status_code", "VARCHAR"},
+		{"received_bytes", "BIGINT"},
+		{"sent_bytes", "BIGINT"},
+		{"request", "VARCHAR"},
+		{"user_agent", "VARCHAR"},
+		{"ssl_cipher", "VARCHAR"},
+		{"ssl_protocol", "VARCHAR"},
+		{"target_group_arn", "VARCHAR"},
+		{"trace_id", "VARCHAR"},
+		{"domain_name", "VARCHAR"},
+		{"chosen_cert_arn", "VARCHAR"},
+		{"matched_rule_priority", "VARCHAR"},
+		{"request_creation_time", "TIMESTAMP"},
+		{"actions_executed", "VARCHAR"},
+		{"redirect_url", "VARCHAR"},
+		{"error_reason", "VARCHAR"},
+		{"target_port_list", "VARCHAR"},
+		{"target_status_code_list", "VARCHAR"},
+		{"classification", "VARCHAR"},
+		{"classification_reason", "VARCHAR"},
+		{"conn_trace_id", "VARCHAR"},
+	},
+	TimestampExpr: "timestamp",
+	// target_status_code は "-" を含むためVARCHARで読み込み、NULLに変換した後にINTEGERにする
+	NullSentinels: joinSentinels(
+		processingTimeSentinels("request_processing_time", "target_processing_time", "response_processing_time"),
+		[]NullSentinel{{Column: "target_status_code", Value: "'-'", Type: "INTEGER"}},
+		dashSentinels("target_ip_port", "ssl_cipher", "ssl_protocol", "target_group_arn", "trace_id", "domain_name",
+			"chosen_cert_arn", "matched_rule_priority", "actions_executed", "redirect_url", "error_reason",
+			"target_port_list", "target_status_code_list", "classification", "classification_reason", "conn_trace_id"),
+	),
+	DerivedColumns: joinDerivedColumns(
+		RequestColumns("request"),
+		IPPortColumns("client_ip_port", "client"),
+		IPPortColumns("target_ip_port", "target"),
+	),
+	ProcessingTimeColumns: []string{"request_processing_time", "target_processing_time", "response_processing_time"},
+	StatusCodeColumn:      "elb_status_code",
+	PathColumn:            "url_path",
+	Delimiter:             " ",
+	Quote:                 `"`,
+	Escape:                `"`,
+	Header:                false,
+}
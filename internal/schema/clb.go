@@ -0,0 +1,42 @@
+package schema
+
+// CLB はClassic Load Balancerのアクセスログ形式です
+// https://docs.aws.amazon.com/elasticloadbalancing/latest/classic/access-log-collection.html
+var CLB = &Format{
+	Name:            "clb",
+	Label:           "CLB",
+	Description:     "Classic Load Balancer アクセスログ",
+	TableNamePrefix: "clb_logs",
+	Columns: []Column{
+		{"timestamp", "TIMESTAMP"},
+		{"elb", "VARCHAR"},
+		{"client_ip_port", "VARCHAR"},
+		{"backend_ip_port", "VARCHAR"},
+		{"request_processing_time", "DOUBLE"},
+		{"backend_processing_time", "DOUBLE"},
+		{"response_processing_time", "DOUBLE"},
+		{"elb_status_code", "INTEGER"},
+		{"backend_status_code", "INTEGER"},
+		{"received_bytes", "BIGINT"},
+		{"sent_bytes", "BIGINT"},
+		{"request", "VARCHAR"},
+		{"user_agent", "VARCHAR"},
+		{"ssl_cipher", "VARCHAR"},
+		{"ssl_protocol", "VARCHAR"},
+	},
+	TimestampExpr: "timestamp",
+	NullSentinels: processingTimeSentinels("request_processing_time", "backend_processing_time", "response_processing_time"),
+	DerivedColumns: joinDerivedColumns(
+		RequestColumns("request"),
+		IPPortColumns("client_ip_port", "client"),
+		IPPortColumns("backend_ip_port", "backend"),
+	),
+	ProcessingTimeColumns: []string{"request_processing_time", "backend_processing_time", "response_processing_time"},
+	StatusCodeColumn:      "elb_status_code",
+	PathColumn:            "url_path",
+	Delimiter:             " ",
+	Quote:                 `"`,
+	Escape:                `"`,
+	Header:                false,
+	NullString:            "-",
+}
@@ -0,0 +1,65 @@
+package schema
+
+import "fmt"
+
+// DerivedColumn はログのカラムから計算して追加するカラムです
+type DerivedColumn struct {
+	// Name はカラム名です
+	Name string
+	// Type はDuckDBのデータ型です
+	Type string
+	// Expr は値を計算するSQL式です
+	Expr string
+	// Extension は Type を使用するために必要なDuckDBの拡張機能です
+	// 空でない場合、Expr はVARCHARの値を返し、拡張機能が使用できるときのみ Type に変換します
+	Extension string
+}
+
+// urlAuthorityPattern はURLのスキームとホスト部分に一致する正規表現です
+// IPv6アドレスのホストは [2001:db8::1] のように角括弧で囲まれます
+const urlAuthorityPattern = `^[a-zA-Z][a-zA-Z0-9+.-]*://(?:\[[^\]]*\]|[^:/?#]*)`
+
+// RequestColumns は "GET https://host:443/path?x=1 HTTP/1.1" 形式のリクエストのカラムを
+// メソッド・URL・スキーム・ホスト・ポート・パス・クエリ・HTTPバージョンに分解する派生カラムを返します
+// ELBが記録する "-" や空文字列はNULLにします
+func RequestColumns(column string) []DerivedColumn {
+	method := nullIfEmpty(fmt.Sprintf("split_part(%s, ' ', 1)", column))
+	url := fmt.Sprintf("split_part(%s, ' ', 2)", column)
+	version := nullIfEmpty(fmt.Sprintf("split_part(%s, ' ', 3)", column))
+
+	return []DerivedColumn{
+		{Name: "http_method", Type: "VARCHAR", Expr: method},
+		{Name: "request_url", Type: "VARCHAR", Expr: nullIfEmpty(url)},
+		{Name: "url_scheme", Type: "VARCHAR", Expr: nullIfEmpty(fmt.Sprintf(`regexp_extract(%s, '^([a-zA-Z][a-zA-Z0-9+.-]*)://', 1)`, url))},
+		{Name: "url_host", Type: "VARCHAR", Expr: nullIfEmpty(fmt.Sprintf(`trim(regexp_extract(%s, '^[a-zA-Z][a-zA-Z0-9+.-]*://(\[[^\]]*\]|[^:/?#]*)', 1), '[]')`, url))},
+		{Name: "url_port", Type: "INTEGER", Expr: fmt.Sprintf(`TRY_CAST(%s AS INTEGER)`, nullIfEmpty(fmt.Sprintf(`regexp_extract(%s, '%s:([0-9]+)', 1)`, url, urlAuthorityPattern)))},
+		{Name: "url_path", Type: "VARCHAR", Expr: nullIfEmpty(fmt.Sprintf(`regexp_extract(%s, '^(?:[a-zA-Z][a-zA-Z0-9+.-]*://[^/?#]*)?(/[^?#]*)', 1)`, url))},
+		{Name: "url_query", Type: "VARCHAR", Expr: nullIfEmpty(fmt.Sprintf(`regexp_extract(%s, '\?([^#]*)', 1)`, url))},
+		{Name: "http_version", Type: "VARCHAR", Expr: version},
+	}
+}
+
+// IPPortColumns は "10.0.0.1:443" や "[2001:db8::1]:443" 形式のカラムを
+// <prefix>_ip と <prefix>_port に分解する派生カラムを返します
+// IPアドレスはinet拡張機能が使用できる場合はINET型、使用できない場合はVARCHAR型になります
+// ターゲットに到達しなかったリクエストの "-" はNULLにします
+func IPPortColumns(column string, prefix string) []DerivedColumn {
+	return []DerivedColumn{
+		{Name: prefix + "_ip", Type: "INET", Expr: nullIfEmpty(fmt.Sprintf(`regexp_extract(%s, '^\[?([^\[\]]*?)\]?:[0-9]+$', 1)`, column)), Extension: "inet"},
+		{Name: prefix + "_port", Type: "INTEGER", Expr: fmt.Sprintf(`TRY_CAST(%s AS INTEGER)`, nullIfEmpty(fmt.Sprintf(`regexp_extract(%s, ':([0-9]+)$', 1)`, column)))},
+	}
+}
+
+// joinDerivedColumns は派生カラムの一覧を結合します
+func joinDerivedColumns(groups ...[]DerivedColumn) []DerivedColumn {
+	var columns []DerivedColumn
+	for _, g := range groups {
+		columns = append(columns, g...)
+	}
+	return columns
+}
+
+// nullIfEmpty は空文字列と "-" をNULLに変換するSQL式を返します
+func nullIfEmpty(expr string) string {
+	return fmt.Sprintf("nullif(nullif(%s, ''), '-')", expr)
+}
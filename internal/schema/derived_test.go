@@ -0,0 +1,71 @@
+package schema
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRequestColumns(t *testing.T) {
+	columns := RequestColumns("request")
+
+	expected := []string{"http_method", "request_url", "url_scheme", "url_host", "url_port", "url_path", "url_query", "http_version"}
+	if len(columns) != len(expected) {
+		t.Fatalf("Expected %d columns, got %d", len(expected), len(columns))
+	}
+	for i, name := range expected {
+		if columns[i].Name != name {
+			t.Errorf("Expected column %d to be %s, got %s", i, name, columns[i].Name)
+		}
+		if !strings.Contains(columns[i].Expr, "request") {
+			t.Errorf("Expression for %s does not reference request column: %s", name, columns[i].Expr)
+		}
+	}
+
+	// ポートは数値として扱う
+	if columns[4].Type != "INTEGER" || !strings.HasPrefix(columns[4].Expr, "TRY_CAST(") {
+		t.Errorf("Unexpected url_port column: %+v", columns[4])
+	}
+}
+
+func TestIPPortColumns(t *testing.T) {
+	columns := IPPortColumns("client_ip_port", "client")
+
+	if len(columns) != 2 || columns[0].Name != "client_ip" || columns[1].Name != "client_port" {
+		t.Fatalf("Unexpected columns: %+v", columns)
+	}
+
+	// IPアドレスはinet拡張機能が使用できる場合のみINET型にする
+	if columns[0].Type != "INET" || columns[0].Extension != "inet" {
+		t.Errorf("Unexpected client_ip column: %+v", columns[0])
+	}
+	if columns[1].Type != "INTEGER" || columns[1].Extension != "" {
+		t.Errorf("Unexpected client_port column: %+v", columns[1])
+	}
+}
+
+func TestDerivedColumnsByFormat(t *testing.T) {
+	testCases := []struct {
+		format   *Format
+		expected []string
+	}{
+		{ALB, []string{"http_method", "url_path", "client_ip", "client_port", "target_ip", "target_port"}},
+		{CLB, []string{"http_method", "url_path", "client_ip", "client_port", "backend_ip", "backend_port"}},
+		{NLB, []string{"client_ip", "client_port", "destination_ip", "destination_port"}},
+		{CloudFront, nil},
+	}
+
+	for _, tc := range testCases {
+		names := make(map[string]bool)
+		for _, d := range tc.format.DerivedColumns {
+			names[d.Name] = true
+		}
+		for _, name := range tc.expected {
+			if !names[name] {
+				t.Errorf("Expected %s to have derived column %s", tc.format.Name, name)
+			}
+		}
+		if tc.expected == nil && len(tc.format.DerivedColumns) != 0 {
+			t.Errorf("Expected %s to have no derived columns", tc.format.Name)
+		}
+	}
+}
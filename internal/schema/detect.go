@@ -0,0 +1,151 @@
+package schema
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// AutoFormatName はログ形式を自動判定する場合に指定する値です
+const AutoFormatName = "auto"
+
+// cloudFrontKeyPattern はCloudFront標準ログのオブジェクトキーの命名規則です
+// 例: E2EXAMPLE.2025-03-03-12.a1b2c3d4.gz
+var cloudFrontKeyPattern = regexp.MustCompile(`^[A-Z0-9]+\.\d{4}-\d{2}-\d{2}-\d{2}\.[0-9a-z]+\.gz$`)
+
+// albKeyPattern と nlbKeyPattern はELBログのファイル名に含まれるロードバランサー種別です
+// 例: 123456789012_elasticloadbalancing_ap-northeast-1_app.my-lb.1234567890abcdef_20250303T0000Z_...log.gz
+var (
+	albKeyPattern = regexp.MustCompile(`(^|[_*])app\.`)
+	nlbKeyPattern = regexp.MustCompile(`(^|[_*])net\.`)
+)
+
+// albRequestTypes はALBログの先頭フィールド (type) に出現する値です
+var albRequestTypes = map[string]bool{
+	"http":  true,
+	"https": true,
+	"h2":    true,
+	"grpcs": true,
+	"ws":    true,
+	"wss":   true,
+}
+
+// Detection はログ形式の自動判定結果です
+type Detection struct {
+	// Format は判定されたログ形式です
+	Format *Format
+	// Reason は判定の根拠です
+	Reason string
+}
+
+// Detect はパスからログ形式を判定します
+// パスだけでは判定できない場合は sampler で取得したログの1行目から判定し、
+// それでも判定できない場合はデフォルトのログ形式 (ALB) を返します
+func Detect(logPath string, sampler func() (string, error)) *Detection {
+	candidates, detection := DetectFromPath(logPath)
+	if detection != nil {
+		return detection
+	}
+
+	if sampler == nil {
+		return fallbackDetection(candidates, "パスから判定できませんでした")
+	}
+
+	line, err := sampler()
+	if err != nil {
+		return fallbackDetection(candidates, fmt.Sprintf("ログの1行目を取得できませんでした: %v", err))
+	}
+
+	detection = DetectFromLine(line)
+	if detection == nil {
+		return fallbackDetection(candidates, "パスおよびログの1行目から判定できませんでした")
+	}
+
+	return detection
+}
+
+// DetectFromPath はS3キーのレイアウトからログ形式を判定します
+// 一意に判定できない場合は候補となるログ形式の一覧と nil を返します
+func DetectFromPath(logPath string) ([]*Format, *Detection) {
+	lower := strings.ToLower(logPath)
+	base := path.Base(logPath)
+
+	// CloudFrontはキーに distribution ID と日時を含む
+	if cloudFrontKeyPattern.MatchString(base) {
+		return nil, &Detection{CloudFront, "ファイル名がCloudFront標準ログの命名規則に一致します"}
+	}
+
+	if strings.Contains(lower, "elasticloadbalancing") {
+		baseLower := strings.ToLower(base)
+		switch {
+		case albKeyPattern.MatchString(baseLower):
+			return nil, &Detection{ALB, "ファイル名にApplication Load Balancerを示す 'app.' が含まれます"}
+		case nlbKeyPattern.MatchString(baseLower):
+			return nil, &Detection{NLB, "ファイル名にNetwork Load Balancerを示す 'net.' が含まれます"}
+		case strings.HasSuffix(baseLower, ".log"):
+			return nil, &Detection{CLB, "elasticloadbalancing 配下の非圧縮 .log ファイルはClassic Load Balancerのログです"}
+		case strings.HasSuffix(baseLower, ".log.gz"):
+			return []*Format{ALB, NLB}, nil
+		}
+		return []*Format{ALB, NLB, CLB}, nil
+	}
+
+	if strings.Contains(lower, "cloudfront") {
+		return nil, &Detection{CloudFront, "パスに 'cloudfront' が含まれます"}
+	}
+
+	return nil, nil
+}
+
+// DetectFromLine はログの1行目からログ形式を判定します
+// 判定できない場合は nil を返します
+func DetectFromLine(line string) *Detection {
+	line = strings.TrimSpace(line)
+	if strings.HasPrefix(line, "#Version:") || strings.HasPrefix(line, "#Fields:") {
+		return &Detection{CloudFront, "1行目がCloudFront標準ログのヘッダー行です"}
+	}
+
+	if strings.Contains(line, "\t") {
+		fields := strings.Split(line, "\t")
+		if len(fields) == len(CloudFront.Columns) {
+			return &Detection{CloudFront, "1行目がタブ区切りでCloudFront標準ログのフィールド数と一致します"}
+		}
+		return nil
+	}
+
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		return nil
+	}
+
+	switch {
+	case albRequestTypes[fields[0]]:
+		return &Detection{ALB, fmt.Sprintf("1行目の先頭フィールドがALBのリクエスト種別 '%s' です", fields[0])}
+	case fields[0] == "tls":
+		return &Detection{NLB, "1行目の先頭フィールドがNLBのリスナー種別 'tls' です"}
+	case isTimestamp(fields[0]):
+		return &Detection{CLB, "1行目の先頭フィールドがタイムスタンプです"}
+	}
+
+	return nil
+}
+
+// fallbackDetection は判定できなかった場合の結果を返します
+func fallbackDetection(candidates []*Format, reason string) *Detection {
+	if len(candidates) > 0 {
+		names := make([]string, 0, len(candidates))
+		for _, c := range candidates {
+			names = append(names, c.Name)
+		}
+		reason = fmt.Sprintf("%s (候補: %s)", reason, strings.Join(names, ", "))
+	}
+	return &Detection{Default(), reason + "。デフォルトのログ形式を使用します"}
+}
+
+// isTimestamp は文字列がISO 8601形式のタイムスタンプかどうかを返します
+func isTimestamp(s string) bool {
+	_, err := time.Parse(time.RFC3339Nano, s)
+	return err == nil
+}
@@ -0,0 +1,121 @@
+package schema
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDetectFromPath(t *testing.T) {
+	testCases := []struct {
+		path     string
+		expected *Format
+	}{
+		{"s3://bucket/AWSLogs/123456789012/elasticloadbalancing/ap-northeast-1/2025/03/03/123456789012_elasticloadbalancing_ap-northeast-1_app.my-lb.1234567890abcdef_20250303T0000Z_10.0.0.1_abc.log.gz", ALB},
+		{"s3://bucket/AWSLogs/123456789012/elasticloadbalancing/ap-northeast-1/2025/03/03/*app.my-lb*.log.gz", ALB},
+		{"s3://bucket/AWSLogs/123456789012/elasticloadbalancing/ap-northeast-1/2025/03/03/123456789012_elasticloadbalancing_ap-northeast-1_net.my-nlb.1234567890abcdef_20250303T0000Z_abc.log.gz", NLB},
+		{"s3://bucket/AWSLogs/123456789012/elasticloadbalancing/ap-northeast-1/2025/03/03/*.log", CLB},
+		{"s3://bucket/cf-logs/E2EXAMPLE.2025-03-03-12.a1b2c3d4.gz", CloudFront},
+		{"s3://bucket/cloudfront/2025/*.gz", CloudFront},
+	}
+
+	for _, tc := range testCases {
+		_, detection := DetectFromPath(tc.path)
+		if detection == nil {
+			t.Errorf("DetectFromPath(%q) could not detect format, expected '%s'", tc.path, tc.expected.Name)
+			continue
+		}
+
+		if detection.Format != tc.expected {
+			t.Errorf("DetectFromPath(%q) = '%s', expected '%s'", tc.path, detection.Format.Name, tc.expected.Name)
+		}
+
+		if detection.Reason == "" {
+			t.Errorf("DetectFromPath(%q) returned empty reason", tc.path)
+		}
+	}
+}
+
+func TestDetectFromPath_Ambiguous(t *testing.T) {
+	// ALBとNLBはどちらも elasticloadbalancing 配下の .log.gz
+	candidates, detection := DetectFromPath("s3://bucket/AWSLogs/123456789012/elasticloadbalancing/ap-northeast-1/2025/03/03/*.log.gz")
+	if detection != nil {
+		t.Fatalf("Expected ambiguous result, got '%s'", detection.Format.Name)
+	}
+
+	if len(candidates) != 2 || candidates[0] != ALB || candidates[1] != NLB {
+		t.Errorf("Expected candidates [alb nlb], got %v", candidates)
+	}
+}
+
+func TestDetectFromLine(t *testing.T) {
+	testCases := []struct {
+		line     string
+		expected *Format
+	}{
+		{`https 2025-03-03T22:23:00.186641Z app/my-lb/50dc6c495c0c9188 192.168.131.39:2817 10.0.0.1:80 0.000 0.001 0.000 200 200 34 366 "GET https://example.com:443/ HTTP/1.1" "curl/7.46.0"`, ALB},
+		{`h2 2025-03-03T22:23:00.186641Z app/my-lb/50dc6c495c0c9188 192.168.131.39:2817 10.0.0.1:80`, ALB},
+		{`tls 2.0 2025-03-03T02:59:40 net/my-nlb/c6e77e28c25b2234 g3d4b5e8bb8464cd 72.21.218.154:51341 172.100.100.185:443 5 2 98 246`, NLB},
+		{`2025-03-03T23:39:43.945958Z my-loadbalancer 192.168.131.39:2817 10.0.0.1:80 0.000073 0.001048 0.000057 200 200 0 29`, CLB},
+		{"#Version: 1.0", CloudFront},
+	}
+
+	for _, tc := range testCases {
+		detection := DetectFromLine(tc.line)
+		if detection == nil {
+			t.Errorf("DetectFromLine could not detect format for line %q, expected '%s'", tc.line, tc.expected.Name)
+			continue
+		}
+
+		if detection.Format != tc.expected {
+			t.Errorf("DetectFromLine(%q) = '%s', expected '%s'", tc.line, detection.Format.Name, tc.expected.Name)
+		}
+	}
+
+	if detection := DetectFromLine("unknown log line"); detection != nil {
+		t.Errorf("DetectFromLine should not detect format for unknown line, got '%s'", detection.Format.Name)
+	}
+}
+
+func TestDetect_UsesSamplerWhenAmbiguous(t *testing.T) {
+	sampled := false
+	sampler := func() (string, error) {
+		sampled = true
+		return "tls 2.0 2025-03-03T02:59:40 net/my-nlb/c6e77e28c25b2234", nil
+	}
+
+	detection := Detect("s3://bucket/AWSLogs/123456789012/elasticloadbalancing/ap-northeast-1/2025/03/03/*.log.gz", sampler)
+	if !sampled {
+		t.Error("Detect should sample the first line when the path is ambiguous")
+	}
+
+	if detection.Format != NLB {
+		t.Errorf("Expected NLB, got '%s'", detection.Format.Name)
+	}
+}
+
+func TestDetect_SkipsSamplerWhenPathIsConclusive(t *testing.T) {
+	sampler := func() (string, error) {
+		t.Error("Detect should not sample when the path is conclusive")
+		return "", nil
+	}
+
+	detection := Detect("s3://bucket/cloudfront/*.gz", sampler)
+	if detection.Format != CloudFront {
+		t.Errorf("Expected CloudFront, got '%s'", detection.Format.Name)
+	}
+}
+
+func TestDetect_FallsBackToDefault(t *testing.T) {
+	sampler := func() (string, error) {
+		return "", errors.New("access denied")
+	}
+
+	detection := Detect("s3://bucket/logs/*.gz", sampler)
+	if detection.Format != Default() {
+		t.Errorf("Expected default format, got '%s'", detection.Format.Name)
+	}
+
+	if detection.Reason == "" {
+		t.Error("Fallback detection should have a reason")
+	}
+}
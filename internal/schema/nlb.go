@@ -0,0 +1,44 @@
+package schema
+
+// NLB はNetwork Load Balancer (TLSリスナー) のアクセスログ形式です
+// https://docs.aws.amazon.com/elasticloadbalancing/latest/network/load-balancer-access-logs.html
+var NLB = &Format{
+	Name:            "nlb",
+	Label:           "NLB",
+	Description:     "Network Load Balancer アクセスログ",
+	TableNamePrefix: "nlb_logs",
+	Columns: []Column{
+		{"type", "VARCHAR"},
+		{"version", "VARCHAR"},
+		{"time", "TIMESTAMP"},
+		{"elb", "VARCHAR"},
+		{"listener", "VARCHAR"},
+		{"client_ip_port", "VARCHAR"},
+		{"destination_ip_port", "VARCHAR"},
+		{"connection_time", "BIGINT"},
+		{"tls_handshake_time", "BIGINT"},
+		{"received_bytes", "BIGINT"},
+		{"sent_bytes", "BIGINT"},
+		{"incoming_tls_alert", "VARCHAR"},
+		{"chosen_cert_arn", "VARCHAR"},
+		{"chosen_cert_serial", "VARCHAR"},
+		{"tls_cipher", "VARCHAR"},
+		{"tls_protocol_version", "VARCHAR"},
+		{"tls_named_group", "VARCHAR"},
+		{"domain_name", "VARCHAR"},
+		{"alpn_fe_protocol", "VARCHAR"},
+		{"alpn_be_protocol", "VARCHAR"},
+		{"alpn_client_preference_list", "VARCHAR"},
+		{"tls_connection_creation_time", "TIMESTAMP"},
+	},
+	TimestampExpr: "time",
+	DerivedColumns: joinDerivedColumns(
+		IPPortColumns("client_ip_port", "client"),
+		IPPortColumns("destination_ip_port", "destination"),
+	),
+	Delimiter:  " ",
+	Quote:      `"`,
+	Escape:     `"`,
+	Header:     false,
+	NullString: "-",
+}
@@ -0,0 +1,108 @@
+package schema
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DefaultFormatName はデフォルトのログ形式名です
+const DefaultFormatName = "alb"
+
+// Column はログのカラム定義です
+type Column struct {
+	// Name はカラム名です
+	Name string
+	// Type はDuckDBのデータ型です
+	Type string
+}
+
+// Format はログ形式の定義です
+type Format struct {
+	// Name はログ形式の識別子です (--format で指定する値)
+	Name string
+	// Label は表示用の短い名前です
+	Label string
+	// Description はログ形式の説明です
+	Description string
+	// TableNamePrefix は自動生成するテーブル名のプレフィックスです
+	TableNamePrefix string
+	// Columns はカラム定義の一覧です (ログの出現順)
+	Columns []Column
+	// TimestampExpr はリクエスト日時を表すSQL式です (時間範囲の絞り込みに使用)
+	TimestampExpr string
+	// Delimiter はフィールドの区切り文字です
+	Delimiter string
+	// Quote はフィールドの囲み文字です (空の場合は囲み文字なし)
+	Quote string
+	// Escape は囲み文字のエスケープ文字です (空の場合はエスケープなし)
+	Escape string
+	// Header は1行目がヘッダー行かどうかです
+	Header bool
+	// SkipRows は各ファイルの先頭で読み飛ばす行数です
+	SkipRows int
+	// NullString はNULLとして扱う文字列です (空の場合はNULL変換なし)
+	NullString string
+	// NullSentinels はNULLの代わりに記録される値をNULLに変換するカラムの一覧です
+	// --raw を指定した場合は変換しません
+	NullSentinels []NullSentinel
+	// DerivedColumns はログのカラムから計算して追加するカラムの一覧です
+	DerivedColumns []DerivedColumn
+	// ProcessingTimeColumns はリクエスト・ターゲット・レスポンスの処理時間 (秒) のカラムです
+	// この順で指定し、合計をリクエスト全体のレイテンシとして扱います (空の場合はレイテンシ分析に対応しない)
+	ProcessingTimeColumns []string
+	// StatusCodeColumn はクライアントに返したHTTPステータスコードのカラムです (空の場合はステータスコードを記録しない)
+	StatusCodeColumn string
+	// PathColumn はリクエストのパスのカラムです (派生カラムも指定可能。空の場合はパスを記録しない)
+	PathColumn string
+}
+
+// Column は指定した名前のカラム定義を返します
+func (f *Format) Column(name string) (Column, bool) {
+	for _, c := range f.Columns {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return Column{}, false
+}
+
+// registry は登録済みのログ形式です
+var registry = map[string]*Format{}
+
+func init() {
+	Register(ALB)
+	Register(NLB)
+	Register(CLB)
+	Register(CloudFront)
+}
+
+// Register はログ形式を登録します
+// 同じ名前のログ形式が登録済みの場合は上書きします
+func Register(f *Format) {
+	registry[f.Name] = f
+}
+
+// Get は指定した名前のログ形式を返します
+func Get(name string) (*Format, error) {
+	f, ok := registry[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("未対応のログ形式です: %s (対応形式: %s)", name, strings.Join(Names(), ", "))
+	}
+	return f, nil
+}
+
+// Default はデフォルトのログ形式 (ALB) を返します
+func Default() *Format {
+	return registry[DefaultFormatName]
+}
+
+// Names は登録済みのログ形式名を名前順で返します
+func Names() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,107 @@
+package schema
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGet(t *testing.T) {
+	testCases := []struct {
+		name        string
+		expected    *Format
+		columnCount int
+	}{
+		{"alb", ALB, 30},
+		{"nlb", NLB, 22},
+		{"clb", CLB, 15},
+		{"cloudfront", CloudFront, 33},
+		{"ALB", ALB, 30},
+	}
+
+	for _, tc := range testCases {
+		format, err := Get(tc.name)
+		if err != nil {
+			t.Errorf("Get(%q) returned error: %v", tc.name, err)
+			continue
+		}
+
+		if format != tc.expected {
+			t.Errorf("Get(%q) returned unexpected format '%s'", tc.name, format.Name)
+		}
+
+		if len(format.Columns) != tc.columnCount {
+			t.Errorf("Format '%s' has %d columns, expected %d", format.Name, len(format.Columns), tc.columnCount)
+		}
+	}
+}
+
+func TestGet_UnknownFormat(t *testing.T) {
+	_, err := Get("unknown")
+	if err == nil {
+		t.Fatal("Get should fail for unknown format")
+	}
+
+	// 対応形式の一覧がエラーメッセージに含まれる
+	if !strings.Contains(err.Error(), "alb, clb, cloudfront, nlb") {
+		t.Errorf("Error message does not list supported formats: %v", err)
+	}
+}
+
+func TestDefault(t *testing.T) {
+	if Default() != ALB {
+		t.Errorf("Default format should be ALB, got '%s'", Default().Name)
+	}
+}
+
+func TestFormatsAreWellFormed(t *testing.T) {
+	for _, name := range Names() {
+		format, _ := Get(name)
+
+		if format.Label == "" || format.Description == "" || format.TableNamePrefix == "" {
+			t.Errorf("Format '%s' is missing display properties", name)
+		}
+
+		if format.Delimiter == "" {
+			t.Errorf("Format '%s' has no delimiter", name)
+		}
+
+		// カラム名が重複していないことを確認
+		seen := map[string]bool{}
+		for _, c := range format.Columns {
+			if seen[c.Name] {
+				t.Errorf("Format '%s' has duplicate column '%s'", name, c.Name)
+			}
+			seen[c.Name] = true
+
+			if c.Type == "" {
+				t.Errorf("Format '%s' column '%s' has no type", name, c.Name)
+			}
+		}
+
+		// パスのカラムはログのカラムまたは派生カラムであることを確認
+		if format.PathColumn != "" && !seen[format.PathColumn] {
+			derived := false
+			for _, d := range format.DerivedColumns {
+				derived = derived || d.Name == format.PathColumn
+			}
+			if !derived {
+				t.Errorf("Format '%s' has unknown path column '%s'", name, format.PathColumn)
+			}
+		}
+	}
+}
+
+func TestFormatColumn(t *testing.T) {
+	column, ok := ALB.Column("elb_status_code")
+	if !ok {
+		t.Fatal("ALB format should have 'elb_status_code' column")
+	}
+
+	if column.Type != "INTEGER" {
+		t.Errorf("Expected 'elb_status_code' to be INTEGER, got '%s'", column.Type)
+	}
+
+	if _, ok := ALB.Column("unknown"); ok {
+		t.Error("ALB format should not have 'unknown' column")
+	}
+}
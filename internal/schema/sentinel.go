@@ -0,0 +1,50 @@
+package schema
+
+import "fmt"
+
+// NullSentinel はログでNULLの代わりに使用される値を持つカラムの定義です
+// ALBはターゲットが応答しなかった場合に処理時間を -1、値がない文字列を "-" として記録します
+type NullSentinel struct {
+	// Column はカラム名です
+	Column string
+	// Value はNULLとして扱う値のSQLリテラルです ('-' や -1)
+	Value string
+	// Type はNULLに変換した後のデータ型です (空の場合は読み込み時の型のまま)
+	Type string
+}
+
+// Expr はセンチネル値をNULLに変換するSQL式を返します
+func (s NullSentinel) Expr() string {
+	expr := fmt.Sprintf("nullif(%s, %s)", s.Column, s.Value)
+	if s.Type != "" {
+		expr = fmt.Sprintf("TRY_CAST(%s AS %s)", expr, s.Type)
+	}
+	return expr
+}
+
+// processingTimeSentinels は処理時間のカラムの -1 をNULLに変換する定義を返します
+func processingTimeSentinels(columns ...string) []NullSentinel {
+	sentinels := make([]NullSentinel, 0, len(columns))
+	for _, c := range columns {
+		sentinels = append(sentinels, NullSentinel{Column: c, Value: "-1"})
+	}
+	return sentinels
+}
+
+// dashSentinels は文字列のカラムの "-" をNULLに変換する定義を返します
+func dashSentinels(columns ...string) []NullSentinel {
+	sentinels := make([]NullSentinel, 0, len(columns))
+	for _, c := range columns {
+		sentinels = append(sentinels, NullSentinel{Column: c, Value: "'-'"})
+	}
+	return sentinels
+}
+
+// joinSentinels はNULL変換の定義の一覧を結合します
+func joinSentinels(groups ...[]NullSentinel) []NullSentinel {
+	var sentinels []NullSentinel
+	for _, g := range groups {
+		sentinels = append(sentinels, g...)
+	}
+	return sentinels
+}
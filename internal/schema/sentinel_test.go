@@ -0,0 +1,32 @@
+package schema
+
+import "testing"
+
+func TestNullSentinelExpr(t *testing.T) {
+	testCases := []struct {
+		sentinel NullSentinel
+		expected string
+	}{
+		{NullSentinel{Column: "request_processing_time", Value: "-1"}, "nullif(request_processing_time, -1)"},
+		{NullSentinel{Column: "ssl_cipher", Value: "'-'"}, "nullif(ssl_cipher, '-')"},
+		{NullSentinel{Column: "target_status_code", Value: "'-'", Type: "INTEGER"}, "TRY_CAST(nullif(target_status_code, '-') AS INTEGER)"},
+	}
+
+	for _, tc := range testCases {
+		if got := tc.sentinel.Expr(); got != tc.expected {
+			t.Errorf("Expr() = %q, expected %q", got, tc.expected)
+		}
+	}
+}
+
+func TestNullSentinelsReferToColumns(t *testing.T) {
+	// センチネル値の変換対象は読み込むカラムに含まれる必要がある
+	for _, name := range Names() {
+		f, _ := Get(name)
+		for _, s := range f.NullSentinels {
+			if _, ok := f.Column(s.Column); !ok {
+				t.Errorf("%s: null sentinel refers to unknown column %s", name, s.Column)
+			}
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/naotama2002/dalv/internal/duckdb"
+)
+
+// arrowTimestampLayout はDuckDBがJSONに出力するTIMESTAMPの形式です
+const arrowTimestampLayout = "2006-01-02 15:04:05.999999999"
+
+// arrowTypes はDuckDBのデータ型に対応するArrowのデータ型です
+// 対応していない型は文字列として返します
+var arrowTypes = map[string]arrow.DataType{
+	"BOOLEAN":   arrow.FixedWidthTypes.Boolean,
+	"TINYINT":   arrow.PrimitiveTypes.Int64,
+	"SMALLINT":  arrow.PrimitiveTypes.Int64,
+	"INTEGER":   arrow.PrimitiveTypes.Int64,
+	"BIGINT":    arrow.PrimitiveTypes.Int64,
+	"UTINYINT":  arrow.PrimitiveTypes.Int64,
+	"USMALLINT": arrow.PrimitiveTypes.Int64,
+	"UINTEGER":  arrow.PrimitiveTypes.Int64,
+	"FLOAT":     arrow.PrimitiveTypes.Float64,
+	"DOUBLE":    arrow.PrimitiveTypes.Float64,
+	"DATE":      arrow.FixedWidthTypes.Date32,
+	"TIMESTAMP": arrow.FixedWidthTypes.Timestamp_us,
+}
+
+// arrowType はDuckDBのデータ型に対応するArrowのデータ型を返します
+func arrowType(duckdbType string) arrow.DataType {
+	if strings.HasPrefix(duckdbType, "DECIMAL") {
+		return arrow.PrimitiveTypes.Float64
+	}
+	if t, ok := arrowTypes[duckdbType]; ok {
+		return t
+	}
+	return arrow.BinaryTypes.String
+}
+
+// queryArrow はクエリを実行し、結果をArrowのIPCストリームで w に書き込みます
+// カラムの型を DESCRIBE で取得し、JSONで受け取った結果を変換します
+// 変換前のJSONにも w と同じ最大サイズを適用します
+func (s *Server) queryArrow(ctx context.Context, query string, w *resultBuffer) error {
+	columns, err := s.db.Describe(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	out := &resultBuffer{limit: w.limit, cancel: w.cancel}
+	err = s.db.Query(ctx, query, duckdb.OutputJSON, out)
+	if out.exceeded {
+		w.exceeded = true
+		return errResultTooLarge
+	}
+	if err != nil {
+		return err
+	}
+
+	record, err := buildArrowRecord(columns, &out.buf)
+	if err != nil {
+		return err
+	}
+	defer record.Release()
+
+	writer := ipc.NewWriter(w, ipc.WithSchema(record.Schema()))
+	if err := writer.Write(record); err != nil {
+		return fmt.Errorf("Arrow形式への変換に失敗しました: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("Arrow形式への変換に失敗しました: %w", err)
+	}
+	return nil
+}
+
+// buildArrowRecord はDuckDBがJSONで出力した結果をArrowのレコードに変換します
+func buildArrowRecord(columns []duckdb.ResultColumn, r io.Reader) (arrow.Record, error) {
+	fields := make([]arrow.Field, len(columns))
+	for i, c := range columns {
+		fields[i] = arrow.Field{Name: c.Name, Type: arrowType(c.Type), Nullable: true}
+	}
+	builder := array.NewRecordBuilder(memory.DefaultAllocator, arrow.NewSchema(fields, nil))
+	defer builder.Release()
+
+	// 結果が0行の場合、DuckDBは何も出力しない
+	var rows []map[string]any
+	decoder := json.NewDecoder(r)
+	// 大きな整数の精度を失わないように数値は文字列のまま受け取る
+	decoder.UseNumber()
+	if err := decoder.Decode(&rows); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("クエリ結果の読み込みに失敗しました: %w", err)
+	}
+
+	for _, row := range rows {
+		for i, c := range columns {
+			if err := appendArrowValue(builder.Field(i), row[c.Name]); err != nil {
+				return nil, fmt.Errorf("カラム %s の値を変換できません: %w", c.Name, err)
+			}
+		}
+	}
+	return builder.NewRecord(), nil
+}
+
+// appendArrowValue は値をArrowのデータ型に変換して追加します
+func appendArrowValue(b array.Builder, value any) error {
+	if value == nil {
+		b.AppendNull()
+		return nil
+	}
+
+	switch b := b.(type) {
+	case *array.BooleanBuilder:
+		v, ok := value.(bool)
+		if !ok {
+			return fmt.Errorf("真偽値ではありません: %v", value)
+		}
+		b.Append(v)
+	case *array.Int64Builder:
+		n, ok := value.(json.Number)
+		if !ok {
+			return fmt.Errorf("整数ではありません: %v", value)
+		}
+		v, err := n.Int64()
+		if err != nil {
+			return err
+		}
+		b.Append(v)
+	case *array.Float64Builder:
+		n, ok := value.(json.Number)
+		if !ok {
+			return fmt.Errorf("数値ではありません: %v", value)
+		}
+		v, err := n.Float64()
+		if err != nil {
+			return err
+		}
+		b.Append(v)
+	case *array.Date32Builder:
+		t, err := time.Parse(time.DateOnly, fmt.Sprint(value))
+		if err != nil {
+			return err
+		}
+		b.Append(arrow.Date32FromTime(t))
+	case *array.TimestampBuilder:
+		t, err := time.Parse(arrowTimestampLayout, fmt.Sprint(value))
+		if err != nil {
+			return err
+		}
+		b.Append(arrow.Timestamp(t.UnixMicro()))
+	case *array.StringBuilder:
+		switch v := value.(type) {
+		case string:
+			b.Append(v)
+		default:
+			// リストや構造体などはJSONの文字列として返す
+			data, err := json.Marshal(v)
+			if err != nil {
+				return err
+			}
+			b.Append(string(data))
+		}
+	default:
+		return fmt.Errorf("未対応の型です: %s", b.Type())
+	}
+	return nil
+}
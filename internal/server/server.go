@@ -0,0 +1,261 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/naotama2002/dalv/internal/duckdb"
+)
+
+const (
+	// FormatJSON はオブジェクトの配列のJSON形式です
+	FormatJSON = "json"
+	// FormatNDJSON は1行に1オブジェクトのJSON形式です
+	FormatNDJSON = "ndjson"
+	// FormatCSV はヘッダー行付きのCSV形式です
+	FormatCSV = "csv"
+	// FormatArrow はApache ArrowのIPCストリーム形式です
+	FormatArrow = "arrow"
+
+	// maxRequestBytes はクエリのリクエストの本文の最大サイズです
+	maxRequestBytes = 1 << 20
+)
+
+// errResultTooLarge はクエリの結果がレスポンスの最大サイズを超えたことを表します
+var errResultTooLarge = errors.New("クエリの結果がレスポンスの最大サイズを超えました")
+
+// contentTypes は結果の形式に対応するContent-Typeです
+var contentTypes = map[string]string{
+	FormatJSON:   "application/json",
+	FormatNDJSON: "application/x-ndjson",
+	FormatCSV:    "text/csv; charset=utf-8",
+	FormatArrow:  "application/vnd.apache.arrow.stream",
+}
+
+// Database はクエリを実行するデータベースです
+type Database interface {
+	// TableName はログを読み込んだテーブル名を返します
+	TableName() string
+	// Query はクエリを読み取り専用で実行し、結果を w に書き込みます
+	Query(ctx context.Context, query string, format duckdb.OutputFormat, w io.Writer) error
+	// Describe はクエリの結果のカラム名と型を返します
+	Describe(ctx context.Context, query string) ([]duckdb.ResultColumn, error)
+}
+
+// Config はHTTP APIサーバーの設定です
+type Config struct {
+	// Database はクエリを実行するデータベースです
+	Database Database
+	// Token はリクエストに必要なBearerトークンです (空の場合は認証しない)
+	Token string
+	// Timeout は1つのクエリの実行時間の上限です (0の場合は制限なし)
+	Timeout time.Duration
+	// MaxBytes は1つのクエリの結果のレスポンスの最大サイズ (バイト) です (0の場合は制限なし)
+	MaxBytes int64
+}
+
+// Server はログを読み込んだテーブルにSQLを実行するHTTP APIサーバーです
+type Server struct {
+	db       Database
+	token    string
+	timeout  time.Duration
+	maxBytes int64
+	mux      *http.ServeMux
+}
+
+// queryRequest はJSON形式のクエリのリクエストです
+type queryRequest struct {
+	SQL    string `json:"sql"`
+	Format string `json:"format"`
+}
+
+// errorResponse はエラーのレスポンスです
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// NewServer は新しいHTTP APIサーバーを作成します
+func NewServer(cfg Config) *Server {
+	s := &Server{
+		db:       cfg.Database,
+		token:    cfg.Token,
+		timeout:  cfg.Timeout,
+		maxBytes: cfg.MaxBytes,
+		mux:      http.NewServeMux(),
+	}
+	s.mux.HandleFunc("GET /health", s.handleHealth)
+	s.mux.Handle("POST /query", s.authenticate(http.HandlerFunc(s.handleQuery)))
+	return s
+}
+
+// ServeHTTP はリクエストを処理します
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// authenticate はBearerトークンを検証するハンドラーを返します
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.token != "" {
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				writeError(w, http.StatusUnauthorized, "認証に失敗しました")
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleHealth はサーバーの状態とテーブル名を返します
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok", "table": s.db.TableName()})
+}
+
+// handleQuery はSQLを実行して結果を返します
+// 本文にはJSON ({"sql": "...", "format": "..."}) またはSQLをそのまま指定できます
+// 形式は本文の format、?format=、Acceptヘッダーの順に決定し、指定がない場合はJSONです
+func (s *Server) handleQuery(w http.ResponseWriter, r *http.Request) {
+	req, err := parseQueryRequest(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if _, ok := contentTypes[req.Format]; !ok {
+		writeError(w, http.StatusNotAcceptable, fmt.Sprintf("未対応の形式です: %s (対応形式: json, ndjson, csv, arrow)", req.Format))
+		return
+	}
+
+	ctx := r.Context()
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+
+	// エラーの場合にステータスコードを返せるように、結果をすべて取得してから書き込む
+	// 結果が最大サイズを超えた場合はクエリを中断する
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	body := &resultBuffer{limit: s.maxBytes, cancel: cancel}
+	if req.Format == FormatArrow {
+		err = s.queryArrow(ctx, req.SQL, body)
+	} else {
+		err = s.db.Query(ctx, req.SQL, duckdb.OutputFormat(req.Format), body)
+	}
+	// 書き込みのエラーを返さないデータベースでも、最大サイズを超えた結果は途中までで返さない
+	if body.exceeded {
+		writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("クエリの結果が %s を超えました。集計するか LIMIT で行数を絞り込んでください", duckdb.FormatSize(s.maxBytes)))
+		return
+	}
+	if err != nil {
+		var queryErr *duckdb.QueryError
+		switch {
+		case errors.Is(err, context.DeadlineExceeded):
+			writeError(w, http.StatusGatewayTimeout, fmt.Sprintf("クエリが %s 以内に完了しませんでした", s.timeout))
+		case errors.As(err, &queryErr):
+			writeError(w, http.StatusBadRequest, queryErr.Message)
+		default:
+			writeError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	// DuckDBは結果が0行の場合にJSONを出力しない
+	if req.Format == FormatJSON && body.buf.Len() == 0 {
+		body.buf.WriteString("[]\n")
+	}
+
+	w.Header().Set("Content-Type", contentTypes[req.Format])
+	w.WriteHeader(http.StatusOK)
+	w.Write(body.buf.Bytes())
+}
+
+// resultBuffer はクエリの結果を保持するバッファです
+// limit を超えて書き込もうとした場合は cancel でクエリを中断し、エラーを返します
+// bytes.Buffer の WriteString などで上限を迂回しないように、埋め込まずにフィールドで保持します
+type resultBuffer struct {
+	buf    bytes.Buffer
+	limit  int64
+	cancel context.CancelFunc
+	// exceeded は limit を超えたかどうかです
+	exceeded bool
+}
+
+// Write は limit を超えない場合に p をバッファに追加します
+func (b *resultBuffer) Write(p []byte) (int, error) {
+	if b.limit > 0 && int64(b.buf.Len()+len(p)) > b.limit {
+		b.exceeded = true
+		b.cancel()
+		return 0, errResultTooLarge
+	}
+	return b.buf.Write(p)
+}
+
+// parseQueryRequest はクエリのリクエストを解析します
+func parseQueryRequest(r *http.Request) (queryRequest, error) {
+	var req queryRequest
+	data, err := io.ReadAll(http.MaxBytesReader(nil, r.Body, maxRequestBytes))
+	if err != nil {
+		return req, fmt.Errorf("リクエストの読み込みに失敗しました: %w", err)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "application/json" {
+		if err := json.Unmarshal(data, &req); err != nil {
+			return req, fmt.Errorf("リクエストのJSONの形式が不正です: %w", err)
+		}
+	} else {
+		req.SQL = string(data)
+	}
+	if strings.TrimSpace(req.SQL) == "" {
+		return req, fmt.Errorf("SQLが指定されていません")
+	}
+
+	if req.Format == "" {
+		req.Format = r.URL.Query().Get("format")
+	}
+	if req.Format == "" {
+		req.Format = formatFromAccept(r.Header.Get("Accept"))
+	}
+	req.Format = strings.ToLower(req.Format)
+	return req, nil
+}
+
+// formatFromAccept はAcceptヘッダーから結果の形式を決定します
+func formatFromAccept(accept string) string {
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		for format, contentType := range contentTypes {
+			if ct, _, _ := mime.ParseMediaType(contentType); ct == mediaType {
+				return format
+			}
+		}
+	}
+	return FormatJSON
+}
+
+// writeJSON は値をJSONで書き込みます
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", contentTypes[FormatJSON])
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError はエラーをJSONで書き込みます
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, errorResponse{Error: message})
+}
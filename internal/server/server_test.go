@@ -0,0 +1,315 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/naotama2002/dalv/internal/duckdb"
+)
+
+// fakeDatabase は受け取ったクエリを記録し、決まった結果を返すデータベースです
+type fakeDatabase struct {
+	queries []string
+	formats []duckdb.OutputFormat
+	output  map[duckdb.OutputFormat]string
+	columns []duckdb.ResultColumn
+	err     error
+	delay   time.Duration
+}
+
+func (f *fakeDatabase) TableName() string {
+	return "alb_logs"
+}
+
+func (f *fakeDatabase) Query(ctx context.Context, query string, format duckdb.OutputFormat, w io.Writer) error {
+	f.queries = append(f.queries, query)
+	f.formats = append(f.formats, format)
+	if f.delay > 0 {
+		select {
+		case <-time.After(f.delay):
+		case <-ctx.Done():
+			return fmt.Errorf("クエリが中断されました: %w", ctx.Err())
+		}
+	}
+	if f.err != nil {
+		return f.err
+	}
+	io.WriteString(w, f.output[format])
+	return nil
+}
+
+func (f *fakeDatabase) Describe(ctx context.Context, query string) ([]duckdb.ResultColumn, error) {
+	return f.columns, f.err
+}
+
+func doRequest(s *Server, method, target, contentType, body string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestQueryFormats(t *testing.T) {
+	db := &fakeDatabase{output: map[duckdb.OutputFormat]string{
+		duckdb.OutputJSON:   `[{"n":1}]` + "\n",
+		duckdb.OutputNDJSON: `{"n":1}` + "\n",
+		duckdb.OutputCSV:    "n\n1\n",
+	}}
+	s := NewServer(Config{Database: db})
+
+	tests := []struct {
+		name        string
+		target      string
+		contentType string
+		body        string
+		accept      string
+		expected    string
+		mediaType   string
+	}{
+		{"raw SQL defaults to json", "/query", "text/plain", "SELECT 1 AS n", "", `[{"n":1}]` + "\n", "application/json"},
+		{"format query parameter", "/query?format=csv", "", "SELECT 1 AS n", "", "n\n1\n", "text/csv; charset=utf-8"},
+		{"format in JSON body", "/query", "application/json", `{"sql":"SELECT 1 AS n","format":"ndjson"}`, "", `{"n":1}` + "\n", "application/x-ndjson"},
+		{"accept header", "/query", "", "SELECT 1 AS n", "text/csv, application/json;q=0.5", "n\n1\n", "text/csv; charset=utf-8"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := doRequest(s, http.MethodPost, tt.target, tt.contentType, tt.body, map[string]string{"Accept": tt.accept})
+			if rec.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+			}
+			if rec.Body.String() != tt.expected {
+				t.Errorf("Expected body %q, got %q", tt.expected, rec.Body.String())
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != tt.mediaType {
+				t.Errorf("Expected Content-Type %q, got %q", tt.mediaType, ct)
+			}
+		})
+	}
+
+	if db.queries[2] != "SELECT 1 AS n" {
+		t.Errorf("Expected SQL from JSON body, got %q", db.queries[2])
+	}
+}
+
+func TestQueryEmptyJSONResult(t *testing.T) {
+	// DuckDBは結果が0行の場合に何も出力しないため空の配列を返す
+	s := NewServer(Config{Database: &fakeDatabase{}})
+	rec := doRequest(s, http.MethodPost, "/query", "", "SELECT 1 WHERE false", nil)
+	if rec.Code != http.StatusOK || rec.Body.String() != "[]\n" {
+		t.Errorf("Expected empty JSON array, got %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestQueryErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		db       *fakeDatabase
+		timeout  time.Duration
+		target   string
+		body     string
+		expected int
+	}{
+		{"empty SQL", &fakeDatabase{}, 0, "/query", "  ", http.StatusBadRequest},
+		{"unsupported format", &fakeDatabase{}, 0, "/query?format=xml", "SELECT 1", http.StatusNotAcceptable},
+		{"query error", &fakeDatabase{err: &duckdb.QueryError{Message: "Parser Error"}}, 0, "/query", "SELEC 1", http.StatusBadRequest},
+		{"timeout", &fakeDatabase{delay: time.Second}, 10 * time.Millisecond, "/query", "SELECT 1", http.StatusGatewayTimeout},
+		{"internal error", &fakeDatabase{err: fmt.Errorf("DuckDBの実行に失敗しました")}, 0, "/query", "SELECT 1", http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewServer(Config{Database: tt.db, Timeout: tt.timeout})
+			rec := doRequest(s, http.MethodPost, tt.target, "", tt.body, nil)
+			if rec.Code != tt.expected {
+				t.Errorf("Expected status %d, got %d", tt.expected, rec.Code)
+			}
+			var resp errorResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || resp.Error == "" {
+				t.Errorf("Expected JSON error response, got %q", rec.Body.String())
+			}
+		})
+	}
+}
+
+// streamingDatabase は書き込みに失敗するかクエリが中断されるまで行を書き込み続けるデータベースです
+type streamingDatabase struct {
+	fakeDatabase
+	// ctxErr はクエリの終了時の ctx のエラーです
+	ctxErr error
+}
+
+func (d *streamingDatabase) Query(ctx context.Context, query string, format duckdb.OutputFormat, w io.Writer) error {
+	for {
+		if _, err := io.WriteString(w, `{"n":1}`+"\n"); err != nil {
+			d.ctxErr = ctx.Err()
+			return fmt.Errorf("クエリの実行に失敗しました: %w", err)
+		}
+	}
+}
+
+func TestQueryMaxBytes(t *testing.T) {
+	db := &fakeDatabase{
+		columns: []duckdb.ResultColumn{{Name: "n", Type: "BIGINT"}},
+		output: map[duckdb.OutputFormat]string{
+			duckdb.OutputJSON: `[{"n":1},{"n":2}]` + "\n",
+			duckdb.OutputCSV:  "n\n1\n2\n",
+		},
+	}
+
+	// 最大サイズ以下の結果はそのまま返す
+	s := NewServer(Config{Database: db, MaxBytes: 64})
+	if rec := doRequest(s, http.MethodPost, "/query", "", "SELECT n FROM t", nil); rec.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// 最大サイズを超えた結果は途中までを返さずに 413 を返す
+	s = NewServer(Config{Database: db, MaxBytes: 4})
+	for _, target := range []string{"/query", "/query?format=csv", "/query?format=arrow"} {
+		rec := doRequest(s, http.MethodPost, target, "", "SELECT n FROM t", nil)
+		if rec.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("%s: expected status 413, got %d: %s", target, rec.Code, rec.Body.String())
+		}
+		var resp errorResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || !strings.Contains(resp.Error, "4 B") {
+			t.Errorf("%s: expected JSON error with the limit, got %q", target, rec.Body.String())
+		}
+	}
+
+	// 0 の場合は制限しない
+	s = NewServer(Config{Database: db})
+	if rec := doRequest(s, http.MethodPost, "/query", "", "SELECT n FROM t", nil); rec.Code != http.StatusOK {
+		t.Errorf("Expected status 200 without limit, got %d", rec.Code)
+	}
+
+	// 最大サイズを超えた時点でクエリを中断する
+	streaming := &streamingDatabase{}
+	s = NewServer(Config{Database: streaming, MaxBytes: 1 << 10})
+	if rec := doRequest(s, http.MethodPost, "/query", "", "SELECT * FROM alb_logs", nil); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status 413, got %d", rec.Code)
+	}
+	if !errors.Is(streaming.ctxErr, context.Canceled) {
+		t.Errorf("Expected query to be canceled, got %v", streaming.ctxErr)
+	}
+}
+
+func TestQueryMethodNotAllowed(t *testing.T) {
+	s := NewServer(Config{Database: &fakeDatabase{}})
+	rec := doRequest(s, http.MethodGet, "/query", "", "", nil)
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405, got %d", rec.Code)
+	}
+}
+
+func TestTokenAuth(t *testing.T) {
+	db := &fakeDatabase{output: map[duckdb.OutputFormat]string{duckdb.OutputJSON: "[]\n"}}
+	s := NewServer(Config{Database: db, Token: "secret"})
+
+	tests := []struct {
+		name          string
+		authorization string
+		expected      int
+	}{
+		{"no token", "", http.StatusUnauthorized},
+		{"wrong token", "Bearer wrong", http.StatusUnauthorized},
+		{"not bearer", "Basic secret", http.StatusUnauthorized},
+		{"valid token", "Bearer secret", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := doRequest(s, http.MethodPost, "/query", "", "SELECT 1", map[string]string{"Authorization": tt.authorization})
+			if rec.Code != tt.expected {
+				t.Errorf("Expected status %d, got %d", tt.expected, rec.Code)
+			}
+		})
+	}
+	if len(db.queries) != 1 {
+		t.Errorf("Expected only authenticated query to run, got %d queries", len(db.queries))
+	}
+
+	// ヘルスチェックは認証不要
+	rec := doRequest(s, http.MethodGet, "/health", "", "", nil)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"table":"alb_logs"`) {
+		t.Errorf("Expected health response, got %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestQueryArrow(t *testing.T) {
+	db := &fakeDatabase{
+		columns: []duckdb.ResultColumn{
+			{Name: "elb", Type: "VARCHAR"},
+			{Name: "requests", Type: "BIGINT"},
+			{Name: "avg_time", Type: "DOUBLE"},
+			{Name: "time", Type: "TIMESTAMP"},
+			{Name: "ok", Type: "BOOLEAN"},
+		},
+		output: map[duckdb.OutputFormat]string{
+			duckdb.OutputJSON: `[{"elb":"app/a","requests":9007199254740993,"avg_time":0.25,"time":"2025-03-30 22:00:00.5","ok":true},
+{"elb":null,"requests":1,"avg_time":null,"time":"2025-03-30 22:05:00","ok":false}]`,
+		},
+	}
+	s := NewServer(Config{Database: db})
+	rec := doRequest(s, http.MethodPost, "/query?format=arrow", "", "SELECT * FROM summary", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	reader, err := ipc.NewReader(rec.Body)
+	if err != nil {
+		t.Fatalf("Failed to read arrow stream: %v", err)
+	}
+	defer reader.Release()
+	if !reader.Next() {
+		t.Fatal("Expected a record in arrow stream")
+	}
+	record := reader.Record()
+	if record.NumRows() != 2 || record.NumCols() != 5 {
+		t.Fatalf("Expected 2x5 record, got %dx%d", record.NumRows(), record.NumCols())
+	}
+
+	// 大きな整数も精度を失わずに変換する
+	if v := record.Column(1).(*array.Int64).Value(0); v != 9007199254740993 {
+		t.Errorf("Expected requests 9007199254740993, got %d", v)
+	}
+	if !record.Column(0).IsNull(1) || !record.Column(2).IsNull(1) {
+		t.Error("Expected null values in second row")
+	}
+	expectedTime := time.Date(2025, 3, 30, 22, 0, 0, 500000000, time.UTC)
+	if v := record.Column(3).(*array.Timestamp).Value(0).ToTime(arrow.Microsecond); !v.Equal(expectedTime) {
+		t.Errorf("Expected time %s, got %s", expectedTime, v)
+	}
+	if rec.Header().Get("Content-Type") != "application/vnd.apache.arrow.stream" {
+		t.Errorf("Unexpected Content-Type: %s", rec.Header().Get("Content-Type"))
+	}
+}
+
+func TestArrowType(t *testing.T) {
+	tests := map[string]arrow.DataType{
+		"INTEGER":       arrow.PrimitiveTypes.Int64,
+		"DECIMAL(18,3)": arrow.PrimitiveTypes.Float64,
+		"DATE":          arrow.FixedWidthTypes.Date32,
+		"VARCHAR":       arrow.BinaryTypes.String,
+		"HUGEINT":       arrow.BinaryTypes.String,
+		"VARCHAR[]":     arrow.BinaryTypes.String,
+	}
+	for duckdbType, expected := range tests {
+		if got := arrowType(duckdbType); !arrow.TypeEqual(got, expected) {
+			t.Errorf("arrowType(%q) = %s, expected %s", duckdbType, got, expected)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package source
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// deliveryDelay はELBがログファイルを出力する間隔です
+// ファイルは集計期間の終了時刻の日付のディレクトリに置かれるため、
+// 範囲の終端からこの時間だけ後のディレクトリまで読み込みます
+// まだログのない日のglobは読み込む前の確認で除外されます
+const deliveryDelay = 5 * time.Minute
+
+// ELBLocation はELBアクセスログの保存先です
+// ログは s3://{bucket}/{prefix}/AWSLogs/{account}/elasticloadbalancing/{region}/yyyy/mm/dd/ に保存されます
+type ELBLocation struct {
+	Bucket  string
+	Prefix  string
+	Account string
+	Region  string
+}
+
+// Validate は保存先の指定に不足がないかを検証します
+func (l *ELBLocation) Validate() error {
+	var missing []string
+	if l.Bucket == "" {
+		missing = append(missing, "--bucket")
+	}
+	if l.Account == "" {
+		missing = append(missing, "--account")
+	}
+	if l.Region == "" {
+		missing = append(missing, "--region")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("ログの保存先を指定するには %s が必要です", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// BasePath は日付ディレクトリの親となるS3パスを返します
+func (l *ELBLocation) BasePath() string {
+	parts := []string{"s3://" + strings.Trim(l.Bucket, "/")}
+	if prefix := strings.Trim(l.Prefix, "/"); prefix != "" {
+		parts = append(parts, prefix)
+	}
+	parts = append(parts, "AWSLogs", l.Account, "elasticloadbalancing", l.Region)
+	return strings.Join(parts, "/")
+}
+
+// Globs は時間範囲のログを読み込むためのS3パスのglobを返します
+// 年または月がまるごと範囲に含まれる場合はまとめて1つのglobにします
+func (l *ELBLocation) Globs(r *TimeRange) []string {
+	base := l.BasePath()
+	end := &TimeRange{From: r.From, To: r.To.Add(deliveryDelay)}
+	days := end.Days()
+	if len(days) == 0 {
+		return nil
+	}
+	first, last := days[0], days[len(days)-1]
+
+	var globs []string
+	for day := first; !day.After(last); {
+		yearEnd := time.Date(day.Year(), time.December, 31, 0, 0, 0, 0, time.UTC)
+		monthEnd := time.Date(day.Year(), day.Month()+1, 0, 0, 0, 0, 0, time.UTC)
+
+		switch {
+		case day.YearDay() == 1 && !yearEnd.After(last):
+			globs = append(globs, fmt.Sprintf("%s/%04d/*/*/*.log*", base, day.Year()))
+			day = yearEnd.AddDate(0, 0, 1)
+		case day.Day() == 1 && !monthEnd.After(last):
+			globs = append(globs, fmt.Sprintf("%s/%04d/%02d/*/*.log*", base, day.Year(), day.Month()))
+			day = monthEnd.AddDate(0, 0, 1)
+		default:
+			globs = append(globs, fmt.Sprintf("%s/%04d/%02d/%02d/*.log*", base, day.Year(), day.Month(), day.Day()))
+			day = day.AddDate(0, 0, 1)
+		}
+	}
+
+	return globs
+}
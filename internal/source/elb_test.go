@@ -0,0 +1,109 @@
+package source
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestELBLocationBasePath(t *testing.T) {
+	testCases := []struct {
+		location ELBLocation
+		expected string
+	}{
+		{
+			ELBLocation{Bucket: "my-bucket", Prefix: "prod/", Account: "123456789012", Region: "ap-northeast-1"},
+			"s3://my-bucket/prod/AWSLogs/123456789012/elasticloadbalancing/ap-northeast-1",
+		},
+		{
+			ELBLocation{Bucket: "my-bucket", Account: "123456789012", Region: "us-east-1"},
+			"s3://my-bucket/AWSLogs/123456789012/elasticloadbalancing/us-east-1",
+		},
+	}
+
+	for _, tc := range testCases {
+		if got := tc.location.BasePath(); got != tc.expected {
+			t.Errorf("BasePath() = %q, expected %q", got, tc.expected)
+		}
+	}
+}
+
+func TestELBLocationValidate(t *testing.T) {
+	location := &ELBLocation{Bucket: "my-bucket"}
+	if err := location.Validate(); err == nil {
+		t.Error("Validate should fail when account and region are missing")
+	}
+
+	location = &ELBLocation{Bucket: "my-bucket", Account: "123456789012", Region: "us-east-1"}
+	if err := location.Validate(); err != nil {
+		t.Errorf("Validate returned error: %v", err)
+	}
+}
+
+func TestELBLocationGlobs(t *testing.T) {
+	location := &ELBLocation{Bucket: "b", Account: "1", Region: "r"}
+	base := "s3://b/AWSLogs/1/elasticloadbalancing/r"
+
+	testCases := []struct {
+		name     string
+		from     time.Time
+		to       time.Time
+		expected []string
+	}{
+		{
+			name: "月をまたぐ範囲",
+			from: time.Date(2025, 3, 30, 22, 0, 0, 0, time.UTC),
+			to:   time.Date(2025, 4, 2, 12, 0, 0, 0, time.UTC),
+			expected: []string{
+				base + "/2025/03/30/*.log*",
+				base + "/2025/03/31/*.log*",
+				base + "/2025/04/01/*.log*",
+				base + "/2025/04/02/*.log*",
+			},
+		},
+		{
+			name: "日の終わりで終わる範囲は翌日の最初のファイルも含む",
+			from: time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC),
+			to:   time.Date(2025, 3, 4, 0, 0, 0, 0, time.UTC),
+			expected: []string{
+				base + "/2025/03/03/*.log*",
+				base + "/2025/03/04/*.log*",
+			},
+		},
+		{
+			name: "年をまたぐ範囲",
+			from: time.Date(2024, 12, 31, 23, 0, 0, 0, time.UTC),
+			to:   time.Date(2025, 1, 1, 1, 0, 0, 0, time.UTC),
+			expected: []string{
+				base + "/2024/12/31/*.log*",
+				base + "/2025/01/01/*.log*",
+			},
+		},
+		{
+			name: "月全体を含む範囲は月単位のglobにまとめる",
+			from: time.Date(2025, 1, 31, 12, 0, 0, 0, time.UTC),
+			to:   time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC),
+			expected: []string{
+				base + "/2025/01/31/*.log*",
+				base + "/2025/02/*/*.log*",
+				base + "/2025/03/01/*.log*",
+			},
+		},
+		{
+			name: "年全体を含む範囲は年単位のglobにまとめる",
+			from: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			to:   time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+			expected: []string{
+				base + "/2024/*/*/*.log*",
+				base + "/2025/01/01/*.log*",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		got := location.Globs(&TimeRange{From: tc.from, To: tc.to})
+		if !reflect.DeepEqual(got, tc.expected) {
+			t.Errorf("%s: Globs() = %v, expected %v", tc.name, got, tc.expected)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package source
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// remoteSchemes はリモートのログとして扱うURIスキームです
+var remoteSchemes = []string{"s3://"}
+
+// localLogExtensions はディレクトリを指定した場合に読み込むログファイルの拡張子です
+var localLogExtensions = []string{".gz", ".log"}
+
+// IsRemote はパスがS3などのリモートのパスかどうかを返します
+func IsRemote(p string) bool {
+	for _, scheme := range remoteSchemes {
+		if strings.HasPrefix(p, scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// AnyRemote はパスの一覧にリモートのパスが含まれるかどうかを返します
+func AnyRemote(paths []string) bool {
+	for _, p := range paths {
+		if IsRemote(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsGlob はパスにglobのメタ文字が含まれるかどうかを返します
+func IsGlob(p string) bool {
+	return strings.ContainsAny(p, "*?[")
+}
+
+// ExpandPath はパスをDuckDBで読み込める形式に変換します
+// リモートのパスはそのまま返します
+// file:// URI はローカルパスに変換し、ディレクトリは配下のログファイルのglobに展開します
+func ExpandPath(p string) ([]string, error) {
+	if IsRemote(p) {
+		return []string{p}, nil
+	}
+
+	local, err := fromFileURI(p)
+	if err != nil {
+		return nil, err
+	}
+
+	if IsGlob(local) {
+		return []string{local}, nil
+	}
+
+	info, err := os.Stat(local)
+	if err != nil || !info.IsDir() {
+		// ファイルの存在確認は検証時に行う
+		return []string{local}, nil
+	}
+
+	return expandDirectory(local)
+}
+
+// fromFileURI は file:// URI をローカルパスに変換します
+// file:// で始まらないパスはそのまま返します
+func fromFileURI(p string) (string, error) {
+	if !strings.HasPrefix(p, "file://") {
+		return p, nil
+	}
+
+	u, err := url.Parse(p)
+	if err != nil {
+		return "", fmt.Errorf("無効なファイルURIです: %s: %w", p, err)
+	}
+	if u.Host != "" && u.Host != "localhost" {
+		return "", fmt.Errorf("ファイルURIにはホスト名を指定できません: %s", p)
+	}
+	if u.Path == "" {
+		return "", fmt.Errorf("ファイルURIにパスが含まれていません: %s", p)
+	}
+
+	return filepath.FromSlash(u.Path), nil
+}
+
+// expandDirectory はディレクトリ配下 (サブディレクトリを含む) のログファイルを読み込むglobを返します
+// 実際にファイルが存在する拡張子のglobのみを返します
+func expandDirectory(dir string) ([]string, error) {
+	found := map[string]bool{}
+	err := filepath.WalkDir(dir, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		for _, ext := range localLogExtensions {
+			if strings.HasSuffix(d.Name(), ext) {
+				found[ext] = true
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ディレクトリの走査に失敗しました: %s: %w", dir, err)
+	}
+
+	if len(found) == 0 {
+		return nil, fmt.Errorf("ディレクトリにログファイル (%s) が見つかりません: %s", strings.Join(localLogExtensions, ", "), dir)
+	}
+
+	var globs []string
+	for ext := range found {
+		globs = append(globs, filepath.Join(dir, "**", "*"+ext))
+	}
+	sort.Strings(globs)
+	return globs, nil
+}
+
+// MatchLocal はローカルのパスまたはglobに一致するファイルを返します
+// DuckDBと同様に ** は任意の深さのディレクトリに一致します
+// 一致するファイルがない場合は空の一覧を返します
+func MatchLocal(pattern string) ([]string, error) {
+	if !IsGlob(pattern) {
+		info, err := os.Stat(pattern)
+		if err != nil || info.IsDir() {
+			return nil, nil
+		}
+		return []string{pattern}, nil
+	}
+
+	// globを含まない親ディレクトリから走査する
+	segments := strings.Split(filepath.ToSlash(pattern), "/")
+	n := 0
+	for n < len(segments) && !IsGlob(segments[n]) {
+		n++
+	}
+	root := strings.Join(segments[:n], "/")
+	switch {
+	case n > 0 && root == "":
+		root = "/"
+	case root == "":
+		root = "."
+	}
+
+	var matches []string
+	err := filepath.WalkDir(filepath.FromSlash(root), func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(filepath.FromSlash(root), p)
+		if err != nil {
+			return err
+		}
+		if matchSegments(segments[n:], strings.Split(filepath.ToSlash(rel), "/")) {
+			matches = append(matches, p)
+		}
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ファイルの検索に失敗しました: %s: %w", pattern, err)
+	}
+	return matches, nil
+}
+
+// matchSegments はパスの要素がglobの要素に一致するかどうかを返します
+func matchSegments(pattern []string, name []string) bool {
+	if len(pattern) == 0 {
+		return len(name) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(name); i++ {
+			if matchSegments(pattern[1:], name[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(name) == 0 {
+		return false
+	}
+	matched, err := path.Match(pattern[0], name[0])
+	return err == nil && matched && matchSegments(pattern[1:], name[1:])
+}
+
+// FirstLine はローカルのログファイルの1行目を返します
+// .gz の場合は展開して読み込みます
+func FirstLine(p string) (string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", fmt.Errorf("ファイルを開けませんでした: %w", err)
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(p, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return "", fmt.Errorf("gzipの展開に失敗しました: %s: %w", p, err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", fmt.Errorf("ファイルの読み込みに失敗しました: %s: %w", p, err)
+	}
+	if line == "" {
+		return "", fmt.Errorf("ファイルが空です: %s", p)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
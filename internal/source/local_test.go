@@ -0,0 +1,200 @@
+package source
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestIsRemote(t *testing.T) {
+	testCases := []struct {
+		path     string
+		expected bool
+	}{
+		{"s3://bucket/path/*.log.gz", true},
+		{"/var/log/alb/*.log.gz", false},
+		{"./logs/alb.log", false},
+		{"file:///var/log/alb.log", false},
+	}
+
+	for _, tc := range testCases {
+		if got := IsRemote(tc.path); got != tc.expected {
+			t.Errorf("IsRemote(%q) = %v, expected %v", tc.path, got, tc.expected)
+		}
+	}
+
+	if AnyRemote([]string{"/tmp/a.log"}) {
+		t.Error("AnyRemote should be false for local paths only")
+	}
+
+	if !AnyRemote([]string{"/tmp/a.log", "s3://bucket/b.log"}) {
+		t.Error("AnyRemote should be true when a remote path is included")
+	}
+}
+
+func TestExpandPath(t *testing.T) {
+	testCases := []struct {
+		path     string
+		expected []string
+	}{
+		{"s3://bucket/path/*.log.gz", []string{"s3://bucket/path/*.log.gz"}},
+		{"/var/log/alb/*.log.gz", []string{"/var/log/alb/*.log.gz"}},
+		{"file:///var/log/alb/app.log.gz", []string{"/var/log/alb/app.log.gz"}},
+		{"file://localhost/var/log/alb/*.log", []string{"/var/log/alb/*.log"}},
+	}
+
+	for _, tc := range testCases {
+		got, err := ExpandPath(tc.path)
+		if err != nil {
+			t.Errorf("ExpandPath(%q) returned error: %v", tc.path, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(got, tc.expected) {
+			t.Errorf("ExpandPath(%q) = %v, expected %v", tc.path, got, tc.expected)
+		}
+	}
+
+	if _, err := ExpandPath("file://remote-host/var/log/alb.log"); err == nil {
+		t.Error("ExpandPath should fail for file URI with remote host")
+	}
+}
+
+func TestExpandPath_Directory(t *testing.T) {
+	dir := t.TempDir()
+	files := []string{
+		"2025/03/03/app.log.gz",
+		"2025/03/04/app.log.gz",
+		"classic/elb.log",
+		"README.txt",
+	}
+	for _, f := range files {
+		p := filepath.Join(dir, f)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(p, nil, 0644); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+	}
+
+	got, err := ExpandPath(dir)
+	if err != nil {
+		t.Fatalf("ExpandPath returned error: %v", err)
+	}
+
+	expected := []string{
+		filepath.Join(dir, "**", "*.gz"),
+		filepath.Join(dir, "**", "*.log"),
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("ExpandPath(dir) = %v, expected %v", got, expected)
+	}
+}
+
+func TestExpandPath_EmptyDirectory(t *testing.T) {
+	if _, err := ExpandPath(t.TempDir()); err == nil {
+		t.Error("ExpandPath should fail for directory without log files")
+	}
+}
+
+func TestMatchLocal(t *testing.T) {
+	dir := t.TempDir()
+	files := []string{
+		"2025/03/03/app.log.gz",
+		"2025/03/04/app.log.gz",
+		"classic/elb.log",
+		"top.log.gz",
+	}
+	for _, f := range files {
+		p := filepath.Join(dir, f)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(p, nil, 0644); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+	}
+
+	testCases := []struct {
+		pattern  string
+		expected []string
+	}{
+		// ** は0個以上のディレクトリに一致する
+		{filepath.Join(dir, "**", "*.gz"), []string{"2025/03/03/app.log.gz", "2025/03/04/app.log.gz", "top.log.gz"}},
+		{filepath.Join(dir, "2025", "*", "05", "*.gz"), nil},
+		{filepath.Join(dir, "2025", "03", "*", "*.gz"), []string{"2025/03/03/app.log.gz", "2025/03/04/app.log.gz"}},
+		{filepath.Join(dir, "*.log.gz"), []string{"top.log.gz"}},
+		{filepath.Join(dir, "classic", "elb.log"), []string{"classic/elb.log"}},
+		{filepath.Join(dir, "missing", "**", "*.gz"), nil},
+		{filepath.Join(dir, "missing.log"), nil},
+		// ディレクトリは一致しない
+		{filepath.Join(dir, "classic"), nil},
+	}
+
+	for _, tc := range testCases {
+		got, err := MatchLocal(tc.pattern)
+		if err != nil {
+			t.Errorf("MatchLocal(%q) returned error: %v", tc.pattern, err)
+			continue
+		}
+		var expected []string
+		for _, f := range tc.expected {
+			expected = append(expected, filepath.Join(dir, filepath.FromSlash(f)))
+		}
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("MatchLocal(%q) = %v, expected %v", tc.pattern, got, expected)
+		}
+	}
+}
+
+func TestFirstLine(t *testing.T) {
+	dir := t.TempDir()
+	plain := filepath.Join(dir, "app.log")
+	if err := os.WriteFile(plain, []byte("first line\r\nsecond line\n"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	// gzipで圧縮したファイルは展開して読み込む
+	compressed := filepath.Join(dir, "app.log.gz")
+	var b bytes.Buffer
+	gz := gzip.NewWriter(&b)
+	if _, err := gz.Write([]byte("compressed line")); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(compressed, b.Bytes(), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	for path, expected := range map[string]string{plain: "first line", compressed: "compressed line"} {
+		line, err := FirstLine(path)
+		if err != nil {
+			t.Errorf("FirstLine(%q) returned error: %v", path, err)
+			continue
+		}
+		if line != expected {
+			t.Errorf("FirstLine(%q) = %q, expected %q", path, line, expected)
+		}
+	}
+
+	// 空のファイル、存在しないファイル、gzip形式でない .gz はエラーにする
+	empty := filepath.Join(dir, "empty.log")
+	broken := filepath.Join(dir, "broken.gz")
+	if err := os.WriteFile(empty, nil, 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	if err := os.WriteFile(broken, []byte("not gzip"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	for _, path := range []string{empty, broken, filepath.Join(dir, "missing.log")} {
+		if _, err := FirstLine(path); err == nil {
+			t.Errorf("FirstLine(%q): expected error, got nil", path)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package source
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// timeLayouts は --from/--to で受け付ける日時の形式です (精度の高い順)
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02T15",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// TimeRange はログを読み込む時間範囲です (From 以上 To 未満、UTC)
+type TimeRange struct {
+	From time.Time
+	To   time.Time
+}
+
+// ParseTimeRange は --from/--to の値から時間範囲を作成します
+// to が空の場合は現在時刻までとします
+// to が日付のみの場合はその日の終わりまでを含みます
+func ParseTimeRange(from string, to string, now time.Time) (*TimeRange, error) {
+	if from == "" {
+		return nil, fmt.Errorf("--from が指定されていません")
+	}
+
+	fromTime, _, err := ParseTime(from, now)
+	if err != nil {
+		return nil, fmt.Errorf("--from の解析に失敗しました: %w", err)
+	}
+
+	toTime := now.UTC()
+	if to != "" {
+		t, dateOnly, err := ParseTime(to, now)
+		if err != nil {
+			return nil, fmt.Errorf("--to の解析に失敗しました: %w", err)
+		}
+		if dateOnly {
+			t = t.AddDate(0, 0, 1)
+		}
+		toTime = t
+	}
+
+	if !fromTime.Before(toTime) {
+		return nil, fmt.Errorf("--from (%s) は --to (%s) より前である必要があります", fromTime.Format(time.RFC3339), toTime.Format(time.RFC3339))
+	}
+
+	return &TimeRange{From: fromTime, To: toTime}, nil
+}
+
+// ParseTime は日時文字列をUTCとして解析します
+// "now"、"today"、"yesterday" も指定できます
+// 日付のみが指定された場合は dateOnly に true を返します
+func ParseTime(s string, now time.Time) (t time.Time, dateOnly bool, err error) {
+	now = now.UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "now":
+		return now, false, nil
+	case "today":
+		return today, true, nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1), true, nil
+	}
+
+	for _, layout := range timeLayouts {
+		parsed, err := time.ParseInLocation(layout, s, time.UTC)
+		if err == nil {
+			return parsed.UTC(), layout == "2006-01-02", nil
+		}
+	}
+
+	return time.Time{}, false, fmt.Errorf("日時の形式が不正です: %s (例: 2025-03-30T22:00, 2025-04-02)", s)
+}
+
+// Days は時間範囲に含まれる日付 (UTCの0時) の一覧を返します
+func (r *TimeRange) Days() []time.Time {
+	var days []time.Time
+	day := time.Date(r.From.Year(), r.From.Month(), r.From.Day(), 0, 0, 0, 0, time.UTC)
+	for day.Before(r.To) {
+		days = append(days, day)
+		day = day.AddDate(0, 0, 1)
+	}
+	return days
+}
+
+// String は時間範囲を表示用の文字列で返します
+func (r *TimeRange) String() string {
+	return fmt.Sprintf("%s 〜 %s (UTC)", r.From.Format("2006-01-02 15:04:05"), r.To.Format("2006-01-02 15:04:05"))
+}
@@ -0,0 +1,107 @@
+package source
+
+import (
+	"testing"
+	"time"
+)
+
+var testNow = time.Date(2025, 4, 10, 12, 34, 56, 0, time.UTC)
+
+func TestParseTime(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected time.Time
+		dateOnly bool
+	}{
+		{"2025-03-30T22:00", time.Date(2025, 3, 30, 22, 0, 0, 0, time.UTC), false},
+		{"2025-03-30T22:00:15", time.Date(2025, 3, 30, 22, 0, 15, 0, time.UTC), false},
+		{"2025-03-30T22", time.Date(2025, 3, 30, 22, 0, 0, 0, time.UTC), false},
+		{"2025-03-30 22:00", time.Date(2025, 3, 30, 22, 0, 0, 0, time.UTC), false},
+		{"2025-03-30T22:00:00+09:00", time.Date(2025, 3, 30, 13, 0, 0, 0, time.UTC), false},
+		{"2025-04-02", time.Date(2025, 4, 2, 0, 0, 0, 0, time.UTC), true},
+		{"now", testNow, false},
+		{"today", time.Date(2025, 4, 10, 0, 0, 0, 0, time.UTC), true},
+		{"yesterday", time.Date(2025, 4, 9, 0, 0, 0, 0, time.UTC), true},
+	}
+
+	for _, tc := range testCases {
+		got, dateOnly, err := ParseTime(tc.input, testNow)
+		if err != nil {
+			t.Errorf("ParseTime(%q) returned error: %v", tc.input, err)
+			continue
+		}
+
+		if !got.Equal(tc.expected) || got.Location() != time.UTC {
+			t.Errorf("ParseTime(%q) = %v, expected %v", tc.input, got, tc.expected)
+		}
+
+		if dateOnly != tc.dateOnly {
+			t.Errorf("ParseTime(%q) dateOnly = %v, expected %v", tc.input, dateOnly, tc.dateOnly)
+		}
+	}
+
+	if _, _, err := ParseTime("2025/03/30", testNow); err == nil {
+		t.Error("ParseTime should fail for invalid format")
+	}
+}
+
+func TestParseTimeRange(t *testing.T) {
+	// 日付のみの --to はその日の終わりまでを含む
+	r, err := ParseTimeRange("2025-03-30T22:00", "2025-04-02", testNow)
+	if err != nil {
+		t.Fatalf("ParseTimeRange returned error: %v", err)
+	}
+
+	if !r.From.Equal(time.Date(2025, 3, 30, 22, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected From: %v", r.From)
+	}
+
+	if !r.To.Equal(time.Date(2025, 4, 3, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected To: %v", r.To)
+	}
+
+	// --to を省略した場合は現在時刻まで
+	r, err = ParseTimeRange("yesterday", "", testNow)
+	if err != nil {
+		t.Fatalf("ParseTimeRange returned error: %v", err)
+	}
+
+	if !r.To.Equal(testNow) {
+		t.Errorf("Expected To to be now, got %v", r.To)
+	}
+}
+
+func TestParseTimeRange_Invalid(t *testing.T) {
+	testCases := []struct {
+		from string
+		to   string
+	}{
+		{"", "2025-04-02"},
+		{"2025-04-02", "2025-03-30"},
+		{"2025-04-02T10:00", "2025-04-02T10:00"},
+		{"invalid", ""},
+		{"2025-03-30", "invalid"},
+	}
+
+	for _, tc := range testCases {
+		if _, err := ParseTimeRange(tc.from, tc.to, testNow); err == nil {
+			t.Errorf("ParseTimeRange(%q, %q) should fail", tc.from, tc.to)
+		}
+	}
+}
+
+func TestTimeRangeDays(t *testing.T) {
+	r := &TimeRange{
+		From: time.Date(2025, 3, 30, 22, 0, 0, 0, time.UTC),
+		To:   time.Date(2025, 4, 2, 0, 0, 0, 0, time.UTC),
+	}
+
+	days := r.Days()
+	if len(days) != 3 {
+		t.Fatalf("Expected 3 days, got %d: %v", len(days), days)
+	}
+
+	if !days[0].Equal(time.Date(2025, 3, 30, 0, 0, 0, 0, time.UTC)) || !days[2].Equal(time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected days: %v", days)
+	}
+}
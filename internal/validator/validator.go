@@ -2,17 +2,39 @@ package validator
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
 	"strings"
+
+	"github.com/naotama2002/dalv/internal/duckdb"
+	"github.com/naotama2002/dalv/internal/source"
 )
 
+// tableNamePattern は指定できるテーブル名の形式です
+var tableNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// bucketNamePattern はAWSのS3バケット名として使用できる形式です
+// 2018年以前に us-east-1 で作成できた大文字やアンダースコアを含む名前も許容します
+var bucketNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{1,253}[A-Za-z0-9]$`)
+
 // S3PathValidator はS3パスの検証を行います
-type S3PathValidator struct{}
+type S3PathValidator struct {
+	// customEndpoint はMinIOなどのS3互換のエンドポイントを使用するかどうかです
+	customEndpoint bool
+}
 
 // NewS3PathValidator は新しいS3パスバリデータを作成します
 func NewS3PathValidator() *S3PathValidator {
 	return &S3PathValidator{}
 }
 
+// NewS3PathValidatorWithEndpoint はS3のエンドポイントを指定して新しいS3パスバリデータを作成します
+// エンドポイントを指定した場合はAWSのバケット名の規則を適用しません
+func NewS3PathValidatorWithEndpoint(endpoint string) *S3PathValidator {
+	return &S3PathValidator{customEndpoint: endpoint != ""}
+}
+
 // ValidateS3Path はS3パスが正しい形式かどうかを検証します
 func (v *S3PathValidator) ValidateS3Path(path string) error {
 	if path == "" {
@@ -23,11 +45,99 @@ func (v *S3PathValidator) ValidateS3Path(path string) error {
 		return fmt.Errorf("S3パスは's3://'で始まる必要があります: %s", path)
 	}
 
-	parts := strings.Split(strings.TrimPrefix(path, "s3://"), "/")
-	if len(parts) < 2 || parts[0] == "" {
+	// バケット全体 (s3://bucket) も指定できる
+	bucket, _, _ := strings.Cut(strings.TrimPrefix(path, "s3://"), "/")
+	if bucket == "" {
 		return fmt.Errorf("無効なS3パス形式です。バケット名が必要です: %s", path)
 	}
 
+	// S3互換のストレージはバケット名の規則が異なるため、空白のみを検証する
+	if v.customEndpoint {
+		if strings.ContainsAny(bucket, " \t\r\n") {
+			return fmt.Errorf("バケット名に空白は使用できません: %s", path)
+		}
+		return nil
+	}
+	if !bucketNamePattern.MatchString(bucket) {
+		return fmt.Errorf("S3バケット名の形式が正しくありません: %s (S3互換のストレージの場合は --s3-endpoint を指定してください)", bucket)
+	}
+
+	return nil
+}
+
+// ValidatePath はログのパスを検証します
+// S3パスの場合は形式を、ローカルパスの場合はファイルが存在するかを検証します
+func (v *S3PathValidator) ValidatePath(path string) error {
+	if source.IsRemote(path) {
+		return v.ValidateS3Path(path)
+	}
+
+	if strings.Contains(path, "://") {
+		return fmt.Errorf("未対応のパス形式です。S3パス (s3://) またはローカルパスを指定してください: %s", path)
+	}
+
+	return v.ValidateLocalPath(path)
+}
+
+// ValidateLocalPath はローカルパスにログファイルが存在するかを検証します
+// globの場合は1つ以上のファイルに一致するかを検証します
+func (v *S3PathValidator) ValidateLocalPath(path string) error {
+	if path == "" {
+		return fmt.Errorf("パスが指定されていません")
+	}
+
+	if !source.IsGlob(path) {
+		info, err := os.Stat(path)
+		if err != nil {
+			return fmt.Errorf("ファイルが見つかりません: %s", path)
+		}
+		if info.IsDir() {
+			return fmt.Errorf("ディレクトリではなくファイルを指定してください: %s", path)
+		}
+		return nil
+	}
+
+	// ** はGoのglobでは扱えないため、globを含まない親ディレクトリの存在のみ確認する
+	if strings.Contains(path, "**") {
+		dir := path[:strings.Index(path, "**")]
+		if strings.HasSuffix(dir, string(filepath.Separator)) {
+			dir = filepath.Clean(dir)
+		} else {
+			dir = filepath.Dir(dir)
+		}
+		if _, err := os.Stat(dir); err != nil {
+			return fmt.Errorf("ディレクトリが見つかりません: %s", dir)
+		}
+		return nil
+	}
+
+	matches, err := filepath.Glob(path)
+	if err != nil {
+		return fmt.Errorf("無効なglobパターンです: %s: %w", path, err)
+	}
+	if len(matches) == 0 {
+		return fmt.Errorf("パターンに一致するファイルが見つかりません: %s", path)
+	}
+
+	return nil
+}
+
+// ValidateTableName はテーブル名を検証します
+// クエリやレポートから引用符なしで参照できるように、英字またはアンダースコアで始まり、
+// 英数字とアンダースコアのみを含むDuckDBの予約語でない名前に限ります
+func (v *S3PathValidator) ValidateTableName(name string) error {
+	if name == "" {
+		return fmt.Errorf("テーブル名が指定されていません")
+	}
+
+	if !tableNamePattern.MatchString(name) {
+		return fmt.Errorf("テーブル名は英字またはアンダースコアで始まり、英数字とアンダースコアのみを含む必要があります: %s", name)
+	}
+
+	if duckdb.IsReservedKeyword(name) {
+		return fmt.Errorf("テーブル名にDuckDBの予約語は使用できません: %s", name)
+	}
+
 	return nil
 }
 
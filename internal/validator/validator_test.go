@@ -1,6 +1,8 @@
 package validator
 
 import (
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -21,6 +23,8 @@ func TestValidateS3Path_ValidPath(t *testing.T) {
 		"s3://bucket/path/to/logs",
 		"s3://bucket/path/to/logs/*.log.gz",
 		"s3://my-bucket-name/AWSLogs/123456789012/elasticloadbalancing/ap-northeast-1/2025/03/03/*.log.gz",
+		"s3://bucket",
+		"s3://Legacy_Bucket/logs/",
 	}
 	
 	for _, path := range validPaths {
@@ -44,6 +48,9 @@ func TestValidateS3Path_InvalidPath(t *testing.T) {
 		{"s3://", "無効なS3パス形式です。バケット名が必要です"},
 		{"s3:///path", "無効なS3パス形式です。バケット名が必要です"},
 		{"http://bucket/path", "S3パスは's3://'で始まる必要があります"},
+		{"s3://localhost:9000/bucket/path", "S3バケット名の形式が正しくありません"},
+		{"s3://my bucket/path", "S3バケット名の形式が正しくありません"},
+		{"s3://-bucket/path", "S3バケット名の形式が正しくありません"},
 	}
 	
 	for _, tc := range invalidPaths {
@@ -60,6 +67,22 @@ func TestValidateS3Path_InvalidPath(t *testing.T) {
 	}
 }
 
+func TestValidateS3Path_CustomEndpoint(t *testing.T) {
+	// S3互換のエンドポイントではAWSのバケット名の規則を適用しない
+	validator := NewS3PathValidatorWithEndpoint("localhost:9000")
+	for _, path := range []string{"s3://dalv-test", "s3://-dev/logs/*.log", "s3://a/logs/"} {
+		if err := validator.ValidateS3Path(path); err != nil {
+			t.Errorf("ValidateS3Path should succeed for '%s' on custom endpoint: %v", path, err)
+		}
+	}
+
+	for _, path := range []string{"s3://", "s3:///path", "s3://my bucket/path"} {
+		if err := validator.ValidateS3Path(path); err == nil {
+			t.Errorf("ValidateS3Path should fail for '%s' on custom endpoint", path)
+		}
+	}
+}
+
 func TestValidateDuckDBInstallation(t *testing.T) {
 	validator := NewS3PathValidator()
 	
@@ -75,3 +98,91 @@ func TestValidateDuckDBInstallation(t *testing.T) {
 func contains(s, sub string) bool {
 	return strings.Contains(s, sub)
 }
+
+func TestValidatePath(t *testing.T) {
+	validator := NewS3PathValidator()
+
+	dir := t.TempDir()
+	logFile := filepath.Join(dir, "app.log.gz")
+	if err := os.WriteFile(logFile, nil, 0644); err != nil {
+		t.Fatalf("Failed to create log file: %v", err)
+	}
+
+	// 有効なパスのテストケース
+	validPaths := []string{
+		"s3://bucket/path/*.log.gz",
+		logFile,
+		filepath.Join(dir, "*.log.gz"),
+		filepath.Join(dir, "**", "*.gz"),
+	}
+
+	for _, path := range validPaths {
+		if err := validator.ValidatePath(path); err != nil {
+			t.Errorf("ValidatePath failed for valid path '%s': %v", path, err)
+		}
+	}
+
+	// 無効なパスのテストケース
+	invalidPaths := []struct {
+		path                string
+		expectedErrContains string
+	}{
+		{"s3://", "無効なS3パス形式です"},
+		{"http://bucket/path", "未対応のパス形式です"},
+		{filepath.Join(dir, "missing.log"), "ファイルが見つかりません"},
+		{filepath.Join(dir, "*.log"), "パターンに一致するファイルが見つかりません"},
+		{filepath.Join(dir, "missing", "**", "*.gz"), "ディレクトリが見つかりません"},
+		{dir, "ディレクトリではなくファイルを指定してください"},
+	}
+
+	for _, tc := range invalidPaths {
+		err := validator.ValidatePath(tc.path)
+		if err == nil {
+			t.Errorf("ValidatePath should fail for invalid path '%s'", tc.path)
+			continue
+		}
+
+		if !contains(err.Error(), tc.expectedErrContains) {
+			t.Errorf("Error message for path '%s' does not contain expected text. Got: '%s', Expected to contain: '%s'",
+				tc.path, err.Error(), tc.expectedErrContains)
+		}
+	}
+}
+
+func TestValidateTableName(t *testing.T) {
+	validator := NewS3PathValidator()
+
+	for _, name := range []string{"alb_logs", "_tmp", "Logs2025", "order_items"} {
+		if err := validator.ValidateTableName(name); err != nil {
+			t.Errorf("ValidateTableName failed for valid name '%s': %v", name, err)
+		}
+	}
+
+	// 無効なテーブル名のテストケース
+	invalidNames := []struct {
+		name                string
+		expectedErrContains string
+	}{
+		{"", "テーブル名が指定されていません"},
+		{"x; DROP TABLE y", "英数字とアンダースコアのみ"},
+		{"1logs", "英字またはアンダースコアで始まり"},
+		{"my-logs", "英数字とアンダースコアのみ"},
+		{`a"b`, "英数字とアンダースコアのみ"},
+		{"main.logs", "英数字とアンダースコアのみ"},
+		{"ログ", "英数字とアンダースコアのみ"},
+		{"select", "予約語"},
+		{"ORDER", "予約語"},
+	}
+
+	for _, tc := range invalidNames {
+		err := validator.ValidateTableName(tc.name)
+		if err == nil {
+			t.Errorf("ValidateTableName should fail for invalid name '%s'", tc.name)
+			continue
+		}
+		if !contains(err.Error(), tc.expectedErrContains) {
+			t.Errorf("ValidateTableName error for '%s' was '%s', expected to contain '%s'",
+				tc.name, err.Error(), tc.expectedErrContains)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package utils
 
 import (
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"time"
@@ -27,11 +28,16 @@ type Logger struct {
 	logger *log.Logger
 }
 
-// NewLogger は新しいロガーを作成します
+// NewLogger は標準エラー出力に出力する新しいロガーを作成します
 func NewLogger(level LogLevel) *Logger {
+	return NewLoggerWithWriter(level, os.Stderr)
+}
+
+// NewLoggerWithWriter は出力先を指定して新しいロガーを作成します
+func NewLoggerWithWriter(level LogLevel, w io.Writer) *Logger {
 	return &Logger{
 		level:  level,
-		logger: log.New(os.Stderr, "", 0),
+		logger: log.New(w, "", 0),
 	}
 }
 